@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var grpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "Creates a gRPC endpoint",
+	Long: `rh: grpc
+Create an endpoint that accepts unary and streaming gRPC calls over h2c, or over TLS with
+--tls. Messages are decoded as JSON with .proto files:
+
+  rh grpc --proto api.proto --proto_path protos
+
+or with a descriptor set, which protoc creates with:
+
+  protoc --include_imports --descriptor_set_out=api.pb api.proto
+
+Without either, messages are shown in the raw protobuf wire format. Listens on port 50051
+unless a port is passed.
+`,
+	Run: grpcCommand,
+}
+
+var (
+	GrpcDescriptorSet string
+	GrpcProtoFiles    []string
+	GrpcProtoPaths    []string
+	GrpcFixture       string
+	GrpcStatus        int
+	GrpcStatusMessage string
+	GrpcTLS           bool
+	GrpcTLSCert       string
+	GrpcTLSKey        string
+)
+
+func init() {
+	rootCmd.AddCommand(grpcCmd)
+
+	grpcCmd.Flags().StringVar(&GrpcDescriptorSet, "descriptor_set", "", "decodes messages with a descriptor set created by protoc --include_imports --descriptor_set_out (example: --descriptor_set api.pb)")
+	grpcCmd.Flags().StringArrayVar(&GrpcProtoFiles, "proto", nil, "decodes messages with a .proto file and the files it imports, can be passed multiple times (example: --proto api.proto)")
+	grpcCmd.Flags().StringArrayVar(&GrpcProtoPaths, "proto_path", nil, "sets a directory imports of --proto files are looked up in, like protoc -I, can be passed multiple times (default is the directory of each file)")
+	grpcCmd.Flags().StringVar(&GrpcFixture, "fixture", "", "JSON file mapping full method names to responses, needs --proto or --descriptor_set (example: --fixture responses.json)")
+	grpcCmd.Flags().IntVar(&GrpcStatus, "status", 0, "sets the gRPC status code of every reply (example: --status 14 for UNAVAILABLE)")
+	grpcCmd.Flags().StringVar(&GrpcStatusMessage, "status_message", "", "sets the message sent with the status")
+	grpcCmd.Flags().BoolVar(&GrpcTLS, "tls", false, "serves HTTP/2 over TLS with a self-signed certificate, unless --tls_cert and --tls_key are passed")
+	grpcCmd.Flags().StringVar(&GrpcTLSCert, "tls_cert", "", "sets the certificate file used for TLS")
+	grpcCmd.Flags().StringVar(&GrpcTLSKey, "tls_key", "", "sets the key file used for TLS")
+}
+
+func grpcCommand(cmd *cobra.Command, args []string) {
+	// gRPC examples and tools usually use 50051.
+	if !cmd.Flags().Changed("port") {
+		Port = 50051
+	}
+
+	grpcServer, err := newGrpcServer()
+	if err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+		return
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("grpc"),
+		Protocol:  grpcServer,
+		Renderers: newRenderers("grpc", newWebRenderer("grpc")),
+	}
+
+	srv.Start()
+}
+
+// newGrpcServer loads the .proto files or descriptor set, fixtures and TLS config passed
+// as flags.
+func newGrpcServer() (*protocol.Grpc, error) {
+	if GrpcDescriptorSet != "" && len(GrpcProtoFiles) > 0 {
+		return nil, errors.New("--proto and --descriptor_set cannot be used together")
+	}
+
+	if GrpcFixture != "" && GrpcDescriptorSet == "" && len(GrpcProtoFiles) == 0 {
+		return nil, errors.New("--fixture needs --proto or --descriptor_set to encode the responses")
+	}
+
+	grpcServer := &protocol.Grpc{
+		Addr:          Address,
+		Port:          Port,
+		Status:        GrpcStatus,
+		StatusMessage: GrpcStatusMessage,
+	}
+
+	if GrpcDescriptorSet != "" {
+		descriptors, err := protocol.LoadGrpcDescriptors(GrpcDescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		grpcServer.Descriptors = descriptors
+	}
+
+	if len(GrpcProtoFiles) > 0 {
+		descriptors, err := protocol.LoadGrpcProtoFiles(GrpcProtoPaths, GrpcProtoFiles...)
+		if err != nil {
+			return nil, err
+		}
+		grpcServer.Descriptors = descriptors
+	}
+
+	if GrpcFixture != "" {
+		fixtures, err := protocol.LoadGrpcFixtures(GrpcFixture)
+		if err != nil {
+			return nil, err
+		}
+		grpcServer.Fixtures = fixtures
+	}
+
+	if GrpcTLS || GrpcTLSCert != "" || GrpcTLSKey != "" {
+		tlsConfig, err := protocol.LoadTLSConfig(GrpcTLSCert, GrpcTLSKey, Address)
+		if err != nil {
+			return nil, err
+		}
+		grpcServer.TLSConfig = tlsConfig
+	}
+
+	return grpcServer, nil
+}
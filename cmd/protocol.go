@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/aaronvb/request_hole/pkg/protocol"
 	"github.com/aaronvb/request_hole/pkg/renderer"
 	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
@@ -25,98 +32,475 @@ Create an endpoint that accepts websocket connections.
 	Run: wsCommand,
 }
 
+var (
+	UnixSocket     string
+	UnixSocketMode string
+)
+
+var (
+	SignatureAlgorithm string
+	SignatureEncoding  string
+	SignatureHeader    string
+	SignaturePrefix    string
+	SignatureProfile   string
+	SignatureReject    bool
+	SignatureSecret    string
+	SignatureTolerance time.Duration
+)
+
+var (
+	ChaosRetryAfter time.Duration
+	ChaosSeed       int64
+	ChaosSpec       string
+)
+
+var (
+	RateLimitAlgorithm string
+	RateLimitKey       string
+	RateLimitSpec      string
+)
+
+var (
+	HttpDelay             string
+	HttpOpenAPI           string
+	HttpResponseBody      string
+	HttpResponseHeaders   []string
+	HttpRules             string
+	HttpSchemas           []string
+	HttpStallAfterHeaders time.Duration
+	HttpStrictValidation  bool
+	HttpTLS               bool
+	HttpTLSCert           string
+	HttpTLSKey            string
+	HttpTrickle           time.Duration
+	HttpTrickleChunk      int
+	HttpUploadDir         string
+)
+
+var (
+	WsAllowedOrigins    []string
+	WsCloseCode         int
+	WsDropAfter         time.Duration
+	WsDropAfterMessages int
+	WsEnableCompression bool
+	WsGraphQL           bool
+	WsGraphQLFixture    string
+	WsGraphQLInterval   time.Duration
+	WsHandshakeDelay    time.Duration
+	WsReadLimit         int64
+	WsRefuseEvery       int
+	WsRejectStatus      int
+	WsSubprotocols      []string
+)
+
 func init() {
 	rootCmd.AddCommand(httpCmd)
 	rootCmd.AddCommand(wsCmd)
+
+	for _, cmd := range []*cobra.Command{httpCmd, wsCmd} {
+		cmd.Flags().StringVar(&UnixSocket, "unix", "", "listens on a Unix domain socket instead of the address and port (example: --unix /tmp/rh.sock)")
+		cmd.Flags().StringVar(&UnixSocketMode, "unix_mode", "0660", "sets the permissions of the Unix domain socket")
+	}
+
+	httpCmd.Flags().BoolVar(&HttpTLS, "tls", false, "serves HTTPS with a self-signed certificate, unless --tls_cert and --tls_key are passed, and negotiates HTTP/2")
+	httpCmd.Flags().StringVar(&HttpTLSCert, "tls_cert", "", "sets the certificate file used for TLS")
+	httpCmd.Flags().StringVar(&HttpTLSKey, "tls_key", "", "sets the key file used for TLS")
+	httpCmd.Flags().StringVar(&HttpResponseBody, "response_body", "", "sets the response body")
+	httpCmd.Flags().StringArrayVar(&HttpResponseHeaders, "response_header", nil, "sets a header on every response, can be repeated (example: --response_header 'Retry-After: 30')")
+
+	// Slow responses
+	httpCmd.Flags().StringVar(&HttpDelay, "delay", "", "delays every response by a fixed duration, a range or a distribution (example: --delay 2s, --delay 100ms-2s, --delay normal:p50=200ms,p99=1s)")
+	httpCmd.Flags().DurationVar(&HttpStallAfterHeaders, "stall_after_headers", 0, "sends the response headers and stalls before the body (example: --stall_after_headers 30s)")
+	httpCmd.Flags().DurationVar(&HttpTrickle, "trickle", 0, "writes the response body in chunks with this delay between them (example: --trickle 500ms)")
+	httpCmd.Flags().IntVar(&HttpTrickleChunk, "trickle_chunk", 1, "sets the size in bytes of each chunk written with --trickle")
+	httpCmd.Flags().StringVar(&HttpOpenAPI, "openapi", "", "validates requests against an OpenAPI 3 spec and answers them with its examples (example: --openapi spec.yaml)")
+	httpCmd.Flags().StringVar(&HttpRules, "rules", "", "JSON file with rules overriding the responses, delay, stall and trickle of matching methods and paths (example: --rules rules.json)")
+	httpCmd.Flags().StringArrayVar(&HttpSchemas, "schema", nil, "validates the JSON bodies of matching requests against a JSON Schema, can be repeated (example: --schema 'POST /orders/*=order.json')")
+	httpCmd.Flags().BoolVar(&HttpStrictValidation, "strict_validation", false, "answers requests which fail --openapi or --schema validation with 422 and the errors")
+
+	// Chaos
+	httpCmd.Flags().StringVar(&ChaosSpec, "chaos", "", "injects faults into a percentage of requests: a 5xx or 429 status code, 5xx, reset or hang (example: --chaos 503=10%,429=5%,reset=1%,hang=1%)")
+	httpCmd.Flags().Int64Var(&ChaosSeed, "chaos_seed", 0, "sets the seed faults are picked with, so a run can be reproduced (default random)")
+	httpCmd.Flags().DurationVar(&ChaosRetryAfter, "chaos_retry_after", 5*time.Second, "sets the Retry-After header sent with injected status codes, 0 does not send it")
+
+	// Rate limit
+	httpCmd.Flags().StringVar(&RateLimitSpec, "rate_limit", "", "answers requests over the limit with 429 (example: --rate_limit 100/1m)")
+	httpCmd.Flags().StringVar(&RateLimitAlgorithm, "rate_limit_algorithm", protocol.FixedWindow, "sets the rate limit algorithm: fixed_window or token_bucket")
+	httpCmd.Flags().StringVar(&RateLimitKey, "rate_limit_key", "ip", "counts requests by ip, path or a header (example: --rate_limit_key header:X-API-Key)")
+
+	// Webhook signatures
+	httpCmd.Flags().StringVar(&SignatureProfile, "verify_signature", "", "verifies the webhook signature of each request with a profile: "+strings.Join(protocol.SignatureProfiles, ", "))
+	httpCmd.Flags().StringVar(&SignatureSecret, "signature_secret", "", "sets the secret signatures are verified with, the auth token for twilio")
+	httpCmd.Flags().DurationVar(&SignatureTolerance, "signature_tolerance", 5*time.Minute, "sets how old a signed timestamp can be for stripe and slack, 0 accepts any timestamp")
+	httpCmd.Flags().StringVar(&SignatureHeader, "signature_header", "", "sets the header the hmac profile reads the signature from (example: --signature_header X-Signature)")
+	httpCmd.Flags().StringVar(&SignatureAlgorithm, "signature_algorithm", "sha256", "sets the hmac profile algorithm: sha1, sha256 or sha512")
+	httpCmd.Flags().StringVar(&SignatureEncoding, "signature_encoding", "hex", "sets the hmac profile signature encoding: hex or base64")
+	httpCmd.Flags().StringVar(&SignaturePrefix, "signature_prefix", "", "sets the prefix before the hmac profile signature (example: --signature_prefix sha256=)")
+	httpCmd.Flags().BoolVar(&SignatureReject, "reject_invalid_signature", false, "answers requests with an invalid or missing signature with 401")
+
+	httpCmd.Flags().StringVar(&HttpUploadDir, "upload_dir", "", "sets the directory files uploaded with multipart forms are stored in, files are not stored by default")
+
+	wsCmd.Flags().StringSliceVar(&WsSubprotocols, "subprotocol", nil, "sets the subprotocols offered during the handshake (example: --subprotocol graphql-ws,mqtt)")
+	wsCmd.Flags().BoolVar(&WsEnableCompression, "compression", false, "negotiates permessage-deflate compression with clients that request it")
+	wsCmd.Flags().Int64Var(&WsReadLimit, "read_limit", 0, "sets the maximum message size in bytes, 0 means no limit")
+	wsCmd.Flags().StringSliceVar(&WsAllowedOrigins, "origin", nil, "only accepts handshakes from these origins, all origins are accepted if empty (example: --origin https://example.com)")
+
+	// Fault injection
+	wsCmd.Flags().DurationVar(&WsHandshakeDelay, "handshake_delay", 0, "delays every handshake (example: --handshake_delay 2s)")
+	wsCmd.Flags().IntVar(&WsRejectStatus, "reject_status", 0, "rejects upgrades with this status, all upgrades are rejected unless --refuse_every is set")
+	wsCmd.Flags().IntVar(&WsRefuseEvery, "refuse_every", 0, "rejects every Nth connection with --reject_status (default 503)")
+	wsCmd.Flags().IntVar(&WsDropAfterMessages, "drop_after_messages", 0, "drops the connection after N messages are received")
+	wsCmd.Flags().DurationVar(&WsDropAfter, "drop_after", 0, "drops the connection after it has been open for the duration (example: --drop_after 30s)")
+	wsCmd.Flags().IntVar(&WsCloseCode, "close_code", 0, "sends a close frame with this code when dropping a connection (example: --close_code 1011)")
+
+	// GraphQL over WebSocket
+	wsCmd.Flags().BoolVar(&WsGraphQL, "graphql", false, "mocks a GraphQL server using the graphql-transport-ws and graphql-ws protocols")
+	wsCmd.Flags().StringVar(&WsGraphQLFixture, "graphql_fixture", "", "JSON file mapping operation names to the results sent to subscribers (example: --graphql_fixture fixtures.json)")
+	wsCmd.Flags().DurationVar(&WsGraphQLInterval, "graphql_interval", 0, "sets the delay between each result sent to subscribers (example: --graphql_interval 1s)")
 }
 
 func httpCommand(cmd *cobra.Command, args []string) {
-	renderers := make([]renderer.Renderer, 0)
-
-	// Collect flag data into struct to use with renderers
-	flagData := server.FlagData{
-		Addr:         Address,
-		BuildInfo:    BuildInfo,
-		Details:      Details,
-		LogFile:      LogFile,
-		Port:         Port,
-		Protocol:     "http",
-		ResponseCode: ResponseCode,
-		Web:          Web,
-		WebAddress:   WebAddress,
-		WebPort:      WebPort,
-	}
-
-	if Web {
-		web := &renderer.Web{
-			Address:      WebAddress,
-			Port:         WebPort,
-			StaticFiles:  StaticFS,
-			RequestAddr:  Address,
-			RequestPort:  Port,
-			ResponseCode: ResponseCode,
-			BuildInfo:    BuildInfo,
-			Protocol:     "http",
-		}
-		renderers = append(renderers, web)
-	} else {
-		printer := &renderer.Printer{Details: Details}
-		renderers = append(renderers, printer)
+	httpServer, flagData, err := newHttpServer(cmd)
+	if err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+		return
 	}
 
-	if LogFile != "" {
-		logger := &renderer.Logger{
-			FilePath: LogFile,
-			Details:  Details,
-			Addr:     Address,
-			Port:     Port,
-			Protocol: "http",
-		}
-		renderers = append(renderers, logger)
+	reloader := &reloader{
+		cmd:    cmd,
+		files:  httpFiles,
+		logger: httpServer,
+		apply: func() error {
+			next, _, err := newHttpServer(cmd)
+			if err != nil {
+				return err
+			}
+
+			return httpServer.Reload(next)
+		},
 	}
+	reloader.start()
 
-	httpServer := &protocol.Http{
-		Addr:         Address,
-		Port:         Port,
-		ResponseCode: ResponseCode,
+	web := newWebRenderer("http")
+	if web != nil {
+		web.SequenceResetter = httpServer
+		web.RateLimiter = httpServer
+		web.ConfigReloader = reloader
+		web.ResponseController = httpServer
 	}
 
 	srv := server.Server{
 		FlagData:  flagData,
 		Protocol:  httpServer,
-		Renderers: renderers,
+		Renderers: newRenderers("http", web),
 	}
 
 	srv.Start()
 }
 
+// newHttpServer returns the http protocol for the flags, with the flag data of its
+// header. It is called again to reload the settings.
+func newHttpServer(cmd *cobra.Command) (*protocol.Http, server.FlagData, error) {
+	flagData := newFlagData("http")
+	flagData.ResponseCode = ResponseCode
+
+	unixSocketMode, err := parseUnixSocketMode()
+	if err != nil {
+		return nil, flagData, err
+	}
+
+	httpServer := &protocol.Http{
+		Addr:           Address,
+		Port:           Port,
+		ResponseCode:   ResponseCode,
+		UnixSocket:     UnixSocket,
+		UnixSocketMode: unixSocketMode,
+		UploadDir:      HttpUploadDir,
+		ResponseBody:   HttpResponseBody,
+		Timing: protocol.HttpTiming{
+			StallAfterHeaders: HttpStallAfterHeaders,
+			Trickle:           HttpTrickle,
+			TrickleChunk:      HttpTrickleChunk,
+		},
+	}
+
+	for _, header := range HttpResponseHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, flagData, fmt.Errorf("invalid --response_header %q, expected a name and a value such as 'Retry-After: 30'", header)
+		}
+
+		if httpServer.ResponseHeaders == nil {
+			httpServer.ResponseHeaders = make(map[string]string)
+		}
+
+		httpServer.ResponseHeaders[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	if HttpDelay != "" {
+		delay, err := protocol.ParseLatency(HttpDelay)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.Timing.Delay = delay
+	}
+
+	if ChaosSpec != "" {
+		seed := ChaosSeed
+		if !cmd.Flags().Changed("chaos_seed") {
+			seed = time.Now().UnixNano()
+		}
+
+		chaos, err := protocol.NewChaos(ChaosSpec, seed, ChaosRetryAfter)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.Chaos = chaos
+		flagData.Chaos = chaos.String()
+	}
+
+	if RateLimitSpec != "" {
+		rateLimit, err := protocol.NewRateLimit(RateLimitSpec, RateLimitAlgorithm, RateLimitKey)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.RateLimit = rateLimit
+		flagData.RateLimit = rateLimit.String()
+	}
+
+	if HttpOpenAPI != "" {
+		spec, err := protocol.LoadOpenAPI(HttpOpenAPI)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.OpenAPI = spec
+		flagData.OpenAPI = spec.String()
+	}
+
+	for _, spec := range HttpSchemas {
+		route, err := protocol.LoadSchemaRoute(spec)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.Schemas = append(httpServer.Schemas, route)
+		flagData.Schemas = append(flagData.Schemas, route.String())
+	}
+
+	httpServer.StrictValidation = HttpStrictValidation
+	flagData.StrictValidation = HttpStrictValidation
+
+	if HttpRules != "" {
+		rules, err := protocol.LoadHttpRules(HttpRules)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.Rules = rules
+	}
+
+	if SignatureProfile != "" {
+		verifier := &protocol.SignatureVerifier{
+			Profile:   SignatureProfile,
+			Secret:    SignatureSecret,
+			Tolerance: SignatureTolerance,
+			Header:    SignatureHeader,
+			Algorithm: SignatureAlgorithm,
+			Encoding:  SignatureEncoding,
+			Prefix:    SignaturePrefix,
+		}
+
+		if err := verifier.Validate(); err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.Signature = verifier
+		httpServer.RejectInvalidSignature = SignatureReject
+	}
+
+	if HttpTLS || HttpTLSCert != "" || HttpTLSKey != "" {
+		tlsConfig, err := protocol.LoadTLSConfig(HttpTLSCert, HttpTLSKey, Address)
+		if err != nil {
+			return nil, flagData, err
+		}
+
+		httpServer.TLSConfig = tlsConfig
+		flagData.Protocol = "https"
+	}
+
+	return httpServer, flagData, nil
+}
+
+// httpFiles returns the files the settings of the http protocol are loaded from.
+func httpFiles() []string {
+	files := []string{configPath, HttpRules, HttpOpenAPI}
+	for _, spec := range HttpSchemas {
+		if i := strings.Index(spec, "="); i >= 0 {
+			files = append(files, spec[i+1:])
+		}
+	}
+
+	return files
+}
+
 func wsCommand(cmd *cobra.Command, args []string) {
-	renderers := make([]renderer.Renderer, 0)
+	wsServer, err := newWsServer()
+	if err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+		return
+	}
+
+	reloader := &reloader{
+		cmd:    cmd,
+		files:  wsFiles,
+		logger: wsServer,
+		apply: func() error {
+			next, err := newWsServer()
+			if err != nil {
+				return err
+			}
+
+			return wsServer.Reload(next)
+		},
+	}
+	reloader.start()
+
+	web := newWebRenderer("ws")
+	if web != nil {
+		web.ConfigReloader = reloader
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("ws"),
+		Protocol:  wsServer,
+		Renderers: newRenderers("ws", web),
+	}
+
+	srv.Start()
+}
+
+// newWsServer returns the ws protocol for the flags. It is called again to reload the
+// settings.
+func newWsServer() (*protocol.Ws, error) {
+	var fixtures protocol.GraphQLFixtures
+	if WsGraphQLFixture != "" {
+		f, err := protocol.LoadGraphQLFixtures(WsGraphQLFixture)
+		if err != nil {
+			return nil, err
+		}
+
+		fixtures = f
+	}
+
+	unixSocketMode, err := parseUnixSocketMode()
+	if err != nil {
+		return nil, err
+	}
 
-	// Collect flag data into struct to use with renderers
-	flagData := server.FlagData{
+	if err := validateWsFaults(); err != nil {
+		return nil, err
+	}
+
+	wsServer := &protocol.Ws{
+		Addr:              Address,
+		Port:              Port,
+		UnixSocket:        UnixSocket,
+		UnixSocketMode:    unixSocketMode,
+		Subprotocols:      WsSubprotocols,
+		EnableCompression: WsEnableCompression,
+		ReadLimit:         WsReadLimit,
+		AllowedOrigins:    WsAllowedOrigins,
+		HandshakeDelay:    WsHandshakeDelay,
+		RejectStatus:      WsRejectStatus,
+		RefuseEvery:       WsRefuseEvery,
+		DropAfterMessages: WsDropAfterMessages,
+		DropAfter:         WsDropAfter,
+		CloseCode:         WsCloseCode,
+		GraphQL:           WsGraphQL || WsGraphQLFixture != "",
+		GraphQLFixtures:   fixtures,
+		GraphQLInterval:   WsGraphQLInterval,
+	}
+
+	return wsServer, nil
+}
+
+// wsFiles returns the files the settings of the ws protocol are loaded from.
+func wsFiles() []string {
+	return []string{configPath, WsGraphQLFixture}
+}
+
+// newFlagData collects the flag data into a struct to use with the server header.
+func newFlagData(protocolName string) server.FlagData {
+	return server.FlagData{
 		Addr:       Address,
 		BuildInfo:  BuildInfo,
+		Config:     configSource,
 		Details:    Details,
 		LogFile:    LogFile,
 		Port:       Port,
-		Protocol:   "ws",
+		Protocol:   protocolName,
+		ReadyFile:  ReadyFile,
+		UnixSocket: UnixSocket,
 		Web:        Web,
 		WebAddress: WebAddress,
 		WebPort:    WebPort,
 	}
+}
 
-	if Web {
-		web := &renderer.Web{
-			Address:      WebAddress,
-			Port:         WebPort,
-			StaticFiles:  StaticFS,
-			RequestAddr:  Address,
-			RequestPort:  Port,
-			ResponseCode: ResponseCode,
-			BuildInfo:    BuildInfo,
-			Protocol:     "ws",
-		}
+// parseUnixSocketMode parses the octal permissions passed with --unix_mode.
+func parseUnixSocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(UnixSocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid --unix_mode %q, expected permissions such as 0660", UnixSocketMode)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// validateWsFaults checks the status passed with --reject_status is an error status, and
+// the code passed with --close_code is one which can be sent in a close frame.
+func validateWsFaults() error {
+	if WsRejectStatus != 0 && (WsRejectStatus < 400 || WsRejectStatus > 599) {
+		return fmt.Errorf("invalid --reject_status %d, expected a status between 400 and 599", WsRejectStatus)
+	}
+
+	switch code := WsCloseCode; {
+	case code == 0, code >= 1000 && code <= 1003, code >= 1007 && code <= 1014, code >= 3000 && code <= 4999:
+		return nil
+	default:
+		return fmt.Errorf("invalid --close_code %d, expected 1000-1003, 1007-1014 or 3000-4999", code)
+	}
+}
+
+// newWebRenderer returns the web renderer if the web flag is passed, otherwise nil.
+// Commands can connect it to their protocol before the server starts.
+func newWebRenderer(protocolName string) *renderer.Web {
+	if !Web {
+		return nil
+	}
+
+	return &renderer.Web{
+		Address:      WebAddress,
+		Port:         WebPort,
+		StaticFiles:  StaticFS,
+		RequestAddr:  Address,
+		RequestPort:  Port,
+		ResponseCode: ResponseCode,
+		BuildInfo:    BuildInfo,
+		Protocol:     protocolName,
+	}
+}
+
+// newRenderers returns the renderers for the flags passed. The web renderer replaces
+// the printer, and the logger is added if a log file is passed.
+func newRenderers(protocolName string, web *renderer.Web) []renderer.Renderer {
+	renderers := make([]renderer.Renderer, 0)
+
+	if web != nil {
 		renderers = append(renderers, web)
 	} else {
 		printer := &renderer.Printer{Details: Details}
@@ -125,25 +509,15 @@ func wsCommand(cmd *cobra.Command, args []string) {
 
 	if LogFile != "" {
 		logger := &renderer.Logger{
-			FilePath: LogFile,
-			Details:  Details,
-			Addr:     Address,
-			Port:     Port,
-			Protocol: "ws",
+			FilePath:   LogFile,
+			Details:    Details,
+			Addr:       Address,
+			Port:       Port,
+			UnixSocket: UnixSocket,
+			Protocol:   protocolName,
 		}
 		renderers = append(renderers, logger)
 	}
 
-	wsServer := &protocol.Ws{
-		Addr: Address,
-		Port: Port,
-	}
-
-	srv := server.Server{
-		FlagData:  flagData,
-		Protocol:  wsServer,
-		Renderers: renderers,
-	}
-
-	srv.Start()
+	return renderers
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aaronvb/request_hole/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// reloader reloads the settings of a running protocol when the config or one of the
+// files it loads changes, on SIGHUP, and from the web UI.
+type reloader struct {
+	cmd *cobra.Command
+
+	// files returns the files the settings are loaded from, which are watched.
+	files func() []string
+
+	// apply builds the protocol from the flags and replaces the settings of the running
+	// one.
+	apply func() error
+
+	// logger logs the result of each reload to the renderers.
+	logger interface {
+		LogReload(source string, err error)
+	}
+
+	watcher config.Watcher
+	mu      sync.Mutex
+}
+
+// start watches the files and listens for SIGHUP. Without a config file or a file it
+// loads there is nothing to reload, so SIGHUP keeps its default of ending the process.
+func (r *reloader) start() {
+	files := r.watched()
+	if len(files) == 0 {
+		return
+	}
+
+	r.watcher.Interval = time.Second
+	r.watcher.Watch(files)
+	go r.watcher.Run(nil, func(files []string) {
+		r.ReloadConfig()
+	})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			r.ReloadConfig()
+		}
+	}()
+}
+
+// ReloadConfig applies the config file and environment to the flags which were not
+// passed again, and replaces the settings of the protocol. The settings are kept if any
+// of them is invalid, and the error is returned.
+func (r *reloader) ReloadConfig() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := config.Reset(r.cmd.Flags(), passedFlags)
+	if err == nil {
+		err = applyConfig(r.cmd)
+	}
+
+	if err == nil {
+		err = r.apply()
+	}
+
+	files := r.watched()
+	r.watcher.Watch(files)
+	r.logger.LogReload(strings.Join(files, ", "), err)
+
+	return err
+}
+
+// watched returns the files the settings are loaded from which are set.
+func (r *reloader) watched() []string {
+	files := make([]string, 0)
+	for _, file := range r.files() {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
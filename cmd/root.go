@@ -2,17 +2,24 @@
 package cmd
 
 import (
+	"fmt"
 	"net/http"
+	"os"
 
+	"github.com/aaronvb/request_hole/pkg/config"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 var (
 	Address      string
 	BuildInfo    map[string]string
+	ConfigFile   string
 	Details      bool
 	LogFile      string
 	Port         int
+	Profile      string
+	ReadyFile    string
 	ResponseCode int
 	Web          bool
 	WebAddress   string
@@ -25,8 +32,21 @@ var rootCmd = &cobra.Command{
 	Short: "A CLI for an ephemeral API endpoint",
 	Long: `rh: Request Hole
 This CLI tool will let you create a temporary API endpoint for testing purposes.`,
+	PersistentPreRunE: loadConfig,
 }
 
+var (
+	// configPath is the config file the settings were loaded from, empty if none was.
+	configPath string
+
+	// configSource describes where the settings were loaded from, shown in the header.
+	configSource string
+
+	// passedFlags are the flags passed on the command line, which the config does not
+	// override when it is reloaded.
+	passedFlags map[string]bool
+)
+
 func Execute(buildInfo map[string]string, staticFS http.FileSystem) error {
 	BuildInfo = buildInfo
 	StaticFS = staticFS
@@ -39,9 +59,73 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&ResponseCode, "response_code", "r", 200, "sets the response code")
 	rootCmd.PersistentFlags().BoolVar(&Details, "details", false, "shows header details in the request")
 	rootCmd.PersistentFlags().StringVar(&LogFile, "log", "", "writes incoming requests to the specified log file (example: --log rh.log)")
+	rootCmd.PersistentFlags().StringVar(&ConfigFile, "config", "", "loads settings from a YAML file, ./.rh.yaml or $XDG_CONFIG_HOME/rh/config.yaml are loaded if it is not passed (example: --config rh.yaml)")
+	rootCmd.PersistentFlags().StringVar(&Profile, "profile", "", "applies the settings of a profile in the config file (example: --profile stripe)")
+	rootCmd.PersistentFlags().StringVar(&ReadyFile, "ready_file", "", "writes the listening URLs as JSON to the file once rh is ready, use with --port 0 to pick a free port (example: --ready_file rh.json)")
 
 	// Web server renderer
 	rootCmd.PersistentFlags().BoolVar(&Web, "web", false, "runs the web UI to show incoming requests")
 	rootCmd.PersistentFlags().StringVar(&WebAddress, "web_address", "localhost", "sets the address for the web UI")
 	rootCmd.PersistentFlags().IntVar(&WebPort, "web_port", 8081, "sets the port for the web UI")
 }
+
+// loadConfig sets the flags which were not passed from the config file, its profile and
+// RH_ environment variables.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	passedFlags = config.Passed(cmd.Flags())
+
+	if err := applyConfig(cmd); err != nil {
+		return configError(cmd, err)
+	}
+
+	return nil
+}
+
+// applyConfig applies the config file, its profile and the environment to the flags. The
+// config file and profile can also be set with RH_CONFIG and RH_PROFILE.
+func applyConfig(cmd *cobra.Command) error {
+	path := ConfigFile
+	if path == "" {
+		path = os.Getenv(config.EnvPrefix + "CONFIG")
+	}
+
+	if path == "" {
+		path = config.Find()
+	}
+
+	profile := Profile
+	if profile == "" {
+		profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+
+	var c *config.Config
+	if path != "" {
+		var err error
+		c, err = config.Load(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := config.Apply(c, cmd.Flags(), cmd.Name(), profile, os.Environ()); err != nil {
+		return err
+	}
+
+	configPath = path
+	configSource = path
+	if profile != "" {
+		configSource = fmt.Sprintf("%s (profile %s)", path, profile)
+	}
+
+	return nil
+}
+
+// configError prints the error, and returns it without cobra printing it again with the
+// usage.
+func configError(cmd *cobra.Command, err error) error {
+	pterm.Error.WithShowLineNumber(false).Println(err)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	return err
+}
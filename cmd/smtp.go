@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"crypto/tls"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var smtpCmd = &cobra.Command{
+	Use:   "smtp",
+	Short: "Creates an SMTP endpoint",
+	Long: `rh: smtp
+Create an SMTP endpoint that accepts any sender, recipient and credentials, and shows each
+message instead of delivering it. Listens on port 1025 unless a port is passed.
+`,
+	Run: smtpCommand,
+}
+
+var (
+	SmtpHostname string
+	SmtpMaxSize  int
+	SmtpStartTLS bool
+	SmtpTLSCert  string
+	SmtpTLSKey   string
+)
+
+func init() {
+	rootCmd.AddCommand(smtpCmd)
+
+	smtpCmd.Flags().StringVar(&SmtpHostname, "hostname", "localhost", "sets the hostname the server greets clients with")
+	smtpCmd.Flags().IntVar(&SmtpMaxSize, "max_size", 10<<20, "sets the maximum message size in bytes, 0 means no limit")
+	smtpCmd.Flags().BoolVar(&SmtpStartTLS, "starttls", false, "offers STARTTLS with a self-signed certificate, unless --tls_cert and --tls_key are passed")
+	smtpCmd.Flags().StringVar(&SmtpTLSCert, "tls_cert", "", "sets the certificate file used for STARTTLS")
+	smtpCmd.Flags().StringVar(&SmtpTLSKey, "tls_key", "", "sets the key file used for STARTTLS")
+}
+
+func smtpCommand(cmd *cobra.Command, args []string) {
+	// Mail clients in development usually send to 1025.
+	if !cmd.Flags().Changed("port") {
+		Port = 1025
+	}
+
+	var tlsConfig *tls.Config
+	if SmtpStartTLS || SmtpTLSCert != "" || SmtpTLSKey != "" {
+		c, err := protocol.LoadTLSConfig(SmtpTLSCert, SmtpTLSKey, SmtpHostname, Address)
+		if err != nil {
+			pterm.Error.WithShowLineNumber(false).Println(err)
+			return
+		}
+
+		tlsConfig = c
+	}
+
+	smtpServer := &protocol.Smtp{
+		Addr:      Address,
+		Port:      Port,
+		Hostname:  SmtpHostname,
+		TLSConfig: tlsConfig,
+		MaxSize:   SmtpMaxSize,
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("smtp"),
+		Protocol:  smtpServer,
+		Renderers: newRenderers("smtp", newWebRenderer("smtp")),
+	}
+
+	srv.Start()
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var sseCmd = &cobra.Command{
+	Use:   "sse",
+	Short: "Creates a Server-Sent Events endpoint",
+	Long: `rh: sse
+Create an endpoint that serves Server-Sent Events to connecting clients.
+Events can be sent on a schedule, from a fixture file, or from the web UI.
+`,
+	Run: sseCommand,
+}
+
+var (
+	SseData     string
+	SseEvent    string
+	SseFixture  string
+	SseInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(sseCmd)
+
+	sseCmd.Flags().DurationVar(&SseInterval, "interval", 0, "sends the scheduled event to all clients on each interval (example: --interval 5s)")
+	sseCmd.Flags().StringVar(&SseEvent, "event", "", "sets the event name of the scheduled event")
+	sseCmd.Flags().StringVar(&SseData, "data", "ping", "sets the data of the scheduled event")
+	sseCmd.Flags().StringVar(&SseFixture, "fixture", "", "JSON file with a list of events sent to each client when it connects (example: --fixture events.json)")
+}
+
+func sseCommand(cmd *cobra.Command, args []string) {
+	if err := (protocol.SseEvent{Event: SseEvent}).Validate(); err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+		return
+	}
+
+	var fixture []protocol.SseFixtureEvent
+	if SseFixture != "" {
+		f, err := protocol.LoadSseFixture(SseFixture)
+		if err != nil {
+			pterm.Error.WithShowLineNumber(false).Println(err)
+			return
+		}
+
+		fixture = f
+	}
+
+	sseServer := &protocol.Sse{
+		Addr:     Address,
+		Port:     Port,
+		Interval: SseInterval,
+		Event:    SseEvent,
+		Data:     SseData,
+		Fixture:  fixture,
+	}
+
+	web := newWebRenderer("sse")
+	if web != nil {
+		web.EventSender = sseServer
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("sse"),
+		Protocol:  sseServer,
+		Renderers: newRenderers("sse", web),
+	}
+
+	srv.Start()
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var statsdCmd = &cobra.Command{
+	Use:   "statsd",
+	Short: "Creates a StatsD endpoint",
+	Long: `rh: statsd
+Create an endpoint that receives StatsD and DogStatsD metrics over UDP and shows each
+metric with its value, type, sample rate and tags. Listens on port 8125 unless a port is passed.
+`,
+	Run: statsdCommand,
+}
+
+var StatsdWindow time.Duration
+
+func init() {
+	rootCmd.AddCommand(statsdCmd)
+
+	statsdCmd.Flags().DurationVar(&StatsdWindow, "window", time.Minute, "sets how far back the metrics aggregate in the web UI goes, 0 aggregates every metric")
+}
+
+func statsdCommand(cmd *cobra.Command, args []string) {
+	// StatsD clients send to 8125 by default.
+	if !cmd.Flags().Changed("port") {
+		Port = 8125
+	}
+
+	statsdServer := &protocol.Statsd{
+		Addr:   Address,
+		Port:   Port,
+		Window: StatsdWindow,
+	}
+
+	web := newWebRenderer("statsd")
+	if web != nil {
+		web.MetricsAggregator = statsdServer
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("statsd"),
+		Protocol:  statsdServer,
+		Renderers: newRenderers("statsd", web),
+	}
+
+	srv.Start()
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var tcpCmd = &cobra.Command{
+	Use:   "tcp",
+	Short: "Creates a raw TCP endpoint",
+	Long: `rh: tcp
+Create an endpoint that accepts raw TCP connections and shows each line or chunk received.
+`,
+	Run: tcpCommand,
+}
+
+var (
+	TcpEcho  bool
+	TcpHex   bool
+	TcpReply string
+	TcpSplit string
+)
+
+func init() {
+	rootCmd.AddCommand(tcpCmd)
+
+	tcpCmd.Flags().StringVar(&TcpSplit, "split", "line", "splits incoming data into a message per line or per chunk read (line, chunk)")
+	tcpCmd.Flags().BoolVar(&TcpHex, "hex", false, "shows messages as a hex dump")
+	tcpCmd.Flags().BoolVar(&TcpEcho, "echo", false, "writes each message back to the client")
+	tcpCmd.Flags().StringVar(&TcpReply, "reply", "", "writes a fixed reply after each message, escape sequences are interpreted (example: --reply '+OK\\r\\n')")
+}
+
+func tcpCommand(cmd *cobra.Command, args []string) {
+	if TcpSplit != "line" && TcpSplit != "chunk" {
+		pterm.Error.WithShowLineNumber(false).Printfln("Invalid split %q, must be line or chunk", TcpSplit)
+		return
+	}
+
+	tcpServer := &protocol.Tcp{
+		Addr:  Address,
+		Port:  Port,
+		Split: TcpSplit,
+		Hex:   TcpHex,
+		Echo:  TcpEcho,
+		Reply: TcpReply,
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("tcp"),
+		Protocol:  tcpServer,
+		Renderers: newRenderers("tcp", newWebRenderer("tcp")),
+	}
+
+	srv.Start()
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var udpCmd = &cobra.Command{
+	Use:   "udp",
+	Short: "Creates a UDP endpoint",
+	Long: `rh: udp
+Create an endpoint that receives UDP datagrams and shows each one with its source address.
+`,
+	Run: udpCommand,
+}
+
+var (
+	UdpEcho  bool
+	UdpHex   bool
+	UdpReply string
+)
+
+func init() {
+	rootCmd.AddCommand(udpCmd)
+
+	udpCmd.Flags().BoolVar(&UdpHex, "hex", false, "shows datagrams as a hex dump")
+	udpCmd.Flags().BoolVar(&UdpEcho, "echo", false, "sends each datagram back to its source")
+	udpCmd.Flags().StringVar(&UdpReply, "reply", "", "sends a fixed reply to the source of each datagram, escape sequences are interpreted")
+}
+
+func udpCommand(cmd *cobra.Command, args []string) {
+	udpServer := &protocol.Udp{
+		Addr:  Address,
+		Port:  Port,
+		Hex:   UdpHex,
+		Echo:  UdpEcho,
+		Reply: UdpReply,
+	}
+
+	srv := server.Server{
+		FlagData:  newFlagData("udp"),
+		Protocol:  udpServer,
+		Renderers: newRenderers("udp", newWebRenderer("udp")),
+	}
+
+	srv.Start()
+}
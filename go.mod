@@ -6,13 +6,20 @@ require (
 	github.com/99designs/gqlgen v0.14.0
 	github.com/aaronvb/logparams v1.3.0
 	github.com/aaronvb/logrequest v1.0.0
+	github.com/andybalholm/brotli v1.0.3
 	github.com/google/uuid v1.2.0
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/websocket v1.4.2
+	github.com/jhump/protoreflect v1.11.0
+	github.com/klauspost/compress v1.13.6
 	github.com/pterm/pterm v0.12.18
 	github.com/rs/cors v1.6.0
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 	github.com/vektah/gqlparser/v2 v2.2.0
-	golang.org/x/sys v0.0.0-20210507161434-a76c4d0a0096 // indirect
+	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
+	golang.org/x/sys v0.0.0-20210507161434-a76c4d0a0096
 	golang.org/x/term v0.0.0-20210503060354-a79de5458b56 // indirect
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
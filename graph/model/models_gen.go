@@ -2,11 +2,18 @@
 
 package model
 
+import (
+	"github.com/aaronvb/request_hole/pkg/protocol"
+)
+
 type ServerInfo struct {
-	RequestAddress string            `json:"request_address"`
-	RequestPort    int               `json:"request_port"`
-	WebPort        int               `json:"web_port"`
-	ResponseCode   int               `json:"response_code"`
-	BuildInfo      map[string]string `json:"build_info"`
-	Protocol       string            `json:"protocol"`
+	RequestAddress  string                       `json:"request_address"`
+	RequestPort     int                          `json:"request_port"`
+	WebPort         int                          `json:"web_port"`
+	ResponseCode    int                          `json:"response_code"`
+	BuildInfo       map[string]string            `json:"build_info"`
+	Protocol        string                       `json:"protocol"`
+	RateLimits      []*protocol.RateLimitCounter `json:"rate_limits"`
+	Response        *protocol.ResponseSettings   `json:"response"`
+	DroppedRequests int                          `json:"dropped_requests"`
 }
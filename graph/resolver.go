@@ -1,10 +1,9 @@
 package graph
 
 import (
-	"sync"
-
 	"github.com/aaronvb/request_hole/graph/model"
 	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/store"
 )
 
 // This file will not be regenerated automatically.
@@ -12,8 +11,13 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	RequestPayloads        *[]*protocol.RequestPayload
-	RequestPayloadObserver *map[string]chan *protocol.RequestPayload
-	Info                   *model.ServerInfo
-	mu                     sync.Mutex
+	RequestStore       *store.Requests
+	Hub                *store.Hub
+	Info               *model.ServerInfo
+	Events             protocol.EventSender
+	MetricsAggregator  protocol.MetricsAggregator
+	SequenceResetter   protocol.SequenceResetter
+	RateLimiter        protocol.RateLimiter
+	ConfigReloader     protocol.ConfigReloader
+	ResponseController protocol.ResponseController
 }
@@ -5,44 +5,150 @@ package graph
 
 import (
 	"context"
+	"errors"
 
 	"github.com/aaronvb/request_hole/graph/generated"
 	"github.com/aaronvb/request_hole/graph/model"
 	"github.com/aaronvb/request_hole/pkg/protocol"
-	"github.com/google/uuid"
 )
 
 func (r *mutationResolver) ClearRequests(ctx context.Context) (bool, error) {
-	reqs := *r.RequestPayloads
-	*r.RequestPayloads = reqs[:0]
+	r.RequestStore.Clear()
 	return true, nil
 }
 
+func (r *mutationResolver) SendEvent(ctx context.Context, input protocol.SseEvent) (int, error) {
+	if r.Events == nil {
+		return 0, errors.New("protocol does not support sending events")
+	}
+
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	return r.Events.SendEvent(input), nil
+}
+
+func (r *mutationResolver) ResetSequence(ctx context.Context, route *string) (int, error) {
+	if r.SequenceResetter == nil {
+		return 0, errors.New("protocol does not support response sequences")
+	}
+
+	if route == nil {
+		return r.SequenceResetter.ResetSequence(""), nil
+	}
+
+	return r.SequenceResetter.ResetSequence(*route), nil
+}
+
+func (r *mutationResolver) ReloadConfig(ctx context.Context) (bool, error) {
+	if r.ConfigReloader == nil {
+		return false, errors.New("protocol does not support reloading its config")
+	}
+
+	if err := r.ConfigReloader.ReloadConfig(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) UpdateResponse(ctx context.Context, input protocol.ResponseUpdate) (*protocol.ResponseSettings, error) {
+	if r.ResponseController == nil {
+		return nil, errors.New("protocol does not support changing its response")
+	}
+
+	response, err := r.ResponseController.UpdateResponse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (r *mutationResolver) ResetResponse(ctx context.Context) (*protocol.ResponseSettings, error) {
+	if r.ResponseController == nil {
+		return nil, errors.New("protocol does not support changing its response")
+	}
+
+	response := r.ResponseController.ResetResponse()
+
+	return &response, nil
+}
+
+func (r *mutationResolver) SetPaused(ctx context.Context, paused bool) (*protocol.ResponseSettings, error) {
+	if r.ResponseController == nil {
+		return nil, errors.New("protocol does not support pausing")
+	}
+
+	response := r.ResponseController.SetPaused(paused)
+
+	return &response, nil
+}
+
 func (r *queryResolver) Requests(ctx context.Context) ([]*protocol.RequestPayload, error) {
-	return *r.RequestPayloads, nil
+	return r.RequestStore.Snapshot(), nil
 }
 
 func (r *queryResolver) ServerInfo(ctx context.Context) (*model.ServerInfo, error) {
-	return r.Info, nil
+	info := *r.Info
+	info.DroppedRequests = int(r.Hub.Stats().Dropped)
+	if r.ResponseController != nil {
+		response := r.ResponseController.Response()
+		info.Response = &response
+		info.ResponseCode = response.StatusCode
+	}
+
+	info.RateLimits = make([]*protocol.RateLimitCounter, 0)
+	if r.RateLimiter == nil {
+		return &info, nil
+	}
+
+	for _, counter := range r.RateLimiter.RateLimits() {
+		c := counter
+		info.RateLimits = append(info.RateLimits, &c)
+	}
+
+	return &info, nil
+}
+
+func (r *queryResolver) Metrics(ctx context.Context) ([]*protocol.StatsdAggregate, error) {
+	metrics := make([]*protocol.StatsdAggregate, 0)
+	if r.MetricsAggregator == nil {
+		return metrics, nil
+	}
+
+	for _, metric := range r.MetricsAggregator.Metrics() {
+		m := metric
+		metrics = append(metrics, &m)
+	}
+
+	return metrics, nil
+}
+
+func (r *queryResolver) Sequences(ctx context.Context) ([]*protocol.ResponseSequence, error) {
+	sequences := make([]*protocol.ResponseSequence, 0)
+	if r.SequenceResetter == nil {
+		return sequences, nil
+	}
+
+	for _, sequence := range r.SequenceResetter.Sequences() {
+		s := sequence
+		sequences = append(sequences, &s)
+	}
+
+	return sequences, nil
 }
 
 func (r *subscriptionResolver) Request(ctx context.Context) (<-chan *protocol.RequestPayload, error) {
-	// Generate UUID for browser connection
-	id := uuid.New().String()
+	id, requests := r.Hub.Subscribe()
 
-	// Go routine to handle deleting channel if browser connection is closed.
+	// Go routine to handle unsubscribing if browser connection is closed.
 	go func() {
 		<-ctx.Done()
-		r.mu.Lock()
-		delete(*r.RequestPayloadObserver, id)
-		r.mu.Unlock()
+		r.Hub.Unsubscribe(id)
 	}()
 
-	requests := make(chan *protocol.RequestPayload, 1)
-	r.mu.Lock()
-	(*r.RequestPayloadObserver)[id] = requests
-	r.mu.Unlock()
-
 	return requests, nil
 }
 
@@ -0,0 +1,328 @@
+// Package config loads the settings of rh from a YAML config file, its profiles and
+// environment variables, and applies them to the flags which were not passed.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the config file discovered in the working directory.
+const File = ".rh.yaml"
+
+// EnvPrefix is the prefix of the environment variables flags are read from, ie: RH_PORT
+// for --port.
+const EnvPrefix = "RH_"
+
+// Config are the settings of a config file. Settings are flag names with their values:
+//
+//	port: 9000
+//	web: true
+//	http:
+//	  rules: rules.json
+//	profiles:
+//	  stripe:
+//	    port: 9001
+//	    http:
+//	      verify_signature: stripe
+//
+// Settings at the top apply to every command which has the flag, and the settings in a
+// section named after a command apply only to it. Profiles have settings of the same
+// shape, which override the others.
+type Config struct {
+	// Path is the file the config was loaded from.
+	Path string
+
+	settings map[string]interface{}
+	profiles map[string]map[string]interface{}
+}
+
+// Find returns the config file which is used when none is passed: ./.rh.yaml, or else
+// $XDG_CONFIG_HOME/rh/config.yaml, which defaults to ~/.config/rh/config.yaml. Returns
+// an empty string if neither exists.
+func Find() string {
+	candidates := []string{File}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+
+	if dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "rh", "config.yaml"))
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// Load loads a config file.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	c := &Config{Path: path, settings: settings, profiles: map[string]map[string]interface{}{}}
+
+	if raw, ok := settings["profiles"]; ok {
+		profiles, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("config %s: expected profiles to be a map of profile names to settings", path)
+		}
+
+		for name, raw := range profiles {
+			profile, ok := raw.(map[string]interface{})
+			if !ok && raw != nil {
+				return nil, fmt.Errorf("config %s: expected profile %s to be a map of settings", path, name)
+			}
+
+			c.profiles[name] = profile
+		}
+
+		delete(settings, "profiles")
+	}
+
+	return c, nil
+}
+
+// Profiles returns the names of the profiles, sorted.
+func (c *Config) Profiles() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Apply sets the flags of the command which were not passed on the command line: from the
+// settings of the config, then of the profile, then from the environment variables, each
+// overriding the last. The config can be nil, in which case only the environment is
+// applied, and a profile cannot be used.
+func Apply(c *Config, flags *pflag.FlagSet, command string, profile string, environ []string) error {
+	passed := Passed(flags)
+
+	if c != nil {
+		if err := apply(flags, passed, command, c.settings); err != nil {
+			return fmt.Errorf("config %s: %w", c.Path, err)
+		}
+	}
+
+	if profile != "" {
+		if c == nil {
+			return fmt.Errorf("profile %s: no config file was found", profile)
+		}
+
+		settings, ok := c.profiles[profile]
+		if !ok {
+			return fmt.Errorf("config %s: unknown profile %q, expected one of: %s", c.Path, profile, strings.Join(c.Profiles(), ", "))
+		}
+
+		if err := apply(flags, passed, command, settings); err != nil {
+			return fmt.Errorf("config %s: profile %s: %w", c.Path, profile, err)
+		}
+	}
+
+	return applyEnv(flags, passed, environ)
+}
+
+// Passed returns the names of the flags which were passed on the command line. It has to
+// be called before Apply, which marks the flags it sets as changed.
+func Passed(flags *pflag.FlagSet) map[string]bool {
+	passed := map[string]bool{}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			passed[f.Name] = true
+		}
+	})
+
+	return passed
+}
+
+// Reset sets the flags which were not passed on the command line back to their defaults,
+// so the settings can be applied again when the config is reloaded.
+func Reset(flags *pflag.FlagSet, passed map[string]bool) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if passed[f.Name] || !f.Changed || err != nil {
+			return
+		}
+
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			values := []string{}
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+
+			err = slice.Replace(values)
+		} else {
+			err = f.Value.Set(f.DefValue)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		f.Changed = false
+	})
+
+	return err
+}
+
+// apply sets the flags from the settings, and then from the section of the command. Top
+// level settings for flags the command does not have are left for other commands, but
+// the section of the command can only have its flags.
+func apply(flags *pflag.FlagSet, passed map[string]bool, command string, settings map[string]interface{}) error {
+	for _, name := range sortedKeys(settings) {
+		value := settings[name]
+		if _, ok := value.(map[string]interface{}); ok {
+			continue
+		}
+
+		if flags.Lookup(name) == nil {
+			continue
+		}
+
+		if err := set(flags, passed, name, value); err != nil {
+			return err
+		}
+	}
+
+	section, ok := settings[command].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, name := range sortedKeys(section) {
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag %s", command, name)
+		}
+
+		if err := set(flags, passed, name, section[name]); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+	}
+
+	return nil
+}
+
+// applyEnv sets the flags from the environment variables named after them, ie: RH_PORT
+// or RH_WEB_PORT. Lists are separated by commas.
+func applyEnv(flags *pflag.FlagSet, passed map[string]bool, environ []string) error {
+	env := map[string]string{}
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], EnvPrefix) {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		key := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := env[key]
+		if !ok || err != nil {
+			return
+		}
+
+		var v interface{} = value
+		if _, ok := f.Value.(pflag.SliceValue); ok {
+			list := []interface{}{}
+			for _, item := range strings.Split(value, ",") {
+				list = append(list, item)
+			}
+			v = list
+		}
+
+		if setErr := set(flags, passed, f.Name, v); setErr != nil {
+			err = fmt.Errorf("%s: %w", key, setErr)
+		}
+	})
+
+	return err
+}
+
+// set sets the flag to the value, unless it was passed on the command line. Lists can only
+// be set on flags which take several values.
+func set(flags *pflag.FlagSet, passed map[string]bool, name string, value interface{}) error {
+	if passed[name] {
+		return nil
+	}
+
+	f := flags.Lookup(name)
+	slice, isSlice := f.Value.(pflag.SliceValue)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return fmt.Errorf("%s: expected a value, got a map", name)
+	case []interface{}:
+		if !isSlice {
+			return fmt.Errorf("%s: expected a single value, got a list", name)
+		}
+
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, format(item))
+		}
+
+		if err := slice.Replace(values); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		f.Changed = true
+
+		return nil
+	}
+
+	if isSlice {
+		return set(flags, passed, name, []interface{}{value})
+	}
+
+	if err := flags.Set(name, format(value)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// format returns a YAML scalar as the string it would be passed as on the command line.
+func format(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
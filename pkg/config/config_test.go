@@ -0,0 +1,261 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+const testConfig = `
+port: 9000
+web: true
+rules: ignored.json
+http:
+  response_code: 201
+  schema: [a.json, b.json]
+profiles:
+  stripe:
+    port: 9001
+    http:
+      verify_signature: stripe
+  empty:
+`
+
+// testFlags are the flags of a command with the values they are set to.
+type testFlags struct {
+	set             *pflag.FlagSet
+	port            int
+	web             bool
+	responseCode    int
+	verifySignature string
+	schemas         []string
+}
+
+func newTestFlags(t *testing.T, args ...string) *testFlags {
+	t.Helper()
+
+	f := &testFlags{set: pflag.NewFlagSet("http", pflag.ContinueOnError)}
+	f.set.IntVarP(&f.port, "port", "p", 8080, "")
+	f.set.BoolVar(&f.web, "web", false, "")
+	f.set.IntVarP(&f.responseCode, "response_code", "r", 200, "")
+	f.set.StringVar(&f.verifySignature, "verify_signature", "", "")
+	f.set.StringArrayVar(&f.schemas, "schema", []string{}, "")
+
+	if err := f.set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func writeConfig(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	file := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func loadTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+
+	c, err := Load(writeConfig(t, t.TempDir(), File, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestLoad(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+
+	if !reflect.DeepEqual(c.Profiles(), []string{"empty", "stripe"}) {
+		t.Errorf("Expected the profiles empty and stripe, got %v", c.Profiles())
+	}
+
+	if filepath.Base(c.Path) != File {
+		t.Errorf("Expected the path to be %s, got %s", File, c.Path)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []string{
+		"port: [",
+		"profiles: [stripe]",
+		"profiles:\n  stripe: 9001",
+	}
+
+	for _, content := range tests {
+		if _, err := Load(writeConfig(t, t.TempDir(), File, content)); err == nil {
+			t.Errorf("%s: expected an error", content)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestApply(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+	tests := []struct {
+		profile         string
+		environ         []string
+		args            []string
+		port            int
+		responseCode    int
+		verifySignature string
+		schemas         []string
+	}{
+		{"", nil, nil, 9000, 201, "", []string{"a.json", "b.json"}},
+		{"stripe", nil, nil, 9001, 201, "stripe", []string{"a.json", "b.json"}},
+		{"empty", nil, nil, 9000, 201, "", []string{"a.json", "b.json"}},
+		{"stripe", []string{"RH_PORT=9002", "RH_SCHEMA=c.json,d.json", "PORT=1"}, nil, 9002, 201, "stripe", []string{"c.json", "d.json"}},
+		{"stripe", []string{"RH_PORT=9002"}, []string{"-p", "9003", "--schema", "e.json"}, 9003, 201, "stripe", []string{"e.json"}},
+	}
+
+	for _, tc := range tests {
+		f := newTestFlags(t, tc.args...)
+		if err := Apply(c, f.set, "http", tc.profile, tc.environ); err != nil {
+			t.Fatalf("%s: %s", tc.profile, err)
+		}
+
+		if f.port != tc.port || f.responseCode != tc.responseCode || f.verifySignature != tc.verifySignature {
+			t.Errorf("%s %v: expected %d %d %q, got %d %d %q", tc.profile, tc.environ, tc.port, tc.responseCode, tc.verifySignature, f.port, f.responseCode, f.verifySignature)
+		}
+
+		if !reflect.DeepEqual(f.schemas, tc.schemas) {
+			t.Errorf("%s %v: expected the schemas %v, got %v", tc.profile, tc.environ, tc.schemas, f.schemas)
+		}
+
+		if !f.web {
+			t.Errorf("%s: expected web to be set", tc.profile)
+		}
+	}
+}
+
+func TestApplyOtherCommand(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+	f := newTestFlags(t)
+
+	if err := Apply(c, f.set, "tcp", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.port != 9000 || f.responseCode != 200 {
+		t.Errorf("Expected only the top level settings, got port %d and response code %d", f.port, f.responseCode)
+	}
+}
+
+func TestApplyWithoutConfig(t *testing.T) {
+	f := newTestFlags(t)
+
+	if err := Apply(nil, f.set, "http", "", []string{"RH_WEB=true", "RH_RESPONSE_CODE=404"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.web || f.responseCode != 404 {
+		t.Errorf("Expected the environment to be applied, got web %t and response code %d", f.web, f.responseCode)
+	}
+
+	if err := Apply(nil, newTestFlags(t).set, "http", "stripe", nil); err == nil {
+		t.Error("Expected an error for a profile without a config")
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		profile string
+		environ []string
+	}{
+		{testConfig, "github", nil},
+		{"http:\n  unknown: 1", "", nil},
+		{"port: eighty", "", nil},
+		{"port: [1, 2]", "", nil},
+		{"http:\n  verify_signature:\n    secret: s", "", nil},
+		{"profiles:\n  stripe:\n    http:\n      unknown: 1", "stripe", nil},
+		{"web: true", "", []string{"RH_PORT=eighty"}},
+	}
+
+	for _, tc := range tests {
+		c := loadTestConfig(t, tc.content)
+		if err := Apply(c, newTestFlags(t).set, "http", tc.profile, tc.environ); err == nil {
+			t.Errorf("%s: expected an error", tc.content)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	xdg := t.TempDir()
+	os.Setenv("XDG_CONFIG_HOME", xdg)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	if path := Find(); path != "" {
+		t.Errorf("Expected no config, got %s", path)
+	}
+
+	expected := writeConfig(t, xdg, filepath.Join("rh", "config.yaml"), "port: 9000")
+	if path := Find(); path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+
+	writeConfig(t, dir, File, "port: 9000")
+	if path := Find(); path != File {
+		t.Errorf("Expected %s, got %s", File, path)
+	}
+}
+
+func TestReset(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+	f := newTestFlags(t, "--web=false")
+	passed := Passed(f.set)
+
+	if !reflect.DeepEqual(passed, map[string]bool{"web": true}) {
+		t.Errorf("Expected web to be passed, got %v", passed)
+	}
+
+	if err := Apply(c, f.set, "http", "stripe", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Reset(f.set, passed); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.port != 8080 || f.responseCode != 200 || f.verifySignature != "" || len(f.schemas) != 0 || f.web {
+		t.Errorf("Expected the defaults, got %d %d %q %v %t", f.port, f.responseCode, f.verifySignature, f.schemas, f.web)
+	}
+
+	c = loadTestConfig(t, "port: 9005")
+	if err := Apply(c, f.set, "http", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.port != 9005 || f.web {
+		t.Errorf("Expected the config to be applied again, got port %d and web %t", f.port, f.web)
+	}
+}
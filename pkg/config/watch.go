@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Watcher polls files for changes. Polling works the same on every platform, and with
+// editors which replace a file when saving it.
+type Watcher struct {
+	// Interval is how often the files are checked.
+	Interval time.Duration
+
+	mu    sync.Mutex
+	files map[string]fileState
+}
+
+// fileState is what a change of a file is detected by. Missing files have a zero state.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch replaces the files which are watched, and records the state of the files which
+// were not watched yet. Empty names are ignored.
+func (w *Watcher) Watch(files []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched := make(map[string]fileState)
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+
+		if state, ok := w.files[file]; ok {
+			watched[file] = state
+		} else {
+			watched[file] = stat(file)
+		}
+	}
+
+	w.files = watched
+}
+
+// Changed returns the files which were changed, created or removed since they were last
+// checked, sorted.
+func (w *Watcher) Changed() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var changed []string
+	for file, state := range w.files {
+		if current := stat(file); current != state {
+			w.files[file] = current
+			changed = append(changed, file)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Run checks the files on each interval until done is closed, and calls changed with
+// the files which changed.
+func (w *Watcher) Run(done <-chan struct{}, changed func([]string)) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if files := w.Changed(); len(files) > 0 {
+				changed(files)
+			}
+		}
+	}
+}
+
+func stat(file string) fileState {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
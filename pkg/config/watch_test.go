@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherChanged(t *testing.T) {
+	dir := t.TempDir()
+	rules := writeConfig(t, dir, "rules.json", "[]")
+	config := writeConfig(t, dir, File, "port: 9000")
+	missing := filepath.Join(dir, "missing.json")
+
+	w := &Watcher{}
+	w.Watch([]string{config, rules, missing, ""})
+
+	if changed := w.Changed(); changed != nil {
+		t.Errorf("Expected no changes, got %v", changed)
+	}
+
+	writeConfig(t, dir, "rules.json", `[{"path": "/charge"}]`)
+	writeConfig(t, dir, "missing.json", "[]")
+	if err := os.Chtimes(config, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{config, missing, rules}
+	if changed := w.Changed(); !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected %v, got %v", expected, changed)
+	}
+
+	if changed := w.Changed(); changed != nil {
+		t.Errorf("Expected the changes to be reported once, got %v", changed)
+	}
+
+	os.Remove(rules)
+	w.Watch([]string{config, rules})
+	if changed := w.Changed(); !reflect.DeepEqual(changed, []string{rules}) {
+		t.Errorf("Expected %v, got %v", []string{rules}, changed)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	config := writeConfig(t, dir, File, "port: 9000")
+
+	w := &Watcher{Interval: 10 * time.Millisecond}
+	w.Watch([]string{config})
+
+	done := make(chan struct{})
+	defer close(done)
+
+	changes := make(chan []string, 1)
+	go w.Run(done, func(files []string) {
+		changes <- files
+	})
+
+	writeConfig(t, dir, File, "port: 9001")
+
+	select {
+	case files := <-changes:
+		if !reflect.DeepEqual(files, []string{config}) {
+			t.Errorf("Expected %v, got %v", []string{config}, files)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the change to be reported")
+	}
+}
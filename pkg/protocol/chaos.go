@@ -0,0 +1,207 @@
+package protocol
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chaos fault kinds.
+const (
+	ChaosStatus = "status"
+	ChaosReset  = "reset"
+	ChaosHang   = "hang"
+)
+
+// chaos5xx are the status codes picked by the 5xx fault.
+var chaos5xx = []int{500, 502, 503, 504}
+
+// ChaosFault is a fault injected into a request.
+type ChaosFault struct {
+	// Kind is status, reset or hang.
+	Kind string `json:"kind"`
+
+	// StatusCode is the response code of a status fault.
+	StatusCode int `json:"status_code,omitempty"`
+
+	// RetryAfter is the Retry-After header in seconds sent with a status fault, 0 if
+	// it was not sent.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// String returns the fault, ie: 503 Service Unavailable, retry after 5s.
+func (f ChaosFault) String() string {
+	switch f.Kind {
+	case ChaosReset:
+		return "connection reset"
+	case ChaosHang:
+		return "hang"
+	}
+
+	str := fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode))
+	if f.RetryAfter > 0 {
+		str = fmt.Sprintf("%s, retry after %ds", str, f.RetryAfter)
+	}
+
+	return str
+}
+
+// ChaosRate is the percentage of requests a fault is injected into.
+type ChaosRate struct {
+	// Fault is a status code, 5xx, reset or hang.
+	Fault string
+
+	// Percent is between 0 and 100.
+	Percent float64
+}
+
+// Chaos injects faults into a percentage of the requests to the http protocol. Faults
+// are picked from a random source with a seed, so a run can be reproduced by sending
+// the same requests in the same order.
+type Chaos struct {
+	// Rates are the faults which are injected, and how often.
+	Rates []ChaosRate
+
+	// RetryAfter is sent in the Retry-After header of status faults, rounded up to
+	// seconds. Default is 0, which does not send the header.
+	RetryAfter time.Duration
+
+	// Seed is the seed of the random source.
+	Seed int64
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewChaos parses the faults to inject, which are a list of faults with the percentage
+// of requests they are injected into, ie: 503=10%,429=5%,reset=1%,hang=1%. A fault is a
+// 5xx or 429 status code, 5xx for any of 500, 502, 503 and 504, reset to reset the
+// HTTP/2 stream or the HTTP/1.x connection, or hang to never respond.
+func NewChaos(spec string, seed int64, retryAfter time.Duration) (*Chaos, error) {
+	chaos := &Chaos{RetryAfter: retryAfter, Seed: seed, rnd: rand.New(rand.NewSource(seed))}
+
+	total := 0.0
+	for _, param := range strings.Split(spec, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("chaos %q: expected faults with a percentage such as 503=10%%", spec)
+		}
+
+		fault := strings.ToLower(strings.TrimSpace(kv[0]))
+		if err := validateChaosFault(fault); err != nil {
+			return nil, fmt.Errorf("chaos %q: %w", spec, err)
+		}
+
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(kv[1]), "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("chaos %q: invalid percentage %s", spec, kv[1])
+		}
+
+		total += percent
+		chaos.Rates = append(chaos.Rates, ChaosRate{Fault: fault, Percent: percent})
+	}
+
+	if total > 100 {
+		return nil, fmt.Errorf("chaos %q: the percentages add up to more than 100%%", spec)
+	}
+
+	return chaos, nil
+}
+
+// validateChaosFault returns an error if the fault cannot be injected.
+func validateChaosFault(fault string) error {
+	switch fault {
+	case ChaosReset, ChaosHang, "5xx":
+		return nil
+	}
+
+	code, err := strconv.Atoi(fault)
+	if err != nil || (code != http.StatusTooManyRequests && (code < 500 || code > 599)) {
+		return fmt.Errorf("unknown fault %s, expected a 5xx or 429 status code, 5xx, reset or hang", fault)
+	}
+
+	return nil
+}
+
+// String returns the faults and the seed, ie: 503=10%, reset=1% (seed 42).
+func (c *Chaos) String() string {
+	rates := make([]string, 0, len(c.Rates))
+	for _, rate := range c.Rates {
+		rates = append(rates, fmt.Sprintf("%s=%s%%", rate.Fault, strconv.FormatFloat(rate.Percent, 'f', -1, 64)))
+	}
+
+	return fmt.Sprintf("%s (seed %d)", strings.Join(rates, ", "), c.Seed)
+}
+
+// Pick returns the fault to inject into the next request, or nil if the request is left
+// alone.
+func (c *Chaos) Pick() *ChaosFault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.rnd.Float64() * 100
+	for _, rate := range c.Rates {
+		if n >= rate.Percent {
+			n -= rate.Percent
+			continue
+		}
+
+		switch rate.Fault {
+		case ChaosReset, ChaosHang:
+			return &ChaosFault{Kind: rate.Fault}
+		case "5xx":
+			return c.statusFault(chaos5xx[c.rnd.Intn(len(chaos5xx))])
+		}
+
+		code, _ := strconv.Atoi(rate.Fault)
+
+		return c.statusFault(code)
+	}
+
+	return nil
+}
+
+// statusFault returns a status fault with the Retry-After of the chaos.
+func (c *Chaos) statusFault(code int) *ChaosFault {
+	return &ChaosFault{
+		Kind:       ChaosStatus,
+		StatusCode: code,
+		RetryAfter: seconds(c.RetryAfter),
+	}
+}
+
+// chaosHandler injects the fault into the request.
+func chaosHandler(fault *ChaosFault) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch fault.Kind {
+		case ChaosReset:
+			resetConnection(r)
+		case ChaosHang:
+			<-r.Context().Done()
+		default:
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(fault.RetryAfter))
+			}
+
+			http.Error(w, http.StatusText(fault.StatusCode), fault.StatusCode)
+		}
+	})
+}
+
+// resetConnection aborts the request without a response. HTTP/2 streams are reset on
+// their own, and the other streams of the connection carry on. HTTP/1.x connections are
+// closed, with a TCP reset if they are plain TCP, so the client sees ECONNRESET.
+func resetConnection(r *http.Request) {
+	if r.ProtoMajor == 1 {
+		if tc, ok := r.Context().Value(connKey{}).(*net.TCPConn); ok {
+			tc.SetLinger(0)
+		}
+	}
+
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,245 @@
+package protocol
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestNewChaos(t *testing.T) {
+	chaos, err := NewChaos("503=10%, 429=5,reset=2.5%,HANG=1%,5xx=1%", 42, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "503=10%, 429=5%, reset=2.5%, hang=1%, 5xx=1% (seed 42)"
+	if chaos.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, chaos.String())
+	}
+}
+
+func TestNewChaosErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"503",
+		"404=10%",
+		"600=10%",
+		"crash=10%",
+		"503=lots",
+		"503=-1%",
+		"503=60%,reset=50%",
+	}
+
+	for _, spec := range tests {
+		if _, err := NewChaos(spec, 1, 0); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestChaosPick(t *testing.T) {
+	picks := func(seed int64) []string {
+		chaos, err := NewChaos("503=20%,5xx=20%,reset=10%,hang=10%", seed, 1500*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var faults []string
+		for i := 0; i < 10000; i++ {
+			fault := chaos.Pick()
+			if fault == nil {
+				faults = append(faults, "")
+				continue
+			}
+
+			if fault.Kind == ChaosStatus && fault.RetryAfter != 2 {
+				t.Errorf("Expected a Retry-After of 2, got %d", fault.RetryAfter)
+			}
+
+			faults = append(faults, fault.String())
+		}
+
+		return faults
+	}
+
+	first, second := picks(7), picks(7)
+	counts := map[string]int{}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same faults with the same seed, request %d got %q and %q", i, first[i], second[i])
+		}
+
+		counts[first[i]]++
+	}
+
+	expected := map[string]int{
+		"": 4000,
+		"503 Service Unavailable, retry after 2s": 2500,
+		"502 Bad Gateway, retry after 2s":         500,
+		"connection reset":                        1000,
+		"hang":                                    1000,
+	}
+
+	for fault, count := range expected {
+		if counts[fault] < count*9/10 || counts[fault] > count*11/10 {
+			t.Errorf("Expected about %d of %q, got %d", count, fault, counts[fault])
+		}
+	}
+}
+
+func TestChaosFaultString(t *testing.T) {
+	tests := []struct {
+		fault    ChaosFault
+		expected string
+	}{
+		{ChaosFault{Kind: ChaosStatus, StatusCode: 429, RetryAfter: 30}, "429 Too Many Requests, retry after 30s"},
+		{ChaosFault{Kind: ChaosStatus, StatusCode: 500}, "500 Internal Server Error"},
+		{ChaosFault{Kind: ChaosReset}, "connection reset"},
+		{ChaosFault{Kind: ChaosHang}, "hang"},
+	}
+
+	for _, tc := range tests {
+		if tc.fault.String() != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, tc.fault.String())
+		}
+	}
+}
+
+func TestHttpChaos(t *testing.T) {
+	tests := []struct {
+		spec string
+		kind string
+	}{
+		{"429=100%", ChaosStatus},
+		{"reset=100%", ChaosReset},
+		{"hang=100%", ChaosHang},
+	}
+
+	for _, tc := range tests {
+		chaos, err := NewChaos(tc.spec, 1, 10*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rpChan := make(chan RequestPayload, 1)
+		httpServer := Http{ResponseCode: 200, Chaos: chaos, rendererChannels: []chan RequestPayload{rpChan}}
+
+		srv := httptest.NewUnstartedServer(httpServer.routes())
+		srv.Config.ConnContext = connContext
+		srv.Start()
+
+		client := &http.Client{Timeout: 200 * time.Millisecond}
+		resp, err := client.Get(srv.URL + "/webhooks")
+
+		switch tc.kind {
+		case ChaosStatus:
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != 429 {
+				t.Errorf("Expected 429, got %d", resp.StatusCode)
+			}
+
+			if resp.Header.Get("Retry-After") != "10" {
+				t.Errorf("Expected a Retry-After of 10, got %q", resp.Header.Get("Retry-After"))
+			}
+		default:
+			if err == nil {
+				resp.Body.Close()
+				t.Errorf("%s: expected the request to fail", tc.kind)
+			}
+		}
+
+		select {
+		case rp := <-rpChan:
+			if rp.Fault == nil || rp.Fault.Kind != tc.kind {
+				t.Errorf("Expected a %s fault, got %+v", tc.kind, rp.Fault)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: expected the request to be logged", tc.kind)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestHttpChaosResetHttp2Stream(t *testing.T) {
+	chaos, err := NewChaos("reset=100%", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChan := make(chan RequestPayload, 2)
+	httpServer := Http{ResponseCode: 200, Chaos: chaos, rendererChannels: []chan RequestPayload{rpChan}}
+
+	var conns int32
+	srv := httptest.NewUnstartedServer(httpServer.routes())
+	srv.Config.ConnContext = connContext
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	// Both requests are reset, on the same connection.
+	for i := 0; i < 2; i++ {
+		if resp, err := client.Get(srv.URL); err == nil {
+			resp.Body.Close()
+			t.Fatal("Expected the stream to be reset")
+		}
+
+		if rp := <-rpChan; rp.Fault == nil || rp.Fault.Kind != ChaosReset {
+			t.Errorf("Expected a reset fault, got %+v", rp.Fault)
+		}
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("Expected the connection to be kept, got %d connections", n)
+	}
+}
+
+func TestHttpChaosHangLoggedWhenItStarts(t *testing.T) {
+	chaos, err := NewChaos("hang=100%", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{ResponseCode: 200, Chaos: chaos, rendererChannels: []chan RequestPayload{rpChan}}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	go http.DefaultClient.Do(req)
+
+	select {
+	case rp := <-rpChan:
+		if rp.Fault == nil || rp.Fault.Kind != ChaosHang {
+			t.Errorf("Expected a hang fault, got %+v", rp.Fault)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the hang to be logged while the request hangs")
+	}
+}
@@ -0,0 +1,155 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDecompressedSize limits how large a decompressed body can be, so a small body
+// cannot expand into something that does not fit in memory.
+const maxDecompressedSize = 64 << 20
+
+// BodyEncoding describes a request body that was sent with a Content-Encoding.
+type BodyEncoding struct {
+	// Encoding is the Content-Encoding header, ie: gzip or gzip, br.
+	Encoding string `json:"encoding"`
+
+	// CompressedSize is the size of the body as it was received.
+	CompressedSize int `json:"compressed_size"`
+
+	// DecompressedSize is the size of the body once it was decoded, 0 if it could
+	// not be decoded.
+	DecompressedSize int `json:"decompressed_size"`
+
+	// Error is why the body could not be decoded. The body is then left as it was
+	// received.
+	Error string `json:"error,omitempty"`
+}
+
+// String returns the encoding with the sizes before and after decoding, or the error.
+func (e BodyEncoding) String() string {
+	if e.Error != "" {
+		return fmt.Sprintf("%s, decoding failed: %s", e.Encoding, e.Error)
+	}
+
+	return fmt.Sprintf("%s, %d → %d bytes", e.Encoding, e.CompressedSize, e.DecompressedSize)
+}
+
+// decodeBody replaces a body sent with a Content-Encoding with the decoded body, so
+// that the params can be parsed. Returns nil if the body is not encoded.
+func decodeBody(r *http.Request) *BodyEncoding {
+	header := r.Header.Get("Content-Encoding")
+	encodings := contentEncodings(header)
+	if len(encodings) == 0 {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return &BodyEncoding{Encoding: header, Error: err.Error()}
+	}
+
+	encoding := &BodyEncoding{Encoding: header, CompressedSize: len(body)}
+	if len(body) == 0 {
+		return encoding
+	}
+
+	decoded, err := decompress(body, encodings)
+	if err != nil {
+		encoding.Error = err.Error()
+		return encoding
+	}
+
+	encoding.DecompressedSize = len(decoded)
+	r.Body = ioutil.NopCloser(bytes.NewReader(decoded))
+	r.ContentLength = int64(len(decoded))
+
+	return encoding
+}
+
+// contentEncodings returns the encodings in the header, in the order they were applied.
+// The identity encoding is skipped.
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+// decompress decodes the body with each encoding, starting with the last one applied.
+func decompress(body []byte, encodings []string) ([]byte, error) {
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoded, err := decompressWith(body, encodings[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", encodings[i], err)
+		}
+
+		body = decoded
+	}
+
+	return body, nil
+}
+
+// decompressWith decodes the body with a single encoding.
+func decompressWith(body []byte, encoding string) ([]byte, error) {
+	var r io.Reader
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some clients send raw deflate.
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(body))
+			defer fr.Close()
+			r = fr
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported encoding")
+	}
+
+	decoded, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decoded) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed body is larger than %d bytes", maxDecompressedSize)
+	}
+
+	return decoded, nil
+}
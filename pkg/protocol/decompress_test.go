@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	}
+
+	w.Write(data)
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func TestHttpDecompressBody(t *testing.T) {
+	data := []byte(`{"foo":"bar","list":["a","b","c"]}`)
+
+	tests := []struct {
+		header string
+		body   []byte
+	}{
+		{"gzip", compress(t, "gzip", data)},
+		{"deflate", compress(t, "deflate", data)},
+		{"deflate", compress(t, "raw deflate", data)},
+		{"br", compress(t, "br", data)},
+		{"zstd", compress(t, "zstd", data)},
+		{"gzip, br", compress(t, "br", compress(t, "gzip", data))},
+	}
+
+	for _, test := range tests {
+		rpChan := make(chan RequestPayload, 1)
+		httpServer := Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChan}}
+		srv := httptest.NewServer(httpServer.routes())
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", test.header)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		rp := <-rpChan
+		if rp.Encoding == nil {
+			t.Fatalf("%s: expected encoding", test.header)
+		}
+
+		if rp.Encoding.Error != "" {
+			t.Errorf("%s: expected no error, got %s", test.header, rp.Encoding.Error)
+		}
+
+		if rp.Encoding.Encoding != test.header {
+			t.Errorf("Expected encoding %s, got %s", test.header, rp.Encoding.Encoding)
+		}
+
+		if rp.Encoding.CompressedSize != len(test.body) {
+			t.Errorf("%s: expected compressed size %d, got %d", test.header, len(test.body), rp.Encoding.CompressedSize)
+		}
+
+		if rp.Encoding.DecompressedSize != len(data) {
+			t.Errorf("%s: expected decompressed size %d, got %d", test.header, len(data), rp.Encoding.DecompressedSize)
+		}
+
+		if rp.ParamFields.Json["foo"] != "bar" {
+			t.Errorf("%s: expected json params to be parsed, got %v", test.header, rp.ParamFields.Json)
+		}
+	}
+}
+
+func TestHttpDecompressBodyErrors(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"gzip", "gzip: "},
+		{"zstd", "zstd: "},
+		{"compress", "compress: unsupported encoding"},
+	}
+
+	for _, test := range tests {
+		rpChan := make(chan RequestPayload, 1)
+		httpServer := Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChan}}
+		srv := httptest.NewServer(httpServer.routes())
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"foo":"bar"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", test.header)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		rp := <-rpChan
+		if rp.Encoding == nil || !strings.HasPrefix(rp.Encoding.Error, test.expected) {
+			t.Errorf("%s: expected error starting with %q, got %+v", test.header, test.expected, rp.Encoding)
+		}
+
+		// The body is left as it was received.
+		if rp.ParamFields.Json["foo"] != "bar" {
+			t.Errorf("%s: expected the body to be left as is, got %v", test.header, rp.ParamFields.Json)
+		}
+	}
+}
+
+func TestHttpIdentityEncoding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("foo"))
+	req.Header.Set("Content-Encoding", "identity")
+
+	if encoding := decodeBody(req); encoding != nil {
+		t.Errorf("Expected no encoding, got %+v", encoding)
+	}
+}
@@ -0,0 +1,378 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronvb/logparams"
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// gRPC status codes for the errors we reply with.
+const (
+	grpcResourceExhausted = 8
+	grpcInternal          = 13
+)
+
+// grpcMaxReceiveSize is the largest message we accept, the default max receive size of
+// gRPC servers.
+const grpcMaxReceiveSize = 4 << 20
+
+// errGrpcMessageTooLarge is returned for messages over grpcMaxReceiveSize.
+var errGrpcMessageTooLarge = fmt.Errorf("gRPC message larger than max (%d bytes)", grpcMaxReceiveSize)
+
+// Grpc is the protocol for accepting gRPC calls. Calls are served over h2c, or over
+// HTTP/2 with TLS when a TLS config is set.
+type Grpc struct {
+	// Addr is the address the gRPC server will bind to.
+	Addr string
+
+	// Port is the port the gRPC server will run on.
+	Port int
+
+	// TLSConfig serves HTTP/2 over TLS when set, otherwise h2c is used.
+	TLSConfig *tls.Config
+
+	// Descriptors decode request messages as JSON and encode fixture responses. Nil
+	// shows messages in the raw protobuf wire format.
+	Descriptors *GrpcDescriptors
+
+	// Fixtures contain the responses we reply with, which need descriptors. Methods
+	// without a fixture reply with an empty message.
+	Fixtures GrpcFixtures
+
+	// Status is the gRPC status code we reply with, ie: 5 for NOT_FOUND. Default is 0,
+	// which is OK.
+	Status int
+
+	// StatusMessage is sent with the status.
+	StatusMessage string
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming call to the Grpc protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+}
+
+// Start will start the gRPC server.
+//
+// Sets the channel on our struct so that incoming messages can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Grpc) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	addr := fmt.Sprintf("%s:%d", s.Addr, s.Port)
+	errorLog := log.New(&httpErrorLog{}, "", 0)
+
+	srv := &http.Server{
+		Addr:      addr,
+		ErrorLog:  errorLog,
+		Handler:   s.routes(),
+		TLSConfig: s.TLSConfig,
+	}
+
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			if s.TLSConfig != nil {
+				err = srv.ServeTLS(s.listener, "", "")
+			} else {
+				err = srv.Serve(s.listener)
+			}
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("gRPC Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Grpc) Bind() (net.Addr, error) {
+	if s.listener == nil {
+		ln, err := listen(s.Addr, s.Port, "", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		s.listener = ln
+	}
+
+	return s.listener.Addr(), nil
+}
+
+func (s *Grpc) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// routes accepts calls to any method. Without TLS, h2c is used so clients can connect
+// with prior knowledge.
+func (s *Grpc) routes() http.Handler {
+	handler := http.HandlerFunc(s.defaultHandler)
+	if s.TLSConfig != nil {
+		return handler
+	}
+
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+// defaultHandler logs each message of the call, then replies with the fixture responses
+// and the status. Responses are sent after the client has finished sending, so
+// bidirectional streams see them once the client closes its side.
+func (s *Grpc) defaultHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "rh grpc only accepts gRPC calls", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var method protoreflect.MethodDescriptor
+	if s.Descriptors != nil {
+		m, err := s.Descriptors.method(r.URL.Path)
+		if err != nil {
+			s.logError(r, err.Error())
+		}
+		method = m
+	}
+
+	status, statusMessage := s.Status, s.StatusMessage
+	frames := 0
+	for {
+		data, err := readGrpcFrame(r.Body, r.Header.Get("Grpc-Encoding"))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.logError(r, err.Error())
+			if errors.Is(err, errGrpcMessageTooLarge) {
+				status, statusMessage = grpcResourceExhausted, err.Error()
+			}
+			break
+		}
+
+		s.logFrame(r, method, data)
+		frames++
+	}
+
+	// Calls without messages, such as an empty client stream, are still shown.
+	if frames == 0 {
+		s.logFrame(r, method, nil)
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	if status == 0 {
+		responses, err := s.responses(r.URL.Path, method)
+		if err != nil {
+			s.logError(r, err.Error())
+			status, statusMessage = grpcInternal, err.Error()
+		}
+
+		for _, response := range responses {
+			w.Write(grpcFrame(response))
+		}
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(status))
+	if statusMessage != "" {
+		w.Header().Set("Grpc-Message", encodeGrpcMessageHeader(statusMessage))
+	}
+}
+
+// responses encodes the fixture responses for the method. Methods without a fixture
+// reply with an empty message, which is valid for any message type.
+func (s *Grpc) responses(fullMethod string, method protoreflect.MethodDescriptor) ([][]byte, error) {
+	fixtures := s.Fixtures.responses(fullMethod)
+	if len(fixtures) == 0 {
+		return [][]byte{{}}, nil
+	}
+
+	if method == nil {
+		return nil, fmt.Errorf("fixture for %s needs the method in the descriptor set", fullMethod)
+	}
+
+	responses := make([][]byte, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		b, err := encodeGrpcMessage(method.Output(), fixture)
+		if err != nil {
+			return nil, fmt.Errorf("fixture for %s: %w", fullMethod, err)
+		}
+
+		responses = append(responses, b)
+	}
+
+	return responses, nil
+}
+
+// logFrame sends a message of the call to the render channel. The message is decoded
+// as JSON when the method is in the descriptor set, otherwise in the wire format.
+func (s *Grpc) logFrame(r *http.Request, method protoreflect.MethodDescriptor, data []byte) {
+	var msg string
+	var params logparams.ParamFields
+
+	if method != nil {
+		decoded, err := decodeGrpcMessage(method.Input(), data)
+		if err != nil {
+			msg = fmt.Sprintf("invalid %s: %s", method.Input().FullName(), err)
+		} else {
+			msg = string(decoded)
+			json.Unmarshal(decoded, &params.Json)
+		}
+	} else {
+		decoded, err := DecodeProtoWire(data)
+		if err != nil {
+			msg = fmt.Sprintf("invalid protobuf: %s", err)
+		} else {
+			msg = decoded
+		}
+	}
+
+	req := RequestPayload{
+		ID:          uuid.New().String(),
+		Fields:      s.fields(r, "GRPC"),
+		Headers:     r.Header,
+		Message:     msg,
+		ParamFields: params,
+		CreatedAt:   time.Now(),
+		Size:        len(data),
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// logError sends errors that happen during a call to the render channel.
+func (s *Grpc) logError(r *http.Request, msg string) {
+	req := RequestPayload{
+		ID:        uuid.New().String(),
+		Fields:    s.fields(r, "ERROR"),
+		Message:   msg,
+		CreatedAt: time.Now(),
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// fields uses the full method name as the url so the printer shows which method was
+// called.
+func (s *Grpc) fields(r *http.Request, method string) logrequest.RequestFields {
+	return logrequest.RequestFields{
+		Method:        method,
+		Url:           r.URL.Path,
+		RemoteAddress: r.RemoteAddr,
+		Protocol:      r.Proto,
+		Time:          time.Now(),
+	}
+}
+
+// readGrpcFrame reads a length-prefixed message. Compressed messages are decompressed
+// with the encoding of the call, which has to be gzip. Messages over grpcMaxReceiveSize,
+// compressed or not, are rejected before they are read.
+func readGrpcFrame(body io.Reader, encoding string) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(body, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated gRPC frame header")
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > grpcMaxReceiveSize {
+		return nil, errGrpcMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, fmt.Errorf("truncated gRPC frame: %w", err)
+	}
+
+	if header[0] == 0 {
+		return data, nil
+	}
+
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("unsupported grpc-encoding %q", encoding)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = ioutil.ReadAll(io.LimitReader(zr, grpcMaxReceiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > grpcMaxReceiveSize {
+		return nil, errGrpcMessageTooLarge
+	}
+
+	return data, nil
+}
+
+// grpcFrame prefixes an uncompressed message with its length.
+func grpcFrame(data []byte) []byte {
+	frame := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+
+	return frame
+}
+
+// encodeGrpcMessageHeader percent-encodes the status message as the gRPC spec requires.
+func encodeGrpcMessageHeader(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,193 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GrpcDescriptors contains the services of a descriptor set or of .proto files, which are
+// used to decode requests and encode fixture responses as JSON.
+type GrpcDescriptors struct {
+	files *protoregistry.Files
+}
+
+// GrpcFixtures maps a full method name(ie: /helloworld.Greeter/SayHello) to the JSON
+// responses we reply with. Server streaming methods reply with each response. The "*"
+// key is used for methods without their own responses.
+type GrpcFixtures map[string][]json.RawMessage
+
+// LoadGrpcDescriptors reads a binary FileDescriptorSet, which protoc creates with
+// --include_imports --descriptor_set_out.
+func LoadGrpcDescriptors(path string) (*GrpcDescriptors, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		if filepath.Ext(path) == ".proto" {
+			return nil, fmt.Errorf("descriptor set %s: .proto files are passed with --proto", path)
+		}
+
+		return nil, fmt.Errorf("descriptor set %s: %w", path, err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("descriptor set %s: %w", path, err)
+	}
+
+	return &GrpcDescriptors{files: files}, nil
+}
+
+// LoadGrpcProtoFiles parses .proto files and the files they import, like protoc does.
+// Imports are looked up in the import paths, or next to the files when there are none.
+// The well-known types, such as google/protobuf/timestamp.proto, are always found.
+func LoadGrpcProtoFiles(importPaths []string, paths ...string) (*GrpcDescriptors, error) {
+	names := paths
+	if len(importPaths) == 0 {
+		names = make([]string, len(paths))
+		for i, path := range paths {
+			importPaths = appendUnique(importPaths, filepath.Dir(path))
+			names[i] = filepath.Base(path)
+		}
+	}
+
+	parser := protoparse.Parser{ImportPaths: importPaths}
+	parsed, err := parser.ParseFiles(names...)
+	if err != nil {
+		return nil, fmt.Errorf("proto: %w", err)
+	}
+
+	// The set has each file after its imports, as protoc --include_imports writes it.
+	var set descriptorpb.FileDescriptorSet
+	seen := make(map[string]bool)
+	var add func(fd *desc.FileDescriptor)
+	add = func(fd *desc.FileDescriptor) {
+		if seen[fd.GetName()] {
+			return
+		}
+		seen[fd.GetName()] = true
+
+		for _, dep := range fd.GetDependencies() {
+			add(dep)
+		}
+		set.File = append(set.File, fd.AsFileDescriptorProto())
+	}
+
+	for _, fd := range parsed {
+		add(fd)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("proto: %w", err)
+	}
+
+	return &GrpcDescriptors{files: files}, nil
+}
+
+// appendUnique appends s to the list if it is not in it.
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
+
+// LoadGrpcFixtures reads a JSON fixture file which maps full method names to a response,
+// or a list of responses for server streaming methods.
+func LoadGrpcFixtures(path string) (GrpcFixtures, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("grpc fixture %s: %w", path, err)
+	}
+
+	fixtures := make(GrpcFixtures, len(raw))
+	for method, value := range raw {
+		var responses []json.RawMessage
+		if err := json.Unmarshal(value, &responses); err != nil {
+			responses = []json.RawMessage{value}
+		}
+
+		fixtures[method] = responses
+	}
+
+	return fixtures, nil
+}
+
+// responses returns the responses for the method, falling back to the "*" key.
+func (f GrpcFixtures) responses(method string) []json.RawMessage {
+	if responses, ok := f[method]; ok {
+		return responses
+	}
+
+	return f["*"]
+}
+
+// method finds the descriptor of a full method name, ie: /helloworld.Greeter/SayHello.
+func (d *GrpcDescriptors) method(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid method %s", fullMethod)
+	}
+
+	desc, err := d.files.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", parts[0], err)
+	}
+
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", parts[0])
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(parts[1]))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", parts[1], parts[0])
+	}
+
+	return method, nil
+}
+
+// decodeGrpcMessage decodes a message of the type into JSON.
+func decodeGrpcMessage(desc protoreflect.MessageDescriptor, b []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+}
+
+// encodeGrpcMessage encodes JSON into a message of the type.
+func encodeGrpcMessage(desc protoreflect.MessageDescriptor, b []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(msg)
+}
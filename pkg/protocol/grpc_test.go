@@ -0,0 +1,428 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// helloRequest is a helloworld.HelloRequest with the name "rh" in the wire format.
+var helloRequest = []byte{0x0a, 0x02, 'r', 'h'}
+
+// writeGrpcDescriptorSet writes a descriptor set for the helloworld.Greeter service.
+func writeGrpcDescriptorSet(t *testing.T) string {
+	stringField := func(name string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(1),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("helloworld.proto"),
+		Package: proto.String("helloworld"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("HelloRequest"), Field: []*descriptorpb.FieldDescriptorProto{stringField("name")}},
+			{Name: proto.String("HelloReply"), Field: []*descriptorpb.FieldDescriptorProto{stringField("message")}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("SayHello"), InputType: proto.String(".helloworld.HelloRequest"), OutputType: proto.String(".helloworld.HelloReply")},
+				{Name: proto.String("SayHellos"), InputType: proto.String(".helloworld.HelloRequest"), OutputType: proto.String(".helloworld.HelloReply"), ServerStreaming: proto.Bool(true)},
+			},
+		}},
+	}
+
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "helloworld.pb")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// grpcCall sends the messages to the method over h2c and returns the response messages
+// and trailers.
+func grpcCall(t *testing.T, s *Grpc, method string, messages ...[]byte) ([][]byte, http.Header) {
+	var body bytes.Buffer
+	for _, message := range messages {
+		body.Write(grpcFrame(message))
+	}
+
+	return grpcCallBody(t, s, method, &body)
+}
+
+// grpcCallBody sends the body, which has the framed messages, to the method over h2c.
+func grpcCallBody(t *testing.T, s *Grpc, method string, body io.Reader) ([][]byte, http.Header) {
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	req, _ := http.NewRequest("POST", srv.URL+method, body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("X-Request-Id", "abc")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var responses [][]byte
+	for {
+		data, err := readGrpcFrame(resp.Body, "")
+		if err != nil {
+			break
+		}
+		responses = append(responses, data)
+	}
+
+	return responses, resp.Trailer
+}
+
+func TestGrpcRawWireFormat(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 1)
+	grpcServer := &Grpc{rendererChannels: []chan RequestPayload{rpChannel}}
+
+	responses, trailer := grpcCall(t, grpcServer, "/helloworld.Greeter/SayHello", helloRequest)
+	rp := <-rpChannel
+
+	if rp.Fields.Method != "GRPC" {
+		t.Errorf("Expected %s, got %s", "GRPC", rp.Fields.Method)
+	}
+
+	if rp.Fields.Url != "/helloworld.Greeter/SayHello" {
+		t.Errorf("Expected %s, got %s", "/helloworld.Greeter/SayHello", rp.Fields.Url)
+	}
+
+	if rp.Fields.Protocol != "HTTP/2.0" {
+		t.Errorf("Expected %s, got %s", "HTTP/2.0", rp.Fields.Protocol)
+	}
+
+	if rp.Message != `1: "rh"` {
+		t.Errorf("Expected %s, got %s", `1: "rh"`, rp.Message)
+	}
+
+	if http.Header(rp.Headers).Get("X-Request-Id") != "abc" {
+		t.Errorf("Expected metadata, got %v", rp.Headers)
+	}
+
+	if len(responses) != 1 || len(responses[0]) != 0 {
+		t.Errorf("Expected an empty response, got %v", responses)
+	}
+
+	if trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("Expected status 0, got %s", trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestGrpcDescriptorsAndFixtures(t *testing.T) {
+	descriptors, err := LoadGrpcDescriptors(writeGrpcDescriptorSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixturePath := filepath.Join(t.TempDir(), "fixtures.json")
+	ioutil.WriteFile(fixturePath, []byte(`{
+		"/helloworld.Greeter/SayHello": {"message": "hello"},
+		"/helloworld.Greeter/SayHellos": [{"message": "one"}, {"message": "two"}]
+	}`), 0644)
+
+	fixtures, err := LoadGrpcFixtures(fixturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChannel := make(chan RequestPayload, 2)
+	grpcServer := &Grpc{Descriptors: descriptors, Fixtures: fixtures, rendererChannels: []chan RequestPayload{rpChannel}}
+
+	responses, _ := grpcCall(t, grpcServer, "/helloworld.Greeter/SayHello", helloRequest)
+	rp := <-rpChannel
+
+	if rp.Message != `{"name":"rh"}` {
+		t.Errorf("Expected %s, got %s", `{"name":"rh"}`, rp.Message)
+	}
+
+	if rp.ParamFields.Json["name"] != "rh" {
+		t.Errorf("Expected json params, got %v", rp.ParamFields.Json)
+	}
+
+	if len(responses) != 1 || string(responses[0]) != "\x0a\x05hello" {
+		t.Errorf("Expected hello reply, got %q", responses)
+	}
+
+	responses, _ = grpcCall(t, grpcServer, "/helloworld.Greeter/SayHellos", helloRequest, helloRequest)
+	<-rpChannel
+	<-rpChannel
+
+	if len(responses) != 2 || string(responses[1]) != "\x0a\x03two" {
+		t.Errorf("Expected two streamed replies, got %q", responses)
+	}
+}
+
+func TestGrpcStatus(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 1)
+	grpcServer := &Grpc{Status: 5, StatusMessage: "not found: 100%", rendererChannels: []chan RequestPayload{rpChannel}}
+
+	responses, trailer := grpcCall(t, grpcServer, "/helloworld.Greeter/SayHello")
+	rp := <-rpChannel
+
+	if rp.Message != "" || rp.Size != 0 {
+		t.Errorf("Expected an empty message, got %s", rp.Message)
+	}
+
+	if len(responses) != 0 {
+		t.Errorf("Expected no responses, got %v", responses)
+	}
+
+	if trailer.Get("Grpc-Status") != "5" {
+		t.Errorf("Expected status 5, got %s", trailer.Get("Grpc-Status"))
+	}
+
+	if trailer.Get("Grpc-Message") != "not found: 100%25" {
+		t.Errorf("Expected %s, got %s", "not found: 100%25", trailer.Get("Grpc-Message"))
+	}
+}
+
+func TestGrpcFixtureWithoutDescriptors(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 2)
+	grpcServer := &Grpc{
+		Fixtures:         GrpcFixtures{"*": {[]byte(`{"message": "hello"}`)}},
+		rendererChannels: []chan RequestPayload{rpChannel},
+	}
+
+	_, trailer := grpcCall(t, grpcServer, "/helloworld.Greeter/SayHello", helloRequest)
+	<-rpChannel
+	rp := <-rpChannel
+
+	if rp.Fields.Method != "ERROR" {
+		t.Errorf("Expected %s, got %s", "ERROR", rp.Fields.Method)
+	}
+
+	if trailer.Get("Grpc-Status") != "13" {
+		t.Errorf("Expected status 13, got %s", trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestGrpcMessageTooLarge(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 2)
+	grpcServer := &Grpc{rendererChannels: []chan RequestPayload{rpChannel}}
+
+	// Only the header is sent: the message is rejected before anything is allocated.
+	header := []byte{0, 0xff, 0xff, 0xff, 0xff}
+	responses, trailer := grpcCallBody(t, grpcServer, "/helloworld.Greeter/SayHello", bytes.NewReader(header))
+	rp := <-rpChannel
+	<-rpChannel
+
+	if rp.Fields.Method != "ERROR" || rp.Message != errGrpcMessageTooLarge.Error() {
+		t.Errorf("Expected %s %s, got %s %s", "ERROR", errGrpcMessageTooLarge, rp.Fields.Method, rp.Message)
+	}
+
+	if len(responses) != 0 {
+		t.Errorf("Expected no responses, got %v", responses)
+	}
+
+	if trailer.Get("Grpc-Status") != "8" {
+		t.Errorf("Expected status 8, got %s", trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestReadGrpcFrameCompressedTooLarge(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(make([]byte, grpcMaxReceiveSize+1))
+	zw.Close()
+
+	frame := grpcFrame(compressed.Bytes())
+	frame[0] = 1
+
+	if _, err := readGrpcFrame(bytes.NewReader(frame), "gzip"); err != errGrpcMessageTooLarge {
+		t.Errorf("Expected %v, got %v", errGrpcMessageTooLarge, err)
+	}
+}
+
+func TestLoadGrpcDescriptorsProtoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "helloworld.proto")
+	ioutil.WriteFile(path, []byte(`syntax = "proto3";`), 0644)
+
+	_, err := LoadGrpcDescriptors(path)
+	if err == nil || !strings.Contains(err.Error(), "--proto") {
+		t.Errorf("Expected error explaining how to pass .proto files, got %v", err)
+	}
+
+	if _, err := LoadGrpcDescriptors(filepath.Join(t.TempDir(), "missing.pb")); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestLoadGrpcProtoFiles(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "greeting.proto"), []byte(`syntax = "proto3";
+package helloworld;
+
+import "google/protobuf/timestamp.proto";
+
+message HelloRequest {
+  string name = 1;
+  google.protobuf.Timestamp sent_at = 2;
+}
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "helloworld.proto"), []byte(`syntax = "proto3";
+package helloworld;
+
+import "greeting.proto";
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+}
+
+message HelloReply {
+  string message = 1;
+}
+`), 0644)
+
+	descriptors, err := LoadGrpcProtoFiles(nil, filepath.Join(dir, "helloworld.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChannel := make(chan RequestPayload, 1)
+	grpcServer := &Grpc{
+		Descriptors:      descriptors,
+		Fixtures:         GrpcFixtures{"*": {[]byte(`{"message": "hello"}`)}},
+		rendererChannels: []chan RequestPayload{rpChannel},
+	}
+
+	// name "rh" and sent_at 1970-01-01T00:00:01Z.
+	request := append(append([]byte(nil), helloRequest...), 0x12, 0x02, 0x08, 0x01)
+	responses, _ := grpcCall(t, grpcServer, "/helloworld.Greeter/SayHello", request)
+	rp := <-rpChannel
+
+	if rp.ParamFields.Json["name"] != "rh" || rp.ParamFields.Json["sent_at"] != "1970-01-01T00:00:01Z" {
+		t.Errorf("Expected the message decoded with the imported timestamp, got %s", rp.Message)
+	}
+
+	if len(responses) != 1 || string(responses[0]) != "\x0a\x05hello" {
+		t.Errorf("Expected hello reply, got %q", responses)
+	}
+
+	if _, err := LoadGrpcProtoFiles([]string{t.TempDir()}, "helloworld.proto"); err == nil {
+		t.Error("Expected an error for a file which is not in the import paths")
+	}
+}
+
+func TestDecodeProtoWire(t *testing.T) {
+	testTable := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte{0x08, 0x96, 0x01}, "1: 150"},
+		{helloRequest, `1: "rh"`},
+		{[]byte{0x1a, 0x02, 0x08, 0x02}, "3: {1: 2}"},
+		{[]byte{0x25, 0x00, 0x00, 0x80, 0x3f}, "4: 0x3f800000"},
+		{[]byte{}, ""},
+	}
+
+	for _, test := range testTable {
+		result, err := DecodeProtoWire(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, result)
+		}
+	}
+
+	if _, err := DecodeProtoWire([]byte{0x0a, 0x05, 'r'}); err == nil {
+		t.Error("Expected error for truncated message")
+	}
+}
+
+func TestDecodeProtoWireDepth(t *testing.T) {
+	for _, levels := range []int{maxProtoWireDepth, maxProtoWireDepth + 1, 10000} {
+		msg := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 2)
+		for i := 0; i < levels; i++ {
+			msg = protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), msg)
+		}
+
+		result, err := DecodeProtoWire(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Messages deeper than the limit are shown as strings.
+		if levels <= maxProtoWireDepth {
+			expected := strings.Repeat("1: {", levels) + "1: 2" + strings.Repeat("}", levels)
+			if result != expected {
+				t.Errorf("Expected %s, got %s", expected, result)
+			}
+		} else if !strings.HasPrefix(result, strings.Repeat("1: {", maxProtoWireDepth)+`1: "`) || !strings.HasSuffix(result, `"`+strings.Repeat("}", maxProtoWireDepth)) {
+			t.Errorf("Expected %d nested messages and a string, got %.200s", maxProtoWireDepth, result)
+		}
+	}
+
+	group := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 2)
+	for i := 0; i <= maxProtoWireDepth; i++ {
+		group = append(protowire.AppendTag(nil, 1, protowire.StartGroupType), group...)
+		group = protowire.AppendTag(group, 1, protowire.EndGroupType)
+	}
+
+	if _, err := DecodeProtoWire(group); err == nil {
+		t.Error("Expected error for groups nested too deeply")
+	}
+}
+
+func TestEncodeGrpcMessageHeader(t *testing.T) {
+	result := encodeGrpcMessageHeader("café 100%\n")
+	expected := "caf%C3%A9 100%25%0A"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestGrpcQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	grpcServer := Grpc{rendererQuitChannels: chans}
+	grpcServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxProtoWireDepth is the number of nested messages DecodeProtoWire renders, like
+// protoc --decode_raw. Length-delimited fields nested deeper are shown as strings.
+const maxProtoWireDepth = 64
+
+// errProtoWireDepth is returned for groups nested deeper than maxProtoWireDepth.
+var errProtoWireDepth = errors.New("message is nested too deeply")
+
+// DecodeProtoWire renders a protobuf message without its schema, similar to
+// protoc --decode_raw, ie: 1: 150 2: "hello" 3: {1: 2}. Length-delimited fields are shown
+// as nested messages when they parse as one, otherwise as strings.
+func DecodeProtoWire(b []byte) (string, error) {
+	var sb strings.Builder
+	if err := decodeProtoWire(&sb, b, 0); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// decodeProtoWire writes the fields of a message nested depth messages deep to sb.
+func decodeProtoWire(sb *strings.Builder, b []byte, depth int) error {
+	for first := true; len(b) > 0; first = false {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if !first {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(strconv.FormatInt(int64(num), 10))
+		sb.WriteString(": ")
+
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			sb.WriteString(strconv.FormatUint(v, 10))
+			b = b[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(sb, "0x%08x", v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(sb, "0x%016x", v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			formatProtoBytes(sb, v, depth+1)
+			b = b[n:]
+		case protowire.StartGroupType:
+			v, n := protowire.ConsumeGroup(num, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if depth+1 > maxProtoWireDepth {
+				return errProtoWireDepth
+			}
+			sb.WriteByte('{')
+			if err := decodeProtoWire(sb, v, depth+1); err != nil {
+				return err
+			}
+			sb.WriteByte('}')
+			b = b[n:]
+		default:
+			return fmt.Errorf("unexpected wire type %d", typ)
+		}
+	}
+
+	return nil
+}
+
+// formatProtoBytes writes a length-delimited field nested depth messages deep to sb, as a
+// nested message, or as a quoted string if it does not parse as one or is too deep.
+func formatProtoBytes(sb *strings.Builder, b []byte, depth int) {
+	if len(b) > 0 && depth <= maxProtoWireDepth {
+		var nested strings.Builder
+		if err := decodeProtoWire(&nested, b, depth); err == nil {
+			sb.WriteByte('{')
+			sb.WriteString(nested.String())
+			sb.WriteByte('}')
+
+			return
+		}
+	}
+
+	if utf8.Valid(b) {
+		sb.WriteString(strconv.Quote(string(b)))
+		return
+	}
+
+	fmt.Fprintf(sb, "%q", b)
+}
@@ -1,9 +1,17 @@
 package protocol
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aaronvb/logparams"
@@ -12,6 +20,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pterm/pterm"
 	"github.com/rs/cors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Http is the protocol for accepting http requests.
@@ -26,6 +36,72 @@ type Http struct {
 	// Default is 200 if no response code is passed.
 	ResponseCode int
 
+	// ResponseBody is written after the response code.
+	ResponseBody string
+
+	// ResponseHeaders are set on every response.
+	ResponseHeaders map[string]string
+
+	// Timing delays and slows down every response, unless a rule overrides it.
+	Timing HttpTiming
+
+	// Rules override the timing and responses of the requests they match.
+	Rules []HttpRule
+
+	// TLSConfig serves HTTPS when set, which negotiates HTTP/2 with clients that
+	// support it. Without TLS, HTTP/2 is accepted with h2c.
+	TLSConfig *tls.Config
+
+	// UnixSocket is the path of a Unix domain socket to listen on instead of the
+	// address and port.
+	UnixSocket string
+
+	// UnixSocketMode sets the permissions of the Unix socket.
+	UnixSocketMode os.FileMode
+
+	// Signature verifies the webhook signature of each request when set.
+	Signature *SignatureVerifier
+
+	// RejectInvalidSignature answers requests with an invalid or missing signature
+	// with 401.
+	RejectInvalidSignature bool
+
+	// Chaos injects faults into a percentage of the requests when set.
+	Chaos *Chaos
+
+	// RateLimit answers requests over the limit with 429 when set.
+	RateLimit *RateLimit
+
+	// OpenAPI validates requests against a spec when set, and answers them with the
+	// responses of the spec, unless a rule has responses for them.
+	OpenAPI *OpenAPI
+
+	// Schemas validate the JSON bodies of the requests they match, instead of the
+	// OpenAPI spec.
+	Schemas []SchemaRoute
+
+	// StrictValidation answers requests which fail validation with 422 and the errors.
+	StrictValidation bool
+
+	// UploadDir is the directory files uploaded with multipart forms are stored in.
+	// Files are not stored if it is empty.
+	UploadDir string
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// sequenceCalls counts the requests answered by the responses of each rule, by the
+	// index of the rule.
+	sequenceCalls map[int]int
+	sequenceMu    sync.Mutex
+
+	// live has the settings new requests are answered with once they were reloaded.
+	live   *Http
+	liveMu sync.RWMutex
+
+	// control has the changes made to the default response while the server runs.
+	control *httpControl
+
 	// rendererChannel is the channel which we send a RequestPayload to when
 	// receiving an incoming request to the Http protocol.
 	rendererChannels     []chan RequestPayload
@@ -43,16 +119,28 @@ func (s *Http) Start(c []chan RequestPayload, quits []chan int, errors []chan in
 	errorLog := log.New(&httpErrorLog{}, "", 0)
 
 	srv := &http.Server{
-		Addr:     addr,
-		ErrorLog: errorLog,
-		Handler:  s.routes(),
+		Addr:        addr,
+		ErrorLog:    errorLog,
+		Handler:     s.routes(),
+		TLSConfig:   s.TLSConfig,
+		ConnContext: connContext,
 	}
 
 	s.rendererChannels = c
 	s.rendererQuitChannels = quits
 
 	go func() {
-		err := srv.ListenAndServe()
+		_, err := s.Bind()
+		if err == nil {
+			if s.TLSConfig != nil {
+				if err = configureHTTP2(srv); err == nil {
+					err = srv.ServeTLS(s.listener, "", "")
+				}
+			} else {
+				err = srv.Serve(frameTapListener{s.listener})
+			}
+		}
+
 		str := pterm.Error.WithShowLineNumber(false).Sprintf("Http Protocol: %s\n", err)
 		pterm.Printo(str) // Overwrite last line
 
@@ -69,6 +157,21 @@ func (s *Http) Start(c []chan RequestPayload, quits []chan int, errors []chan in
 	}
 }
 
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Http) Bind() (net.Addr, error) {
+	if s.listener == nil {
+		ln, err := listen(s.Addr, s.Port, s.UnixSocket, s.UnixSocketMode)
+		if err != nil {
+			return nil, err
+		}
+
+		s.listener = ln
+	}
+
+	return s.listener.Addr(), nil
+}
+
 func (s *Http) quitRenderers() {
 	for _, quit := range s.rendererQuitChannels {
 		quit <- 1
@@ -76,6 +179,7 @@ func (s *Http) quitRenderers() {
 }
 
 // routes handles the routes for our HTTP server and currently accepts any path.
+// Cleartext HTTP/2 is accepted with prior knowledge or an h2c upgrade.
 func (s *Http) routes() http.Handler {
 	r := mux.NewRouter()
 	r.PathPrefix("/").HandlerFunc(s.defaultHandler)
@@ -83,38 +187,308 @@ func (s *Http) routes() http.Handler {
 
 	handler := cors.AllowAll().Handler(r)
 
-	return handler
+	return h2c.NewHandler(handler, &http2.Server{})
 }
 
-// defaultHandler returns the response code which is provided as a flag.
-// Defaults to 200.
+// defaultHandler returns the response code, headers and body which are provided as flags
+// or changed while the server runs, or the response picked from the sequence of the rule
+// the request matches, as slowly as the timing of the request says. Defaults to 200.
+//
+// If the client goes away while we wait, the response is abandoned.
 func (s *Http) defaultHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(s.ResponseCode)
+	s = s.requestSettings(r)
+	defaults, _ := s.defaults()
+	timing := s.timing(r)
+	ctx := r.Context()
+
+	if timing.Delay != nil && !sleep(ctx, timing.Delay.sample(globalRand{})) {
+		return
+	}
+
+	code, body := defaults.StatusCode, []byte(defaults.Body)
+	for key, value := range defaults.Headers {
+		w.Header().Set(key, value)
+	}
+
+	if response, ok := ctx.Value(responseKey{}).(*HttpResponse); ok {
+		if response.StatusCode != 0 {
+			code = response.StatusCode
+		}
+
+		body = []byte(response.Body)
+		for key, value := range response.Headers {
+			w.Header().Set(key, value)
+		}
+	}
+
+	if timing.Trickle > 0 && len(body) > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	w.WriteHeader(code)
+
+	if timing.StallAfterHeaders > 0 {
+		flush(w)
+		if !sleep(ctx, timing.StallAfterHeaders) {
+			return
+		}
+	}
+
+	if timing.Trickle <= 0 {
+		w.Write(body)
+		return
+	}
+
+	chunk := timing.TrickleChunk
+	if chunk <= 0 {
+		chunk = 1
+	}
+
+	for i := 0; i < len(body); i += chunk {
+		if i > 0 && !sleep(ctx, timing.Trickle) {
+			return
+		}
+
+		end := i + chunk
+		if end > len(body) {
+			end = len(body)
+		}
+
+		if _, err := w.Write(body[i:end]); err != nil {
+			return
+		}
+		flush(w)
+	}
+}
+
+// timing returns the timing of the request, from the rule it matches.
+func (s *Http) timing(r *http.Request) HttpTiming {
+	_, timing := s.defaults()
+	if _, rule := s.rule(r); rule != nil {
+		return rule.Apply(timing)
+	}
+
+	return timing
+}
+
+// RateLimits returns the counters of the rate limit, or nil if requests are not rate
+// limited.
+func (s *Http) RateLimits() []RateLimitCounter {
+	if live := s.current(); live != s {
+		return live.RateLimits()
+	}
+
+	if s.RateLimit == nil {
+		return nil
+	}
+
+	return s.RateLimit.Counters()
+}
+
+// rule returns the first rule the request matches and its index, or nil if it matches
+// none.
+func (s *Http) rule(r *http.Request) (int, *HttpRule) {
+	for i := range s.Rules {
+		if s.Rules[i].Matches(r) {
+			return i, &s.Rules[i]
+		}
+	}
+
+	return -1, nil
 }
 
 // logRequest is the middleware that passes the request data and parameters to
 // the Renderer IncomingRequest interface method.
 func (s *Http) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		lr := logrequest.LogRequest{Request: r, Writer: w, Handler: next}
-		fields := lr.ToFields()
+		live := s.current()
+		r = r.WithContext(context.WithValue(r.Context(), settingsKey{}, live))
+
+		streamID := requestStreamID(r)
+		trailers := readTrailers(r)
+		signature := live.verifySignature(r)
+		encoding := decodeBody(r)
+		attachments := readUploadedFiles(r, live.UploadDir)
+
+		validation, mock, routed := live.validate(r)
+
+		var fault *ChaosFault
+		if live.Chaos != nil {
+			fault = live.Chaos.Pick()
+		}
+
+		// Requests which are rejected do not count against the rate limit or the response
+		// sequences.
+		var sequence *SequencePosition
+		handler := next
+		switch {
+		case live.paused():
+			handler = pausedHandler()
+		case fault != nil:
+			handler = chaosHandler(fault)
+		case signature != nil && signature.Result != SignatureValid && live.RejectInvalidSignature:
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, signature.String(), http.StatusUnauthorized)
+			})
+		case validation != nil && !validation.Valid && routed && live.StrictValidation:
+			handler = validationHandler(validation)
+		default:
+			allowed := true
+			if live.RateLimit != nil {
+				handler, allowed = live.RateLimit.handler(next, r)
+			}
+
+			if allowed {
+				var response *HttpResponse
+				response, sequence = live.nextResponse(r)
+				if response == nil {
+					response = mock
+				}
+
+				if response != nil {
+					r = r.WithContext(context.WithValue(r.Context(), responseKey{}, response))
+				}
+			}
+		}
+
+		flusher, _ := w.(http.Flusher)
+		inner := handler
+		aborted := false
+		handler = http.HandlerFunc(func(lw http.ResponseWriter, r *http.Request) {
+			// A reset fault aborts the handler, which is done again once the request is
+			// logged.
+			defer func() {
+				if err := recover(); err != nil {
+					if err != http.ErrAbortHandler {
+						panic(err)
+					}
+					aborted = true
+				}
+			}()
+
+			inner.ServeHTTP(&flushWriter{ResponseWriter: lw, flusher: flusher}, r)
+		})
+
 		params := logparams.LogParams{Request: r, HidePrefix: true}
+		logPayload := func(fields logrequest.RequestFields) {
+			req := RequestPayload{
+				ID:          uuid.New().String(),
+				Fields:      fields,
+				Message:     params.ToString(),
+				Headers:     r.Header,
+				ParamFields: params.ToFields(),
+				CreatedAt:   time.Now(),
+				StreamID:    streamID,
+				Trailers:    trailers,
+				Peer:        requestPeerCredentials(r),
+				Attachments: attachments,
+				Encoding:    encoding,
+				Signature:   signature,
+				Fault:       fault,
+				Sequence:    sequence,
+				Validation:  validation,
+			}
+
+			for _, rendererChannel := range s.rendererChannels {
+				rendererChannel <- req
+			}
+		}
 
-		req := RequestPayload{
-			ID:          uuid.New().String(),
-			Fields:      fields,
-			Message:     params.ToString(),
-			Headers:     r.Header,
-			ParamFields: params.ToFields(),
-			CreatedAt:   time.Now(),
+		// A hanging request is logged when the hang starts rather than when the client
+		// gives up, which can be never.
+		if fault != nil && fault.Kind == ChaosHang {
+			logPayload(logrequest.RequestFields{
+				Method:        r.Method,
+				Url:           r.URL.RequestURI(),
+				RemoteAddress: r.RemoteAddr,
+				Protocol:      r.Proto,
+				Time:          time.Now(),
+			})
+			handler.ServeHTTP(w, r)
+			return
 		}
 
-		for _, rendererChannel := range s.rendererChannels {
-			rendererChannel <- req
+		lr := logrequest.LogRequest{Request: r, Writer: w, Handler: handler}
+		logPayload(lr.ToFields())
+
+		if aborted {
+			panic(http.ErrAbortHandler)
 		}
 	})
 }
 
+// verifySignature verifies the signature of the request, or returns nil if signatures
+// are not verified. The signature is verified over the body as it was received.
+func (s *Http) verifySignature(r *http.Request) *SignatureVerification {
+	if s.Signature == nil {
+		return nil
+	}
+
+	verification := s.Signature.Verify(r, readBody(r))
+
+	return &verification
+}
+
+// readTrailers returns the trailers the client declared. Trailers arrive after the body,
+// so the body is read first.
+func readTrailers(r *http.Request) map[string][]string {
+	if len(r.Trailer) == 0 {
+		return nil
+	}
+
+	readBody(r)
+
+	return r.Trailer
+}
+
+// readBody reads the body and replaces it with a copy, so it can be read again by the
+// params.
+func readBody(r *http.Request) []byte {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body
+}
+
+// sleep waits for the duration, and returns false if the context is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// flushWriter is the response writer the handlers get. logrequest wraps the response
+// writer in a type which does not implement http.Flusher, so flushWriter flushes the
+// response writer logrequest wraps.
+type flushWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+}
+
+// Flush sends what was written to the client.
+func (w *flushWriter) Flush() {
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+}
+
+// flush sends what was written to the client, if the response writer can.
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // httpErrorLog implements the logger interface.
 type httpErrorLog struct{}
 
@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ResponseSettings is the default response of the http protocol, which answers the
+// requests no rule or spec answers.
+type ResponseSettings struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+
+	// Delay is the latency spec every response is delayed by, empty for none.
+	Delay string `json:"delay"`
+
+	// Paused answers every request with 503 while it is true.
+	Paused bool `json:"paused"`
+}
+
+// ResponseUpdate changes the default response while the protocol runs. Fields which are
+// nil are left as they are, and an empty delay removes the delay.
+type ResponseUpdate struct {
+	StatusCode *int
+	Headers    map[string]string
+	Body       *string
+	Delay      *string
+}
+
+// httpControl has the changes made to the default response while the protocol runs. It
+// is shared with the settings a reload replaces, so the changes outlive reloads.
+type httpControl struct {
+	mu     sync.RWMutex
+	update ResponseUpdate
+	delay  *Latency
+	paused bool
+}
+
+// controls returns the changes made to the default response. Every request reads them,
+// so the exclusive lock is only taken the first time, to create them.
+func (s *Http) controls() *httpControl {
+	s.liveMu.RLock()
+	c := s.control
+	s.liveMu.RUnlock()
+
+	if c != nil {
+		return c
+	}
+
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+
+	if s.control == nil {
+		s.control = &httpControl{}
+	}
+
+	return s.control
+}
+
+// Response returns the default response, with the changes made while the protocol runs.
+func (s *Http) Response() ResponseSettings {
+	response, _ := s.current().defaults()
+
+	return response
+}
+
+// defaults returns the default response and timing of the settings, with the changes made
+// while the protocol runs.
+func (s *Http) defaults() (ResponseSettings, HttpTiming) {
+	c := s.controls()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	response := ResponseSettings{
+		StatusCode: s.ResponseCode,
+		Headers:    make(map[string]string),
+		Body:       s.ResponseBody,
+		Paused:     c.paused,
+	}
+
+	headers := s.ResponseHeaders
+	if c.update.Headers != nil {
+		headers = c.update.Headers
+	}
+
+	for key, value := range headers {
+		response.Headers[key] = value
+	}
+
+	if c.update.StatusCode != nil {
+		response.StatusCode = *c.update.StatusCode
+	}
+
+	if c.update.Body != nil {
+		response.Body = *c.update.Body
+	}
+
+	timing := s.Timing
+	if c.update.Delay != nil {
+		timing.Delay = c.delay
+	}
+
+	if timing.Delay != nil {
+		response.Delay = timing.Delay.String()
+	}
+
+	return response, timing
+}
+
+// UpdateResponse changes the fields of the default response which are set, until the
+// protocol stops or ResetResponse is called. Reloads keep the changes. Rules and specs
+// with responses still answer the requests they match.
+func (s *Http) UpdateResponse(update ResponseUpdate) (ResponseSettings, error) {
+	if update.StatusCode != nil && (*update.StatusCode < 100 || *update.StatusCode > 999) {
+		return s.Response(), fmt.Errorf("invalid status %d", *update.StatusCode)
+	}
+
+	var delay *Latency
+	if update.Delay != nil && *update.Delay != "" {
+		var err error
+		if delay, err = ParseLatency(*update.Delay); err != nil {
+			return s.Response(), err
+		}
+	}
+
+	c := s.controls()
+	c.mu.Lock()
+	if update.StatusCode != nil {
+		c.update.StatusCode = update.StatusCode
+	}
+
+	if update.Headers != nil {
+		c.update.Headers = update.Headers
+	}
+
+	if update.Body != nil {
+		c.update.Body = update.Body
+	}
+
+	if update.Delay != nil {
+		c.update.Delay = update.Delay
+		c.delay = delay
+	}
+	c.mu.Unlock()
+
+	return s.Response(), nil
+}
+
+// ResetResponse undoes the changes made to the default response. Requests stay paused.
+func (s *Http) ResetResponse() ResponseSettings {
+	c := s.controls()
+	c.mu.Lock()
+	c.update = ResponseUpdate{}
+	c.delay = nil
+	c.mu.Unlock()
+
+	return s.Response()
+}
+
+// SetPaused pauses the protocol, which answers every request with 503 until it is
+// resumed, or resumes it.
+func (s *Http) SetPaused(paused bool) ResponseSettings {
+	c := s.controls()
+	c.mu.Lock()
+	c.paused = paused
+	c.mu.Unlock()
+
+	return s.Response()
+}
+
+// paused returns true if requests are answered with 503.
+func (s *Http) paused() bool {
+	c := s.controls()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.paused
+}
+
+// pausedHandler answers a request while the protocol is paused.
+func pausedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	})
+}
@@ -0,0 +1,176 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHttpUpdateResponse(t *testing.T) {
+	httpServer := &Http{
+		ResponseCode:    200,
+		ResponseBody:    "ok",
+		ResponseHeaders: map[string]string{"X-Server": "rh"},
+	}
+
+	expected := ResponseSettings{StatusCode: 200, Headers: map[string]string{"X-Server": "rh"}, Body: "ok"}
+	if response := httpServer.Response(); !reflect.DeepEqual(response, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+
+	code, body, delay := 503, "failing", "100ms-2s"
+	response, err := httpServer.UpdateResponse(ResponseUpdate{StatusCode: &code, Body: &body, Delay: &delay})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = ResponseSettings{StatusCode: 503, Headers: map[string]string{"X-Server": "rh"}, Body: "failing", Delay: "100ms-2s"}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+
+	empty := ""
+	response, err = httpServer.UpdateResponse(ResponseUpdate{Headers: map[string]string{"Retry-After": "30"}, Delay: &empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = ResponseSettings{StatusCode: 503, Headers: map[string]string{"Retry-After": "30"}, Body: "failing"}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+
+	expected = ResponseSettings{StatusCode: 200, Headers: map[string]string{"X-Server": "rh"}, Body: "ok"}
+	if response := httpServer.ResetResponse(); !reflect.DeepEqual(response, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+}
+
+func TestHttpUpdateResponseErrors(t *testing.T) {
+	httpServer := &Http{ResponseCode: 200}
+	code, delay := 42, "soon"
+
+	tests := []ResponseUpdate{
+		{StatusCode: &code},
+		{Delay: &delay},
+	}
+
+	for _, update := range tests {
+		if _, err := httpServer.UpdateResponse(update); err == nil {
+			t.Errorf("%+v: expected an error", update)
+		}
+	}
+
+	if response := httpServer.Response(); response.StatusCode != 200 || response.Delay != "" {
+		t.Errorf("Expected the response to be kept, got %+v", response)
+	}
+}
+
+func TestHttpControl(t *testing.T) {
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := &Http{
+		ResponseCode:     200,
+		Rules:            []HttpRule{{Path: "/charge", Responses: []HttpResponse{{StatusCode: 402}}}},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	get := func(path string) (int, string, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		<-rpChan
+
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.StatusCode, resp.Header.Get("Retry-After"), string(body)
+	}
+
+	code, body := 500, "failing"
+	if _, err := httpServer.UpdateResponse(ResponseUpdate{StatusCode: &code, Body: &body, Headers: map[string]string{"Retry-After": "30"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := httpServer.Reload(&Http{ResponseCode: 201, Rules: httpServer.Rules}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		paused     bool
+		path       string
+		code       int
+		retryAfter string
+		body       string
+	}{
+		{false, "/", 500, "30", "failing"},
+		{false, "/charge", 402, "30", ""},
+		{true, "/", 503, "", "Service Unavailable\n"},
+		{true, "/charge", 503, "", "Service Unavailable\n"},
+		{false, "/", 500, "30", "failing"},
+	}
+
+	for _, tc := range tests {
+		if response := httpServer.SetPaused(tc.paused); response.Paused != tc.paused {
+			t.Errorf("Expected paused to be %t", tc.paused)
+		}
+
+		code, retryAfter, body := get(tc.path)
+		if code != tc.code || retryAfter != tc.retryAfter || body != tc.body {
+			t.Errorf("%s paused %t: expected %d %q %q, got %d %q %q", tc.path, tc.paused, tc.code, tc.retryAfter, tc.body, code, retryAfter, body)
+		}
+	}
+
+	if sequences := httpServer.Sequences(); sequences[0].Calls != 1 {
+		t.Errorf("Expected paused requests not to count against the sequence, got %d calls", sequences[0].Calls)
+	}
+
+	if response := httpServer.ResetResponse(); response.StatusCode != 201 {
+		t.Errorf("Expected the reloaded response code 201, got %d", response.StatusCode)
+	}
+}
+
+func TestHttpControlConcurrent(t *testing.T) {
+	rpChan := make(chan RequestPayload, 100)
+	httpServer := &Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChan}}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+
+			code := 500 + i
+			httpServer.UpdateResponse(ResponseUpdate{StatusCode: &code})
+			httpServer.SetPaused(i%2 == 0)
+			if i%5 == 0 {
+				httpServer.Reload(&Http{ResponseCode: 201})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if httpServer.controls() != httpServer.current().controls() {
+		t.Error("Expected reloads to share the changes to the default response")
+	}
+}
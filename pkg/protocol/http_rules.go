@@ -0,0 +1,199 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// HttpTiming controls how slowly the http protocol responds.
+type HttpTiming struct {
+	// Delay is waited before the response headers are written. Default is nil, which
+	// responds instantly.
+	Delay *Latency
+
+	// StallAfterHeaders is waited after the response headers are flushed, before the
+	// body is written.
+	StallAfterHeaders time.Duration
+
+	// Trickle writes the body in chunks of TrickleChunk bytes, waiting Trickle before
+	// each chunk after the first. Default is 0, which writes the body at once.
+	Trickle      time.Duration
+	TrickleChunk int
+}
+
+// Sequence repeat modes, for once every response of a sequence was sent.
+const (
+	RepeatLast  = "last"
+	RepeatCycle = "cycle"
+)
+
+// HttpResponse is a response sent by a rule.
+type HttpResponse struct {
+	// StatusCode defaults to the response code passed as a flag.
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+}
+
+// HttpRule overrides the timing and responses of requests matching its method and path.
+// Fields which are not set keep the values passed as flags.
+type HttpRule struct {
+	// Method matches the request method, any method matches if it is empty.
+	Method string
+
+	// Path matches the request path, with * matching a single segment(ie: /hooks/*).
+	Path string
+
+	Delay             *Latency
+	StallAfterHeaders *time.Duration
+	Trickle           *time.Duration
+	TrickleChunk      int
+
+	// Responses are sent in order, one for each request. Once every response was
+	// sent, Repeat either keeps sending the last one or cycles from the first.
+	Responses []HttpResponse
+	Repeat    string
+}
+
+// Route returns the method and path the rule matches, ie: POST /charge.
+func (rule HttpRule) Route() string {
+	return strings.TrimSpace(rule.Method + " " + rule.Path)
+}
+
+// Matches returns true if the rule applies to the request.
+func (rule HttpRule) Matches(r *http.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+		return false
+	}
+
+	if rule.Path == "" {
+		return true
+	}
+
+	matched, _ := path.Match(rule.Path, r.URL.Path)
+
+	return matched
+}
+
+// Apply returns the timing with the fields set on the rule replaced.
+func (rule HttpRule) Apply(timing HttpTiming) HttpTiming {
+	if rule.Delay != nil {
+		timing.Delay = rule.Delay
+	}
+
+	if rule.StallAfterHeaders != nil {
+		timing.StallAfterHeaders = *rule.StallAfterHeaders
+	}
+
+	if rule.Trickle != nil {
+		timing.Trickle = *rule.Trickle
+	}
+
+	if rule.TrickleChunk > 0 {
+		timing.TrickleChunk = rule.TrickleChunk
+	}
+
+	return timing
+}
+
+// LoadHttpRules reads a JSON file containing a list of rules. The first rule matching a
+// request is applied. Delay is a latency spec(see ParseLatency), and the stall and
+// trickle are durations(ie: 500ms). A response body can be a string or any JSON value,
+// and repeat is last(the default) or cycle.
+func LoadHttpRules(file string) ([]HttpRule, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		Method            string `json:"method"`
+		Path              string `json:"path"`
+		Delay             string `json:"delay"`
+		StallAfterHeaders string `json:"stall_after_headers"`
+		Trickle           string `json:"trickle"`
+		TrickleChunk      int    `json:"trickle_chunk"`
+		Responses         []struct {
+			Status  int               `json:"status"`
+			Headers map[string]string `json:"headers"`
+			Body    json.RawMessage   `json:"body"`
+		} `json:"responses"`
+		Repeat string `json:"repeat"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("http rules %s: %w", file, err)
+	}
+
+	rules := make([]HttpRule, 0, len(raw))
+	for _, r := range raw {
+		rule := HttpRule{Method: r.Method, Path: r.Path, TrickleChunk: r.TrickleChunk, Repeat: RepeatLast}
+
+		if _, err := path.Match(r.Path, "/"); err != nil {
+			return nil, fmt.Errorf("http rules %s: path %q: %w", file, r.Path, err)
+		}
+
+		if r.Delay != "" {
+			delay, err := ParseLatency(r.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("http rules %s: %w", file, err)
+			}
+			rule.Delay = delay
+		}
+
+		if rule.StallAfterHeaders, err = parseRuleDuration(r.StallAfterHeaders); err != nil {
+			return nil, fmt.Errorf("http rules %s: %w", file, err)
+		}
+
+		if rule.Trickle, err = parseRuleDuration(r.Trickle); err != nil {
+			return nil, fmt.Errorf("http rules %s: %w", file, err)
+		}
+
+		if r.Repeat != "" {
+			if r.Repeat != RepeatLast && r.Repeat != RepeatCycle {
+				return nil, fmt.Errorf("http rules %s: unknown repeat %q, expected last or cycle", file, r.Repeat)
+			}
+			rule.Repeat = r.Repeat
+		}
+
+		for _, response := range r.Responses {
+			body, err := fixtureText(response.Body)
+			if err != nil {
+				return nil, fmt.Errorf("http rules %s: %w", file, err)
+			}
+
+			if response.Status != 0 && (response.Status < 100 || response.Status > 999) {
+				return nil, fmt.Errorf("http rules %s: invalid status %d", file, response.Status)
+			}
+
+			rule.Responses = append(rule.Responses, HttpResponse{
+				StatusCode: response.Status,
+				Headers:    response.Headers,
+				Body:       body,
+			})
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parseRuleDuration parses a duration of a rule, or returns nil if it is not set.
+func parseRuleDuration(s string) (*time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,217 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadHttpRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	rules := `[
+		{"method": "POST", "path": "/hooks/*", "delay": "100ms-2s", "trickle": "50ms", "trickle_chunk": 4},
+		{"path": "/slow", "stall_after_headers": "5s"},
+		{"delay": "0"}
+	]`
+	if err := ioutil.WriteFile(file, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadHttpRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(loaded))
+	}
+
+	hooks := loaded[0]
+	if hooks.Method != "POST" || hooks.Path != "/hooks/*" || hooks.Delay.String() != "100ms-2s" {
+		t.Errorf("Unexpected rule %+v", hooks)
+	}
+
+	if *hooks.Trickle != 50*time.Millisecond || hooks.TrickleChunk != 4 || hooks.StallAfterHeaders != nil {
+		t.Errorf("Unexpected rule %+v", hooks)
+	}
+
+	if *loaded[1].StallAfterHeaders != 5*time.Second || loaded[1].Delay != nil {
+		t.Errorf("Unexpected rule %+v", loaded[1])
+	}
+}
+
+func TestLoadHttpRulesErrors(t *testing.T) {
+	tests := []string{
+		`{"path": "/"}`,
+		`[{"delay": "soon"}]`,
+		`[{"trickle": "soon"}]`,
+		`[{"path": "/hooks/["}]`,
+	}
+
+	for _, rules := range tests {
+		file := filepath.Join(t.TempDir(), "rules.json")
+		if err := ioutil.WriteFile(file, []byte(rules), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadHttpRules(file); err == nil {
+			t.Errorf("%s: expected an error", rules)
+		}
+	}
+
+	if _, err := LoadHttpRules(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
+
+func TestHttpTimingRules(t *testing.T) {
+	fixed, _ := ParseLatency("2s")
+	none, _ := ParseLatency("0")
+	stall := time.Second
+
+	s := Http{
+		Timing: HttpTiming{Delay: fixed, TrickleChunk: 8},
+		Rules: []HttpRule{
+			{Method: "post", Path: "/hooks/*", Delay: none, StallAfterHeaders: &stall},
+			{Path: "/hooks/*/retry", TrickleChunk: 2},
+		},
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		timing HttpTiming
+	}{
+		{http.MethodPost, "/hooks/github", HttpTiming{Delay: none, StallAfterHeaders: stall, TrickleChunk: 8}},
+		{http.MethodGet, "/hooks/github", HttpTiming{Delay: fixed, TrickleChunk: 8}},
+		{http.MethodPost, "/hooks/github/retry", HttpTiming{Delay: fixed, TrickleChunk: 2}},
+		{http.MethodGet, "/", HttpTiming{Delay: fixed, TrickleChunk: 8}},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if timing := s.timing(r); timing != tc.timing {
+			t.Errorf("%s %s: expected %+v, got %+v", tc.method, tc.path, tc.timing, timing)
+		}
+	}
+}
+
+func TestHttpDelay(t *testing.T) {
+	delay, _ := ParseLatency("100ms")
+	none, _ := ParseLatency("0")
+	httpServer := Http{
+		ResponseCode: 202,
+		Timing:       HttpTiming{Delay: delay},
+		Rules:        []HttpRule{{Path: "/fast", Delay: none}},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	for path, min := range map[string]time.Duration{"/slow": 100 * time.Millisecond, "/fast": 0} {
+		start := time.Now()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		elapsed := time.Since(start)
+		if resp.StatusCode != 202 {
+			t.Errorf("Expected 202, got %d", resp.StatusCode)
+		}
+
+		if elapsed < min || (min == 0 && elapsed >= 100*time.Millisecond) {
+			t.Errorf("%s: expected a delay of %s, took %s", path, min, elapsed)
+		}
+	}
+}
+
+func TestHttpStallAfterHeaders(t *testing.T) {
+	httpServer := Http{
+		ResponseCode: 200,
+		ResponseBody: "hello",
+		Timing:       HttpTiming{StallAfterHeaders: 200 * time.Millisecond},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if headers := time.Since(start); headers >= 200*time.Millisecond {
+		t.Errorf("Expected the headers before the stall, took %s", headers)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello" {
+		t.Errorf("Expected hello, got %q", body)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the body after the stall, took %s", elapsed)
+	}
+}
+
+func TestHttpTrickle(t *testing.T) {
+	httpServer := Http{
+		ResponseCode: 200,
+		ResponseBody: "0123456789",
+		Timing:       HttpTiming{Trickle: 20 * time.Millisecond, TrickleChunk: 3},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength != 10 {
+		t.Errorf("Expected a content length of 10, got %d", resp.ContentLength)
+	}
+
+	var chunks []string
+	buf := make([]byte, 10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			chunks = append(chunks, string(buf[:n]))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// 4 chunks with 3 waits between them.
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected the body to trickle for 60ms, took %s", elapsed)
+	}
+
+	expected := []string{"012", "345", "678", "9"}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected chunks %q, got %q", expected, chunks)
+	}
+
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("Expected chunks %q, got %q", expected, chunks)
+		}
+	}
+}
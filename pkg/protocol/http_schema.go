@@ -0,0 +1,151 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SchemaRoute validates the JSON bodies of the requests matching its method and path
+// against a JSON Schema (draft 2020-12).
+type SchemaRoute struct {
+	// Method matches any method when empty.
+	Method string
+
+	// Path is a glob matched with path.Match, ie: /orders/*.
+	Path string
+
+	// File is the schema file.
+	File string
+
+	schema    interface{}
+	validator *schemaValidator
+}
+
+// LoadSchemaRoute parses a route and loads its schema, ie: POST /orders/*=order.json. The
+// method is optional. $refs are resolved within the schema file, and against the files
+// next to it, ie: address.json#/$defs/street.
+func LoadSchemaRoute(spec string) (SchemaRoute, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || parts[1] == "" {
+		return SchemaRoute{}, fmt.Errorf("schema %q: expected a path and a schema file such as POST /orders/*=order.json", spec)
+	}
+
+	route := SchemaRoute{File: parts[1]}
+	fields := strings.Fields(parts[0])
+	switch len(fields) {
+	case 1:
+		route.Path = fields[0]
+	case 2:
+		route.Method, route.Path = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return SchemaRoute{}, fmt.Errorf("schema %q: expected an optional method and a path before =", spec)
+	}
+
+	if _, err := path.Match(route.Path, ""); err != nil {
+		return SchemaRoute{}, fmt.Errorf("schema %q: %w", spec, err)
+	}
+
+	schema, err := readSchemaFile(route.File)
+	if err != nil {
+		return SchemaRoute{}, fmt.Errorf("schema %s: %w", route.File, err)
+	}
+
+	route.schema = schema
+	switch route.schema.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return SchemaRoute{}, fmt.Errorf("schema %s: expected an object or a boolean", route.File)
+	}
+
+	route.validator, err = newSchemaValidator(route.schema, route.File)
+	if err != nil {
+		return SchemaRoute{}, fmt.Errorf("schema %s: %w", route.File, err)
+	}
+
+	return route, nil
+}
+
+// String returns the route with its schema file, ie: POST /orders/* (order.json).
+func (route SchemaRoute) String() string {
+	return fmt.Sprintf("%s (%s)", HttpRule{Method: route.Method, Path: route.Path}.Route(), filepath.Base(route.File))
+}
+
+// Matches returns true if the route applies to the request.
+func (route SchemaRoute) Matches(r *http.Request) bool {
+	return HttpRule{Method: route.Method, Path: route.Path}.Matches(r)
+}
+
+// Validate validates the body of the request against the schema. Requests without a
+// body are not validated, and return nil.
+func (route SchemaRoute) Validate(r *http.Request) *Validation {
+	if r.MultipartForm != nil {
+		return nil
+	}
+
+	body := readBody(r)
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return newValidation(route.String(), []ValidationError{{"/body", fmt.Sprintf("invalid JSON: %s", err)}})
+	}
+
+	return newValidation(route.String(), route.validator.validate(route.schema, value, "/body"))
+}
+
+// validate validates the request against the OpenAPI spec and the first schema route it
+// matches, and returns the errors of both with the response of the spec. Returns false
+// if the spec has no operation for the request, which is answered with 404 or 405 even in
+// strict mode.
+func (s *Http) validate(r *http.Request) (*Validation, *HttpResponse, bool) {
+	var validation *Validation
+	var mock *HttpResponse
+	routed := true
+	if s.OpenAPI != nil {
+		validation, mock, routed = s.OpenAPI.mock(r)
+	}
+
+	for _, route := range s.Schemas {
+		if route.Matches(r) {
+			validation = mergeValidations(validation, route.Validate(r))
+			break
+		}
+	}
+
+	return validation, mock, routed
+}
+
+// mergeValidations returns the errors of both validations as one, either of which may be
+// nil.
+func mergeValidations(a *Validation, b *Validation) *Validation {
+	if a == nil {
+		return b
+	}
+
+	if b == nil {
+		return a
+	}
+
+	errs := append(append([]ValidationError{}, a.Errors...), b.Errors...)
+
+	return newValidation(a.Operation+", "+b.Operation, errs)
+}
+
+// validationHandler answers an invalid request with 422 and the validation errors.
+func validationHandler(validation *Validation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := errorResponse(http.StatusUnprocessableEntity, validation.Errors)
+		for key, value := range response.Headers {
+			w.Header().Set(key, value)
+		}
+
+		w.WriteHeader(response.StatusCode)
+		w.Write([]byte(response.Body))
+	})
+}
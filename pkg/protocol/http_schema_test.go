@@ -0,0 +1,275 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const orderSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["id", "items"],
+	"properties": {
+		"id": {"type": "string"},
+		"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/item"}}
+	},
+	"$defs": {
+		"item": {
+			"type": "object",
+			"required": ["price"],
+			"properties": {"price": {"type": "number", "minimum": 0}}
+		}
+	}
+}`
+
+// writeSchema writes the schema to a file and returns the route spec for it.
+func writeSchema(t *testing.T, route string, schema string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "order.json")
+	if err := ioutil.WriteFile(file, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return route + "=" + file
+}
+
+func TestLoadSchemaRoute(t *testing.T) {
+	route, err := LoadSchemaRoute(writeSchema(t, "post /orders/*", orderSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if route.Method != "POST" || route.Path != "/orders/*" {
+		t.Errorf("Expected POST /orders/*, got %s %s", route.Method, route.Path)
+	}
+
+	if route.String() != "POST /orders/* (order.json)" {
+		t.Errorf("Expected POST /orders/* (order.json), got %s", route)
+	}
+
+	route, err = LoadSchemaRoute(writeSchema(t, "/orders", "true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if route.String() != "/orders (order.json)" {
+		t.Errorf("Expected /orders (order.json), got %s", route)
+	}
+}
+
+func TestLoadSchemaRouteErrors(t *testing.T) {
+	tests := []string{
+		"order.json",
+		"=order.json",
+		"/orders=",
+		"POST /orders extra=order.json",
+		"/orders/[=order.json",
+		"/orders=" + filepath.Join(t.TempDir(), "missing.json"),
+		writeSchema(t, "/orders", "[1, 2]"),
+		writeSchema(t, "/orders", "{"),
+		writeSchema(t, "/orders", `{"$ref": "missing.json"}`),
+	}
+
+	for _, spec := range tests {
+		if _, err := LoadSchemaRoute(spec); err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestSchemaRouteValidate(t *testing.T) {
+	route, err := LoadSchemaRoute(writeSchema(t, "POST /orders", orderSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		body     string
+		expected *Validation
+	}{
+		{"", nil},
+		{
+			`{"id": "1", "items": [{"price": 10}]}`,
+			&Validation{Operation: "POST /orders (order.json)", Valid: true, Errors: []ValidationError{}},
+		},
+		{
+			`{"items": [{"price": 10}, {"price": -1}, {}]}`,
+			&Validation{Operation: "POST /orders (order.json)", Errors: []ValidationError{
+				{"/body/id", "is required"},
+				{"/body/items/1/price", "must be at least 0"},
+				{"/body/items/2/price", "is required"},
+			}},
+		},
+		{
+			`id=1`,
+			&Validation{Operation: "POST /orders (order.json)", Errors: []ValidationError{
+				{"/body", "invalid JSON: invalid character 'i' looking for beginning of value"},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest("POST", "/orders", strings.NewReader(tc.body))
+		validation := route.Validate(r)
+
+		if !reflect.DeepEqual(validation, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.body, tc.expected, validation)
+		}
+
+		if body, _ := ioutil.ReadAll(r.Body); string(body) != tc.body {
+			t.Errorf("Expected the body to be left for the params, got %s", body)
+		}
+	}
+}
+
+func TestHttpSchemaValidation(t *testing.T) {
+	route, err := LoadSchemaRoute(writeSchema(t, "POST /orders", orderSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		strict     bool
+		path       string
+		body       string
+		code       int
+		response   string
+		validation string
+	}{
+		{false, "/orders", `{"id": "1"}`, 200, "", "POST /orders (order.json) invalid: /body/items: is required"},
+		{true, "/orders", `{"id": "1"}`, 422, `{"errors":[{"location":"/body/items","message":"is required"}]}`, "POST /orders (order.json) invalid: /body/items: is required"},
+		{true, "/orders", `{"id": "1", "items": [{"price": 1}]}`, 200, "", "POST /orders (order.json) valid"},
+		{true, "/users", `{}`, 200, "", ""},
+	}
+
+	for _, tc := range tests {
+		rpChan := make(chan RequestPayload, 1)
+		httpServer := Http{
+			ResponseCode:     200,
+			Schemas:          []SchemaRoute{route},
+			StrictValidation: tc.strict,
+			rendererChannels: []chan RequestPayload{rpChan},
+		}
+
+		srv := httptest.NewServer(httpServer.routes())
+		resp, err := http.Post(srv.URL+tc.path, "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != tc.code || string(body) != tc.response {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.body, tc.code, tc.response, resp.StatusCode, body)
+		}
+
+		rp := <-rpChan
+		validation := ""
+		if rp.Validation != nil {
+			validation = rp.Validation.String()
+		}
+
+		if validation != tc.validation {
+			t.Errorf("%s: expected %q, got %q", tc.body, tc.validation, validation)
+		}
+	}
+}
+
+func TestHttpStrictValidationOpenAPI(t *testing.T) {
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{
+		ResponseCode:     200,
+		OpenAPI:          loadPetstore(t),
+		StrictValidation: true,
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/v1/pets?limit=1000", 422},
+		{"/v1/pets?limit=10", 200},
+		{"/v1/owners", 404},
+	}
+
+	for _, tc := range tests {
+		resp, err := http.Get(srv.URL + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		<-rpChan
+
+		if resp.StatusCode != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.path, tc.code, resp.StatusCode)
+		}
+	}
+}
+
+func TestHttpValidationOpenAPIAndSchema(t *testing.T) {
+	route, err := LoadSchemaRoute(writeSchema(t, "POST /v1/*", `{"type": "object", "required": ["tag"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{
+		ResponseCode:     200,
+		OpenAPI:          loadPetstore(t),
+		Schemas:          []SchemaRoute{route},
+		StrictValidation: true,
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	tests := []struct {
+		path       string
+		requestID  string
+		body       string
+		code       int
+		validation string
+	}{
+		{"/v1/pets", "", `{"name": "Rex", "tag": "dog"}`, 422, "POST /pets, POST /v1/* (order.json) invalid: /header/X-Request-Id: is required"},
+		{"/v1/pets", "6f1c1d3e-8a4b-4c5d-9e6f-7a8b9c0d1e2f", `{"name": "Rex"}`, 422, "POST /pets, POST /v1/* (order.json) invalid: /body/tag: is required"},
+		{"/v1/pets", "", `{"name": "Rex"}`, 422, "POST /pets, POST /v1/* (order.json) invalid: 2 errors"},
+		{"/v1/pets", "6f1c1d3e-8a4b-4c5d-9e6f-7a8b9c0d1e2f", `{"name": "Rex", "tag": "dog"}`, 201, "POST /pets, POST /v1/* (order.json) valid"},
+		{"/v1/owners", "", `{"tag": "dog"}`, 404, "POST /v1/owners, POST /v1/* (order.json) invalid: /path: no operation matches POST /v1/owners"},
+	}
+
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.requestID != "" {
+			req.Header.Set("X-Request-Id", tc.requestID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		rp := <-rpChan
+		if resp.StatusCode != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.body, tc.code, resp.StatusCode)
+		}
+
+		if rp.Validation == nil || rp.Validation.String() != tc.validation {
+			t.Errorf("%s: expected %q, got %v", tc.body, tc.validation, rp.Validation)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"golang.org/x/net/http2"
+)
+
+// streamIDHeader is the header frameTap passes the stream id of HTTP/2 requests in,
+// since net/http does not expose it.
+const streamIDHeader = "rh-stream-id"
+
+// maxTappedFrameSize is the largest header frame frameTap reads into memory, the frame
+// size the HTTP/2 server accepts. Larger frames are passed on for the server to reject.
+const maxTappedFrameSize = 1 << 20
+
+// HTTP/2 frame types and flags read by frameTap.
+const (
+	frameHeaders      = 0x1
+	frameContinuation = 0x9
+	flagEndHeaders    = 0x4
+	flagPadded        = 0x8
+)
+
+// States of a frameTap.
+const (
+	tapPreface = iota
+	tapHttp1
+	tapFrames
+)
+
+// frameTapKey is the context key for the frameTap a request was received on.
+type frameTapKey struct{}
+
+// frameTap is a connection which adds the id of each HTTP/2 stream to the first header
+// block the client sends on it, as a literal header field without indexing. Those leave
+// the HPACK tables alone, so the block decodes as the client encoded it.
+//
+// Connections start out waiting for the client preface. Cleartext connections which
+// send anything else are HTTP/1.x, and wait for the preface again once the server
+// answers an h2c upgrade.
+type frameTap struct {
+	net.Conn
+
+	mu    sync.Mutex
+	state int
+
+	// preface is the number of bytes of the client preface read so far.
+	preface int
+
+	// out has the bytes read from the connection which were not returned yet, and frame
+	// the header being read, followed by the payload of header frames.
+	out   []byte
+	frame []byte
+
+	// err is the error read along with the bytes in out.
+	err error
+
+	// skip is the number of bytes of the current frame to pass on as they are.
+	skip int
+
+	// lastStream is the highest stream the client opened, and continued the stream whose
+	// first header block goes on in CONTINUATION frames.
+	lastStream uint32
+	continued  uint32
+}
+
+// tlsFrameTap is a frameTap over TLS, which passes the connection state on to the
+// HTTP/2 server.
+type tlsFrameTap struct {
+	*frameTap
+	tlsConn *tls.Conn
+}
+
+// ConnectionState returns the state of the TLS connection.
+func (t *tlsFrameTap) ConnectionState() tls.ConnectionState {
+	return t.tlsConn.ConnectionState()
+}
+
+// frameTapListener taps the connections it accepts.
+type frameTapListener struct {
+	net.Listener
+}
+
+// Accept waits for the next connection and taps it.
+func (l frameTapListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameTap{Conn: c}, nil
+}
+
+// configureHTTP2 serves HTTP/2 over TLS on srv, with the connections tapped once TLS is
+// negotiated.
+func configureHTTP2(srv *http.Server) error {
+	h2 := &http2.Server{}
+	if err := http2.ConfigureServer(srv, h2); err != nil {
+		return err
+	}
+
+	srv.TLSNextProto[http2.NextProtoTLS] = func(hs *http.Server, c *tls.Conn, h http.Handler) {
+		ctx := context.Background()
+		if bc, ok := h.(interface{ BaseContext() context.Context }); ok {
+			ctx = bc.BaseContext()
+		}
+
+		tap := &tlsFrameTap{frameTap: &frameTap{Conn: c}, tlsConn: c}
+		h2.ServeConn(tap, &http2.ServeConnOpts{
+			Context:    context.WithValue(ctx, frameTapKey{}, tap.frameTap),
+			Handler:    h,
+			BaseConfig: hs,
+		})
+	}
+
+	return nil
+}
+
+// requestStreamID returns the HTTP/2 stream id of the request, and removes the header
+// frameTap passed it in. Requests which are not tapped, and HTTP/1.x requests, have 0.
+// The request of an h2c upgrade has no header block of its own, and is stream 1.
+func requestStreamID(r *http.Request) int {
+	if _, ok := r.Context().Value(frameTapKey{}).(*frameTap); !ok || r.ProtoMajor != 2 {
+		return 0
+	}
+
+	key := http.CanonicalHeaderKey(streamIDHeader)
+	values := r.Header[key]
+	if len(values) == 0 {
+		return 1
+	}
+
+	// The field is added after the ones the client sent.
+	id, _ := strconv.Atoi(values[len(values)-1])
+	if len(values) == 1 {
+		delete(r.Header, key)
+	} else {
+		r.Header[key] = values[:len(values)-1]
+	}
+
+	return id
+}
+
+// Read reads from the connection, with the stream ids added to the header frames.
+func (t *frameTap) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// An error read along with bytes is returned once they are. Errors do not stick, as
+	// the server reads again after timeouts it causes itself.
+	if len(t.out) == 0 && t.err != nil {
+		err := t.err
+		t.err = nil
+
+		return 0, err
+	}
+
+	buf := make([]byte, len(p))
+	for len(t.out) == 0 {
+		n, err := t.Conn.Read(buf)
+		t.feed(buf[:n])
+
+		if err != nil {
+			if len(t.out) == 0 {
+				return 0, err
+			}
+
+			t.err = err
+		}
+	}
+
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+
+	return n, nil
+}
+
+// Write writes to the connection, and waits for the client preface again when the
+// server answers an h2c upgrade.
+func (t *frameTap) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte("HTTP/1.1 101 ")) && bytes.Contains(p, []byte("\r\nUpgrade: h2c\r\n")) {
+		t.mu.Lock()
+		if t.state == tapHttp1 {
+			t.state = tapPreface
+			t.preface = 0
+		}
+		t.mu.Unlock()
+	}
+
+	return t.Conn.Write(p)
+}
+
+// CloseWrite shuts down the writing side of TCP connections, which the HTTP/1.x server
+// does before closing connections with unread requests.
+func (t *frameTap) CloseWrite() error {
+	if cw, ok := t.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+
+	return nil
+}
+
+// feed reads the bytes received on the connection into out.
+func (t *frameTap) feed(b []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(b) > 0 {
+		switch t.state {
+		case tapHttp1:
+			t.out = append(t.out, b...)
+			return
+		case tapPreface:
+			n := 0
+			for n < len(b) && t.preface < len(http2.ClientPreface) && b[n] == http2.ClientPreface[t.preface] {
+				n++
+				t.preface++
+			}
+
+			t.out = append(t.out, b[:n]...)
+			b = b[n:]
+
+			if t.preface == len(http2.ClientPreface) {
+				t.state = tapFrames
+			} else if len(b) > 0 {
+				t.state = tapHttp1
+			}
+		case tapFrames:
+			b = t.feedFrame(b)
+		}
+	}
+}
+
+// feedFrame reads the bytes of the current frame, and returns the rest.
+func (t *frameTap) feedFrame(b []byte) []byte {
+	if t.skip > 0 {
+		n := t.skip
+		if n > len(b) {
+			n = len(b)
+		}
+
+		t.out = append(t.out, b[:n]...)
+		t.skip -= n
+
+		return b[n:]
+	}
+
+	if len(t.frame) < 9 {
+		n := 9 - len(t.frame)
+		if n > len(b) {
+			n = len(b)
+		}
+
+		t.frame = append(t.frame, b[:n]...)
+		b = b[n:]
+
+		if len(t.frame) < 9 {
+			return b
+		}
+
+		length := int(t.frame[0])<<16 | int(t.frame[1])<<8 | int(t.frame[2])
+		if (t.frame[3] != frameHeaders && t.frame[3] != frameContinuation) || length > maxTappedFrameSize {
+			t.out = append(t.out, t.frame...)
+			t.frame = t.frame[:0]
+			t.skip = length
+
+			return b
+		}
+	}
+
+	size := 9 + (int(t.frame[0])<<16 | int(t.frame[1])<<8 | int(t.frame[2]))
+	n := size - len(t.frame)
+	if n > len(b) {
+		n = len(b)
+	}
+
+	t.frame = append(t.frame, b[:n]...)
+
+	if len(t.frame) == size {
+		t.out = append(t.out, t.tapHeaders(t.frame)...)
+		t.frame = t.frame[:0]
+	}
+
+	return b[n:]
+}
+
+// tapHeaders returns the header frame, with the stream id added if it ends the first
+// header block of its stream. Later blocks are trailers.
+func (t *frameTap) tapHeaders(frame []byte) []byte {
+	flags := frame[4]
+	stream := binary.BigEndian.Uint32(frame[5:9]) & (1<<31 - 1)
+
+	first := false
+	switch frame[3] {
+	case frameHeaders:
+		if stream > t.lastStream {
+			t.lastStream = stream
+			first = true
+		}
+	case frameContinuation:
+		first = stream != 0 && stream == t.continued
+	}
+
+	if !first {
+		return frame
+	}
+
+	if flags&flagEndHeaders == 0 {
+		t.continued = stream
+		return frame
+	}
+
+	t.continued = 0
+
+	// Padding follows the header block.
+	pad := 0
+	if frame[3] == frameHeaders && flags&flagPadded != 0 {
+		if len(frame) == 9 || int(frame[9]) >= len(frame)-9 {
+			return frame
+		}
+
+		pad = int(frame[9])
+	}
+
+	value := strconv.FormatUint(uint64(stream), 10)
+	field := append([]byte{0x00, byte(len(streamIDHeader))}, streamIDHeader...)
+	field = append(field, byte(len(value)))
+	field = append(field, value...)
+
+	end := len(frame) - pad
+	tapped := make([]byte, 0, len(frame)+len(field))
+	tapped = append(tapped, frame[:end]...)
+	tapped = append(tapped, field...)
+	tapped = append(tapped, frame[end:]...)
+
+	length := len(tapped) - 9
+	tapped[0], tapped[1], tapped[2] = byte(length>>16), byte(length>>8), byte(length)
+
+	return tapped
+}
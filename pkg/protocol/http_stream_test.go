@@ -0,0 +1,222 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func startTappedServer(t *testing.T, httpServer *Http, useTLS bool) *httptest.Server {
+	srv := httptest.NewUnstartedServer(httpServer.routes())
+	srv.Config.ConnContext = connContext
+	if useTLS {
+		if err := configureHTTP2(srv.Config); err != nil {
+			t.Fatal(err)
+		}
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+	} else {
+		srv.Listener = frameTapListener{srv.Listener}
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestHttpStreamID(t *testing.T) {
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	testTable := []struct {
+		name   string
+		tls    bool
+		client func(srv *httptest.Server) *http.Client
+		http2  bool
+	}{
+		{"http/1.1", false, func(srv *httptest.Server) *http.Client { return srv.Client() }, false},
+		{"h2c", false, func(srv *httptest.Server) *http.Client { return h2cClient }, true},
+		{"h2", true, func(srv *httptest.Server) *http.Client { return srv.Client() }, true},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 1)
+		httpServer := &Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChannel}}
+		srv := startTappedServer(t, httpServer, test.tls)
+		client := test.client(srv)
+
+		var streams []int
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/foo", io.MultiReader(strings.NewReader("bar")))
+			req.Header.Set(streamIDHeader, "99")
+			req.Trailer = http.Header{"X-Checksum": []string{"abc"}}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			resp.Body.Close()
+
+			rp := <-rpChannel
+			streams = append(streams, rp.StreamID)
+
+			// The header the client sent is kept, and the trailers are not tapped.
+			if values := rp.Headers[http.CanonicalHeaderKey(streamIDHeader)]; len(values) != 1 || values[0] != "99" {
+				t.Errorf("%s: expected the header of the client, got %v", test.name, values)
+			}
+
+			if len(rp.Trailers) != 1 || rp.Trailers["X-Checksum"][0] != "abc" {
+				t.Errorf("%s: expected trailer, got %v", test.name, rp.Trailers)
+			}
+		}
+
+		// Requests on the same connection are the next streams the client opened.
+		if (test.http2 && !consecutiveStreams(streams)) || (!test.http2 && !reflect.DeepEqual(streams, []int{0, 0, 0})) {
+			t.Errorf("%s: unexpected streams %v", test.name, streams)
+		}
+	}
+}
+
+// consecutiveStreams returns true if the streams are the odd ids clients open, one after
+// the other.
+func consecutiveStreams(streams []int) bool {
+	for i, stream := range streams {
+		if stream%2 != 1 || (i > 0 && stream != streams[i-1]+2) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestHttpStreamIDConcurrent(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	httpServer := &Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := startTappedServer(t, httpServer, false)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	var streams []int
+	for i := 0; i < 10; i++ {
+		streams = append(streams, (<-rpChannel).StreamID)
+	}
+	sort.Ints(streams)
+
+	if !consecutiveStreams(streams) {
+		t.Errorf("Expected a stream for each request, got %v", streams)
+	}
+}
+
+// TestHttpStreamIDUpgrade sends the frames by hand after an h2c upgrade, with header
+// blocks which are padded, continued, and use the HPACK table of the ones before.
+func TestHttpStreamIDUpgrade(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 3)
+	httpServer := &Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := startTappedServer(t, httpServer, false)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "GET /upgrade HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: \r\n\r\n", srv.Listener.Addr())
+
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(status, "HTTP/1.1 101 ") {
+		t.Fatalf("Expected 101, got %q %v", status, err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(http2.ClientPreface)
+	framer := http2.NewFramer(&buf, nil)
+	framer.WriteSettings()
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	encode := func(path string, fields ...string) []byte {
+		block.Reset()
+		encoder.WriteField(hpack.HeaderField{Name: ":method", Value: "POST"})
+		encoder.WriteField(hpack.HeaderField{Name: ":scheme", Value: "http"})
+		encoder.WriteField(hpack.HeaderField{Name: ":authority", Value: "example.com"})
+		encoder.WriteField(hpack.HeaderField{Name: ":path", Value: path})
+		for i := 0; i < len(fields); i += 2 {
+			encoder.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]})
+		}
+
+		return append([]byte(nil), block.Bytes()...)
+	}
+
+	padded := encode("/padded", "trailer", "x-checksum", "x-foo", "bar")
+	framer.WriteHeaders(http2.HeadersFrameParam{StreamID: 3, BlockFragment: padded[:5], PadLength: 7})
+	framer.WriteContinuation(3, true, padded[5:])
+	framer.WriteData(3, false, []byte("body"))
+
+	block.Reset()
+	encoder.WriteField(hpack.HeaderField{Name: "x-checksum", Value: "abc"})
+	framer.WriteHeaders(http2.HeadersFrameParam{StreamID: 3, BlockFragment: block.Bytes(), EndHeaders: true, EndStream: true})
+
+	framer.WriteHeaders(http2.HeadersFrameParam{StreamID: 5, BlockFragment: encode("/indexed", "x-foo", "bar"), EndHeaders: true, EndStream: true, PadLength: 3})
+
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"/upgrade": 1, "/padded": 3, "/indexed": 5}
+	for i := 0; i < len(expected); i++ {
+		rp := <-rpChannel
+		if stream, ok := expected[rp.Fields.Url]; !ok || rp.StreamID != stream {
+			t.Errorf("Expected %s to be stream %d, got %d", rp.Fields.Url, stream, rp.StreamID)
+		}
+
+		if rp.Fields.Url != "/upgrade" && http.Header(rp.Headers).Get("X-Foo") != "bar" {
+			t.Errorf("Expected %s to have its headers, got %v", rp.Fields.Url, rp.Headers)
+		}
+
+		if _, ok := rp.Headers[http.CanonicalHeaderKey(streamIDHeader)]; ok {
+			t.Errorf("Expected the stream id header to be removed, got %v", rp.Headers)
+		}
+
+		if rp.Fields.Url == "/padded" && (len(rp.Trailers) != 1 || rp.Trailers["X-Checksum"][0] != "abc") {
+			t.Errorf("Expected trailer, got %v", rp.Trailers)
+		}
+	}
+}
@@ -2,13 +2,18 @@ package protocol
 
 import (
 	"bytes"
+	"crypto/tls"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
 )
 
 func TestResponseCodeFlag(t *testing.T) {
@@ -240,6 +245,91 @@ func TestLogRequestManyRenderers(t *testing.T) {
 	}
 }
 
+func TestHttp2(t *testing.T) {
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	testTable := []struct {
+		name     string
+		tls      bool
+		client   func(srv *httptest.Server) *http.Client
+		protocol string
+	}{
+		{"http/1.1", false, func(srv *httptest.Server) *http.Client { return srv.Client() }, "HTTP/1.1"},
+		{"h2c", false, func(srv *httptest.Server) *http.Client { return h2cClient }, "HTTP/2.0"},
+		{"h2", true, func(srv *httptest.Server) *http.Client { return srv.Client() }, "HTTP/2.0"},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 1)
+		httpServer := Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChannel}}
+
+		srv := httptest.NewUnstartedServer(httpServer.routes())
+		if test.tls {
+			srv.EnableHTTP2 = true
+			srv.StartTLS()
+		} else {
+			srv.Start()
+		}
+
+		body := io.MultiReader(strings.NewReader(`{"foo": "bar"}`))
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/foo", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Trailer = http.Header{"X-Checksum": []string{"abc"}}
+
+		resp, err := test.client(srv).Do(req)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		resp.Body.Close()
+
+		rp := <-rpChannel
+		srv.Close()
+
+		if rp.Fields.Protocol != test.protocol {
+			t.Errorf("%s: expected %s, got %s", test.name, test.protocol, rp.Fields.Protocol)
+		}
+
+		if len(rp.Trailers["X-Checksum"]) != 1 || rp.Trailers["X-Checksum"][0] != "abc" {
+			t.Errorf("%s: expected trailer, got %v", test.name, rp.Trailers)
+		}
+
+		if rp.ParamFields.Json["foo"] != "bar" {
+			t.Errorf("%s: expected body to be parsed after trailers, got %v", test.name, rp.ParamFields.Json)
+		}
+	}
+}
+
+func TestHttpFlushesStalledHeaders(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 1)
+	httpServer := Http{ResponseCode: 200, ResponseBody: "ok", Timing: HttpTiming{StallAfterHeaders: time.Second}, rendererChannels: []chan RequestPayload{rpChannel}}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected the headers before the stall, got them after %s", elapsed)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("Expected ok, got %s", body)
+	}
+
+	<-rpChannel
+}
+
 func TestQuitRenderers(t *testing.T) {
 	q1 := make(chan int, 1)
 	q2 := make(chan int, 1)
@@ -0,0 +1,1074 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSchemaDepth limits how deeply schemas are followed, so recursive $refs end.
+const maxSchemaDepth = 64
+
+// ValidationError is a part of a request which does not match its schema.
+type ValidationError struct {
+	// Location is a JSON pointer to the invalid value, starting with the part of the
+	// request it is in, ie: /body/items/0/price or /query/limit.
+	Location string `json:"location"`
+
+	Message string `json:"message"`
+}
+
+// String returns the error, ie: /query/limit: must be at most 100.
+func (e ValidationError) String() string {
+	return fmt.Sprintf("%s: %s", e.Location, e.Message)
+}
+
+// Validation is the result of validating a request against a schema.
+type Validation struct {
+	// Operation is what the request was validated against, ie: GET /pets/{petId}.
+	Operation string `json:"operation"`
+
+	Valid  bool              `json:"valid"`
+	Errors []ValidationError `json:"errors"`
+}
+
+// String returns the result, ie: GET /pets/{petId} invalid: 2 errors.
+func (v Validation) String() string {
+	if v.Valid {
+		return fmt.Sprintf("%s valid", v.Operation)
+	}
+
+	if len(v.Errors) == 1 {
+		return fmt.Sprintf("%s invalid: %s", v.Operation, v.Errors[0])
+	}
+
+	return fmt.Sprintf("%s invalid: %d errors", v.Operation, len(v.Errors))
+}
+
+// newValidation returns the result of a validation which found the errors.
+func newValidation(operation string, errs []ValidationError) *Validation {
+	if errs == nil {
+		errs = []ValidationError{}
+	}
+
+	return &Validation{Operation: operation, Valid: len(errs) == 0, Errors: errs}
+}
+
+// schemaValidator validates values decoded from JSON against JSON Schemas (draft 2020-12),
+// which are also the schemas of OpenAPI 3.1. The keywords of the core, applicator,
+// unevaluated and validation vocabularies are supported, and format is asserted for the
+// formats validFormat knows; the content and meta-data keywords are annotations, and are
+// ignored. The nullable and boolean exclusiveMinimum and exclusiveMaximum keywords of
+// OpenAPI 3.0 are understood as well.
+//
+// $ref and $dynamicRef are resolved against the $ids, $anchors and $dynamicAnchors of the
+// document, and of the files it references with relative URIs, ie: address.json#/$defs/street,
+// which are loaded when the validator is created. References to other URLs are not
+// fetched, and fail to load.
+type schemaValidator struct {
+	root interface{}
+
+	// base is the URI of the root document, which relative $refs in it are resolved
+	// against.
+	base string
+
+	// resources are the documents and the schemas with an $id, by URI.
+	resources map[string]interface{}
+
+	// anchors are the schemas with an $anchor or a $dynamicAnchor, by URI with the anchor
+	// as the fragment, ie: file:///schemas/order.json#item. dynamicAnchors are the ones
+	// with a $dynamicAnchor.
+	anchors        map[string]interface{}
+	dynamicAnchors map[string]interface{}
+
+	// bases are the URIs of the resources the schemas are in, by the address of the
+	// schema's map.
+	bases map[uintptr]string
+
+	patterns sync.Map
+}
+
+// schemaRef is a $ref or $dynamicRef found in a document.
+type schemaRef struct {
+	ref string
+
+	// resource is the URI of the document or schema the ref points to, without the
+	// fragment.
+	resource string
+
+	// file is the URI of the file the ref is in, which the file of the resource is
+	// relative to.
+	file string
+}
+
+// schemaScope is the dynamic scope of a validation: the resources the schemas being
+// validated are in, innermost first, which $dynamicRefs are resolved against.
+type schemaScope struct {
+	base   string
+	parent *schemaScope
+}
+
+// evaluated are the properties and items of a value which a schema evaluated.
+// unevaluatedProperties and unevaluatedItems apply to the others.
+type evaluated struct {
+	properties map[string]bool
+	items      map[int]bool
+}
+
+func (e *evaluated) property(name string) {
+	if e.properties == nil {
+		e.properties = map[string]bool{}
+	}
+
+	e.properties[name] = true
+}
+
+func (e *evaluated) item(i int) {
+	if e.items == nil {
+		e.items = map[int]bool{}
+	}
+
+	e.items[i] = true
+}
+
+func (e *evaluated) merge(other *evaluated) {
+	for name := range other.properties {
+		e.property(name)
+	}
+
+	for i := range other.items {
+		e.item(i)
+	}
+}
+
+// newSchemaValidator returns a validator of the schemas in the root document, which was
+// read from the file. The files its $refs point to are loaded, so a missing one is an
+// error when the schemas load rather than when a request is validated. The file is empty
+// if the document was not read from one, and then only refs within it are resolved.
+func newSchemaValidator(root interface{}, file string) (*schemaValidator, error) {
+	v := &schemaValidator{
+		root:           root,
+		resources:      map[string]interface{}{},
+		anchors:        map[string]interface{}{},
+		dynamicAnchors: map[string]interface{}{},
+		bases:          map[uintptr]string{},
+	}
+
+	if file != "" {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		v.base = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+
+	if err := v.index(root, v.base, v.base); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// index registers the resources and anchors of a document, identified by base, and loads
+// the files its $refs point to from next to the file.
+func (v *schemaValidator) index(doc interface{}, base string, file string) error {
+	if _, ok := v.resources[base]; !ok {
+		v.resources[base] = doc
+	}
+
+	var refs []schemaRef
+	v.walk(doc, base, file, &refs)
+
+	for _, ref := range refs {
+		if _, ok := v.resources[ref.resource]; ok {
+			continue
+		}
+
+		location := stripFragment(resolveURI(ref.file, ref.ref))
+		u, err := url.Parse(location)
+		if err != nil || u.Scheme != "file" {
+			return fmt.Errorf("cannot load $ref %s, only references to files are supported", ref.ref)
+		}
+
+		doc, err := readSchemaFile(filepath.FromSlash(u.Path))
+		if err != nil {
+			return fmt.Errorf("$ref %s: %w", ref.ref, err)
+		}
+
+		if err := v.index(doc, ref.resource, location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walk registers the $ids and anchors of the schemas in the node, and collects its refs.
+func (v *schemaValidator) walk(node interface{}, base string, file string, refs *[]schemaRef) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if id, ok := n["$id"].(string); ok {
+			base = stripFragment(resolveURI(base, id))
+			v.resources[base] = n
+		}
+
+		v.bases[reflect.ValueOf(n).Pointer()] = base
+
+		if anchor, ok := n["$anchor"].(string); ok {
+			v.anchors[base+"#"+anchor] = n
+		}
+
+		if anchor, ok := n["$dynamicAnchor"].(string); ok {
+			v.anchors[base+"#"+anchor] = n
+			v.dynamicAnchors[base+"#"+anchor] = n
+		}
+
+		for _, keyword := range []string{"$ref", "$dynamicRef"} {
+			if ref, ok := n[keyword].(string); ok {
+				*refs = append(*refs, schemaRef{ref: ref, resource: stripFragment(resolveURI(base, ref)), file: file})
+			}
+		}
+
+		for key, child := range n {
+			switch key {
+			case "const", "enum", "default", "example", "examples":
+				// Values rather than schemas.
+				continue
+			}
+
+			v.walk(child, base, file, refs)
+		}
+	case []interface{}:
+		for _, child := range n {
+			v.walk(child, base, file, refs)
+		}
+	}
+}
+
+// validate returns the errors of the value against the schema, located under location.
+func (v *schemaValidator) validate(schema interface{}, value interface{}, location string) []ValidationError {
+	return v.validateDepth(schema, value, location, &schemaScope{base: v.baseOf(schema, v.base)}, 0, &evaluated{})
+}
+
+// validateDepth validates the value against the schema, and adds the properties and items
+// of the value the schema evaluated to seen.
+func (v *schemaValidator) validateDepth(schema interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	if depth > maxSchemaDepth {
+		return nil
+	}
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []ValidationError{{location, "is not allowed"}}
+		}
+		return nil
+	case map[string]interface{}:
+		return v.validateObject(s, value, location, scope, depth, seen)
+	}
+
+	return nil
+}
+
+// matches returns true if the value is valid against a schema applied to the same value,
+// such as the schemas of anyOf, and then adds what the schema evaluated to seen. The
+// schemas of allOf, $ref and the like add it even if the value is not valid against them,
+// so the properties they find invalid are not reported as unevaluated too.
+func (v *schemaValidator) matches(schema interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) bool {
+	inner := &evaluated{}
+	if len(v.validateDepth(schema, value, location, scope, depth+1, inner)) != 0 {
+		return false
+	}
+
+	seen.merge(inner)
+
+	return true
+}
+
+func (v *schemaValidator) validateObject(s map[string]interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	var errs []ValidationError
+
+	// Entering a schema of another resource, through a $ref or an $id, adds it to the
+	// dynamic scope.
+	if base := v.baseOf(s, scope.base); base != scope.base {
+		scope = &schemaScope{base: base, parent: scope}
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolve(ref, scope.base)
+		if err != nil {
+			return []ValidationError{{location, err.Error()}}
+		}
+
+		errs = append(errs, v.validateDepth(target, value, location, scope, depth+1, seen)...)
+	}
+
+	if ref, ok := s["$dynamicRef"].(string); ok {
+		target, err := v.resolveDynamic(ref, scope)
+		if err != nil {
+			return []ValidationError{{location, err.Error()}}
+		}
+
+		errs = append(errs, v.validateDepth(target, value, location, scope, depth+1, seen)...)
+	}
+
+	if value == nil && s["nullable"] == true {
+		return errs
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		return append(errs, ValidationError{location, fmt.Sprintf("expected %s, got %s", typeNames(t), jsonType(value))})
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be one of %s", joinValues(enum))})
+	}
+
+	if c, ok := s["const"]; ok && !equalValues(c, value) {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be %s", formatValue(c))})
+	}
+
+	switch val := value.(type) {
+	case string:
+		errs = append(errs, v.validateString(s, val, location)...)
+	case float64:
+		errs = append(errs, validateNumber(s, val, location)...)
+	case []interface{}:
+		errs = append(errs, v.validateArray(s, val, location, scope, depth, seen)...)
+	case map[string]interface{}:
+		errs = append(errs, v.validateProperties(s, val, location, scope, depth, seen)...)
+	}
+
+	errs = append(errs, v.validateCombinators(s, value, location, scope, depth, seen)...)
+
+	// The unevaluated keywords apply after all the others, which they depend on.
+	return append(errs, v.validateUnevaluated(s, value, location, scope, depth, seen)...)
+}
+
+// validateCombinators validates the value against allOf, anyOf, oneOf, not and if.
+func (v *schemaValidator) validateCombinators(s map[string]interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	var errs []ValidationError
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, v.validateDepth(sub, value, location, scope, depth+1, seen)...)
+		}
+	}
+
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && v.countMatches(anyOf, value, location, scope, depth, seen) == 0 {
+		errs = append(errs, ValidationError{location, "must match at least one schema of anyOf"})
+	}
+
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		if n := v.countMatches(oneOf, value, location, scope, depth, seen); n != 1 {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must match exactly one schema of oneOf, matched %d", n)})
+		}
+	}
+
+	if not, ok := s["not"]; ok && len(v.validateDepth(not, value, location, scope, depth+1, &evaluated{})) == 0 {
+		errs = append(errs, ValidationError{location, "must not match the schema of not"})
+	}
+
+	if cond, ok := s["if"]; ok {
+		if v.matches(cond, value, location, scope, depth, seen) {
+			if then, ok := s["then"]; ok {
+				errs = append(errs, v.validateDepth(then, value, location, scope, depth+1, seen)...)
+			}
+		} else if els, ok := s["else"]; ok {
+			errs = append(errs, v.validateDepth(els, value, location, scope, depth+1, seen)...)
+		}
+	}
+
+	return errs
+}
+
+// countMatches returns the number of schemas the value is valid against. All of them are
+// validated, since the ones which match evaluate properties and items.
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) int {
+	n := 0
+	for _, sub := range schemas {
+		if v.matches(sub, value, location, scope, depth, seen) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// validateUnevaluated validates the properties and items of the value which neither the
+// other keywords of the schema nor the schemas it applies to the value in place, such as
+// its $ref and allOf, evaluated.
+func (v *schemaValidator) validateUnevaluated(s map[string]interface{}, value interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	var errs []ValidationError
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		sub, ok := s["unevaluatedProperties"]
+		if !ok {
+			return nil
+		}
+
+		for _, name := range sortedKeys(val) {
+			if seen.properties[name] {
+				continue
+			}
+
+			errs = append(errs, v.validateDepth(sub, val[name], pointer(location, name), scope, depth+1, &evaluated{})...)
+			seen.property(name)
+		}
+	case []interface{}:
+		sub, ok := s["unevaluatedItems"]
+		if !ok {
+			return nil
+		}
+
+		for i, item := range val {
+			if seen.items[i] {
+				continue
+			}
+
+			errs = append(errs, v.validateDepth(sub, item, pointer(location, strconv.Itoa(i)), scope, depth+1, &evaluated{})...)
+			seen.item(i)
+		}
+	}
+
+	return errs
+}
+
+func (v *schemaValidator) validateString(s map[string]interface{}, val string, location string) []ValidationError {
+	var errs []ValidationError
+	length := utf8.RuneCountInString(val)
+
+	if min, ok := number(s["minLength"]); ok && float64(length) < min {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be at least %s characters", formatNumber(min))})
+	}
+
+	if max, ok := number(s["maxLength"]); ok && float64(length) > max {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be at most %s characters", formatNumber(max))})
+	}
+
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := v.pattern(pattern)
+		if err != nil {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("invalid pattern %s: %s", pattern, err)})
+		} else if !re.MatchString(val) {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must match pattern %s", pattern)})
+		}
+	}
+
+	if format, ok := s["format"].(string); ok && !validFormat(format, val) {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be a valid %s", format)})
+	}
+
+	return errs
+}
+
+// pattern compiles the regular expression, which is cached since the same schemas are
+// validated against every request.
+func (v *schemaValidator) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	v.patterns.Store(pattern, re)
+
+	return re, nil
+}
+
+func validateNumber(s map[string]interface{}, val float64, location string) []ValidationError {
+	var errs []ValidationError
+
+	if min, ok := number(s["minimum"]); ok {
+		if s["exclusiveMinimum"] == true && val <= min {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must be greater than %s", formatNumber(min))})
+		} else if val < min {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must be at least %s", formatNumber(min))})
+		}
+	}
+
+	if max, ok := number(s["maximum"]); ok {
+		if s["exclusiveMaximum"] == true && val >= max {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must be less than %s", formatNumber(max))})
+		} else if val > max {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must be at most %s", formatNumber(max))})
+		}
+	}
+
+	if min, ok := number(s["exclusiveMinimum"]); ok && val <= min {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be greater than %s", formatNumber(min))})
+	}
+
+	if max, ok := number(s["exclusiveMaximum"]); ok && val >= max {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must be less than %s", formatNumber(max))})
+	}
+
+	if multiple, ok := number(s["multipleOf"]); ok && multiple > 0 {
+		q := val / multiple
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must be a multiple of %s", formatNumber(multiple))})
+		}
+	}
+
+	return errs
+}
+
+func (v *schemaValidator) validateArray(s map[string]interface{}, val []interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	var errs []ValidationError
+
+	if min, ok := number(s["minItems"]); ok && float64(len(val)) < min {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must have at least %s", pluralItems(min))})
+	}
+
+	if max, ok := number(s["maxItems"]); ok && float64(len(val)) > max {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must have at most %s", pluralItems(max))})
+	}
+
+	if s["uniqueItems"] == true {
+		for i := 1; i < len(val); i++ {
+			if containsValue(val[:i], val[i]) {
+				errs = append(errs, ValidationError{pointer(location, strconv.Itoa(i)), "must be unique"})
+			}
+		}
+	}
+
+	// prefixItems validates the items by position, and items the rest of them. Before
+	// 2020-12, items could be an array doing the same as prefixItems.
+	prefix, _ := s["prefixItems"].([]interface{})
+	items := s["items"]
+	if tuple, ok := items.([]interface{}); ok {
+		prefix, items = tuple, s["additionalItems"]
+	}
+
+	for i, item := range val {
+		var sub interface{}
+		if i < len(prefix) {
+			sub = prefix[i]
+		} else if items != nil {
+			sub = items
+		} else {
+			continue
+		}
+
+		errs = append(errs, v.validateDepth(sub, item, pointer(location, strconv.Itoa(i)), scope, depth+1, &evaluated{})...)
+		seen.item(i)
+	}
+
+	if contains, ok := s["contains"]; ok {
+		min := 1.0
+		if n, ok := number(s["minContains"]); ok {
+			min = n
+		}
+
+		matches := 0
+		for i, item := range val {
+			if len(v.validateDepth(contains, item, pointer(location, strconv.Itoa(i)), scope, depth+1, &evaluated{})) == 0 {
+				matches++
+				seen.item(i)
+			}
+		}
+
+		if float64(matches) < min {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must contain at least %s matching the schema of contains", pluralItems(min))})
+		}
+
+		if max, ok := number(s["maxContains"]); ok && float64(matches) > max {
+			errs = append(errs, ValidationError{location, fmt.Sprintf("must contain at most %s matching the schema of contains", pluralItems(max))})
+		}
+	}
+
+	return errs
+}
+
+func (v *schemaValidator) validateProperties(s map[string]interface{}, val map[string]interface{}, location string, scope *schemaScope, depth int, seen *evaluated) []ValidationError {
+	var errs []ValidationError
+
+	if min, ok := number(s["minProperties"]); ok && float64(len(val)) < min {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must have at least %s properties", formatNumber(min))})
+	}
+
+	if max, ok := number(s["maxProperties"]); ok && float64(len(val)) > max {
+		errs = append(errs, ValidationError{location, fmt.Sprintf("must have at most %s properties", formatNumber(max))})
+	}
+
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := val[name]; !ok {
+					errs = append(errs, ValidationError{pointer(location, name), "is required"})
+				}
+			}
+		}
+	}
+
+	if dependent, ok := s["dependentRequired"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependent) {
+			if _, ok := val[name]; !ok {
+				continue
+			}
+
+			required, _ := dependent[name].([]interface{})
+			for _, other := range required {
+				if other, ok := other.(string); ok {
+					if _, ok := val[other]; !ok {
+						errs = append(errs, ValidationError{pointer(location, other), fmt.Sprintf("is required when %s is present", name)})
+					}
+				}
+			}
+		}
+	}
+
+	// dependentSchemas applies a schema to the whole object when a property is present.
+	if dependent, ok := s["dependentSchemas"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependent) {
+			if _, ok := val[name]; ok {
+				errs = append(errs, v.validateDepth(dependent[name], val, location, scope, depth+1, seen)...)
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	names, hasNames := s["propertyNames"]
+
+	for _, name := range sortedKeys(val) {
+		location := pointer(location, name)
+
+		if hasNames {
+			errs = append(errs, v.validateDepth(names, name, location, scope, depth+1, &evaluated{})...)
+		}
+
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			errs = append(errs, v.validateDepth(sub, val[name], location, scope, depth+1, &evaluated{})...)
+		}
+
+		for _, pattern := range sortedKeys(patternProperties) {
+			re, err := v.pattern(pattern)
+			if err != nil || !re.MatchString(name) {
+				continue
+			}
+
+			matched = true
+			errs = append(errs, v.validateDepth(patternProperties[pattern], val[name], location, scope, depth+1, &evaluated{})...)
+		}
+
+		if !matched && hasAdditional {
+			matched = true
+			errs = append(errs, v.validateDepth(additional, val[name], location, scope, depth+1, &evaluated{})...)
+		}
+
+		if matched {
+			seen.property(name)
+		}
+	}
+
+	return errs
+}
+
+// resolve returns the schema a $ref points to, resolving the ref against the URI of the
+// resource it is in, ie: #/$defs/address, #item, or address.json#/$defs/street.
+func (v *schemaValidator) resolve(ref string, base string) (interface{}, error) {
+	uri := resolveURI(base, ref)
+	resource, fragment := uri, ""
+	if i := strings.Index(uri, "#"); i >= 0 {
+		resource, fragment = uri[:i], uri[i+1:]
+	}
+
+	fragment, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %s", ref)
+	}
+
+	// A fragment which is not a JSON pointer is an anchor.
+	if fragment != "" && !strings.HasPrefix(fragment, "/") {
+		if node, ok := v.anchors[resource+"#"+fragment]; ok {
+			return node, nil
+		}
+
+		return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+	}
+
+	node, ok := v.resources[resource]
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+	}
+
+	if fragment == "" {
+		return node, nil
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch n := node.(type) {
+		case map[string]interface{}:
+			next, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+		}
+	}
+
+	return node, nil
+}
+
+// resolveDynamic returns the schema a $dynamicRef points to. It is resolved like a $ref,
+// unless it points to a $dynamicAnchor: then it points to the schema with the same
+// $dynamicAnchor in the outermost resource of the dynamic scope which has one, so a
+// recursive schema can be extended.
+func (v *schemaValidator) resolveDynamic(ref string, scope *schemaScope) (interface{}, error) {
+	target, err := v.resolve(ref, scope.base)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		anchor = ref[i+1:]
+	}
+
+	if s, ok := target.(map[string]interface{}); !ok || anchor == "" || s["$dynamicAnchor"] != anchor {
+		return target, nil
+	}
+
+	for ; scope != nil; scope = scope.parent {
+		if node, ok := v.dynamicAnchors[scope.base+"#"+anchor]; ok {
+			target = node
+		}
+	}
+
+	return target, nil
+}
+
+// deref follows the $ref of the schema, if it has one.
+func (v *schemaValidator) deref(schema interface{}) interface{} {
+	base := v.base
+	for i := 0; i < maxSchemaDepth; i++ {
+		s, ok := schema.(map[string]interface{})
+		if !ok {
+			return schema
+		}
+
+		base = v.baseOf(s, base)
+		ref, ok := s["$ref"].(string)
+		if !ok {
+			return schema
+		}
+
+		target, err := v.resolve(ref, base)
+		if err != nil {
+			return schema
+		}
+
+		schema = target
+	}
+
+	return schema
+}
+
+// baseOf returns the URI of the resource the schema is in, or base if the schema is not
+// in the documents of the validator.
+func (v *schemaValidator) baseOf(schema interface{}, base string) string {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return base
+	}
+
+	if b, ok := v.bases[reflect.ValueOf(s).Pointer()]; ok {
+		return b
+	}
+
+	return base
+}
+
+// resolveURI resolves a reference against a base URI. References are returned as they
+// are when there is no base.
+func resolveURI(base string, ref string) string {
+	if base == "" {
+		return ref
+	}
+
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return b.ResolveReference(r).String()
+}
+
+// stripFragment returns the URI without its fragment.
+func stripFragment(uri string) string {
+	if i := strings.Index(uri, "#"); i >= 0 {
+		return uri[:i]
+	}
+
+	return uri
+}
+
+// readSchemaFile reads a YAML or JSON document with schemas.
+func readSchemaFile(file string) (interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return normalizeDocument(doc), nil
+}
+
+// matchesType returns true if the value is one of the types, which is a type name or a
+// list of them.
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if name, ok := name.(string); ok && matchesTypeName(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := jsonType(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+
+	return name == actual
+}
+
+// jsonType returns the JSON Schema type of the value. Numbers without a fraction are
+// integers.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// typeNames returns the type or types of a schema, ie: string or null.
+func typeNames(t interface{}) string {
+	list, ok := t.([]interface{})
+	if !ok {
+		return fmt.Sprint(t)
+	}
+
+	names := make([]string, 0, len(list))
+	for _, name := range list {
+		names = append(names, fmt.Sprint(name))
+	}
+
+	return strings.Join(names, " or ")
+}
+
+// validFormat returns false if the value is not in the format. Formats which are not
+// known are not checked.
+func validFormat(format string, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", value)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "uri", "url":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	case "hostname":
+		return hostnamePattern.MatchString(value)
+	case "int32", "int64":
+		bits := 32
+		if format == "int64" {
+			bits = 64
+		}
+		_, err := strconv.ParseInt(value, 10, bits)
+		return err == nil
+	}
+
+	return true
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// number returns the value of a numeric keyword.
+func number(value interface{}) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
+
+// formatNumber formats a number without a fraction if it has none.
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func pluralItems(n float64) string {
+	if n == 1 {
+		return "1 item"
+	}
+
+	return fmt.Sprintf("%s items", formatNumber(n))
+}
+
+// formatValue returns the value as JSON.
+func formatValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
+
+func joinValues(values []interface{}) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, formatValue(value))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if equalValues(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// pointer appends a token to a JSON pointer, escaping it.
+func pointer(location string, token string) string {
+	return location + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// normalizeDocument converts a document decoded from YAML into the types decoded from
+// JSON, so YAML and JSON schemas are validated the same way: maps with string keys and
+// float64 numbers.
+func normalizeDocument(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = normalizeDocument(val)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeDocument(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeDocument(val)
+		}
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+
+	return value
+}
@@ -0,0 +1,288 @@
+package protocol
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// decodeJSON decodes a schema or value written as JSON in a test.
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func TestSchemaValidatorKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		value    string
+		expected []ValidationError
+	}{
+		{"type", `{"type": "integer"}`, `1.5`, []ValidationError{{"", "expected integer, got number"}}},
+		{"number accepts integers", `{"type": "number"}`, `1`, nil},
+		{"type list", `{"type": ["string", "null"]}`, `null`, nil},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, nil},
+		{"enum", `{"enum": ["a", "b"]}`, `"c"`, []ValidationError{{"", `must be one of "a", "b"`}}},
+		{"const", `{"const": 1}`, `2`, []ValidationError{{"", "must be 1"}}},
+		{"minLength", `{"minLength": 3}`, `"ab"`, []ValidationError{{"", "must be at least 3 characters"}}},
+		{"maxLength counts characters", `{"maxLength": 2}`, `"éé"`, nil},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, `"A1"`, []ValidationError{{"", "must match pattern ^[a-z]+$"}}},
+		{"format", `{"format": "email"}`, `"not an email"`, []ValidationError{{"", "must be a valid email"}}},
+		{"unknown format", `{"format": "color"}`, `"blue"`, nil},
+		{"minimum", `{"minimum": 1}`, `0`, []ValidationError{{"", "must be at least 1"}}},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 10}`, `10`, []ValidationError{{"", "must be less than 10"}}},
+		{"OpenAPI 3.0 exclusiveMinimum", `{"minimum": 0, "exclusiveMinimum": true}`, `0`, []ValidationError{{"", "must be greater than 0"}}},
+		{"multipleOf", `{"multipleOf": 0.01}`, `1.23`, nil},
+		{"multipleOf fails", `{"multipleOf": 5}`, `12`, []ValidationError{{"", "must be a multiple of 5"}}},
+		{"minItems", `{"minItems": 2}`, `[1]`, []ValidationError{{"", "must have at least 2 items"}}},
+		{"uniqueItems", `{"uniqueItems": true}`, `[1, 2, 1]`, []ValidationError{{"/2", "must be unique"}}},
+		{
+			"items",
+			`{"items": {"type": "string"}}`,
+			`["a", 1]`,
+			[]ValidationError{{"/1", "expected string, got integer"}},
+		},
+		{
+			"prefixItems",
+			`{"prefixItems": [{"type": "string"}], "items": false}`,
+			`["a", 1]`,
+			[]ValidationError{{"/1", "is not allowed"}},
+		},
+		{
+			"contains",
+			`{"contains": {"type": "string"}}`,
+			`[1, 2]`,
+			[]ValidationError{{"", "must contain at least 1 item matching the schema of contains"}},
+		},
+		{
+			"required",
+			`{"required": ["name", "age"]}`,
+			`{"age": 1}`,
+			[]ValidationError{{"/name", "is required"}},
+		},
+		{
+			"properties",
+			`{"properties": {"items": {"items": {"properties": {"price": {"type": "number"}}}}}}`,
+			`{"items": [{"price": 1}, {"price": "free"}]}`,
+			[]ValidationError{{"/items/1/price", "expected number, got string"}},
+		},
+		{
+			"additionalProperties",
+			`{"properties": {"a": {}}, "patternProperties": {"^x-": {}}, "additionalProperties": false}`,
+			`{"a": 1, "x-b": 2, "c/d": 3}`,
+			[]ValidationError{{"/c~1d", "is not allowed"}},
+		},
+		{
+			"dependentRequired",
+			`{"dependentRequired": {"card": ["cvc"]}}`,
+			`{"card": "4242"}`,
+			[]ValidationError{{"/cvc", "is required when card is present"}},
+		},
+		{
+			"propertyNames",
+			`{"propertyNames": {"maxLength": 3}}`,
+			`{"long": 1}`,
+			[]ValidationError{{"/long", "must be at most 3 characters"}},
+		},
+		{
+			"allOf",
+			`{"allOf": [{"required": ["a"]}, {"required": ["b"]}]}`,
+			`{}`,
+			[]ValidationError{{"/a", "is required"}, {"/b", "is required"}},
+		},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, []ValidationError{{"", "must match at least one schema of anyOf"}}},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, []ValidationError{{"", "must match exactly one schema of oneOf, matched 2"}}},
+		{"not", `{"not": {"type": "null"}}`, `null`, []ValidationError{{"", "must not match the schema of not"}}},
+		{
+			"if then",
+			`{"if": {"properties": {"type": {"const": "card"}}}, "then": {"required": ["card"]}, "else": {"required": ["iban"]}}`,
+			`{"type": "card"}`,
+			[]ValidationError{{"/card", "is required"}},
+		},
+		{"false", `false`, `1`, []ValidationError{{"", "is not allowed"}}},
+		{
+			"dependentSchemas",
+			`{"dependentSchemas": {"card": {"required": ["cvc"]}}}`,
+			`{"card": "4242"}`,
+			[]ValidationError{{"/cvc", "is required"}},
+		},
+		{
+			"unevaluatedProperties",
+			`{"properties": {"a": {}}, "allOf": [{"properties": {"b": {}}}], "unevaluatedProperties": false}`,
+			`{"a": 1, "b": 2, "c": 3}`,
+			[]ValidationError{{"/c", "is not allowed"}},
+		},
+		{
+			"unevaluatedProperties of the matching anyOf",
+			`{"anyOf": [{"properties": {"a": {"type": "string"}}}, {"properties": {"b": {}}}], "unevaluatedProperties": false}`,
+			`{"a": 1, "b": 2}`,
+			[]ValidationError{{"/a", "is not allowed"}},
+		},
+		{
+			"unevaluatedProperties of if then",
+			`{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"properties": {"cvc": {}}}, "unevaluatedProperties": false}`,
+			`{"kind": "card", "cvc": "123"}`,
+			nil,
+		},
+		{
+			"unevaluatedItems",
+			`{"prefixItems": [{"type": "string"}], "unevaluatedItems": {"type": "integer"}}`,
+			`["a", 1, "b"]`,
+			[]ValidationError{{"/2", "expected integer, got string"}},
+		},
+		{
+			"unevaluatedItems with contains",
+			`{"contains": {"type": "string"}, "unevaluatedItems": false}`,
+			`["a", 1]`,
+			[]ValidationError{{"/1", "is not allowed"}},
+		},
+		{
+			"$anchor",
+			`{"$defs": {"id": {"$anchor": "id", "type": "integer"}}, "properties": {"id": {"$ref": "#id"}}}`,
+			`{"id": "1"}`,
+			[]ValidationError{{"/id", "expected integer, got string"}},
+		},
+		{
+			"$id",
+			`{"$id": "https://example.com/order", "$defs": {"item": {"$id": "item", "type": "string"}}, "items": {"$ref": "item"}}`,
+			`[1]`,
+			[]ValidationError{{"/0", "expected string, got integer"}},
+		},
+	}
+
+	for _, tc := range tests {
+		schema := decodeJSON(t, tc.schema)
+		v, err := newSchemaValidator(schema, "")
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+
+		errs := v.validate(schema, decodeJSON(t, tc.value), "")
+
+		if !reflect.DeepEqual(errs, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, errs)
+		}
+	}
+}
+
+func TestSchemaValidatorRefs(t *testing.T) {
+	root := decodeJSON(t, `{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"required": ["value"],
+				"properties": {
+					"value": {"type": "integer"},
+					"next": {"$ref": "#/$defs/node"}
+				}
+			}
+		},
+		"$ref": "#/$defs/node"
+	}`)
+	v, err := newSchemaValidator(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := v.validate(root, decodeJSON(t, `{"value": 1, "next": {"value": 2, "next": {"value": "3"}}}`), "/body")
+	expected := []ValidationError{{"/body/next/next/value", "expected integer, got string"}}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %v, got %v", expected, errs)
+	}
+
+	errs = v.validate(decodeJSON(t, `{"$ref": "#/$defs/missing"}`), 1, "/body")
+	expected = []ValidationError{{"/body", "cannot resolve $ref #/$defs/missing"}}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %v, got %v", expected, errs)
+	}
+}
+
+func TestSchemaValidatorDynamicRefs(t *testing.T) {
+	// The tree allows any values in its nodes, and the strict tree extends it to only allow
+	// strings, through its $dynamicAnchor.
+	tree := decodeJSON(t, `{
+		"$id": "https://example.com/tree",
+		"$dynamicAnchor": "node",
+		"type": "object",
+		"properties": {
+			"value": true,
+			"children": {"type": "array", "items": {"$dynamicRef": "#node"}}
+		}
+	}`)
+	strict := decodeJSON(t, `{
+		"$id": "https://example.com/strict-tree",
+		"$dynamicAnchor": "node",
+		"$ref": "tree",
+		"properties": {"value": {"type": "string"}},
+		"unevaluatedProperties": false
+	}`)
+	root := map[string]interface{}{"$defs": map[string]interface{}{"tree": tree, "strict": strict}}
+	v, err := newSchemaValidator(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := decodeJSON(t, `{"value": "a", "children": [{"value": 1, "extra": true}]}`)
+
+	if errs := v.validate(tree, value, "/body"); len(errs) != 0 {
+		t.Errorf("Expected the tree to be valid, got %v", errs)
+	}
+
+	errs := v.validate(strict, value, "/body")
+	expected := []ValidationError{
+		{"/body/children/0/value", "expected string, got integer"},
+		{"/body/children/0/extra", "is not allowed"},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %v, got %v", expected, errs)
+	}
+}
+
+func TestSchemaValidatorFileRefs(t *testing.T) {
+	dir := t.TempDir()
+	address := filepath.Join(dir, "address.json")
+	if err := ioutil.WriteFile(address, []byte(`{"$defs": {"street": {"type": "string", "minLength": 1}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := decodeJSON(t, `{"properties": {"street": {"$ref": "address.json#/$defs/street"}}}`)
+	v, err := newSchemaValidator(root, filepath.Join(dir, "order.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := v.validate(root, decodeJSON(t, `{"street": ""}`), "/body")
+	expected := []ValidationError{{"/body/street", "must be at least 1 characters"}}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %v, got %v", expected, errs)
+	}
+
+	for _, ref := range []string{"missing.json", "https://example.com/address.json"} {
+		root := map[string]interface{}{"$ref": ref}
+		if _, err := newSchemaValidator(root, filepath.Join(dir, "order.json")); err == nil {
+			t.Errorf("Expected an error loading $ref %s", ref)
+		}
+	}
+}
+
+func TestNormalizeDocument(t *testing.T) {
+	doc := map[string]interface{}{
+		"responses": map[interface{}]interface{}{200: map[string]interface{}{"maxItems": 10}},
+	}
+	expected := map[string]interface{}{
+		"responses": map[string]interface{}{"200": map[string]interface{}{"maxItems": 10.0}},
+	}
+
+	if result := normalizeDocument(doc); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
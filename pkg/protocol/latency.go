@@ -0,0 +1,203 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Latency is a response delay, which is fixed, picked from a range, or sampled from a
+// normal or exponential distribution.
+type Latency struct {
+	// Spec is the latency as it was parsed, ie: 100ms-2s.
+	Spec string
+
+	// Min and Max are the range a delay is picked from. They are equal for a fixed
+	// delay.
+	Min time.Duration
+	Max time.Duration
+
+	// Distribution is normal or exponential, empty for a fixed delay or range.
+	Distribution string
+
+	// Mean and Stddev describe a normal distribution. For an exponential distribution
+	// Mean is the mean of the exponential part, which starts at Min.
+	Mean   time.Duration
+	Stddev time.Duration
+}
+
+// randSource is the random numbers a delay is sampled with. The math/rand functions are
+// safe to use from every request, and tests use a seeded *rand.Rand.
+type randSource interface {
+	Float64() float64
+	NormFloat64() float64
+	ExpFloat64() float64
+}
+
+// globalRand is a randSource using the math/rand functions.
+type globalRand struct{}
+
+func (globalRand) Float64() float64     { return rand.Float64() }
+func (globalRand) NormFloat64() float64 { return rand.NormFloat64() }
+func (globalRand) ExpFloat64() float64  { return rand.ExpFloat64() }
+
+// ParseLatency parses a latency spec, which is one of:
+//
+//	2s                              a fixed delay
+//	100ms-2s                        a delay picked evenly from the range
+//	normal:p50=200ms,p99=1s         a normal distribution with these percentiles
+//	exponential:p50=200ms           an exponential distribution with this percentile
+//	exponential:p50=200ms,p99=1s    an exponential distribution shifted to fit both
+//
+// Sampled delays are never negative.
+func ParseLatency(spec string) (*Latency, error) {
+	spec = strings.TrimSpace(spec)
+
+	if i := strings.Index(spec, ":"); i >= 0 {
+		return parseLatencyDistribution(spec, spec[:i], spec[i+1:])
+	}
+
+	if parts := strings.SplitN(spec, "-", 2); len(parts) == 2 && parts[0] != "" {
+		min, err := time.ParseDuration(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %w", spec, err)
+		}
+
+		max, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %w", spec, err)
+		}
+
+		if min < 0 || max < min {
+			return nil, fmt.Errorf("latency %q: range must go from a lower to a higher delay", spec)
+		}
+
+		return &Latency{Spec: spec, Min: min, Max: max}, nil
+	}
+
+	delay, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, fmt.Errorf("latency %q: %w", spec, err)
+	}
+
+	if delay < 0 {
+		return nil, fmt.Errorf("latency %q: delay cannot be negative", spec)
+	}
+
+	return &Latency{Spec: spec, Min: delay, Max: delay}, nil
+}
+
+// latencyPercentile is a delay at a percentile of a distribution, with p between 0 and 1.
+type latencyPercentile struct {
+	p     float64
+	delay time.Duration
+}
+
+// parseLatencyDistribution fits a distribution to the percentiles, ie: p50=200ms,p99=1s.
+func parseLatencyDistribution(spec string, distribution string, params string) (*Latency, error) {
+	var percentiles []latencyPercentile
+	for _, param := range strings.Split(params, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "p") {
+			return nil, fmt.Errorf("latency %q: expected percentiles such as p50=200ms", spec)
+		}
+
+		p, err := strconv.ParseFloat(kv[0][1:], 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("latency %q: invalid percentile %s", spec, kv[0])
+		}
+
+		delay, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %w", spec, err)
+		}
+
+		percentiles = append(percentiles, latencyPercentile{p: p / 100, delay: delay})
+	}
+
+	if len(percentiles) > 2 {
+		return nil, fmt.Errorf("latency %q: expected at most two percentiles", spec)
+	}
+
+	if len(percentiles) == 2 {
+		if percentiles[0].p > percentiles[1].p {
+			percentiles[0], percentiles[1] = percentiles[1], percentiles[0]
+		}
+
+		if percentiles[0].p == percentiles[1].p || percentiles[0].delay >= percentiles[1].delay {
+			return nil, fmt.Errorf("latency %q: a higher percentile must have a longer delay", spec)
+		}
+	}
+
+	latency := &Latency{Spec: spec, Distribution: distribution}
+
+	switch distribution {
+	case "normal":
+		if len(percentiles) != 2 {
+			return nil, fmt.Errorf("latency %q: a normal distribution needs two percentiles", spec)
+		}
+
+		// Each percentile is mean + z * stddev, where z is the quantile of the
+		// standard normal distribution.
+		lo, hi := percentiles[0], percentiles[1]
+		zlo, zhi := normalQuantile(lo.p), normalQuantile(hi.p)
+		stddev := float64(hi.delay-lo.delay) / (zhi - zlo)
+
+		latency.Stddev = time.Duration(stddev)
+		latency.Mean = lo.delay - time.Duration(zlo*stddev)
+	case "exponential":
+		// Each percentile is min - ln(1-p) * mean. With a single percentile the
+		// distribution starts at 0.
+		lo := percentiles[0]
+		mean := float64(lo.delay) / -math.Log(1-lo.p)
+
+		if len(percentiles) == 2 {
+			hi := percentiles[1]
+			mean = float64(hi.delay-lo.delay) / (math.Log(1-lo.p) - math.Log(1-hi.p))
+			latency.Min = lo.delay + time.Duration(math.Log(1-lo.p)*mean)
+		}
+
+		if latency.Min < 0 {
+			return nil, fmt.Errorf("latency %q: the percentiles do not fit an exponential distribution", spec)
+		}
+
+		latency.Mean = time.Duration(mean)
+	default:
+		return nil, fmt.Errorf("latency %q: unknown distribution %s, expected normal or exponential", spec, distribution)
+	}
+
+	return latency, nil
+}
+
+// normalQuantile returns the quantile of the standard normal distribution at p.
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// sample returns a delay.
+func (l *Latency) sample(rnd randSource) time.Duration {
+	var delay time.Duration
+
+	switch l.Distribution {
+	case "normal":
+		delay = l.Mean + time.Duration(rnd.NormFloat64()*float64(l.Stddev))
+	case "exponential":
+		delay = l.Min + time.Duration(rnd.ExpFloat64()*float64(l.Mean))
+	default:
+		delay = l.Min + time.Duration(rnd.Float64()*float64(l.Max-l.Min))
+	}
+
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+// String returns the spec the latency was parsed from.
+func (l *Latency) String() string {
+	return l.Spec
+}
@@ -0,0 +1,121 @@
+package protocol
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseLatency(t *testing.T) {
+	tests := []struct {
+		spec         string
+		min          time.Duration
+		max          time.Duration
+		distribution string
+	}{
+		{"2s", 2 * time.Second, 2 * time.Second, ""},
+		{"0", 0, 0, ""},
+		{"100ms-2s", 100 * time.Millisecond, 2 * time.Second, ""},
+		{"normal:p50=200ms,p99=1s", 0, 0, "normal"},
+		{"exponential:p50=200ms", 0, 0, "exponential"},
+	}
+
+	for _, tc := range tests {
+		latency, err := ParseLatency(tc.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.spec, err)
+		}
+
+		if latency.Min != tc.min || latency.Max != tc.max || latency.Distribution != tc.distribution {
+			t.Errorf("%s: expected %s-%s %q, got %s-%s %q", tc.spec, tc.min, tc.max, tc.distribution, latency.Min, latency.Max, latency.Distribution)
+		}
+
+		if latency.String() != tc.spec {
+			t.Errorf("Expected %s, got %s", tc.spec, latency.String())
+		}
+	}
+}
+
+func TestParseLatencyErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"soon",
+		"-1s",
+		"2s-1s",
+		"1s-later",
+		"normal:p50=200ms",
+		"normal:p99=200ms,p50=1s",
+		"normal:p50=200ms,p50=1s",
+		"normal:median=200ms",
+		"normal:p100=1s,p50=200ms",
+		"exponential:p50=1s,p99=1100ms,p99.9=2s",
+		"exponential:p50=100ms,p99=2s",
+		"pareto:p50=200ms",
+	}
+
+	for _, spec := range tests {
+		if _, err := ParseLatency(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	tests := []struct {
+		spec string
+		p50  time.Duration
+		p99  time.Duration
+	}{
+		{"normal:p50=200ms,p99=1s", 200 * time.Millisecond, time.Second},
+		{"exponential:p50=200ms,p99=1s", 200 * time.Millisecond, time.Second},
+		{"exponential:p99=1s", 151 * time.Millisecond, time.Second},
+		{"100ms-300ms", 200 * time.Millisecond, 298 * time.Millisecond},
+	}
+
+	for _, tc := range tests {
+		latency, err := ParseLatency(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rnd := rand.New(rand.NewSource(1))
+		samples := make([]time.Duration, 100000)
+		for i := range samples {
+			samples[i] = latency.sample(rnd)
+			if samples[i] < 0 {
+				t.Fatalf("%s: sampled a negative delay %s", tc.spec, samples[i])
+			}
+		}
+
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+		for _, expected := range []struct {
+			name  string
+			got   time.Duration
+			delay time.Duration
+		}{
+			{"p50", samples[len(samples)/2], tc.p50},
+			{"p99", samples[len(samples)*99/100], tc.p99},
+		} {
+			diff := expected.got - expected.delay
+			if diff < 0 {
+				diff = -diff
+			}
+
+			if diff > expected.delay/20 {
+				t.Errorf("%s: expected %s of %s, got %s", tc.spec, expected.name, expected.delay, expected.got)
+			}
+		}
+	}
+}
+
+func TestLatencySampleFixed(t *testing.T) {
+	latency, _ := ParseLatency("250ms")
+
+	for i := 0; i < 10; i++ {
+		if delay := latency.sample(globalRand{}); delay != 250*time.Millisecond {
+			t.Errorf("Expected 250ms, got %s", delay)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// maxMultipartMemory is how much of a multipart form is kept in memory while it is
+// parsed, the rest is written to temporary files. This matches logparams, which parses
+// the same form for the fields.
+const maxMultipartMemory = 32 << 20
+
+// UploadedFile is a file uploaded with a multipart/form-data request.
+type UploadedFile struct {
+	ID          string `json:"id"`
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Sha256      string `json:"sha256"`
+
+	// Path is where the contents are stored, empty if they were not stored.
+	Path string `json:"path,omitempty"`
+}
+
+// readUploadedFiles parses a multipart/form-data request and returns its files, sorted by
+// field. The contents are stored in dir, unless it is empty. The form is left parsed on
+// the request, so the fields are still read by logparams.
+func readUploadedFiles(r *http.Request, dir string) []UploadedFile {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return nil
+	}
+
+	if err := r.ParseMultipartForm(maxMultipartMemory); err != nil || r.MultipartForm == nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(r.MultipartForm.File))
+	for field := range r.MultipartForm.File {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	var files []UploadedFile
+	for _, field := range fields {
+		for _, fh := range r.MultipartForm.File[field] {
+			files = append(files, readUploadedFile(field, fh, dir))
+		}
+	}
+
+	return files
+}
+
+// readUploadedFile hashes the file and copies it to dir. If the file cannot be stored,
+// it is still hashed but its Path is left empty.
+func readUploadedFile(field string, fh *multipart.FileHeader, dir string) UploadedFile {
+	file := UploadedFile{
+		ID:          uuid.New().String(),
+		Field:       field,
+		Filename:    fh.Filename,
+		ContentType: fh.Header.Get("Content-Type"),
+		Size:        int(fh.Size),
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return file
+	}
+	defer src.Close()
+
+	hash := sha256.New()
+	var dst io.Writer = hash
+
+	if dir != "" {
+		if out, path, err := createUploadFile(dir, file.ID, fh.Filename); err == nil {
+			defer out.Close()
+			dst = io.MultiWriter(hash, out)
+			file.Path = path
+		}
+	}
+
+	n, err := io.Copy(dst, src)
+	if err != nil {
+		file.Path = ""
+	}
+
+	file.Size = int(n)
+	file.Sha256 = hex.EncodeToString(hash.Sum(nil))
+
+	return file
+}
+
+// createUploadFile creates the file an upload is stored in. The id keeps uploads with the
+// same filename apart, and only the base of the filename is used so it cannot escape dir.
+func createUploadFile(dir string, id string, filename string) (*os.File, string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, "", err
+	}
+
+	name := id
+	if base := filepath.Base(filename); base != "." && base != string(filepath.Separator) {
+		name = id + "-" + base
+	}
+
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return f, path, nil
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"testing"
+)
+
+func TestHttpMultipartUpload(t *testing.T) {
+	contents := []byte("\x89PNG fake image")
+	sum := sha256.Sum256(contents)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "foo")
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="avatar"; filename="../me.png"`)
+	header.Set("Content-Type", "image/png")
+	part, _ := mw.CreatePart(header)
+	part.Write(contents)
+
+	docs, _ := mw.CreateFormFile("docs", "readme.txt")
+	docs.Write([]byte("hello"))
+	mw.Close()
+
+	tests := []struct {
+		name   string
+		dir    string
+		stored bool
+	}{
+		{"stored", t.TempDir(), true},
+		{"not stored", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rpChan := make(chan RequestPayload, 1)
+			httpServer := Http{ResponseCode: 200, UploadDir: test.dir, rendererChannels: []chan RequestPayload{rpChan}}
+			srv := httptest.NewServer(httpServer.routes())
+			defer srv.Close()
+
+			resp, err := http.Post(srv.URL+"/upload", mw.FormDataContentType(), bytes.NewReader(body.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			rp := <-rpChan
+			if rp.ParamFields.Form["name"] != "foo" {
+				t.Errorf("Expected form field name to be foo, got %v", rp.ParamFields.Form)
+			}
+
+			if len(rp.Attachments) != 2 {
+				t.Fatalf("Expected 2 attachments, got %d", len(rp.Attachments))
+			}
+
+			file := rp.Attachments[0]
+			if file.Field != "avatar" || file.ContentType != "image/png" || file.Size != len(contents) {
+				t.Errorf("Unexpected attachment %+v", file)
+			}
+
+			if file.Sha256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("Expected sha256 %x, got %s", sum, file.Sha256)
+			}
+
+			if rp.Attachments[1].Filename != "readme.txt" {
+				t.Errorf("Expected readme.txt, got %s", rp.Attachments[1].Filename)
+			}
+
+			if !test.stored {
+				if file.Path != "" {
+					t.Errorf("Expected file not to be stored, got %s", file.Path)
+				}
+				return
+			}
+
+			if filepath.Dir(file.Path) != test.dir {
+				t.Errorf("Expected file to be stored in %s, got %s", test.dir, file.Path)
+			}
+
+			stored, err := ioutil.ReadFile(file.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(stored, contents) {
+				t.Errorf("Expected stored contents %q, got %q", contents, stored)
+			}
+		})
+	}
+}
+
+func TestHttpNotMultipart(t *testing.T) {
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{ResponseCode: 200, UploadDir: t.TempDir(), rendererChannels: []chan RequestPayload{rpChan}}
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewBufferString(`{"foo":"bar"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	rp := <-rpChan
+	if rp.Attachments != nil {
+		t.Errorf("Expected no attachments, got %v", rp.Attachments)
+	}
+}
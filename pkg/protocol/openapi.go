@@ -0,0 +1,732 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operations a path item can have, in the order they are listed.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// OpenAPI mocks the API described by an OpenAPI 3 spec. Requests are routed to the
+// operations of the spec, validated against their parameters and request bodies, and
+// answered with the examples of their responses, or with responses generated from their
+// schemas.
+type OpenAPI struct {
+	Title   string
+	Version string
+
+	// BasePath is the path of the first server of the spec, which is stripped from
+	// requests before they are routed, ie: /v1.
+	BasePath string
+
+	Operations []OpenAPIOperation
+
+	schemas *schemaValidator
+}
+
+// OpenAPIOperation is an operation of a spec.
+type OpenAPIOperation struct {
+	Method string
+
+	// Path is the path template, ie: /pets/{petId}.
+	Path string
+
+	OperationID string
+
+	parameters  []openAPIParameter
+	requestBody map[string]interface{}
+	responses   map[string]interface{}
+
+	// pattern matches the request paths of the template, capturing params in the
+	// order of paramNames.
+	pattern    *regexp.Regexp
+	paramNames []string
+}
+
+// openAPIParameter is a path, query, header or cookie parameter of an operation.
+type openAPIParameter struct {
+	Name     string
+	In       string
+	Required bool
+	Explode  bool
+	Schema   interface{}
+}
+
+// String returns the operation, ie: GET /pets/{petId}.
+func (op *OpenAPIOperation) String() string {
+	return fmt.Sprintf("%s %s", op.Method, op.Path)
+}
+
+// LoadOpenAPI loads an OpenAPI 3 spec from a YAML or JSON file.
+func LoadOpenAPI(file string) (*OpenAPI, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("openapi %s: %w", file, err)
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi %s: %w", file, err)
+	}
+
+	spec, err := newOpenAPI(normalizeDocument(doc), file)
+	if err != nil {
+		return nil, fmt.Errorf("openapi %s: %w", file, err)
+	}
+
+	return spec, nil
+}
+
+// newOpenAPI reads the operations of the spec document, which was read from the file.
+func newOpenAPI(doc interface{}, file string) (*OpenAPI, error) {
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an OpenAPI document")
+	}
+
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", version)
+	}
+
+	schemas, err := newSchemaValidator(root, file)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &OpenAPI{schemas: schemas}
+
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		spec.Title, _ = info["title"].(string)
+		spec.Version = fmt.Sprint(info["version"])
+	}
+
+	if servers, ok := root["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			serverURL, _ := server["url"].(string)
+			if u, err := url.Parse(serverURL); err == nil && !strings.Contains(u.Path, "{") {
+				spec.BasePath = strings.TrimSuffix(u.Path, "/")
+			}
+		}
+	}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for _, path := range sortedKeys(paths) {
+		item, ok := spec.schemas.deref(paths[path]).(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			op, err := spec.newOperation(strings.ToUpper(method), path, item, operation)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+
+			spec.Operations = append(spec.Operations, op)
+		}
+	}
+
+	if len(spec.Operations) == 0 {
+		return nil, fmt.Errorf("no operations in paths")
+	}
+
+	// Paths with fewer params are more specific, so /pets/mine is matched before
+	// /pets/{petId}.
+	sort.SliceStable(spec.Operations, func(i, j int) bool {
+		return len(spec.Operations[i].paramNames) < len(spec.Operations[j].paramNames)
+	})
+
+	return spec, nil
+}
+
+// newOperation reads the operation, with the parameters of its path item.
+func (o *OpenAPI) newOperation(method string, path string, item map[string]interface{}, operation map[string]interface{}) (OpenAPIOperation, error) {
+	op := OpenAPIOperation{Method: method, Path: path}
+	op.OperationID, _ = operation["operationId"].(string)
+
+	var err error
+	op.pattern, op.paramNames, err = pathPattern(path)
+	if err != nil {
+		return op, err
+	}
+
+	// Parameters of the operation override the parameters of the path item with the
+	// same name and location.
+	params := map[string]openAPIParameter{}
+	var order []string
+	for _, list := range []interface{}{item["parameters"], operation["parameters"]} {
+		list, _ := list.([]interface{})
+		for _, raw := range list {
+			raw, ok := o.schemas.deref(raw).(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			param := openAPIParameter{Schema: raw["schema"]}
+			param.Name, _ = raw["name"].(string)
+			param.In, _ = raw["in"].(string)
+			param.Required = raw["required"] == true || param.In == "path"
+
+			style, _ := raw["style"].(string)
+			param.Explode = style == "" || style == "form"
+			if explode, ok := raw["explode"].(bool); ok {
+				param.Explode = explode
+			}
+
+			key := param.In + " " + param.Name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+
+	for _, key := range order {
+		op.parameters = append(op.parameters, params[key])
+	}
+
+	op.requestBody, _ = o.schemas.deref(operation["requestBody"]).(map[string]interface{})
+	op.responses, _ = operation["responses"].(map[string]interface{})
+
+	return op, nil
+}
+
+// pathPattern compiles the path template into a regular expression, and returns the
+// names of its params in the order they are captured.
+func pathPattern(path string) (*regexp.Regexp, []string, error) {
+	var names []string
+	var expr strings.Builder
+
+	expr.WriteString("^")
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			expr.WriteString(regexp.QuoteMeta(path))
+			break
+		}
+
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unclosed param in path")
+		}
+
+		expr.WriteString(regexp.QuoteMeta(path[:start]))
+		expr.WriteString("([^/]+)")
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+	expr.WriteString("/?$")
+
+	re, err := regexp.Compile(expr.String())
+
+	return re, names, err
+}
+
+// String returns the spec, ie: Petstore 1.0.0 (3 operations).
+func (o *OpenAPI) String() string {
+	if len(o.Operations) == 1 {
+		return fmt.Sprintf("%s %s (1 operation)", o.Title, o.Version)
+	}
+
+	return fmt.Sprintf("%s %s (%d operations)", o.Title, o.Version, len(o.Operations))
+}
+
+// route returns the operation the request is for, and its path params. If no operation
+// matches, the status code to answer with is returned: 404 if no path matches, or 405
+// if the path has no operation for the method.
+func (o *OpenAPI) route(r *http.Request) (*OpenAPIOperation, map[string]string, int) {
+	path := r.URL.Path
+	if o.BasePath != "" && strings.HasPrefix(path, o.BasePath) {
+		path = strings.TrimPrefix(path, o.BasePath)
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	code := http.StatusNotFound
+	for i := range o.Operations {
+		op := &o.Operations[i]
+
+		matches := op.pattern.FindStringSubmatch(path)
+		if matches == nil {
+			continue
+		}
+
+		if op.Method != r.Method {
+			code = http.StatusMethodNotAllowed
+			continue
+		}
+
+		params := make(map[string]string, len(op.paramNames))
+		for i, name := range op.paramNames {
+			value, err := url.PathUnescape(matches[i+1])
+			if err != nil {
+				value = matches[i+1]
+			}
+			params[name] = value
+		}
+
+		return op, params, 0
+	}
+
+	return nil, nil, code
+}
+
+// mock validates the request against the spec, and returns the result with the response
+// to answer the request with. Returns false if no operation matches the request.
+func (o *OpenAPI) mock(r *http.Request) (*Validation, *HttpResponse, bool) {
+	op, params, code := o.route(r)
+	if op == nil {
+		message := fmt.Sprintf("no operation matches %s %s", r.Method, r.URL.Path)
+		if code == http.StatusMethodNotAllowed {
+			message = fmt.Sprintf("no %s operation for %s", r.Method, r.URL.Path)
+		}
+
+		validation := newValidation(fmt.Sprintf("%s %s", r.Method, r.URL.Path), []ValidationError{{"/path", message}})
+
+		return validation, errorResponse(code, validation.Errors), false
+	}
+
+	errs := o.validateParameters(op, r, params)
+	errs = append(errs, o.validateBody(op, r)...)
+
+	return newValidation(op.String(), errs), o.response(op), true
+}
+
+// validateParameters validates the path, query, header and cookie params of the request.
+func (o *OpenAPI) validateParameters(op *OpenAPIOperation, r *http.Request, pathParams map[string]string) []ValidationError {
+	var errs []ValidationError
+	query := r.URL.Query()
+
+	for _, param := range op.parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			if value, ok := pathParams[param.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			// Accept, Content-Type and Authorization are described by the spec in other
+			// ways, so they are ignored as params.
+			switch http.CanonicalHeaderKey(param.Name) {
+			case "Accept", "Content-Type", "Authorization":
+				continue
+			}
+			values = r.Header.Values(param.Name)
+		case "cookie":
+			if cookie, err := r.Cookie(param.Name); err == nil {
+				values = []string{cookie.Value}
+			}
+		default:
+			continue
+		}
+
+		location := pointer("/"+param.In, param.Name)
+		if len(values) == 0 {
+			if param.Required {
+				errs = append(errs, ValidationError{location, "is required"})
+			}
+			continue
+		}
+
+		value := o.paramValue(param, values)
+		errs = append(errs, o.schemas.validate(param.Schema, value, location)...)
+	}
+
+	return errs
+}
+
+// paramValue converts the values of a param to the type of its schema, so they can be
+// validated. Values which cannot be converted are left as strings, and fail validation.
+func (o *OpenAPI) paramValue(param openAPIParameter, values []string) interface{} {
+	schema, _ := o.schemas.deref(param.Schema).(map[string]interface{})
+	if schemaType(schema) != "array" {
+		return o.coerce(schema, values[0])
+	}
+
+	if !param.Explode || param.In != "query" {
+		values = strings.Split(strings.Join(values, ","), ",")
+	}
+
+	items, _ := o.schemas.deref(schema["items"]).(map[string]interface{})
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, o.coerce(items, value))
+	}
+
+	return list
+}
+
+// coerce converts a string to the type of the schema.
+func (o *OpenAPI) coerce(schema map[string]interface{}, value string) interface{} {
+	switch schemaType(schema) {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil && (value == "true" || value == "false") {
+			return b
+		}
+	}
+
+	return value
+}
+
+// schemaType returns the type of the schema, or the first type which is not null if it
+// has several.
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, name := range t {
+			if name, ok := name.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// validateBody validates the body of the request against the schema of its content type.
+// JSON, form and multipart bodies are validated, other content types are only checked
+// against the content types of the operation.
+func (o *OpenAPI) validateBody(op *OpenAPIOperation, r *http.Request) []ValidationError {
+	if op.requestBody == nil {
+		return nil
+	}
+
+	// Multipart forms were already read for their files.
+	var body []byte
+	if r.MultipartForm == nil {
+		body = readBody(r)
+	}
+
+	if len(body) == 0 && r.MultipartForm == nil {
+		if op.requestBody["required"] == true {
+			return []ValidationError{{"/body", "is required"}}
+		}
+		return nil
+	}
+
+	content, _ := op.requestBody["content"].(map[string]interface{})
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	mediaType, ok := matchMediaType(content, contentType)
+	if !ok {
+		return []ValidationError{{"/header/Content-Type", fmt.Sprintf("unsupported content type %q, expected %s", contentType, strings.Join(sortedKeys(content), ", "))}}
+	}
+
+	media, _ := content[mediaType].(map[string]interface{})
+	schema, ok := media["schema"]
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	switch {
+	case isJSONMediaType(contentType):
+		if err := json.Unmarshal(body, &value); err != nil {
+			return []ValidationError{{"/body", fmt.Sprintf("invalid JSON: %s", err)}}
+		}
+	case contentType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return []ValidationError{{"/body", fmt.Sprintf("invalid form: %s", err)}}
+		}
+		value = o.formValue(schema, form)
+	case r.MultipartForm != nil:
+		// Files are validated as strings with their filenames, since their contents are
+		// binary.
+		form := url.Values{}
+		for name, values := range r.MultipartForm.Value {
+			form[name] = values
+		}
+		for name, files := range r.MultipartForm.File {
+			for _, file := range files {
+				form.Add(name, file.Filename)
+			}
+		}
+		value = o.formValue(schema, form)
+	default:
+		return nil
+	}
+
+	return o.schemas.validate(schema, value, "/body")
+}
+
+// formValue converts a form to an object, with the fields converted to the types of the
+// properties of the schema.
+func (o *OpenAPI) formValue(schema interface{}, form url.Values) map[string]interface{} {
+	s, _ := o.schemas.deref(schema).(map[string]interface{})
+	properties, _ := s["properties"].(map[string]interface{})
+
+	value := make(map[string]interface{}, len(form))
+	for name, values := range form {
+		property, _ := o.schemas.deref(properties[name]).(map[string]interface{})
+		value[name] = o.paramValue(openAPIParameter{In: "query", Explode: true, Schema: property}, values)
+	}
+
+	return value
+}
+
+// matchMediaType returns the media type of the content which matches the content type,
+// trying an exact match, then a wildcard subtype, then any type.
+func matchMediaType(content map[string]interface{}, contentType string) (string, bool) {
+	candidates := []string{contentType, "*/*"}
+	if i := strings.Index(contentType, "/"); i > 0 {
+		candidates = []string{contentType, contentType[:i] + "/*", "*/*"}
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := content[candidate]; ok {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// isJSONMediaType returns true for application/json and JSON based media types, such as
+// application/problem+json.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// response returns the response of the operation a request is answered with: the first
+// success response, or the default response. Its body is the example of its content,
+// or is generated from its schema.
+func (o *OpenAPI) response(op *OpenAPIOperation) *HttpResponse {
+	codes := sortedKeys(op.responses)
+	picked := ""
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			picked = code
+			break
+		}
+	}
+
+	if picked == "" && op.responses["default"] != nil {
+		picked = "default"
+	} else if picked == "" && len(codes) > 0 {
+		picked = codes[0]
+	}
+
+	response := &HttpResponse{StatusCode: responseStatusCode(picked), Headers: map[string]string{}}
+
+	spec, _ := o.schemas.deref(op.responses[picked]).(map[string]interface{})
+	content, _ := spec["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return response
+	}
+
+	mediaType := "application/json"
+	if _, ok := content[mediaType]; !ok {
+		mediaType = sortedKeys(content)[0]
+	}
+
+	media, _ := content[mediaType].(map[string]interface{})
+	example, ok := o.mediaExample(media)
+	if !ok {
+		example = o.generate(media["schema"], 0)
+	}
+
+	if s, ok := example.(string); ok && !isJSONMediaType(mediaType) {
+		response.Body = s
+	} else {
+		body, _ := json.Marshal(example)
+		response.Body = string(body)
+	}
+
+	if !strings.Contains(mediaType, "*") {
+		response.Headers["Content-Type"] = mediaType
+	}
+
+	return response
+}
+
+// responseStatusCode returns the status code of a response code of a spec, which can be
+// a range such as 2XX, or default.
+func responseStatusCode(code string) int {
+	if n, err := strconv.Atoi(code); err == nil {
+		return n
+	}
+
+	if len(code) == 3 && strings.HasSuffix(strings.ToUpper(code), "XX") {
+		if n, err := strconv.Atoi(code[:1]); err == nil {
+			return n * 100
+		}
+	}
+
+	return http.StatusOK
+}
+
+// mediaExample returns the example of the content, or its first named example.
+func (o *OpenAPI) mediaExample(media map[string]interface{}) (interface{}, bool) {
+	if example, ok := media["example"]; ok {
+		return example, true
+	}
+
+	examples, _ := media["examples"].(map[string]interface{})
+	for _, name := range sortedKeys(examples) {
+		example, _ := o.schemas.deref(examples[name]).(map[string]interface{})
+		if value, ok := example["value"]; ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// generate returns a value matching the schema, from its examples and defaults where it
+// has them.
+func (o *OpenAPI) generate(schema interface{}, depth int) interface{} {
+	s, ok := o.schemas.deref(schema).(map[string]interface{})
+	if !ok || depth > 8 {
+		return nil
+	}
+
+	if example, ok := s["example"]; ok {
+		return example
+	}
+
+	if examples, ok := s["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+
+	for _, keyword := range []string{"default", "const"} {
+		if value, ok := s[keyword]; ok {
+			return value
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, sub := range all {
+			if object, ok := o.generate(sub, depth+1).(map[string]interface{}); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if subs, ok := s[keyword].([]interface{}); ok && len(subs) > 0 {
+			return o.generate(subs[0], depth+1)
+		}
+	}
+
+	t := schemaType(s)
+	if t == "" && s["properties"] != nil {
+		t = "object"
+	} else if t == "" && s["items"] != nil {
+		t = "array"
+	}
+
+	switch t {
+	case "object":
+		object := map[string]interface{}{}
+		properties, _ := s["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if p, ok := o.schemas.deref(property).(map[string]interface{}); ok && p["writeOnly"] == true {
+				continue
+			}
+			object[name] = o.generate(property, depth+1)
+		}
+		return object
+	case "array":
+		n := 1
+		if min, ok := number(s["minItems"]); ok && min > 1 {
+			n = int(min)
+		}
+
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			list = append(list, o.generate(s["items"], depth+1))
+		}
+		return list
+	case "integer", "number":
+		n := 0.0
+		if min, ok := number(s["minimum"]); ok {
+			n = min
+		} else if min, ok := number(s["exclusiveMinimum"]); ok {
+			n = min + 1
+		}
+		return n
+	case "boolean":
+		return true
+	case "string":
+		return generateString(s)
+	}
+
+	return nil
+}
+
+// generateString returns a string in the format of the schema.
+func generateString(s map[string]interface{}) string {
+	format, _ := s["format"].(string)
+	switch format {
+	case "date-time":
+		return "2021-01-01T00:00:00Z"
+	case "date":
+		return "2021-01-01"
+	case "time":
+		return "00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	}
+
+	str := "string"
+	if min, ok := number(s["minLength"]); ok && float64(len(str)) < min {
+		str += strings.Repeat("x", int(min)-len(str))
+	}
+
+	return str
+}
+
+// errorResponse returns a JSON response with the validation errors.
+func errorResponse(code int, errs []ValidationError) *HttpResponse {
+	body, _ := json.Marshal(map[string]interface{}{"errors": errs})
+
+	return &HttpResponse{
+		StatusCode: code,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,380 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstoreSpec = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+      responses:
+        200:
+          description: The pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      operationId: createPet
+      parameters:
+        - name: X-Request-Id
+          in: header
+          required: true
+          schema:
+            type: string
+            format: uuid
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewPet'
+      responses:
+        201:
+          description: The pet
+          content:
+            application/json:
+              examples:
+                rex:
+                  value: {id: 1, name: Rex}
+        400:
+          $ref: '#/components/responses/Error'
+  /pets/mine:
+    get:
+      responses:
+        204:
+          description: No pets
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        schema:
+          type: integer
+    get:
+      operationId: getPet
+      responses:
+        200:
+          description: The pet
+          content:
+            application/json:
+              example: {id: 1, name: Rex, tag: dog}
+components:
+  schemas:
+    NewPet:
+      type: object
+      required: [name]
+      additionalProperties: false
+      properties:
+        name:
+          type: string
+          minLength: 1
+        tag:
+          type: string
+          enum: [dog, cat]
+    Pet:
+      allOf:
+        - type: object
+          properties:
+            id:
+              type: integer
+              format: int64
+              minimum: 1
+        - $ref: '#/components/schemas/NewPet'
+  responses:
+    Error:
+      description: An error
+`
+
+func loadPetstore(t *testing.T) *OpenAPI {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "petstore.yaml")
+	if err := ioutil.WriteFile(file, []byte(petstoreSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := LoadOpenAPI(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return spec
+}
+
+func TestLoadOpenAPI(t *testing.T) {
+	spec := loadPetstore(t)
+
+	if spec.String() != "Petstore 1.0.0 (4 operations)" {
+		t.Errorf("Expected Petstore 1.0.0 (4 operations), got %s", spec)
+	}
+
+	if spec.BasePath != "/v1" {
+		t.Errorf("Expected the base path /v1, got %s", spec.BasePath)
+	}
+
+	var operations []string
+	for _, op := range spec.Operations {
+		operations = append(operations, op.String())
+	}
+
+	expected := []string{"GET /pets", "POST /pets", "GET /pets/mine", "GET /pets/{petId}"}
+	if !reflect.DeepEqual(operations, expected) {
+		t.Errorf("Expected %v, got %v", expected, operations)
+	}
+}
+
+func TestLoadOpenAPIErrors(t *testing.T) {
+	tests := []string{
+		`swagger: "2.0"`,
+		`openapi: 3.0.0`,
+		`[`,
+	}
+
+	for _, spec := range tests {
+		file := filepath.Join(t.TempDir(), "spec.yaml")
+		ioutil.WriteFile(file, []byte(spec), 0644)
+
+		if _, err := LoadOpenAPI(file); err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+	spec := loadPetstore(t)
+	tests := []struct {
+		method    string
+		path      string
+		operation string
+		params    map[string]string
+		code      int
+	}{
+		{"GET", "/v1/pets", "GET /pets", map[string]string{}, 0},
+		{"GET", "/pets/", "GET /pets", map[string]string{}, 0},
+		{"GET", "/v1/pets/mine", "GET /pets/mine", map[string]string{}, 0},
+		{"GET", "/v1/pets/a%20b", "GET /pets/{petId}", map[string]string{"petId": "a b"}, 0},
+		{"DELETE", "/v1/pets/1", "", nil, http.StatusMethodNotAllowed},
+		{"GET", "/v1/owners", "", nil, http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		op, params, code := spec.route(r)
+
+		operation := ""
+		if op != nil {
+			operation = op.String()
+		}
+
+		if operation != tc.operation || !reflect.DeepEqual(params, tc.params) || code != tc.code {
+			t.Errorf("%s %s: expected %q %v %d, got %q %v %d", tc.method, tc.path, tc.operation, tc.params, tc.code, operation, params, code)
+		}
+	}
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	spec := loadPetstore(t)
+	tests := []struct {
+		method    string
+		path      string
+		headers   map[string]string
+		body      string
+		operation string
+		expected  []ValidationError
+	}{
+		{
+			method:    "GET",
+			path:      "/v1/pets?limit=10&tags=a&tags=b",
+			operation: "GET /pets",
+			expected:  []ValidationError{},
+		},
+		{
+			method:    "GET",
+			path:      "/v1/pets?limit=500",
+			operation: "GET /pets",
+			expected:  []ValidationError{{"/query/limit", "must be at most 100"}},
+		},
+		{
+			method:    "GET",
+			path:      "/v1/pets/rex",
+			operation: "GET /pets/{petId}",
+			expected:  []ValidationError{{"/path/petId", "expected integer, got string"}},
+		},
+		{
+			method:    "POST",
+			path:      "/v1/pets",
+			headers:   map[string]string{"X-Request-Id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "Content-Type": "application/json"},
+			body:      `{"name": "Rex", "tag": "dog"}`,
+			operation: "POST /pets",
+			expected:  []ValidationError{},
+		},
+		{
+			method:    "POST",
+			path:      "/v1/pets",
+			headers:   map[string]string{"Content-Type": "application/json; charset=utf-8"},
+			body:      `{"tag": "bird", "age": 2}`,
+			operation: "POST /pets",
+			expected: []ValidationError{
+				{"/header/X-Request-Id", "is required"},
+				{"/body/name", "is required"},
+				{"/body/age", "is not allowed"},
+				{"/body/tag", `must be one of "dog", "cat"`},
+			},
+		},
+		{
+			method:    "POST",
+			path:      "/v1/pets",
+			headers:   map[string]string{"X-Request-Id": "1", "Content-Type": "text/plain"},
+			body:      `Rex`,
+			operation: "POST /pets",
+			expected: []ValidationError{
+				{"/header/X-Request-Id", "must be a valid uuid"},
+				{"/header/Content-Type", `unsupported content type "text/plain", expected application/json`},
+			},
+		},
+		{
+			method:    "POST",
+			path:      "/v1/pets",
+			headers:   map[string]string{"X-Request-Id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "Content-Type": "application/json"},
+			body:      `{"name":`,
+			operation: "POST /pets",
+			expected:  []ValidationError{{"/body", "invalid JSON: unexpected end of JSON input"}},
+		},
+		{
+			method:    "POST",
+			path:      "/v1/pets",
+			headers:   map[string]string{"X-Request-Id": "3fa85f64-5717-4562-b3fc-2c963f66afa6"},
+			operation: "POST /pets",
+			expected:  []ValidationError{{"/body", "is required"}},
+		},
+		{
+			method:    "PUT",
+			path:      "/v1/pets",
+			operation: "PUT /v1/pets",
+			expected:  []ValidationError{{"/path", "no PUT operation for /v1/pets"}},
+		},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		for key, value := range tc.headers {
+			r.Header.Set(key, value)
+		}
+
+		validation, _, _ := spec.mock(r)
+		expected := &Validation{Operation: tc.operation, Valid: len(tc.expected) == 0, Errors: tc.expected}
+
+		if !reflect.DeepEqual(validation, expected) {
+			t.Errorf("%s %s: expected %+v, got %+v", tc.method, tc.path, expected, validation)
+		}
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	spec := loadPetstore(t)
+	tests := []struct {
+		method   string
+		path     string
+		expected HttpResponse
+	}{
+		{
+			"GET", "/v1/pets",
+			HttpResponse{200, map[string]string{"Content-Type": "application/json"}, `[{"id":1,"name":"string","tag":"dog"}]`},
+		},
+		{
+			"POST", "/v1/pets",
+			HttpResponse{201, map[string]string{"Content-Type": "application/json"}, `{"id":1,"name":"Rex"}`},
+		},
+		{
+			"GET", "/v1/pets/1",
+			HttpResponse{200, map[string]string{"Content-Type": "application/json"}, `{"id":1,"name":"Rex","tag":"dog"}`},
+		},
+		{
+			"GET", "/v1/pets/mine",
+			HttpResponse{204, map[string]string{}, ""},
+		},
+		{
+			"GET", "/v1/owners",
+			HttpResponse{404, map[string]string{"Content-Type": "application/json"}, `{"errors":[{"location":"/path","message":"no operation matches GET /v1/owners"}]}`},
+		},
+	}
+
+	for _, tc := range tests {
+		_, response, _ := spec.mock(httptest.NewRequest(tc.method, tc.path, nil))
+
+		if !reflect.DeepEqual(*response, tc.expected) {
+			t.Errorf("%s %s: expected %+v, got %+v", tc.method, tc.path, tc.expected, *response)
+		}
+	}
+}
+
+func TestHttpOpenAPI(t *testing.T) {
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{
+		ResponseCode:     200,
+		OpenAPI:          loadPetstore(t),
+		Rules:            []HttpRule{{Method: "GET", Path: "/v1/pets/mine", Responses: []HttpResponse{{StatusCode: 503}}}},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	tests := []struct {
+		path       string
+		code       int
+		body       string
+		validation string
+	}{
+		{"/v1/pets/1", 200, `{"id":1,"name":"Rex","tag":"dog"}`, "GET /pets/{petId} valid"},
+		{"/v1/pets?limit=none", 200, `[{"id":1,"name":"string","tag":"dog"}]`, "GET /pets invalid: /query/limit: expected integer, got string"},
+		{"/v1/pets/mine", 503, "", "GET /pets/mine valid"},
+	}
+
+	for _, tc := range tests {
+		resp, err := http.Get(srv.URL + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code || string(body) != tc.body {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.path, tc.code, tc.body, resp.StatusCode, body)
+		}
+
+		rp := <-rpChan
+		if rp.Validation == nil || rp.Validation.String() != tc.validation {
+			t.Errorf("%s: expected %s, got %v", tc.path, tc.validation, rp.Validation)
+		}
+	}
+}
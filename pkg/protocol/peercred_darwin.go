@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the credentials of the process connected to c, which macOS
+// provides with LOCAL_PEERCRED and LOCAL_PEERPID.
+func peerCredentials(c *net.UnixConn) (*PeerCredentials, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var xucred *unix.Xucred
+	var pid int
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		xucred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if credErr == nil {
+			// The pid is best effort, older versions of macOS do not provide it.
+			pid, _ = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	creds := &PeerCredentials{Uid: int(xucred.Uid), Pid: pid}
+	if xucred.Ngroups > 0 {
+		creds.Gid = int(xucred.Groups[0])
+	}
+
+	return creds, nil
+}
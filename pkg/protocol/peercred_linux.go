@@ -0,0 +1,31 @@
+package protocol
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the credentials of the process connected to c, which Linux
+// provides with SO_PEERCRED.
+func peerCredentials(c *net.UnixConn) (*PeerCredentials, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	return &PeerCredentials{Uid: int(ucred.Uid), Gid: int(ucred.Gid), Pid: int(ucred.Pid)}, nil
+}
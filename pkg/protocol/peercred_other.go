@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package protocol
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredentials is not supported on this platform.
+func peerCredentials(c *net.UnixConn) (*PeerCredentials, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
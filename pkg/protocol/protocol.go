@@ -15,6 +15,64 @@ type Protocol interface {
 	Start([]chan RequestPayload, []chan int, []chan int)
 }
 
+// EventSender is implemented by protocols that can push events to connected clients,
+// which lets the web UI send events on demand.
+type EventSender interface {
+	// SendEvent sends the event to every connected client and returns the number of
+	// clients it was sent to.
+	SendEvent(SseEvent) int
+}
+
+// MetricsAggregator is implemented by protocols that aggregate the metrics they receive,
+// which lets the web UI show the aggregate.
+type MetricsAggregator interface {
+	// Metrics returns the current aggregate of each metric.
+	Metrics() []StatsdAggregate
+}
+
+// SequenceResetter is implemented by protocols that answer routes with response
+// sequences, which lets the web UI show and reset them.
+type SequenceResetter interface {
+	// Sequences returns the state of each response sequence.
+	Sequences() []ResponseSequence
+
+	// ResetSequence starts the sequence of the route over, or of every route if route
+	// is empty, and returns the number of sequences reset.
+	ResetSequence(route string) int
+}
+
+// RateLimiter is implemented by protocols that emulate a rate limit, which lets the web
+// UI show its counters.
+type RateLimiter interface {
+	// RateLimits returns the counters of each key the rate limit counts requests by.
+	RateLimits() []RateLimitCounter
+}
+
+// ResponseController is implemented by protocols whose default response can be changed
+// while they run, which lets the web UI change it and pause the endpoint.
+type ResponseController interface {
+	// Response returns the default response.
+	Response() ResponseSettings
+
+	// UpdateResponse changes the fields of the default response which are set, and
+	// returns it.
+	UpdateResponse(ResponseUpdate) (ResponseSettings, error)
+
+	// ResetResponse undoes the changes made to the default response, and returns it.
+	ResetResponse() ResponseSettings
+
+	// SetPaused pauses or resumes the endpoint, and returns the default response.
+	SetPaused(paused bool) ResponseSettings
+}
+
+// ConfigReloader reloads the settings of a running protocol from its config and the files
+// it loads, which lets the web UI trigger a reload.
+type ConfigReloader interface {
+	// ReloadConfig reloads the settings, and returns the error which kept them from being
+	// applied.
+	ReloadConfig() error
+}
+
 // RequestPayload is the request payload we receive from an incoming request that we use with
 // the renderers.
 type RequestPayload struct {
@@ -24,4 +82,41 @@ type RequestPayload struct {
 	Message     string                   `json:"message"`
 	ParamFields logparams.ParamFields    `json:"paramFields"`
 	CreatedAt   time.Time                `json:"createdAt"`
+
+	// StreamID is the HTTP/2 stream id of the request, 0 for HTTP/1.x.
+	StreamID int `json:"streamId,omitempty"`
+
+	// Trailers are the trailers sent after the request body.
+	Trailers map[string][]string `json:"trailers,omitempty"`
+
+	// Peer are the credentials of the client process, for connections over a Unix socket.
+	Peer *PeerCredentials `json:"peer,omitempty"`
+
+	// Signature is the result of verifying the webhook signature of the request.
+	Signature *SignatureVerification `json:"signature,omitempty"`
+
+	// Fault is the fault chaos mode injected into the request.
+	Fault *ChaosFault `json:"fault,omitempty"`
+
+	// Sequence is the position of the request in the response sequence of its route.
+	Sequence *SequencePosition `json:"sequence,omitempty"`
+
+	// Validation is the result of validating the request against the OpenAPI spec.
+	Validation *Validation `json:"validation,omitempty"`
+
+	// Encoding describes how the body was decoded, when it was sent with a
+	// Content-Encoding.
+	Encoding *BodyEncoding `json:"encoding,omitempty"`
+
+	// Attachments are the files uploaded with a multipart form.
+	Attachments []UploadedFile `json:"attachments,omitempty"`
+
+	// Size is the size in bytes of the message, for protocols that receive raw data.
+	Size int `json:"size,omitempty"`
+
+	// Metric is the parsed metric, for protocols that receive metrics.
+	Metric *StatsdMetric `json:"metric,omitempty"`
+
+	// Email is the parsed message, for protocols that receive email.
+	Email *SmtpMessage `json:"email,omitempty"`
 }
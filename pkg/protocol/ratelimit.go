@@ -0,0 +1,270 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit algorithms.
+const (
+	FixedWindow = "fixed_window"
+	TokenBucket = "token_bucket"
+)
+
+// RateLimitCounter is the state of the rate limit of a single key.
+type RateLimitCounter struct {
+	// Key is the client IP, header value or path the requests are counted by.
+	Key string `json:"key"`
+
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
+
+	// Reset is the number of seconds until the limit is fully restored.
+	Reset int `json:"reset"`
+
+	// Allowed and Rejected count the requests which were let through and answered
+	// with 429.
+	Allowed  int `json:"allowed"`
+	Rejected int `json:"rejected"`
+}
+
+// RateLimit emulates the rate limit of an API, answering requests over the limit with
+// 429. Requests are counted by client IP, the value of a header(ie: an API key), or
+// path.
+type RateLimit struct {
+	// Algorithm is fixed_window, which allows Limit requests in each Window, or
+	// token_bucket, which allows bursts of Limit requests and refills Limit tokens
+	// evenly over each Window.
+	Algorithm string
+
+	Limit  int
+	Window time.Duration
+
+	// Key is ip, path or header:<name>.
+	Key string
+
+	mu       sync.Mutex
+	counters map[string]*rateLimitState
+
+	// now returns the current time, which tests replace.
+	now func() time.Time
+}
+
+// rateLimitState is the usage of a single key.
+type rateLimitState struct {
+	// start is the start of the window of a fixed window, and used counts the
+	// requests in it.
+	start time.Time
+	used  int
+
+	// tokens are the tokens left in a token bucket at updated.
+	tokens  float64
+	updated time.Time
+
+	allowed  int
+	rejected int
+}
+
+// rateLimitResult is the outcome of counting a request.
+type rateLimitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+	window     time.Duration
+}
+
+// NewRateLimit parses the limit, ie: 100/1m for 100 requests each minute, and returns a
+// rate limit using the algorithm and key.
+func NewRateLimit(spec string, algorithm string, key string) (*RateLimit, error) {
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rate limit %q: expected requests per window such as 100/1m", spec)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("rate limit %q: invalid number of requests %s", spec, parts[0])
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("rate limit %q: invalid window %s", spec, parts[1])
+	}
+
+	if algorithm != FixedWindow && algorithm != TokenBucket {
+		return nil, fmt.Errorf("unknown rate limit algorithm %q, expected fixed_window or token_bucket", algorithm)
+	}
+
+	if key != "ip" && key != "path" && (!strings.HasPrefix(key, "header:") || key == "header:") {
+		return nil, fmt.Errorf("unknown rate limit key %q, expected ip, path or header:<name>", key)
+	}
+
+	return &RateLimit{
+		Algorithm: algorithm,
+		Limit:     limit,
+		Window:    window,
+		Key:       key,
+		counters:  make(map[string]*rateLimitState),
+		now:       time.Now,
+	}, nil
+}
+
+// String returns the limit, ie: 100 requests per 1m0s by ip (fixed_window).
+func (rl *RateLimit) String() string {
+	return fmt.Sprintf("%d requests per %s by %s (%s)", rl.Limit, rl.Window, rl.Key, rl.Algorithm)
+}
+
+// key returns what the request is counted by.
+func (rl *RateLimit) key(r *http.Request) string {
+	switch {
+	case rl.Key == "path":
+		return r.URL.Path
+	case strings.HasPrefix(rl.Key, "header:"):
+		return r.Header.Get(strings.TrimPrefix(rl.Key, "header:"))
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// take counts the request against the limit of its key.
+func (rl *RateLimit) take(r *http.Request) rateLimitResult {
+	key := rl.key(r)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	state, ok := rl.counters[key]
+	if !ok {
+		state = &rateLimitState{tokens: float64(rl.Limit), updated: rl.now()}
+		rl.counters[key] = state
+	}
+
+	result := rl.update(state, true)
+	if result.allowed {
+		state.allowed++
+	} else {
+		state.rejected++
+	}
+
+	return result
+}
+
+// update brings the state up to date, and takes a request from it if take is set.
+func (rl *RateLimit) update(state *rateLimitState, take bool) rateLimitResult {
+	now := rl.now()
+	result := rateLimitResult{limit: rl.Limit, window: rl.Window}
+
+	if rl.Algorithm == FixedWindow {
+		start := now.Truncate(rl.Window)
+		if !state.start.Equal(start) {
+			state.start = start
+			state.used = 0
+		}
+
+		result.allowed = state.used < rl.Limit
+		if take && result.allowed {
+			state.used++
+		}
+
+		result.remaining = rl.Limit - state.used
+		result.reset = start.Add(rl.Window).Sub(now)
+		result.retryAfter = result.reset
+
+		return result
+	}
+
+	// The bucket refills Limit tokens over each Window.
+	rate := float64(rl.Limit) / float64(rl.Window)
+	state.tokens = math.Min(float64(rl.Limit), state.tokens+float64(now.Sub(state.updated))*rate)
+	state.updated = now
+
+	result.allowed = state.tokens >= 1
+	if take && result.allowed {
+		state.tokens--
+	}
+
+	result.remaining = int(state.tokens)
+	result.reset = time.Duration((float64(rl.Limit) - state.tokens) / rate)
+	result.retryAfter = time.Duration((1 - state.tokens) / rate)
+
+	return result
+}
+
+// Counters returns the state of the limit of each key, sorted by key.
+func (rl *RateLimit) Counters() []RateLimitCounter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	counters := make([]RateLimitCounter, 0, len(rl.counters))
+	for key, state := range rl.counters {
+		result := rl.update(state, false)
+		counters = append(counters, RateLimitCounter{
+			Key:       key,
+			Limit:     result.limit,
+			Remaining: result.remaining,
+			Reset:     seconds(result.reset),
+			Allowed:   state.allowed,
+			Rejected:  state.rejected,
+		})
+	}
+
+	sort.Slice(counters, func(i, j int) bool { return counters[i].Key < counters[j].Key })
+
+	return counters
+}
+
+// writeHeaders sets the rate limit headers: the X-RateLimit headers most APIs send, with
+// the reset as a unix timestamp, and the RateLimit headers of the IETF draft, with the
+// reset in seconds.
+func (result rateLimitResult) writeHeaders(w http.ResponseWriter, now time.Time) {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(result.limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(result.reset).Unix(), 10))
+	h.Set("RateLimit-Limit", strconv.Itoa(result.limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(result.reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.limit, seconds(result.window)))
+
+	if !result.allowed {
+		h.Set("Retry-After", strconv.Itoa(seconds(result.retryAfter)))
+	}
+}
+
+// seconds rounds the duration up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// handler counts the request, and returns a handler which answers it with 429 if it is
+// over the rate limit, or otherwise passes it to next. The rate limit headers are sent
+// either way. Returns false if the request is over the limit.
+func (rl *RateLimit) handler(next http.Handler, r *http.Request) (http.Handler, bool) {
+	result := rl.take(r)
+	now := rl.now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result.writeHeaders(w, now)
+
+		if !result.allowed {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}), result.allowed
+}
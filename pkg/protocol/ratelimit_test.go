@@ -0,0 +1,207 @@
+package protocol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testClock is a clock tests move forward by hand.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+
+func newTestRateLimit(t *testing.T, spec string, algorithm string, key string) (*RateLimit, *testClock) {
+	rl, err := NewRateLimit(spec, algorithm, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &testClock{t: time.Unix(1700000000, 0)}
+	rl.now = clock.now
+
+	return rl, clock
+}
+
+func TestNewRateLimitErrors(t *testing.T) {
+	tests := []struct {
+		spec      string
+		algorithm string
+		key       string
+	}{
+		{"100", FixedWindow, "ip"},
+		{"0/1m", FixedWindow, "ip"},
+		{"lots/1m", FixedWindow, "ip"},
+		{"100/0s", FixedWindow, "ip"},
+		{"100/minute", FixedWindow, "ip"},
+		{"100/1m", "sliding_window", "ip"},
+		{"100/1m", FixedWindow, "user"},
+		{"100/1m", FixedWindow, "header:"},
+	}
+
+	for _, tc := range tests {
+		if _, err := NewRateLimit(tc.spec, tc.algorithm, tc.key); err == nil {
+			t.Errorf("%s %s %s: expected an error", tc.spec, tc.algorithm, tc.key)
+		}
+	}
+}
+
+func TestRateLimitFixedWindow(t *testing.T) {
+	rl, clock := newTestRateLimit(t, "2/1m", FixedWindow, "ip")
+	r := httptest.NewRequest("GET", "/", nil)
+
+	for i, allowed := range []bool{true, true, false} {
+		if result := rl.take(r); result.allowed != allowed {
+			t.Errorf("Request %d: expected allowed %t, got %t", i+1, allowed, result.allowed)
+		}
+	}
+
+	// The clock starts 20s into a minute.
+	result := rl.take(r)
+	if result.remaining != 0 || result.retryAfter != 40*time.Second {
+		t.Errorf("Expected 0 remaining for 40s, got %d for %s", result.remaining, result.retryAfter)
+	}
+
+	clock.t = clock.t.Add(40 * time.Second)
+	if result := rl.take(r); !result.allowed || result.remaining != 1 {
+		t.Errorf("Expected the next window to allow the request, got %+v", result)
+	}
+
+	expected := []RateLimitCounter{{Key: "192.0.2.1", Limit: 2, Remaining: 1, Reset: 60, Allowed: 3, Rejected: 2}}
+	if counters := rl.Counters(); !reflect.DeepEqual(counters, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, counters)
+	}
+}
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	rl, clock := newTestRateLimit(t, "10/10s", TokenBucket, "ip")
+	r := httptest.NewRequest("GET", "/", nil)
+
+	for i := 0; i < 10; i++ {
+		if result := rl.take(r); !result.allowed {
+			t.Fatalf("Request %d: expected the burst to be allowed", i+1)
+		}
+	}
+
+	result := rl.take(r)
+	if result.allowed || seconds(result.retryAfter) != 1 || seconds(result.reset) != 10 {
+		t.Errorf("Expected to wait 1s for a token and 10s for a full bucket, got %+v", result)
+	}
+
+	clock.t = clock.t.Add(2500 * time.Millisecond)
+	for i, allowed := range []bool{true, true, false} {
+		if result := rl.take(r); result.allowed != allowed {
+			t.Errorf("Request %d after refill: expected allowed %t, got %t", i+1, allowed, result.allowed)
+		}
+	}
+
+	clock.t = clock.t.Add(time.Hour)
+	if result := rl.take(r); result.remaining != 9 {
+		t.Errorf("Expected the bucket to refill up to its limit, got %d remaining", result.remaining)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"ip", []string{"192.0.2.1"}},
+		{"path", []string{"/a", "/b"}},
+		{"header:X-API-Key", []string{"", "k1", "k2"}},
+	}
+
+	for _, tc := range tests {
+		rl, _ := newTestRateLimit(t, "1/1m", FixedWindow, tc.key)
+
+		for _, req := range []struct{ path, apiKey string }{{"/a", "k1"}, {"/b", "k2"}, {"/a", ""}} {
+			r := httptest.NewRequest("GET", req.path, nil)
+			if req.apiKey != "" {
+				r.Header.Set("X-API-Key", req.apiKey)
+			}
+			rl.take(r)
+		}
+
+		var keys []string
+		for _, counter := range rl.Counters() {
+			keys = append(keys, counter.Key)
+		}
+
+		if !reflect.DeepEqual(keys, tc.expected) {
+			t.Errorf("%s: expected keys %q, got %q", tc.key, tc.expected, keys)
+		}
+	}
+}
+
+func TestHttpRateLimit(t *testing.T) {
+	rl, clock := newTestRateLimit(t, "1/1m", FixedWindow, "ip")
+	rpChan := make(chan RequestPayload, 2)
+	httpServer := Http{
+		ResponseCode:     200,
+		RateLimit:        rl,
+		Rules:            []HttpRule{{Path: "/charge", Responses: []HttpResponse{{StatusCode: 201}}}},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	tests := []struct {
+		code      int
+		remaining string
+	}{
+		{201, "0"},
+		{429, "0"},
+	}
+
+	for _, tc := range tests {
+		resp, err := http.Post(srv.URL+"/charge", "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code {
+			t.Errorf("Expected %d, got %d", tc.code, resp.StatusCode)
+		}
+
+		reset := strconv.FormatInt(clock.t.Add(40*time.Second).Unix(), 10)
+		expected := map[string]string{
+			"X-Ratelimit-Limit":     "1",
+			"X-Ratelimit-Remaining": tc.remaining,
+			"X-Ratelimit-Reset":     reset,
+			"Ratelimit-Limit":       "1",
+			"Ratelimit-Remaining":   tc.remaining,
+			"Ratelimit-Reset":       "40",
+			"Ratelimit-Policy":      "1;w=60",
+		}
+
+		if tc.code == 429 {
+			expected["Retry-After"] = "40"
+		}
+
+		for header, value := range expected {
+			if resp.Header.Get(header) != value {
+				t.Errorf("%d: expected %s %q, got %q", tc.code, header, value, resp.Header.Get(header))
+			}
+		}
+
+		rp := <-rpChan
+		if tc.code == 429 && rp.Sequence != nil {
+			t.Errorf("Expected the rate limited request to not count against the sequence, got %+v", rp.Sequence)
+		}
+	}
+
+	if counters := httpServer.RateLimits(); len(counters) != 1 || counters[0].Allowed != 1 || counters[0].Rejected != 1 {
+		t.Errorf("Expected 1 allowed and 1 rejected request, got %+v", counters)
+	}
+
+	if counters := (&Http{}).RateLimits(); counters != nil {
+		t.Errorf("Expected no counters without a rate limit, got %+v", counters)
+	}
+}
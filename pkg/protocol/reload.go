@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+)
+
+// Events logged when the settings of a running protocol are reloaded.
+const (
+	ConfigReloaded = "CONFIG_RELOADED"
+	ConfigError    = "CONFIG_ERROR"
+)
+
+// settingsKey is the context key for the Http a request is answered with.
+type settingsKey struct{}
+
+// errRestart is returned when a reload changes where the protocol listens.
+var errRestart = errors.New("the address, port, Unix socket and TLS cannot change without a restart")
+
+// current returns the Http whose settings new requests are answered with: the last one
+// passed to Reload, or s.
+func (s *Http) current() *Http {
+	s.liveMu.RLock()
+	defer s.liveMu.RUnlock()
+
+	if s.live == nil {
+		return s
+	}
+
+	return s.live
+}
+
+// requestSettings returns the Http the request is answered with. logRequest puts it in
+// the context of the request, so a reload does not change the settings halfway through.
+func (s *Http) requestSettings(r *http.Request) *Http {
+	if live, ok := r.Context().Value(settingsKey{}).(*Http); ok {
+		return live
+	}
+
+	return s.current()
+}
+
+// Reload replaces the settings new requests are answered with by those of next, without
+// dropping connections. Requests in flight finish with the settings they started with.
+// The response sequences of the rules which did not change carry on, the rate limit
+// counters are kept if the limit did not change, and so are the changes made to the
+// default response.
+func (s *Http) Reload(next *Http) error {
+	if next.Addr != s.Addr || next.Port != s.Port || next.UnixSocket != s.UnixSocket || (next.TLSConfig == nil) != (s.TLSConfig == nil) {
+		return errRestart
+	}
+
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+
+	live := s.live
+	if live == nil {
+		live = s
+	}
+
+	if next.RateLimit != nil && live.RateLimit != nil && next.RateLimit.String() == live.RateLimit.String() {
+		next.RateLimit = live.RateLimit
+	}
+
+	next.sequenceCalls = live.keptSequences(next.Rules)
+
+	if s.control == nil {
+		s.control = &httpControl{}
+	}
+
+	next.control = s.control
+	next.listener = s.listener
+	next.rendererChannels = s.rendererChannels
+	next.rendererQuitChannels = s.rendererQuitChannels
+	s.live = next
+
+	return nil
+}
+
+// LogReload logs the result of a reload to the renderers: CONFIG_RELOADED with the
+// files the settings were loaded from, or CONFIG_ERROR with the error which kept them
+// from being applied.
+func (s *Http) LogReload(source string, err error) {
+	req := reloadPayload(source, err)
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// current returns the Ws whose settings new connections are accepted with: the last one
+// passed to Reload, or ws.
+func (ws *Ws) current() *Ws {
+	ws.liveMu.RLock()
+	defer ws.liveMu.RUnlock()
+
+	if ws.live == nil {
+		return ws
+	}
+
+	return ws.live
+}
+
+// Reload replaces the settings new connections are accepted with by those of next.
+// Open connections keep the settings they were accepted with.
+func (ws *Ws) Reload(next *Ws) error {
+	if next.Addr != ws.Addr || next.Port != ws.Port || next.UnixSocket != ws.UnixSocket {
+		return errRestart
+	}
+
+	ws.liveMu.Lock()
+	defer ws.liveMu.Unlock()
+
+	next.listener = ws.listener
+	next.rendererChannels = ws.rendererChannels
+	next.rendererQuitChannels = ws.rendererQuitChannels
+	ws.live = next
+
+	return nil
+}
+
+// LogReload logs the result of a reload to the renderers, like Http.LogReload.
+func (ws *Ws) LogReload(source string, err error) {
+	req := reloadPayload(source, err)
+	for _, rendererChannel := range ws.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// reloadPayload returns the event logged for the result of a reload.
+func reloadPayload(source string, err error) RequestPayload {
+	req := RequestPayload{
+		ID:        uuid.New().String(),
+		Fields:    logrequest.RequestFields{Method: ConfigReloaded},
+		CreatedAt: time.Now(),
+		Message:   source,
+	}
+
+	if err != nil {
+		req.Fields.Method = ConfigError
+		req.Message = err.Error()
+	}
+
+	return req
+}
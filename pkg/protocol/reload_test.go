@@ -0,0 +1,243 @@
+package protocol
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHttpReload(t *testing.T) {
+	rpChan := make(chan RequestPayload, 10)
+	httpServer := &Http{
+		ResponseCode:     200,
+		Rules:            []HttpRule{{Path: "/charge", Responses: []HttpResponse{{StatusCode: 402}, {StatusCode: 200}}}},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		<-rpChan
+
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := get("/charge"); code != 402 {
+		t.Errorf("Expected 402, got %d", code)
+	}
+
+	err := httpServer.Reload(&Http{
+		ResponseCode: 201,
+		ResponseBody: "reloaded",
+		Rules:        []HttpRule{{Path: "/refund", Responses: []HttpResponse{{StatusCode: 409}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/charge", 201, "reloaded"},
+		{"/refund", 409, ""},
+	}
+
+	for _, tc := range tests {
+		if code, body := get(tc.path); code != tc.code || body != tc.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tc.path, tc.code, tc.body, code, body)
+		}
+	}
+
+	sequences := httpServer.Sequences()
+	if len(sequences) != 1 || sequences[0].Route != "/refund" || sequences[0].Calls != 1 {
+		t.Errorf("Expected the sequence of /refund, got %+v", sequences)
+	}
+
+	if reset := httpServer.ResetSequence(""); reset != 1 {
+		t.Errorf("Expected 1 sequence to be reset, got %d", reset)
+	}
+}
+
+func TestHttpReloadInFlight(t *testing.T) {
+	delay, err := ParseLatency("200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := &Http{
+		ResponseCode:     200,
+		Timing:           HttpTiming{Delay: delay},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	codes := make(chan int)
+	go func() {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			codes <- 0
+			return
+		}
+		resp.Body.Close()
+		codes <- resp.StatusCode
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := httpServer.Reload(&Http{ResponseCode: 503}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := <-codes; code != 200 {
+		t.Errorf("Expected the request in flight to be answered with 200, got %d", code)
+	}
+	<-rpChan
+}
+
+func TestHttpReloadRateLimit(t *testing.T) {
+	rateLimit, _ := NewRateLimit("1/1m", FixedWindow, "ip")
+	httpServer := &Http{RateLimit: rateLimit}
+
+	same, _ := NewRateLimit("1/1m", FixedWindow, "ip")
+	if err := httpServer.Reload(&Http{RateLimit: same}); err != nil {
+		t.Fatal(err)
+	}
+
+	if httpServer.current().RateLimit != rateLimit {
+		t.Error("Expected the rate limit counters to be kept")
+	}
+
+	other, _ := NewRateLimit("2/1m", FixedWindow, "ip")
+	if err := httpServer.Reload(&Http{RateLimit: other}); err != nil {
+		t.Fatal(err)
+	}
+
+	if httpServer.current().RateLimit != other {
+		t.Error("Expected the rate limit to be replaced")
+	}
+}
+
+func TestHttpReloadSequences(t *testing.T) {
+	charge := HttpRule{Path: "/charge", Responses: []HttpResponse{{StatusCode: 402}, {StatusCode: 200}}}
+	refund := HttpRule{Path: "/refund", Responses: []HttpResponse{{StatusCode: 409}, {StatusCode: 200}}}
+	httpServer := &Http{Rules: []HttpRule{charge, refund}}
+
+	for _, path := range []string{"/charge", "/refund"} {
+		httpServer.nextResponse(httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	// The charge rule moves and is unchanged, the refund rule changes.
+	changed := HttpRule{Path: "/refund", Responses: []HttpResponse{{StatusCode: 500}, {StatusCode: 200}}}
+	if err := httpServer.Reload(&Http{Rules: []HttpRule{changed, charge}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ResponseSequence{
+		{Route: "/refund", Calls: 0, Next: 1, Length: 2},
+		{Route: "/charge", Calls: 1, Next: 2, Length: 2},
+	}
+	if sequences := httpServer.Sequences(); !reflect.DeepEqual(sequences, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sequences)
+	}
+}
+
+func TestHttpReloadRestart(t *testing.T) {
+	httpServer := &Http{Addr: "localhost", Port: 8080, ResponseCode: 200}
+
+	tests := []*Http{
+		{Addr: "localhost", Port: 9000},
+		{Addr: "0.0.0.0", Port: 8080},
+		{Addr: "localhost", Port: 8080, UnixSocket: "/tmp/rh.sock"},
+	}
+
+	for _, next := range tests {
+		if err := httpServer.Reload(next); err == nil {
+			t.Errorf("%s:%d: expected an error", next.Addr, next.Port)
+		}
+	}
+
+	if httpServer.current() != httpServer {
+		t.Error("Expected the settings to be kept")
+	}
+}
+
+func TestLogReload(t *testing.T) {
+	rpChan := make(chan RequestPayload, 2)
+	httpServer := &Http{rendererChannels: []chan RequestPayload{rpChan}}
+
+	httpServer.LogReload(".rh.yaml, rules.json", nil)
+	httpServer.LogReload(".rh.yaml", errors.New("config .rh.yaml: unknown profile"))
+
+	tests := []struct {
+		method  string
+		message string
+	}{
+		{ConfigReloaded, ".rh.yaml, rules.json"},
+		{ConfigError, "config .rh.yaml: unknown profile"},
+	}
+
+	for _, tc := range tests {
+		rp := <-rpChan
+		if rp.Fields.Method != tc.method || rp.Message != tc.message {
+			t.Errorf("Expected %s %s, got %s %s", tc.method, tc.message, rp.Fields.Method, rp.Message)
+		}
+	}
+}
+
+func TestWsReload(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := &Ws{rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wsReq.Close()
+
+	if err := wsServer.Reload(&Ws{RejectStatus: http.StatusServiceUnavailable}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected new connections to be rejected with 503, got %v", err)
+	}
+
+	if err := wsReq.WriteMessage(websocket.TextMessage, []byte("still open")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"GET", "CONNECTED", "GET", "FAULT", "RECEIVE"}
+	for _, method := range expected {
+		rp := <-rpChannel
+		if rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+		}
+	}
+
+	if err := wsServer.Reload(&Ws{Port: 9000}); err == nil {
+		t.Error("Expected an error for a new port")
+	}
+}
@@ -0,0 +1,164 @@
+package protocol
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// SequencePosition is where a request was in the response sequence of its route.
+type SequencePosition struct {
+	// Route is the method and path of the rule, ie: POST /charge.
+	Route string `json:"route"`
+
+	// Call counts the requests the route answered since it was reset, including this
+	// one.
+	Call int `json:"call"`
+
+	// Response is the response of the sequence the request was answered with, starting
+	// at 1.
+	Response int `json:"response"`
+
+	// Length is the number of responses in the sequence.
+	Length int `json:"length"`
+}
+
+// String returns the position, ie: POST /charge response 2 of 3, call 2.
+func (p SequencePosition) String() string {
+	return fmt.Sprintf("%s response %d of %d, call %d", p.Route, p.Response, p.Length, p.Call)
+}
+
+// ResponseSequence is the state of the response sequence of a route.
+type ResponseSequence struct {
+	Route string `json:"route"`
+
+	// Calls counts the requests the route answered since it was reset.
+	Calls int `json:"calls"`
+
+	// Next is the response the next request is answered with, starting at 1.
+	Next int `json:"next"`
+
+	Length int    `json:"length"`
+	Repeat string `json:"repeat"`
+}
+
+// responseKey is the context key for the response a request is answered with.
+type responseKey struct{}
+
+// nextResponse counts the request against the sequence of the rule it matches, and
+// returns the response to answer with and its position. Returns nil if the rule has no
+// responses.
+func (s *Http) nextResponse(r *http.Request) (*HttpResponse, *SequencePosition) {
+	i, rule := s.rule(r)
+	if rule == nil || len(rule.Responses) == 0 {
+		return nil, nil
+	}
+
+	s.sequenceMu.Lock()
+	defer s.sequenceMu.Unlock()
+
+	if s.sequenceCalls == nil {
+		s.sequenceCalls = make(map[int]int)
+	}
+
+	s.sequenceCalls[i]++
+	call := s.sequenceCalls[i]
+	n := sequenceIndex(*rule, call)
+
+	position := &SequencePosition{
+		Route:    rule.Route(),
+		Call:     call,
+		Response: n + 1,
+		Length:   len(rule.Responses),
+	}
+
+	return &rule.Responses[n], position
+}
+
+// sequenceIndex returns the index of the response the call is answered with.
+func sequenceIndex(rule HttpRule, call int) int {
+	if call <= len(rule.Responses) {
+		return call - 1
+	}
+
+	if rule.Repeat == RepeatCycle {
+		return (call - 1) % len(rule.Responses)
+	}
+
+	return len(rule.Responses) - 1
+}
+
+// keptSequences returns the calls counted by the response sequences of the rules which
+// are in rules unchanged, by their index in rules, so a reload does not start them over.
+func (s *Http) keptSequences(rules []HttpRule) map[int]int {
+	s.sequenceMu.Lock()
+	defer s.sequenceMu.Unlock()
+
+	kept := make(map[int]int)
+	taken := make(map[int]bool)
+	for i, rule := range rules {
+		for j, old := range s.Rules {
+			if taken[j] || s.sequenceCalls[j] == 0 || !reflect.DeepEqual(rule, old) {
+				continue
+			}
+
+			kept[i] = s.sequenceCalls[j]
+			taken[j] = true
+			break
+		}
+	}
+
+	return kept
+}
+
+// Sequences returns the state of the response sequence of each rule with responses, in
+// the order of the rules.
+func (s *Http) Sequences() []ResponseSequence {
+	if live := s.current(); live != s {
+		return live.Sequences()
+	}
+
+	s.sequenceMu.Lock()
+	defer s.sequenceMu.Unlock()
+
+	sequences := make([]ResponseSequence, 0)
+	for i, rule := range s.Rules {
+		if len(rule.Responses) == 0 {
+			continue
+		}
+
+		calls := s.sequenceCalls[i]
+		sequences = append(sequences, ResponseSequence{
+			Route:  rule.Route(),
+			Calls:  calls,
+			Next:   sequenceIndex(rule, calls+1) + 1,
+			Length: len(rule.Responses),
+			Repeat: rule.Repeat,
+		})
+	}
+
+	return sequences
+}
+
+// ResetSequence starts the response sequence of the route over, or of every route if
+// route is empty. Returns the number of sequences which were reset.
+func (s *Http) ResetSequence(route string) int {
+	if live := s.current(); live != s {
+		return live.ResetSequence(route)
+	}
+
+	s.sequenceMu.Lock()
+	defer s.sequenceMu.Unlock()
+
+	reset := 0
+	for i, rule := range s.Rules {
+		if len(rule.Responses) == 0 || (route != "" && rule.Route() != route) {
+			continue
+		}
+
+		delete(s.sequenceCalls, i)
+		reset++
+	}
+
+	return reset
+}
@@ -0,0 +1,192 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadHttpRulesResponses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	rules := `[
+		{
+			"method": "POST",
+			"path": "/charge",
+			"responses": [
+				{"status": 500},
+				{"status": 201, "headers": {"Content-Type": "application/json"}, "body": {"id": "ch_1"}},
+				{"body": "done"}
+			],
+			"repeat": "cycle"
+		},
+		{"path": "/refund", "responses": [{"status": 202}]}
+	]`
+	if err := ioutil.WriteFile(file, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadHttpRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []HttpResponse{
+		{StatusCode: 500},
+		{StatusCode: 201, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"id":"ch_1"}`},
+		{Body: "done"},
+	}
+
+	if !reflect.DeepEqual(loaded[0].Responses, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, loaded[0].Responses)
+	}
+
+	if loaded[0].Repeat != RepeatCycle || loaded[1].Repeat != RepeatLast {
+		t.Errorf("Expected cycle and last, got %s and %s", loaded[0].Repeat, loaded[1].Repeat)
+	}
+
+	if loaded[0].Route() != "POST /charge" || loaded[1].Route() != "/refund" {
+		t.Errorf("Expected POST /charge and /refund, got %s and %s", loaded[0].Route(), loaded[1].Route())
+	}
+
+	for _, invalid := range []string{
+		`[{"responses": [{"status": 42}]}]`,
+		`[{"responses": [{"status": 200}], "repeat": "forever"}]`,
+	} {
+		if err := ioutil.WriteFile(file, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadHttpRules(file); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestHttpResponseSequence(t *testing.T) {
+	tests := []struct {
+		repeat   string
+		expected []int
+	}{
+		{RepeatLast, []int{500, 500, 200, 200, 200}},
+		{RepeatCycle, []int{500, 500, 200, 500, 500}},
+	}
+
+	for _, tc := range tests {
+		rpChan := make(chan RequestPayload, 5)
+		httpServer := Http{
+			ResponseCode: 200,
+			Rules: []HttpRule{{
+				Method:    http.MethodPost,
+				Path:      "/charge",
+				Responses: []HttpResponse{{StatusCode: 500}, {StatusCode: 500}, {Body: "ok"}},
+				Repeat:    tc.repeat,
+			}},
+			rendererChannels: []chan RequestPayload{rpChan},
+		}
+
+		srv := httptest.NewServer(httpServer.routes())
+
+		for i, code := range tc.expected {
+			resp, err := http.Post(srv.URL+"/charge", "text/plain", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != code {
+				t.Errorf("%s call %d: expected %d, got %d", tc.repeat, i+1, code, resp.StatusCode)
+			}
+
+			if code == 200 && string(body) != "ok" {
+				t.Errorf("%s call %d: expected ok, got %q", tc.repeat, i+1, body)
+			}
+
+			rp := <-rpChan
+			if rp.Sequence == nil || rp.Sequence.Call != i+1 || rp.Sequence.Length != 3 {
+				t.Errorf("%s call %d: unexpected position %+v", tc.repeat, i+1, rp.Sequence)
+			}
+		}
+
+		srv.Close()
+	}
+}
+
+func TestHttpResponseSequenceOtherRoutes(t *testing.T) {
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{
+		ResponseCode: 204,
+		Rules: []HttpRule{
+			{Path: "/charge", Responses: []HttpResponse{{StatusCode: 500}}},
+			{Path: "/slow"},
+		},
+		rendererChannels: []chan RequestPayload{rpChan},
+	}
+
+	srv := httptest.NewServer(httpServer.routes())
+	defer srv.Close()
+
+	for _, path := range []string{"/slow", "/other"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != 204 {
+			t.Errorf("%s: expected 204, got %d", path, resp.StatusCode)
+		}
+
+		if rp := <-rpChan; rp.Sequence != nil {
+			t.Errorf("%s: expected no sequence, got %+v", path, rp.Sequence)
+		}
+	}
+}
+
+func TestHttpSequencesReset(t *testing.T) {
+	httpServer := Http{
+		Rules: []HttpRule{
+			{Method: "POST", Path: "/charge", Responses: []HttpResponse{{StatusCode: 500}, {StatusCode: 200}}, Repeat: RepeatLast},
+			{Path: "/refund", Responses: []HttpResponse{{StatusCode: 500}, {StatusCode: 200}}, Repeat: RepeatCycle},
+			{Path: "/slow"},
+		},
+	}
+
+	for _, path := range []string{"/charge", "/charge", "/charge", "/refund", "/refund"} {
+		httpServer.nextResponse(httptest.NewRequest("POST", path, strings.NewReader("")))
+	}
+
+	expected := []ResponseSequence{
+		{Route: "POST /charge", Calls: 3, Next: 2, Length: 2, Repeat: RepeatLast},
+		{Route: "/refund", Calls: 2, Next: 1, Length: 2, Repeat: RepeatCycle},
+	}
+
+	if sequences := httpServer.Sequences(); !reflect.DeepEqual(sequences, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sequences)
+	}
+
+	if reset := httpServer.ResetSequence("POST /charge"); reset != 1 {
+		t.Errorf("Expected 1 sequence reset, got %d", reset)
+	}
+
+	if sequences := httpServer.Sequences(); sequences[0].Calls != 0 || sequences[0].Next != 1 || sequences[1].Calls != 2 {
+		t.Errorf("Expected only POST /charge to be reset, got %+v", sequences)
+	}
+
+	if reset := httpServer.ResetSequence(""); reset != 2 {
+		t.Errorf("Expected 2 sequences reset, got %d", reset)
+	}
+
+	if reset := httpServer.ResetSequence("/unknown"); reset != 0 {
+		t.Errorf("Expected no sequences reset, got %d", reset)
+	}
+
+	_, position := httpServer.nextResponse(httptest.NewRequest("GET", "/refund", nil))
+	if position.String() != "/refund response 1 of 2, call 1" {
+		t.Errorf("Expected the sequence to start over, got %s", position)
+	}
+}
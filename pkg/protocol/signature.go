@@ -0,0 +1,352 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature verification results.
+const (
+	SignatureValid   = "valid"
+	SignatureInvalid = "invalid"
+	SignatureMissing = "missing"
+)
+
+// SignatureProfiles are the webhook providers we can verify signatures for. The hmac
+// profile is configured with the header, algorithm and encoding of the signature.
+var SignatureProfiles = []string{"github", "stripe", "slack", "shopify", "twilio", "hmac"}
+
+// SignatureVerification is the result of verifying the signature of a request.
+type SignatureVerification struct {
+	// Profile is the provider the signature was verified for, ie: github.
+	Profile string `json:"profile"`
+
+	// Result is valid, invalid or missing.
+	Result string `json:"result"`
+
+	// Reason explains why the signature is invalid or missing.
+	Reason string `json:"reason,omitempty"`
+}
+
+// String returns the result, ie: github signature invalid: signature does not match.
+func (v SignatureVerification) String() string {
+	str := fmt.Sprintf("%s signature %s", v.Profile, v.Result)
+	if v.Reason != "" {
+		str = fmt.Sprintf("%s: %s", str, v.Reason)
+	}
+
+	return str
+}
+
+// SignatureVerifier verifies the signatures webhook providers send with their requests.
+type SignatureVerifier struct {
+	// Profile is one of the SignatureProfiles.
+	Profile string
+
+	// Secret is the signing secret, or the auth token for Twilio.
+	Secret string
+
+	// Tolerance is how old a signed timestamp can be, for Stripe and Slack. Default is
+	// 0, which does not check the timestamp.
+	Tolerance time.Duration
+
+	// Header, Algorithm(sha1, sha256 or sha512), Encoding(hex or base64) and Prefix
+	// configure the hmac profile. The signature is computed over the body and sent
+	// in the header, after the prefix(ie: sha256=).
+	Header    string
+	Algorithm string
+	Encoding  string
+	Prefix    string
+}
+
+// Validate returns an error if the verifier cannot verify signatures.
+func (v *SignatureVerifier) Validate() error {
+	known := false
+	for _, profile := range SignatureProfiles {
+		if v.Profile == profile {
+			known = true
+		}
+	}
+
+	if !known {
+		return fmt.Errorf("unknown signature profile %q, expected one of %s", v.Profile, strings.Join(SignatureProfiles, ", "))
+	}
+
+	if v.Secret == "" {
+		return errors.New("verifying signatures needs a secret")
+	}
+
+	if v.Profile != "hmac" {
+		return nil
+	}
+
+	if v.Header == "" {
+		return errors.New("the hmac signature profile needs a header")
+	}
+
+	if _, err := hashFunc(v.Algorithm); err != nil {
+		return err
+	}
+
+	if v.Encoding != "hex" && v.Encoding != "base64" {
+		return fmt.Errorf("unknown signature encoding %q, expected hex or base64", v.Encoding)
+	}
+
+	return nil
+}
+
+// Verify verifies the signature of the request, which was received with the body.
+func (v *SignatureVerifier) Verify(r *http.Request, body []byte) SignatureVerification {
+	var result, reason string
+
+	switch v.Profile {
+	case "github":
+		result, reason = v.verifyGithub(r, body)
+	case "stripe":
+		result, reason = v.verifyStripe(r, body)
+	case "slack":
+		result, reason = v.verifySlack(r, body)
+	case "shopify":
+		result, reason = v.verifyHeader(r.Header.Get("X-Shopify-Hmac-Sha256"), "X-Shopify-Hmac-Sha256", sha256.New, "base64", body)
+	case "twilio":
+		result, reason = v.verifyTwilio(r, body)
+	case "hmac":
+		h, _ := hashFunc(v.Algorithm)
+		signature := r.Header.Get(v.Header)
+		if signature != "" && !strings.HasPrefix(signature, v.Prefix) {
+			result, reason = SignatureInvalid, fmt.Sprintf("%s does not start with %s", v.Header, v.Prefix)
+		} else {
+			result, reason = v.verifyHeader(strings.TrimPrefix(signature, v.Prefix), v.Header, h, v.Encoding, body)
+		}
+	}
+
+	return SignatureVerification{Profile: v.Profile, Result: result, Reason: reason}
+}
+
+// verifyGithub verifies X-Hub-Signature-256, or X-Hub-Signature from older webhooks.
+func (v *SignatureVerifier) verifyGithub(r *http.Request, body []byte) (string, string) {
+	if signature := r.Header.Get("X-Hub-Signature-256"); signature != "" {
+		if !strings.HasPrefix(signature, "sha256=") {
+			return SignatureInvalid, "X-Hub-Signature-256 does not start with sha256="
+		}
+
+		return v.verifyHeader(strings.TrimPrefix(signature, "sha256="), "X-Hub-Signature-256", sha256.New, "hex", body)
+	}
+
+	signature := r.Header.Get("X-Hub-Signature")
+	if signature == "" {
+		return SignatureMissing, "no X-Hub-Signature-256 header"
+	}
+
+	if !strings.HasPrefix(signature, "sha1=") {
+		return SignatureInvalid, "X-Hub-Signature does not start with sha1="
+	}
+
+	return v.verifyHeader(strings.TrimPrefix(signature, "sha1="), "X-Hub-Signature", sha1.New, "hex", body)
+}
+
+// verifyStripe verifies Stripe-Signature, which signs the timestamp and the body. Any of
+// the v1 signatures can match, as Stripe sends one for each active secret.
+func (v *SignatureVerifier) verifyStripe(r *http.Request, body []byte) (string, string) {
+	header := r.Header.Get("Stripe-Signature")
+	if header == "" {
+		return SignatureMissing, "no Stripe-Signature header"
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	if timestamp == "" {
+		return SignatureInvalid, "Stripe-Signature has no timestamp"
+	}
+
+	if len(signatures) == 0 {
+		return SignatureInvalid, "Stripe-Signature has no v1 signature"
+	}
+
+	if reason := v.checkTimestamp(timestamp); reason != "" {
+		return SignatureInvalid, reason
+	}
+
+	expected := hex.EncodeToString(v.sign(sha256.New, []byte(timestamp+"."), body))
+	for _, signature := range signatures {
+		if equalSignatures(strings.ToLower(signature), expected) {
+			return SignatureValid, ""
+		}
+	}
+
+	return SignatureInvalid, "no v1 signature matches"
+}
+
+// verifySlack verifies X-Slack-Signature, which signs the version, timestamp and body.
+func (v *SignatureVerifier) verifySlack(r *http.Request, body []byte) (string, string) {
+	signature := r.Header.Get("X-Slack-Signature")
+	if signature == "" {
+		return SignatureMissing, "no X-Slack-Signature header"
+	}
+
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	if timestamp == "" {
+		return SignatureInvalid, "no X-Slack-Request-Timestamp header"
+	}
+
+	if reason := v.checkTimestamp(timestamp); reason != "" {
+		return SignatureInvalid, reason
+	}
+
+	expected := "v0=" + hex.EncodeToString(v.sign(sha256.New, []byte("v0:"+timestamp+":"), body))
+	if !equalSignatures(strings.ToLower(signature), expected) {
+		return SignatureInvalid, "signature does not match"
+	}
+
+	return SignatureValid, ""
+}
+
+// verifyTwilio verifies X-Twilio-Signature, which signs the full URL followed by the
+// sorted params of form bodies. JSON bodies are signed with their hash in the bodySHA256
+// query param instead.
+func (v *SignatureVerifier) verifyTwilio(r *http.Request, body []byte) (string, string) {
+	signature := r.Header.Get("X-Twilio-Signature")
+	if signature == "" {
+		return SignatureMissing, "no X-Twilio-Signature header"
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	data := scheme + "://" + r.Host + r.URL.RequestURI()
+
+	bodyHash := r.URL.Query().Get("bodySHA256")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if bodyHash == "" && mediaType == "application/x-www-form-urlencoded" {
+		form, _ := url.ParseQuery(string(body))
+		keys := make([]string, 0, len(form))
+		for key := range form {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, value := range form[key] {
+				data += key + value
+			}
+		}
+	}
+
+	expected := base64.StdEncoding.EncodeToString(v.sign(sha1.New, []byte(data)))
+	if !equalSignatures(signature, expected) {
+		return SignatureInvalid, fmt.Sprintf("signature does not match for %s://%s%s", scheme, r.Host, r.URL.RequestURI())
+	}
+
+	if bodyHash != "" {
+		sum := sha256.Sum256(body)
+		if !equalSignatures(strings.ToLower(bodyHash), hex.EncodeToString(sum[:])) {
+			return SignatureInvalid, "bodySHA256 does not match the body"
+		}
+	}
+
+	return SignatureValid, ""
+}
+
+// verifyHeader verifies a signature computed over the body alone.
+func (v *SignatureVerifier) verifyHeader(signature string, header string, h func() hash.Hash, encoding string, body []byte) (string, string) {
+	if signature == "" {
+		return SignatureMissing, fmt.Sprintf("no %s header", header)
+	}
+
+	mac := v.sign(h, body)
+	expected := base64.StdEncoding.EncodeToString(mac)
+	if encoding == "hex" {
+		expected = hex.EncodeToString(mac)
+		signature = strings.ToLower(signature)
+	}
+
+	if !equalSignatures(signature, expected) {
+		return SignatureInvalid, "signature does not match"
+	}
+
+	return SignatureValid, ""
+}
+
+// sign returns the HMAC of the parts with the secret.
+func (v *SignatureVerifier) sign(h func() hash.Hash, parts ...[]byte) []byte {
+	mac := hmac.New(h, []byte(v.Secret))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+
+	return mac.Sum(nil)
+}
+
+// checkTimestamp returns why a signed unix timestamp is not accepted, or an empty string.
+func (v *SignatureVerifier) checkTimestamp(timestamp string) string {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Sprintf("invalid timestamp %q", timestamp)
+	}
+
+	if v.Tolerance <= 0 {
+		return ""
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > v.Tolerance || age < -v.Tolerance {
+		return fmt.Sprintf("timestamp is outside the %s tolerance", v.Tolerance)
+	}
+
+	return ""
+}
+
+// equalSignatures compares signatures in constant time.
+func equalSignatures(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// hashFunc returns the hash for the algorithm of the hmac profile.
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+
+	return nil, fmt.Errorf("unknown signature algorithm %q, expected sha1, sha256 or sha512", algorithm)
+}
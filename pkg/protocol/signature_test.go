@@ -0,0 +1,292 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hmacOf(h func() hash.Hash, secret string, data string) []byte {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	secret := "shhh"
+	body := `{"foo":"bar"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	stripeSignature := func(timestamp string) string {
+		return fmt.Sprintf("t=%s,v1=%x,v0=foo", timestamp, hmacOf(sha256.New, secret, timestamp+"."+body))
+	}
+
+	tests := []struct {
+		name     string
+		verifier SignatureVerifier
+		headers  map[string]string
+		result   string
+		reason   string
+	}{
+		{
+			"github valid",
+			SignatureVerifier{Profile: "github", Secret: secret},
+			map[string]string{"X-Hub-Signature-256": fmt.Sprintf("sha256=%x", hmacOf(sha256.New, secret, body))},
+			SignatureValid, "",
+		},
+		{
+			"github sha1",
+			SignatureVerifier{Profile: "github", Secret: secret},
+			map[string]string{"X-Hub-Signature": fmt.Sprintf("sha1=%x", hmacOf(sha1.New, secret, body))},
+			SignatureValid, "",
+		},
+		{
+			"github sha1 empty",
+			SignatureVerifier{Profile: "github", Secret: secret},
+			map[string]string{"X-Hub-Signature": "sha1="},
+			SignatureMissing, "no X-Hub-Signature header",
+		},
+		{
+			"github invalid",
+			SignatureVerifier{Profile: "github", Secret: "wrong"},
+			map[string]string{"X-Hub-Signature-256": fmt.Sprintf("sha256=%x", hmacOf(sha256.New, secret, body))},
+			SignatureInvalid, "signature does not match",
+		},
+		{
+			"github missing",
+			SignatureVerifier{Profile: "github", Secret: secret},
+			nil,
+			SignatureMissing, "no X-Hub-Signature-256 header",
+		},
+		{
+			"stripe valid",
+			SignatureVerifier{Profile: "stripe", Secret: secret, Tolerance: 5 * time.Minute},
+			map[string]string{"Stripe-Signature": stripeSignature(now)},
+			SignatureValid, "",
+		},
+		{
+			"stripe outside tolerance",
+			SignatureVerifier{Profile: "stripe", Secret: secret, Tolerance: 5 * time.Minute},
+			map[string]string{"Stripe-Signature": stripeSignature(old)},
+			SignatureInvalid, "timestamp is outside the 5m0s tolerance",
+		},
+		{
+			"stripe no tolerance",
+			SignatureVerifier{Profile: "stripe", Secret: secret},
+			map[string]string{"Stripe-Signature": stripeSignature(old)},
+			SignatureValid, "",
+		},
+		{
+			"stripe invalid",
+			SignatureVerifier{Profile: "stripe", Secret: "wrong"},
+			map[string]string{"Stripe-Signature": stripeSignature(now)},
+			SignatureInvalid, "no v1 signature matches",
+		},
+		{
+			"slack valid",
+			SignatureVerifier{Profile: "slack", Secret: secret, Tolerance: 5 * time.Minute},
+			map[string]string{
+				"X-Slack-Signature":         fmt.Sprintf("v0=%x", hmacOf(sha256.New, secret, "v0:"+now+":"+body)),
+				"X-Slack-Request-Timestamp": now,
+			},
+			SignatureValid, "",
+		},
+		{
+			"slack missing timestamp",
+			SignatureVerifier{Profile: "slack", Secret: secret},
+			map[string]string{"X-Slack-Signature": "v0=foo"},
+			SignatureInvalid, "no X-Slack-Request-Timestamp header",
+		},
+		{
+			"shopify valid",
+			SignatureVerifier{Profile: "shopify", Secret: secret},
+			map[string]string{"X-Shopify-Hmac-Sha256": base64.StdEncoding.EncodeToString(hmacOf(sha256.New, secret, body))},
+			SignatureValid, "",
+		},
+		{
+			"shopify missing",
+			SignatureVerifier{Profile: "shopify", Secret: secret},
+			nil,
+			SignatureMissing, "no X-Shopify-Hmac-Sha256 header",
+		},
+		{
+			"hmac valid",
+			SignatureVerifier{Profile: "hmac", Secret: secret, Header: "X-Signature", Algorithm: "sha512", Encoding: "base64"},
+			map[string]string{"X-Signature": base64.StdEncoding.EncodeToString(hmacOf(sha512.New, secret, body))},
+			SignatureValid, "",
+		},
+		{
+			"hmac prefix",
+			SignatureVerifier{Profile: "hmac", Secret: secret, Header: "X-Signature", Algorithm: "sha256", Encoding: "hex", Prefix: "sha256="},
+			map[string]string{"X-Signature": "sha256=" + strings.ToUpper(hex.EncodeToString(hmacOf(sha256.New, secret, body)))},
+			SignatureValid, "",
+		},
+		{
+			"hmac wrong prefix",
+			SignatureVerifier{Profile: "hmac", Secret: secret, Header: "X-Signature", Algorithm: "sha256", Encoding: "hex", Prefix: "sha256="},
+			map[string]string{"X-Signature": "sha1=foo"},
+			SignatureInvalid, "X-Signature does not start with sha256=",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			result := test.verifier.Verify(req, []byte(body))
+			if result.Result != test.result || result.Reason != test.reason {
+				t.Errorf("Expected %s %q, got %s %q", test.result, test.reason, result.Result, result.Reason)
+			}
+
+			if result.Profile != test.verifier.Profile {
+				t.Errorf("Expected profile %s, got %s", test.verifier.Profile, result.Profile)
+			}
+		})
+	}
+}
+
+func TestSignatureVerifierTwilio(t *testing.T) {
+	token := "token"
+	verifier := SignatureVerifier{Profile: "twilio", Secret: token}
+
+	body := "To=%2B15551234567&Body=hello&From=%2B15557654321"
+	data := "https://example.com/sms?foo=bar" + "Bodyhello" + "From+15557654321" + "To+15551234567"
+	signature := base64.StdEncoding.EncodeToString(hmacOf(sha1.New, token, data))
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/sms?foo=bar", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("X-Twilio-Signature", signature)
+
+	if result := verifier.Verify(req, []byte(body)); result.Result != SignatureValid {
+		t.Errorf("Expected valid, got %s", result)
+	}
+
+	// Behind a tunnel the request is received over http, so the scheme is forwarded.
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/sms?foo=bar", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", signature)
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	if result := verifier.Verify(req, []byte(body)); result.Result != SignatureValid {
+		t.Errorf("Expected valid, got %s", result)
+	}
+
+	// JSON bodies are signed with their hash in the URL.
+	jsonBody := `{"foo":"bar"}`
+	sum := sha256.Sum256([]byte(jsonBody))
+	url := fmt.Sprintf("https://example.com/hook?bodySHA256=%x", sum)
+	req = httptest.NewRequest(http.MethodPost, url, strings.NewReader(jsonBody))
+	req.Header.Set("X-Twilio-Signature", base64.StdEncoding.EncodeToString(hmacOf(sha1.New, token, url)))
+
+	if result := verifier.Verify(req, []byte(jsonBody)); result.Result != SignatureValid {
+		t.Errorf("Expected valid, got %s", result)
+	}
+
+	if result := verifier.Verify(req, []byte(`{"foo":"baz"}`)); result.Reason != "bodySHA256 does not match the body" {
+		t.Errorf("Expected body hash mismatch, got %s", result)
+	}
+
+	// Other bodies are not parsed as params, even when they look like a form.
+	req = httptest.NewRequest(http.MethodPost, "https://example.com/sms", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Twilio-Signature", base64.StdEncoding.EncodeToString(hmacOf(sha1.New, token, "https://example.com/sms")))
+
+	if result := verifier.Verify(req, []byte(body)); result.Result != SignatureValid {
+		t.Errorf("Expected valid, got %s", result)
+	}
+}
+
+func TestSignatureVerifierValidate(t *testing.T) {
+	tests := []struct {
+		verifier SignatureVerifier
+		err      string
+	}{
+		{SignatureVerifier{Profile: "github", Secret: "foo"}, ""},
+		{SignatureVerifier{Profile: "foo", Secret: "foo"}, "unknown signature profile"},
+		{SignatureVerifier{Profile: "github"}, "needs a secret"},
+		{SignatureVerifier{Profile: "hmac", Secret: "foo", Algorithm: "sha256", Encoding: "hex"}, "needs a header"},
+		{SignatureVerifier{Profile: "hmac", Secret: "foo", Header: "X-Sig", Algorithm: "md5", Encoding: "hex"}, "unknown signature algorithm"},
+		{SignatureVerifier{Profile: "hmac", Secret: "foo", Header: "X-Sig", Algorithm: "sha1", Encoding: "base32"}, "unknown signature encoding"},
+	}
+
+	for _, test := range tests {
+		err := test.verifier.Validate()
+		if test.err == "" && err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("Expected error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestHttpRejectInvalidSignature(t *testing.T) {
+	body := `{"foo":"bar"}`
+	signature := fmt.Sprintf("sha256=%x", hmacOf(sha256.New, "shhh", body))
+
+	tests := []struct {
+		reject    bool
+		signature string
+		status    int
+		result    string
+	}{
+		{true, signature, http.StatusOK, SignatureValid},
+		{true, "sha256=foo", http.StatusUnauthorized, SignatureInvalid},
+		{true, "", http.StatusUnauthorized, SignatureMissing},
+		{false, "sha256=foo", http.StatusOK, SignatureInvalid},
+	}
+
+	for _, test := range tests {
+		rpChan := make(chan RequestPayload, 1)
+		httpServer := Http{
+			ResponseCode:           200,
+			Signature:              &SignatureVerifier{Profile: "github", Secret: "shhh"},
+			RejectInvalidSignature: test.reject,
+			rendererChannels:       []chan RequestPayload{rpChan},
+		}
+		srv := httptest.NewServer(httpServer.routes())
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if test.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", test.signature)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("Expected %d, got %d", test.status, resp.StatusCode)
+		}
+
+		rp := <-rpChan
+		if rp.Signature == nil || rp.Signature.Result != test.result {
+			t.Errorf("Expected signature %s, got %v", test.result, rp.Signature)
+		}
+
+		if rp.ParamFields.Json["foo"] != "bar" {
+			t.Errorf("Expected the body to still be parsed, got %v", rp.ParamFields.Json)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+)
+
+// Smtp is the protocol for receiving email. It accepts any sender, recipient and
+// credentials, and records each message instead of delivering it.
+type Smtp struct {
+	// Addr is the address the SMTP server will bind to.
+	Addr string
+
+	// Port is the port the SMTP server will run on.
+	Port int
+
+	// Hostname is the name the server greets clients with. Default is "localhost".
+	Hostname string
+
+	// TLSConfig enables the STARTTLS extension when set.
+	TLSConfig *tls.Config
+
+	// MaxSize is the maximum size in bytes of a message, which is advertised with the
+	// SIZE extension. Default is 0, which means no limit.
+	MaxSize int
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming message to the Smtp protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+}
+
+// Start will start the SMTP server.
+//
+// Sets the channel on our struct so that incoming messages can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Smtp) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			err = s.serve(s.listener)
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("SMTP Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Smtp) Bind() (net.Addr, error) {
+	if s.listener == nil {
+		ln, err := listen(s.Addr, s.Port, "", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		s.listener = ln
+	}
+
+	return s.listener.Addr(), nil
+}
+
+func (s *Smtp) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// serve accepts connections on the listener until it is closed.
+func (s *Smtp) serve(ln net.Listener) error {
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		session := &smtpSession{smtp: s, conn: conn, text: textproto.NewConn(conn)}
+		go session.serve()
+	}
+}
+
+func (s *Smtp) hostname() string {
+	if s.Hostname == "" {
+		return "localhost"
+	}
+
+	return s.Hostname
+}
+
+// smtpSession holds the state of a single SMTP connection.
+type smtpSession struct {
+	smtp *Smtp
+	conn net.Conn
+	text *textproto.Conn
+
+	helo     string
+	authUser string
+	tls      bool
+
+	// mailing is true after MAIL, from can be blank for bounces.
+	mailing bool
+	from    string
+	to      []string
+}
+
+// serve reads commands from the client until it quits or disconnects.
+func (s *smtpSession) serve() {
+	defer s.text.Close()
+
+	s.reply(220, "%s ESMTP Request Hole", s.smtp.hostname())
+
+	for {
+		line, err := s.text.ReadLine()
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				s.smtp.logError(s.conn.RemoteAddr().String(), err.Error())
+			}
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			s.helo = arg
+			s.reset()
+			s.reply(250, "%s", s.smtp.hostname())
+		case "EHLO":
+			s.helo = arg
+			s.reset()
+			s.ehlo()
+		case "STARTTLS":
+			if !s.startTLS() {
+				return
+			}
+		case "AUTH":
+			s.auth(arg)
+		case "MAIL":
+			s.mail(arg)
+		case "RCPT":
+			s.rcpt(arg)
+		case "DATA":
+			s.data()
+		case "RSET":
+			s.reset()
+			s.reply(250, "2.0.0 OK")
+		case "NOOP":
+			s.reply(250, "2.0.0 OK")
+		case "VRFY":
+			s.reply(252, "2.5.0 Cannot VRFY user, but will accept message")
+		case "QUIT":
+			s.reply(221, "2.0.0 Bye")
+			return
+		default:
+			s.reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+// ehlo replies with the extensions we support.
+func (s *smtpSession) ehlo() {
+	lines := []string{s.smtp.hostname(), "PIPELINING", "8BITMIME", "AUTH PLAIN LOGIN"}
+
+	if s.smtp.MaxSize > 0 {
+		lines = append(lines, fmt.Sprintf("SIZE %d", s.smtp.MaxSize))
+	}
+
+	if s.smtp.TLSConfig != nil && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		s.text.PrintfLine("250%s%s", separator, line)
+	}
+}
+
+// startTLS upgrades the connection. The client has to introduce itself again after the
+// handshake. Returns false if the connection can no longer be used.
+func (s *smtpSession) startTLS() bool {
+	if s.smtp.TLSConfig == nil || s.tls {
+		s.reply(502, "5.5.1 STARTTLS not available")
+		return true
+	}
+
+	s.reply(220, "2.0.0 Ready to start TLS")
+
+	tlsConn := tls.Server(s.conn, s.smtp.TLSConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		s.smtp.logError(s.conn.RemoteAddr().String(), err.Error())
+		return false
+	}
+
+	s.conn = tlsConn
+	s.text = textproto.NewConn(tlsConn)
+	s.tls = true
+	s.helo = ""
+	s.authUser = ""
+	s.reset()
+
+	return true
+}
+
+// auth accepts any credentials with the PLAIN and LOGIN mechanisms.
+func (s *smtpSession) auth(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		s.reply(501, "5.5.4 Syntax error")
+		return
+	}
+
+	var user string
+	var err error
+
+	switch strings.ToUpper(fields[0]) {
+	case "PLAIN":
+		response := ""
+		if len(fields) > 1 {
+			response = fields[1]
+		} else if response, err = s.challenge(""); err != nil {
+			return
+		}
+
+		// The response is authzid\x00user\x00password.
+		parts := strings.Split(decodeBase64(response), "\x00")
+		if len(parts) != 3 {
+			s.reply(501, "5.5.2 Invalid PLAIN response")
+			return
+		}
+		user = parts[1]
+	case "LOGIN":
+		response := ""
+		if len(fields) > 1 {
+			response = fields[1]
+		} else if response, err = s.challenge("Username:"); err != nil {
+			return
+		}
+		user = decodeBase64(response)
+
+		if _, err = s.challenge("Password:"); err != nil {
+			return
+		}
+	default:
+		s.reply(504, "5.5.4 Unrecognized authentication type")
+		return
+	}
+
+	s.authUser = user
+	s.reply(235, "2.7.0 Authentication successful")
+}
+
+// challenge sends a base64 encoded challenge and returns the client response.
+func (s *smtpSession) challenge(prompt string) (string, error) {
+	s.reply(334, "%s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+
+	response, err := s.text.ReadLine()
+	if err == nil && response == "*" {
+		s.reply(501, "5.0.0 Authentication cancelled")
+		err = errors.New("authentication cancelled")
+	}
+
+	return response, err
+}
+
+func (s *smtpSession) mail(arg string) {
+	if s.helo == "" {
+		s.reply(503, "5.5.1 Send EHLO first")
+		return
+	}
+
+	from, ok := parsePath(arg, "FROM:")
+	if !ok {
+		s.reply(501, "5.5.4 Syntax error in MAIL command")
+		return
+	}
+
+	s.reset()
+	s.mailing = true
+	s.from = from
+	s.reply(250, "2.1.0 OK")
+}
+
+func (s *smtpSession) rcpt(arg string) {
+	if !s.mailing {
+		s.reply(503, "5.5.1 Send MAIL first")
+		return
+	}
+
+	to, ok := parsePath(arg, "TO:")
+	if !ok || to == "" {
+		s.reply(501, "5.5.4 Syntax error in RCPT command")
+		return
+	}
+
+	s.to = append(s.to, to)
+	s.reply(250, "2.1.5 OK")
+}
+
+// data reads the message, records it and resets the envelope.
+func (s *smtpSession) data() {
+	if len(s.to) == 0 {
+		s.reply(503, "5.5.1 Send RCPT first")
+		return
+	}
+
+	s.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+
+	dotReader := s.text.DotReader()
+	reader := dotReader
+	if s.smtp.MaxSize > 0 {
+		reader = io.LimitReader(dotReader, int64(s.smtp.MaxSize)+1)
+	}
+
+	var data bytes.Buffer
+	if _, err := data.ReadFrom(reader); err != nil {
+		s.smtp.logError(s.conn.RemoteAddr().String(), err.Error())
+		return
+	}
+
+	if s.smtp.MaxSize > 0 && data.Len() > s.smtp.MaxSize {
+		// Discard the rest of the message before replying.
+		io.Copy(io.Discard, dotReader)
+		s.reply(552, "5.3.4 Message size exceeds fixed limit")
+		s.reset()
+		return
+	}
+
+	s.smtp.logMessage(s, data.Bytes())
+	s.reply(250, "2.0.0 OK: queued as %s", uuid.New().String())
+	s.reset()
+}
+
+// reset clears the envelope.
+func (s *smtpSession) reset() {
+	s.mailing = false
+	s.from = ""
+	s.to = nil
+}
+
+func (s *smtpSession) reply(code int, format string, args ...interface{}) {
+	s.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+// logMessage parses the message and sends it to the render channel. The sender is used
+// as the url so the printer shows who the message is from.
+func (s *Smtp) logMessage(session *smtpSession, data []byte) {
+	email, headers, err := ParseSmtpMessage(data)
+	email.Helo = session.helo
+	email.AuthUser = session.authUser
+	email.TLS = session.tls
+	email.From = session.from
+	email.To = session.to
+
+	msg := fmt.Sprintf("to: %s subject: %s", strings.Join(email.To, ", "), email.Subject)
+	if n := len(email.Attachments); n == 1 {
+		msg = fmt.Sprintf("%s (1 attachment)", msg)
+	} else if n > 1 {
+		msg = fmt.Sprintf("%s (%d attachments)", msg, n)
+	}
+	if err != nil {
+		msg = fmt.Sprintf("%s (invalid message: %s)", msg, err)
+	}
+
+	req := RequestPayload{
+		ID: uuid.New().String(),
+		Fields: logrequest.RequestFields{
+			Method:        "MAIL",
+			Url:           email.From,
+			RemoteAddress: session.conn.RemoteAddr().String(),
+			Protocol:      "smtp",
+			Time:          time.Now(),
+		},
+		Headers:   headers,
+		CreatedAt: time.Now(),
+		Message:   msg,
+		Size:      len(data),
+		Email:     email,
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// logError sends connection errors to the render channel.
+func (s *Smtp) logError(remoteAddr string, msg string) {
+	req := RequestPayload{
+		ID: uuid.New().String(),
+		Fields: logrequest.RequestFields{
+			Method:        "ERROR",
+			Url:           remoteAddr,
+			RemoteAddress: remoteAddr,
+			Protocol:      "smtp",
+			Time:          time.Now(),
+		},
+		CreatedAt: time.Now(),
+		Message:   msg,
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// parsePath returns the address of a MAIL FROM:<address> or RCPT TO:<address>
+// argument, ignoring parameters such as SIZE=1024.
+func parsePath(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	path := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.IndexByte(path, '>'); strings.HasPrefix(path, "<") && i > 0 {
+		return path[1:i], true
+	}
+
+	if fields := strings.Fields(path); len(fields) > 0 {
+		return fields[0], true
+	}
+
+	return "", false
+}
+
+// decodeBase64 decodes an authentication response, returning it as is if it is not
+// valid base64.
+func decodeBase64(s string) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return s
+	}
+
+	return string(b)
+}
@@ -0,0 +1,167 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// SmtpMessage is an email received by the Smtp protocol, with its envelope and the
+// parsed MIME message.
+type SmtpMessage struct {
+	// Helo is the name the client introduced itself with.
+	Helo string `json:"helo"`
+
+	// AuthUser is the username the client authenticated with, blank if it did not.
+	AuthUser string `json:"auth_user"`
+
+	// TLS is true when the message was sent after STARTTLS.
+	TLS bool `json:"tls"`
+
+	// From and To are the envelope sender and recipients.
+	From string   `json:"from"`
+	To   []string `json:"to"`
+
+	Subject string `json:"subject"`
+
+	// Text and Html are the first text/plain and text/html bodies of the message.
+	Text string `json:"text"`
+	Html string `json:"html"`
+
+	// Parts are the bodies of the message, in the order they appear.
+	Parts []SmtpPart `json:"parts"`
+
+	Attachments []SmtpAttachment `json:"attachments"`
+
+	// Size is the size in bytes of the raw message.
+	Size int `json:"size"`
+}
+
+// SmtpPart is a body of a MIME message, decoded from its transfer encoding.
+type SmtpPart struct {
+	ContentType string `json:"content_type"`
+	Charset     string `json:"charset"`
+	Body        string `json:"body"`
+	Size        int    `json:"size"`
+}
+
+// SmtpAttachment is a file attached to a MIME message. Inline attachments, such as
+// images referenced by the html body, have a content id.
+type SmtpAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id"`
+	Disposition string `json:"disposition"`
+	Size        int    `json:"size"`
+}
+
+// ParseSmtpMessage parses the headers and MIME parts of a raw message. The headers are
+// returned separately so they can be shown like the headers of other protocols.
+func ParseSmtpMessage(data []byte) (*SmtpMessage, map[string][]string, error) {
+	msg := &SmtpMessage{Size: len(data)}
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return msg, nil, err
+	}
+
+	msg.Subject = decodeHeader(m.Header.Get("Subject"))
+
+	err = msg.walk(textproto.MIMEHeader(m.Header), m.Body)
+
+	return msg, m.Header, err
+}
+
+// walk adds the part to the message, recursing into multipart bodies.
+func (msg *SmtpMessage) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := msg.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := ioutil.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	if disposition == "attachment" || filename != "" {
+		msg.Attachments = append(msg.Attachments, SmtpAttachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   strings.Trim(header.Get("Content-ID"), "<>"),
+			Disposition: disposition,
+			Size:        len(content),
+		})
+
+		return nil
+	}
+
+	msg.Parts = append(msg.Parts, SmtpPart{
+		ContentType: mediaType,
+		Charset:     params["charset"],
+		Body:        string(content),
+		Size:        len(content),
+	})
+
+	if mediaType == "text/plain" && msg.Text == "" {
+		msg.Text = string(content)
+	} else if mediaType == "text/html" && msg.Html == "" {
+		msg.Html = string(content)
+	}
+
+	return nil
+}
+
+// decodeTransferEncoding decodes base64 and quoted-printable bodies. Multipart parts
+// with quoted-printable bodies are already decoded by the multipart reader.
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// decodeHeader decodes RFC 2047 encoded words, ie: =?UTF-8?q?caf=C3=A9?=. The header
+// is returned as is if it cannot be decoded.
+func decodeHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
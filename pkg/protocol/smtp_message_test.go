@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+const multipartEmail = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: =?UTF-8?q?Caf=C3=A9_order?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=C3=A9 is ready\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+Q2Fmw6kgaXMg\r\n" +
+	"cmVhZHk8L3A+\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=receipt.pdf\r\n" +
+	"Content-Disposition: attachment; filename=receipt.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQ=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Disposition: inline; filename=logo.png\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"\r\n" +
+	"png\r\n" +
+	"--outer--\r\n"
+
+func TestParseSmtpMessage(t *testing.T) {
+	msg, headers, err := ParseSmtpMessage([]byte(multipartEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "Café order" {
+		t.Errorf("Expected %s, got %s", "Café order", msg.Subject)
+	}
+
+	if headers["From"][0] != "Alice <alice@example.com>" {
+		t.Errorf("Expected From header, got %v", headers["From"])
+	}
+
+	if msg.Text != "Café is ready" {
+		t.Errorf("Expected %q, got %q", "Café is ready", msg.Text)
+	}
+
+	if msg.Html != "<p>Café is ready</p>" {
+		t.Errorf("Expected %q, got %q", "<p>Café is ready</p>", msg.Html)
+	}
+
+	if len(msg.Parts) != 2 || msg.Parts[0].Charset != "utf-8" {
+		t.Errorf("Expected 2 utf-8 parts, got %+v", msg.Parts)
+	}
+
+	expected := []SmtpAttachment{
+		{Filename: "receipt.pdf", ContentType: "application/pdf", Disposition: "attachment", Size: 8},
+		{Filename: "logo.png", ContentType: "image/png", ContentID: "logo@example.com", Disposition: "inline", Size: 3},
+	}
+
+	if len(msg.Attachments) != len(expected) {
+		t.Fatalf("Expected %d attachments, got %+v", len(expected), msg.Attachments)
+	}
+
+	for i, attachment := range expected {
+		if msg.Attachments[i] != attachment {
+			t.Errorf("Expected %+v, got %+v", attachment, msg.Attachments[i])
+		}
+	}
+
+	if msg.Size != len(multipartEmail) {
+		t.Errorf("Expected %d, got %d", len(multipartEmail), msg.Size)
+	}
+}
+
+func TestParseSmtpMessagePlain(t *testing.T) {
+	data := "Subject: Hello\r\n\r\nJust text\r\n"
+
+	msg, _, err := ParseSmtpMessage([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(msg.Text) != "Just text" || msg.Html != "" {
+		t.Errorf("Expected text body, got %+v", msg)
+	}
+}
+
+func TestParseSmtpMessageInvalid(t *testing.T) {
+	msg, _, err := ParseSmtpMessage([]byte("not a message"))
+	if err == nil {
+		t.Error("Expected error for invalid message")
+	}
+
+	if msg == nil || msg.Size != len("not a message") {
+		t.Errorf("Expected message with size, got %+v", msg)
+	}
+}
@@ -0,0 +1,211 @@
+package protocol
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// startSmtp serves the protocol on a random port and returns the address.
+func startSmtp(t *testing.T, s *Smtp) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go s.serve(ln)
+
+	return ln.Addr().String()
+}
+
+func TestSmtpSendMail(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 1)
+	smtpServer := &Smtp{rendererChannels: []chan RequestPayload{rpChannel}}
+	addr := startSmtp(t, smtpServer)
+
+	auth := smtp.PlainAuth("", "app", "secret", "127.0.0.1")
+	msg := "Subject: Welcome\r\n\r\nHello Bob\r\n"
+	err := smtp.SendMail(addr, auth, "app@example.com", []string{"bob@example.com", "carol@example.com"}, []byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rp := <-rpChannel
+
+	if rp.Fields.Method != "MAIL" {
+		t.Errorf("Expected %s, got %s", "MAIL", rp.Fields.Method)
+	}
+
+	if rp.Fields.Url != "app@example.com" {
+		t.Errorf("Expected %s, got %s", "app@example.com", rp.Fields.Url)
+	}
+
+	expectedMsg := "to: bob@example.com, carol@example.com subject: Welcome"
+	if rp.Message != expectedMsg {
+		t.Errorf("Expected %s, got %s", expectedMsg, rp.Message)
+	}
+
+	if rp.Email.AuthUser != "app" {
+		t.Errorf("Expected %s, got %s", "app", rp.Email.AuthUser)
+	}
+
+	if rp.Email.TLS {
+		t.Error("Expected message without TLS")
+	}
+
+	if strings.TrimSpace(rp.Email.Text) != "Hello Bob" {
+		t.Errorf("Expected %s, got %s", "Hello Bob", rp.Email.Text)
+	}
+
+	if rp.Headers["Subject"][0] != "Welcome" {
+		t.Errorf("Expected Subject header, got %v", rp.Headers)
+	}
+}
+
+func TestSmtpStartTLS(t *testing.T) {
+	tlsConfig, err := LoadTLSConfig("", "", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpChannel := make(chan RequestPayload, 1)
+	smtpServer := &Smtp{TLSConfig: tlsConfig, rendererChannels: []chan RequestPayload{rpChannel}}
+	addr := startSmtp(t, smtpServer)
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		t.Fatal("Expected STARTTLS extension")
+	}
+
+	if err := c.StartTLS(&tls.Config{InsecureSkipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Mail("app@example.com")
+	c.Rcpt("bob@example.com")
+	w, err := c.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("Subject: Secure\r\n\r\nHi\r\n"))
+	w.Close()
+	c.Quit()
+
+	rp := <-rpChannel
+	if !rp.Email.TLS {
+		t.Error("Expected message sent over TLS")
+	}
+}
+
+func TestSmtpAuthLogin(t *testing.T) {
+	smtpServer := &Smtp{}
+	addr := startSmtp(t, smtpServer)
+
+	conn, err := textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expect := func(code int) {
+		if _, _, err := conn.ReadResponse(code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect(220)
+	conn.PrintfLine("EHLO client")
+	expect(250)
+	conn.PrintfLine("AUTH LOGIN")
+	expect(334)
+	conn.PrintfLine(base64.StdEncoding.EncodeToString([]byte("app")))
+	expect(334)
+	conn.PrintfLine(base64.StdEncoding.EncodeToString([]byte("secret")))
+	expect(235)
+}
+
+func TestSmtpCommandOrder(t *testing.T) {
+	smtpServer := &Smtp{MaxSize: 10}
+	addr := startSmtp(t, smtpServer)
+
+	conn, err := textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	testTable := []struct {
+		command string
+		code    int
+	}{
+		{"", 220},
+		{"MAIL FROM:<a@example.com>", 503},
+		{"EHLO client", 250},
+		{"RCPT TO:<b@example.com>", 503},
+		{"MAIL FROM:<>", 250},
+		{"DATA", 503},
+		{"RCPT TO:<b@example.com>", 250},
+		{"STARTTLS", 502},
+		{"BOGUS", 502},
+		{"DATA", 354},
+		{"Subject: this message is too big\r\n.", 552},
+		{"QUIT", 221},
+	}
+
+	for _, test := range testTable {
+		if test.command != "" {
+			conn.PrintfLine("%s", test.command)
+		}
+
+		if _, _, err := conn.ReadResponse(test.code); err != nil {
+			t.Errorf("%s: %s", test.command, err)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	testTable := []struct {
+		arg      string
+		prefix   string
+		expected string
+		ok       bool
+	}{
+		{"FROM:<a@example.com>", "FROM:", "a@example.com", true},
+		{"from: <a@example.com> SIZE=100", "FROM:", "a@example.com", true},
+		{"FROM:<>", "FROM:", "", true},
+		{"TO:b@example.com", "TO:", "b@example.com", true},
+		{"b@example.com", "TO:", "", false},
+	}
+
+	for _, test := range testTable {
+		result, ok := parsePath(test.arg, test.prefix)
+		if result != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %s %t, got %s %t", test.arg, test.expected, test.ok, result, ok)
+		}
+	}
+}
+
+func TestSmtpQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	smtpServer := Smtp{rendererQuitChannels: chans}
+	smtpServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
@@ -0,0 +1,437 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronvb/logparams"
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pterm/pterm"
+	"github.com/rs/cors"
+)
+
+// SseEvent is a single Server-Sent Event.
+type SseEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+	Retry int    `json:"retry"`
+}
+
+// SseFixtureEvent is an event from a fixture file, which is sent to each client
+// after the delay.
+type SseFixtureEvent struct {
+	SseEvent
+	Delay time.Duration
+}
+
+// Sse is the protocol for serving Server-Sent Events.
+type Sse struct {
+	// Addr is the address the SSE server will bind to.
+	Addr string
+
+	// Port is the port the SSE server will run on.
+	Port int
+
+	// Interval sends the scheduled event to all clients on each tick. Default is 0,
+	// which disables scheduled events.
+	Interval time.Duration
+
+	// Event and Data are the event name and data of the scheduled event.
+	Event string
+	Data  string
+
+	// Fixture contains the events sent to each client when it connects.
+	Fixture []SseFixtureEvent
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming request to the Sse protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+
+	mu sync.Mutex
+
+	// clients contain a channel of events for each connected client.
+	clients map[string]chan SseEvent
+
+	// lastID is incremented for events sent without an ID.
+	lastID int
+}
+
+// Start will start the SSE server.
+//
+// Sets the channel on our struct so that connections and events can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Sse) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	addr := fmt.Sprintf("%s:%d", s.Addr, s.Port)
+	errorLog := log.New(&sseErrorLog{}, "", 0)
+
+	srv := &http.Server{
+		Addr:     addr,
+		ErrorLog: errorLog,
+		Handler:  s.routes(),
+	}
+
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			err = srv.Serve(s.listener)
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("SSE Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	if s.Interval > 0 {
+		go s.schedule()
+	}
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Sse) Bind() (net.Addr, error) {
+	if s.listener == nil {
+		ln, err := listen(s.Addr, s.Port, "", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		s.listener = ln
+	}
+
+	return s.listener.Addr(), nil
+}
+
+func (s *Sse) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// routes handles the routes for our SSE server and currently accepts any path.
+func (s *Sse) routes() http.Handler {
+	r := mux.NewRouter()
+	r.PathPrefix("/").HandlerFunc(s.defaultHandler)
+
+	handler := cors.AllowAll().Handler(r)
+
+	return handler
+}
+
+// defaultHandler keeps the connection open and writes events to it as they are sent
+// until the client disconnects.
+func (s *Sse) defaultHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	id, events := s.addClient()
+	defer s.removeClient(id)
+
+	s.logConnection("CONNECTED", r)
+	defer s.logConnection("DISCONNECTED", r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := make(chan struct{})
+	defer close(done)
+	if len(s.Fixture) > 0 {
+		go s.playFixture(events, done)
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if _, err := w.Write(event.Bytes()); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// SendEvent sends the event to every connected client and returns the number of
+// clients it was sent to. Clients that are not keeping up miss the event.
+func (s *Sse) SendEvent(event SseEvent) int {
+	s.mu.Lock()
+	if event.ID == "" {
+		s.lastID++
+		event.ID = strconv.Itoa(s.lastID)
+	}
+
+	sent := 0
+	for _, events := range s.clients {
+		select {
+		case events <- event:
+			sent++
+		default:
+		}
+	}
+	s.mu.Unlock()
+
+	s.logMessage("SEND", event.String())
+
+	return sent
+}
+
+// schedule sends the scheduled event to all clients on each interval.
+func (s *Sse) schedule() {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.SendEvent(SseEvent{Event: s.Event, Data: s.Data})
+	}
+}
+
+// playFixture sends the fixture events to a single client.
+func (s *Sse) playFixture(events chan SseEvent, done chan struct{}) {
+	for _, fixture := range s.Fixture {
+		select {
+		case <-done:
+			return
+		case <-time.After(fixture.Delay):
+		}
+
+		select {
+		case events <- fixture.SseEvent:
+			s.logMessage("SEND", fixture.SseEvent.String())
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *Sse) addClient() (string, chan SseEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients == nil {
+		s.clients = make(map[string]chan SseEvent)
+	}
+
+	id := uuid.New().String()
+	events := make(chan SseEvent, 16)
+	s.clients[id] = events
+
+	return id, events
+}
+
+func (s *Sse) removeClient(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.clients, id)
+}
+
+// logConnection sends the connect and disconnect of a client to the renderers.
+func (s *Sse) logConnection(method string, r *http.Request) {
+	params := logparams.LogParams{Request: r, HidePrefix: true}
+	msg := params.ToString()
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		msg = strings.TrimSpace(fmt.Sprintf("Last-Event-ID: %s %s", lastEventID, msg))
+	}
+
+	req := RequestPayload{
+		ID:          uuid.New().String(),
+		Fields:      logrequest.RequestFields{Method: method, Url: r.URL.RequestURI()},
+		Message:     msg,
+		Headers:     r.Header,
+		ParamFields: params.ToFields(),
+		CreatedAt:   time.Now(),
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// logMessage sends the events we send to clients to the render channel.
+func (s *Sse) logMessage(method string, msg string) {
+	req := RequestPayload{
+		ID:        uuid.New().String(),
+		Fields:    logrequest.RequestFields{Method: method},
+		CreatedAt: time.Now(),
+		Message:   msg,
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// Validate returns an error if the id or event name contain a line break, which would end
+// the field early and send the rest of it as other fields.
+func (e SseEvent) Validate() error {
+	if strings.ContainsAny(e.ID, "\r\n") {
+		return fmt.Errorf("event id %q contains a line break", e.ID)
+	}
+
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return fmt.Errorf("event name %q contains a line break", e.Event)
+	}
+
+	return nil
+}
+
+// sseLineBreaks normalizes the line breaks of the text/event-stream format to \n.
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// Bytes returns the event in the text/event-stream format. Multiline data is sent
+// as multiple data fields, split on any of the line breaks clients split on.
+func (e SseEvent) Bytes() []byte {
+	var b bytes.Buffer
+
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry)
+	}
+
+	for _, line := range strings.Split(sseLineBreaks.Replace(e.Data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// String returns the event as a single line for the renderers.
+func (e SseEvent) String() string {
+	fields := make([]string, 0, 3)
+
+	if e.ID != "" {
+		fields = append(fields, fmt.Sprintf("id: %s", e.ID))
+	}
+
+	if e.Event != "" {
+		fields = append(fields, fmt.Sprintf("event: %s", e.Event))
+	}
+
+	fields = append(fields, e.Data)
+
+	return strings.Join(fields, " ")
+}
+
+// LoadSseFixture reads a JSON fixture file containing a list of events. Event data can
+// be a string or any JSON value, and delay is a duration(ie: 1s) waited before the
+// event is sent.
+func LoadSseFixture(path string) ([]SseFixtureEvent, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		ID    string          `json:"id"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+		Retry int             `json:"retry"`
+		Delay string          `json:"delay"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("sse fixture %s: %w", path, err)
+	}
+
+	events := make([]SseFixtureEvent, 0, len(raw))
+	for _, r := range raw {
+		event := SseFixtureEvent{SseEvent: SseEvent{ID: r.ID, Event: r.Event, Retry: r.Retry}}
+		if err := event.Validate(); err != nil {
+			return nil, fmt.Errorf("sse fixture %s: %w", path, err)
+		}
+
+		data, err := fixtureText(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("sse fixture %s: %w", path, err)
+		}
+		event.Data = data
+
+		if r.Delay != "" {
+			delay, err := time.ParseDuration(r.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("sse fixture %s: %w", path, err)
+			}
+			event.Delay = delay
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// fixtureText returns a fixture value which can be a string, or any JSON value which is
+// compacted.
+func fixtureText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", err
+	}
+
+	return compact.String(), nil
+}
+
+// sseErrorLog implements the logger interface.
+type sseErrorLog struct{}
+
+// Write let's us override the logger required for http errors and
+// prints to the terminal using pterm.
+func (e *sseErrorLog) Write(b []byte) (n int, err error) {
+	pterm.Error.WithShowLineNumber(false).Println(string(b))
+	return len(b), nil
+}
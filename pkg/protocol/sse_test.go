@@ -0,0 +1,230 @@
+package protocol
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSseEvent reads the lines of a single event from the stream.
+func readSseEvent(t *testing.T, reader *bufio.Reader) []string {
+	lines := make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+func TestSseConnect(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	sseServer := Sse{rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(sseServer.routes())
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/events?hello=world", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "42")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected %s, got %s", "text/event-stream", resp.Header.Get("Content-Type"))
+	}
+
+	rp := <-rpChannel
+	if rp.Fields.Method != "CONNECTED" {
+		t.Errorf("Expected %s, got %s", "CONNECTED", rp.Fields.Method)
+	}
+
+	if rp.Fields.Url != "/events?hello=world" {
+		t.Errorf("Expected %s, got %s", "/events?hello=world", rp.Fields.Url)
+	}
+
+	expected := "Last-Event-ID: 42 {\"hello\" => \"world\"}"
+	if rp.Message != expected {
+		t.Errorf("Expected %s, got %s", expected, rp.Message)
+	}
+
+	resp.Body.Close()
+
+	rp = <-rpChannel
+	if rp.Fields.Method != "DISCONNECTED" {
+		t.Errorf("Expected %s, got %s", "DISCONNECTED", rp.Fields.Method)
+	}
+}
+
+func TestSseSendEvent(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	sseServer := Sse{rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(sseServer.routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	<-rpChannel // CONNECTED
+
+	sent := sseServer.SendEvent(SseEvent{Event: "update", Data: "hello\nworld", Retry: 1000})
+	if sent != 1 {
+		t.Errorf("Expected %d, got %d", 1, sent)
+	}
+
+	rp := <-rpChannel
+	if rp.Fields.Method != "SEND" {
+		t.Errorf("Expected %s, got %s", "SEND", rp.Fields.Method)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	lines := readSseEvent(t, reader)
+	expected := []string{"id: 1", "event: update", "retry: 1000", "data: hello", "data: world"}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+
+	sseServer.SendEvent(SseEvent{ID: "custom", Data: "foo"})
+	lines = readSseEvent(t, reader)
+	expected = []string{"id: custom", "data: foo"}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestSseEventBytes(t *testing.T) {
+	event := SseEvent{ID: "1", Data: "a\r\nb\rc\nd"}
+	expected := "id: 1\ndata: a\ndata: b\ndata: c\ndata: d\n\n"
+	if string(event.Bytes()) != expected {
+		t.Errorf("Expected %q, got %q", expected, event.Bytes())
+	}
+}
+
+func TestSseEventValidate(t *testing.T) {
+	testTable := []struct {
+		event SseEvent
+		valid bool
+	}{
+		{SseEvent{ID: "1", Event: "update", Data: "a\nb"}, true},
+		{SseEvent{ID: "1\ndata: x"}, false},
+		{SseEvent{ID: "1\r"}, false},
+		{SseEvent{Event: "update\r\n\r\ndata: x"}, false},
+	}
+
+	for _, test := range testTable {
+		if err := test.event.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %t, got %v", test.event, test.valid, err)
+		}
+	}
+}
+
+func TestSseFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.json")
+	fixture := `[
+		{"event": "greeting", "data": "hello"},
+		{"id": "2", "data": {"foo": "bar"}, "delay": "10ms"}
+	]`
+	if err := ioutil.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := LoadSseFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[1].Data != `{"foo":"bar"}` || events[1].Delay != 10*time.Millisecond {
+		t.Fatalf("Unexpected fixture %v", events)
+	}
+
+	rpChannel := make(chan RequestPayload, 10)
+	sseServer := Sse{Fixture: events, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(sseServer.routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	expected := [][]string{
+		{"event: greeting", "data: hello"},
+		{"id: 2", "data: {\"foo\":\"bar\"}"},
+	}
+
+	for _, e := range expected {
+		lines := readSseEvent(t, reader)
+		if strings.Join(lines, "\n") != strings.Join(e, "\n") {
+			t.Errorf("Expected %v, got %v", e, lines)
+		}
+	}
+}
+
+func TestLoadSseFixtureInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testTable := []string{
+		`{"data": "not a list"}`,
+		`[{"data": "hello", "delay": "soon"}]`,
+		`[{"id": "1\ndata: x", "data": "hello"}]`,
+		`[{"event": "update\r", "data": "hello"}]`,
+	}
+
+	for _, fixture := range testTable {
+		path := filepath.Join(dir, "events.json")
+		if err := ioutil.WriteFile(path, []byte(fixture), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadSseFixture(path); err == nil {
+			t.Errorf("Expected error for fixture %s", fixture)
+		}
+	}
+}
+
+func TestSseQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	sseServer := Sse{rendererQuitChannels: chans}
+	sseServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
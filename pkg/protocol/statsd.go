@@ -0,0 +1,268 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+)
+
+// statsdTypes maps the StatsD type of a metric to the method shown by the renderers.
+var statsdTypes = map[string]string{
+	"c":  "COUNTER",
+	"g":  "GAUGE",
+	"ms": "TIMER",
+	"h":  "HISTOGRAM",
+	"s":  "SET",
+	"d":  "DISTRIBUTION",
+}
+
+// StatsdMetric is a single metric parsed from a StatsD or DogStatsD datagram, ie:
+// page.views:1|c|@0.5|#env:prod.
+type StatsdMetric struct {
+	Name string `json:"name"`
+
+	// Value is the numeric value of the metric. Set members which are not numbers have
+	// a value of 0.
+	Value float64 `json:"value"`
+
+	// Raw is the value as it was sent, ie: +5 for a gauge delta or the member of a set.
+	Raw string `json:"raw"`
+
+	// Type is the StatsD type: c, g, ms, h, s or d.
+	Type string `json:"type"`
+
+	// SampleRate is the rate the metric was sampled at. Default is 1.
+	SampleRate float64 `json:"sample_rate"`
+
+	// Tags are the DogStatsD tags, ie: env:prod.
+	Tags []string `json:"tags"`
+}
+
+// Statsd is the protocol for receiving StatsD and DogStatsD metrics over UDP.
+type Statsd struct {
+	// Addr is the address the StatsD server will bind to.
+	Addr string
+
+	// Port is the port the StatsD server will run on.
+	Port int
+
+	// Window is how far back the aggregate of the metrics goes. Default is 0, which
+	// aggregates every metric received.
+	Window time.Duration
+
+	// conn is bound by Bind, before the server starts.
+	conn net.PacketConn
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming metric to the Statsd protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+
+	aggregator StatsdAggregator
+}
+
+// Start will start the StatsD server.
+//
+// Sets the channel on our struct so that incoming metrics can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Statsd) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			err = s.serve(s.conn)
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("StatsD Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the connection which Start reads from, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Statsd) Bind() (net.Addr, error) {
+	if s.conn == nil {
+		conn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", s.Addr, s.Port))
+		if err != nil {
+			return nil, err
+		}
+
+		s.conn = conn
+	}
+
+	return s.conn.LocalAddr(), nil
+}
+
+func (s *Statsd) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// Metrics returns the aggregate of the metrics received within the window.
+func (s *Statsd) Metrics() []StatsdAggregate {
+	return s.aggregator.Snapshot(s.Window)
+}
+
+// serve reads datagrams from the connection until it is closed. A datagram can contain
+// multiple metrics separated by newlines.
+func (s *Statsd) serve(conn net.PacketConn) error {
+	return servePackets(conn, func(addr net.Addr, data []byte) {
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimRight(line, "\r")
+			if line != "" {
+				s.handleLine(addr.String(), line)
+			}
+		}
+	})
+}
+
+// handleLine parses a single line and sends each metric in it to the renderers.
+// DogStatsD events and service checks are shown as they were sent.
+func (s *Statsd) handleLine(remoteAddr string, line string) {
+	switch {
+	case strings.HasPrefix(line, "_e{"):
+		s.logLine("EVENT", remoteAddr, "", line, nil)
+		return
+	case strings.HasPrefix(line, "_sc|"):
+		s.logLine("SERVICE_CHECK", remoteAddr, "", line, nil)
+		return
+	}
+
+	metrics, err := ParseStatsdLine(line)
+	if err != nil {
+		s.logLine("ERROR", remoteAddr, "", fmt.Sprintf("%s: %s", err, line), nil)
+		return
+	}
+
+	now := time.Now()
+	for i := range metrics {
+		metric := metrics[i]
+		s.aggregator.Add(metric, now, s.Window)
+		s.logLine(statsdTypes[metric.Type], remoteAddr, metric.Name, metric.String(), &metric)
+	}
+}
+
+// logLine sends a metric, or a line we could not parse, to the render channel. The
+// metric name is used as the url so the printer shows it first.
+func (s *Statsd) logLine(method string, remoteAddr string, name string, msg string, metric *StatsdMetric) {
+	req := RequestPayload{
+		ID: uuid.New().String(),
+		Fields: logrequest.RequestFields{
+			Method:        method,
+			Url:           name,
+			RemoteAddress: remoteAddr,
+			Protocol:      "statsd",
+			Time:          time.Now(),
+		},
+		CreatedAt: time.Now(),
+		Message:   msg,
+		Metric:    metric,
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// ParseStatsdLine parses a StatsD line in the form name:value|type|@rate|#tags. Multiple
+// values packed into one line(ie: latency:10:12:9|h) return a metric for each value.
+func ParseStatsdLine(line string) ([]StatsdMetric, error) {
+	sections := strings.Split(line, "|")
+	if len(sections) < 2 {
+		return nil, errors.New("invalid metric, missing type")
+	}
+
+	nameAndValues := strings.Split(sections[0], ":")
+	if len(nameAndValues) < 2 || nameAndValues[0] == "" {
+		return nil, errors.New("invalid metric, missing name or value")
+	}
+
+	metricType := sections[1]
+	if _, ok := statsdTypes[metricType]; !ok {
+		return nil, fmt.Errorf("invalid metric type %q", metricType)
+	}
+
+	sampleRate := 1.0
+	var tags []string
+	for _, section := range sections[2:] {
+		switch {
+		case strings.HasPrefix(section, "@"):
+			rate, err := strconv.ParseFloat(section[1:], 64)
+			if err != nil || math.IsNaN(rate) || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid sample rate %q", section[1:])
+			}
+			sampleRate = rate
+		case strings.HasPrefix(section, "#"):
+			for _, tag := range strings.Split(section[1:], ",") {
+				if tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		// Other DogStatsD fields such as container ids(c:) and timestamps(T) are ignored.
+	}
+
+	metrics := make([]StatsdMetric, 0, len(nameAndValues)-1)
+	for _, raw := range nameAndValues[1:] {
+		// Values which are not finite cannot be written as JSON. Sets count unique values,
+		// which do not have to be numbers.
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			if metricType != "s" {
+				return nil, fmt.Errorf("invalid metric value %q", raw)
+			}
+			value = 0
+		}
+
+		metrics = append(metrics, StatsdMetric{
+			Name:       nameAndValues[0],
+			Value:      value,
+			Raw:        raw,
+			Type:       metricType,
+			SampleRate: sampleRate,
+			Tags:       tags,
+		})
+	}
+
+	return metrics, nil
+}
+
+// String returns the metric without its name for the renderers, ie: 1 @0.5 #env:prod.
+func (m StatsdMetric) String() string {
+	fields := []string{m.Raw}
+
+	if m.SampleRate != 1 {
+		fields = append(fields, fmt.Sprintf("@%g", m.SampleRate))
+	}
+
+	if len(m.Tags) > 0 {
+		fields = append(fields, "#"+strings.Join(m.Tags, ","))
+	}
+
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,211 @@
+package protocol
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsdAggregate is the rolling aggregate of a metric. Metrics are grouped by name,
+// type and tags.
+type StatsdAggregate struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Tags []string `json:"tags"`
+
+	// Count is the number of samples received.
+	Count int `json:"count"`
+
+	// Value is the sum of counters scaled by their sample rate, the last value of
+	// gauges, the number of unique members of sets, and the mean of timers, histograms
+	// and distributions.
+	Value float64 `json:"value"`
+
+	// P50 and P95 are the percentiles of timers, histograms and distributions, nil for
+	// other types.
+	P50 *float64 `json:"p50"`
+	P95 *float64 `json:"p95"`
+}
+
+// statsdSample is a single value of a metric and when it was received.
+type statsdSample struct {
+	at     time.Time
+	metric StatsdMetric
+}
+
+// statsdSeries holds the samples of a metric.
+type statsdSeries struct {
+	name    string
+	typ     string
+	tags    []string
+	samples []statsdSample
+
+	// gauge is the current value of a gauge, which is kept outside of the window so
+	// that deltas apply to the last value sent.
+	gauge float64
+}
+
+// StatsdAggregator keeps a rolling aggregate of metrics. The zero value is ready to use.
+type StatsdAggregator struct {
+	mu     sync.Mutex
+	series map[string]*statsdSeries
+
+	// swept is when the samples of every series were last pruned by Add.
+	swept time.Time
+}
+
+// Add adds a metric received at the time to the aggregate. Samples received more than
+// the window before it are removed, so the aggregate does not grow while nothing takes
+// snapshots. A window of 0 keeps every sample.
+func (a *StatsdAggregator) Add(metric StatsdMetric, at time.Time, window time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.series == nil {
+		a.series = make(map[string]*statsdSeries)
+	}
+
+	tags := append([]string(nil), metric.Tags...)
+	sort.Strings(tags)
+	key := metric.Name + "|" + metric.Type + "|" + strings.Join(tags, ",")
+
+	series, ok := a.series[key]
+	if !ok {
+		series = &statsdSeries{name: metric.Name, typ: metric.Type, tags: tags}
+		a.series[key] = series
+	}
+
+	if metric.Type == "g" {
+		if strings.HasPrefix(metric.Raw, "+") || strings.HasPrefix(metric.Raw, "-") {
+			series.gauge = clampFinite(series.gauge + metric.Value)
+		} else {
+			series.gauge = metric.Value
+		}
+	}
+
+	series.samples = append(series.samples, statsdSample{at: at, metric: metric})
+
+	if window > 0 {
+		cutoff := at.Add(-window)
+		series.prune(cutoff)
+
+		// Series which stopped receiving metrics are pruned once per window.
+		if at.Sub(a.swept) >= window {
+			a.prune(cutoff)
+			a.swept = at
+		}
+	}
+}
+
+// Snapshot returns the aggregate of the samples received within the window, sorted by
+// name. A window of 0 includes every sample. Samples outside of the window are removed.
+// Gauges keep their last value when no samples are left in the window.
+func (a *StatsdAggregator) Snapshot(window time.Duration) []StatsdAggregate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if window > 0 {
+		a.prune(time.Now().Add(-window))
+	}
+
+	aggregates := make([]StatsdAggregate, 0, len(a.series))
+	for _, series := range a.series {
+		aggregates = append(aggregates, series.aggregate())
+	}
+
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Name != aggregates[j].Name {
+			return aggregates[i].Name < aggregates[j].Name
+		}
+		if aggregates[i].Type != aggregates[j].Type {
+			return aggregates[i].Type < aggregates[j].Type
+		}
+		return strings.Join(aggregates[i].Tags, ",") < strings.Join(aggregates[j].Tags, ",")
+	})
+
+	return aggregates
+}
+
+// prune removes the samples received before the cutoff from every series, and the series
+// left without samples. Gauges are kept, so deltas still apply to their last value.
+func (a *StatsdAggregator) prune(cutoff time.Time) {
+	for key, series := range a.series {
+		series.prune(cutoff)
+
+		if len(series.samples) == 0 && series.typ != "g" {
+			delete(a.series, key)
+		}
+	}
+}
+
+// prune removes the samples received before the cutoff.
+func (s *statsdSeries) prune(cutoff time.Time) {
+	i := 0
+	for i < len(s.samples) && s.samples[i].at.Before(cutoff) {
+		i++
+	}
+
+	s.samples = s.samples[i:]
+}
+
+// aggregate calculates the aggregate of the samples based on the type of the metric.
+func (s *statsdSeries) aggregate() StatsdAggregate {
+	agg := StatsdAggregate{Name: s.name, Type: s.typ, Tags: s.tags, Count: len(s.samples)}
+
+	switch s.typ {
+	case "c":
+		for _, sample := range s.samples {
+			agg.Value += sample.metric.Value / sample.metric.SampleRate
+		}
+		agg.Value = clampFinite(agg.Value)
+	case "g":
+		agg.Value = s.gauge
+	case "s":
+		members := make(map[string]struct{})
+		for _, sample := range s.samples {
+			members[sample.metric.Raw] = struct{}{}
+		}
+		agg.Value = float64(len(members))
+	default:
+		values := make([]float64, len(s.samples))
+		sum := 0.0
+		for i, sample := range s.samples {
+			values[i] = sample.metric.Value
+			sum += sample.metric.Value
+		}
+		sort.Float64s(values)
+
+		p50 := percentile(values, 50)
+		p95 := percentile(values, 95)
+		agg.Value = clampFinite(sum / float64(len(values)))
+		agg.P50 = &p50
+		agg.P95 = &p95
+	}
+
+	return agg
+}
+
+// percentile returns the nearest-rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// clampFinite keeps sums of values which overflow at the largest float, since infinite
+// values cannot be written as JSON.
+func clampFinite(v float64) float64 {
+	switch {
+	case math.IsInf(v, 1):
+		return math.MaxFloat64
+	case math.IsInf(v, -1):
+		return -math.MaxFloat64
+	}
+
+	return v
+}
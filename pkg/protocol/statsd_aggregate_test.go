@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatsdAggregator(t *testing.T) {
+	var a StatsdAggregator
+	now := time.Now()
+
+	lines := []string{
+		"hits:1|c",
+		"hits:2|c|@0.5",
+		"hits:1|c|#env:prod",
+		"temp:20|g",
+		"temp:+5|g",
+		"temp:-3|g",
+		"users:alice|s",
+		"users:bob|s",
+		"users:alice|s",
+		"latency:10:20:30:40|ms",
+	}
+
+	for _, line := range lines {
+		metrics, err := ParseStatsdLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range metrics {
+			a.Add(m, now, 0)
+		}
+	}
+
+	p50, p95 := 20.0, 40.0
+	expected := []StatsdAggregate{
+		{Name: "hits", Type: "c", Tags: []string{}, Count: 2, Value: 5},
+		{Name: "hits", Type: "c", Tags: []string{"env:prod"}, Count: 1, Value: 1},
+		{Name: "latency", Type: "ms", Tags: []string{}, Count: 4, Value: 25, P50: &p50, P95: &p95},
+		{Name: "temp", Type: "g", Tags: []string{}, Count: 3, Value: 22},
+		{Name: "users", Type: "s", Tags: []string{}, Count: 3, Value: 2},
+	}
+
+	// Normalize nil tags so the comparison is not affected by how they were copied.
+	aggregates := a.Snapshot(0)
+	for i := range aggregates {
+		if aggregates[i].Tags == nil {
+			aggregates[i].Tags = []string{}
+		}
+	}
+
+	if !reflect.DeepEqual(aggregates, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, aggregates)
+	}
+}
+
+func TestStatsdAggregatorOverflow(t *testing.T) {
+	var a StatsdAggregator
+	now := time.Now()
+
+	for _, line := range []string{"hits:1e308|c", "hits:1e308|c", "temp:-1e308|g", "temp:-1e308|g", "latency:1e308:1e308|ms"} {
+		metrics, err := ParseStatsdLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range metrics {
+			a.Add(m, now, 0)
+		}
+	}
+
+	aggregates := a.Snapshot(0)
+	if _, err := json.Marshal(aggregates); err != nil {
+		t.Fatalf("Expected the aggregates to be written as JSON, got %s", err)
+	}
+
+	expected := []float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64}
+	for i, aggregate := range aggregates {
+		if aggregate.Value != expected[i] {
+			t.Errorf("%s: expected %g, got %g", aggregate.Name, expected[i], aggregate.Value)
+		}
+	}
+}
+
+func TestStatsdAggregatorWindow(t *testing.T) {
+	var a StatsdAggregator
+	now := time.Now()
+
+	a.Add(StatsdMetric{Name: "hits", Value: 1, Raw: "1", Type: "c", SampleRate: 1}, now.Add(-2*time.Minute), 0)
+	a.Add(StatsdMetric{Name: "hits", Value: 3, Raw: "3", Type: "c", SampleRate: 1}, now, 0)
+	a.Add(StatsdMetric{Name: "old", Value: 1, Raw: "1", Type: "c", SampleRate: 1}, now.Add(-2*time.Minute), 0)
+
+	aggregates := a.Snapshot(time.Minute)
+	if len(aggregates) != 1 {
+		t.Fatalf("Expected 1 aggregate, got %d", len(aggregates))
+	}
+
+	if aggregates[0].Name != "hits" || aggregates[0].Value != 3 || aggregates[0].Count != 1 {
+		t.Errorf("Expected hits with a value of 3, got %+v", aggregates[0])
+	}
+}
+
+func TestStatsdAggregatorPrunesOnAdd(t *testing.T) {
+	var a StatsdAggregator
+	start := time.Now().Add(-time.Hour)
+
+	// An hour of metrics, one a second, without a snapshot being taken.
+	for i := 0; i < 3600; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		a.Add(StatsdMetric{Name: "hits", Value: 1, Raw: "1", Type: "c", SampleRate: 1}, at, time.Minute)
+		if i < 10 {
+			a.Add(StatsdMetric{Name: "old", Value: 1, Raw: "1", Type: "c", SampleRate: 1}, at, time.Minute)
+		}
+	}
+
+	if samples := len(a.series["hits|c|"].samples); samples > 61 {
+		t.Errorf("Expected at most a minute of samples, got %d", samples)
+	}
+
+	if _, ok := a.series["old|c|"]; ok {
+		t.Error("Expected the series without samples in the window to be removed")
+	}
+}
+
+func TestStatsdAggregatorGaugeOutsideWindow(t *testing.T) {
+	var a StatsdAggregator
+	now := time.Now()
+
+	a.Add(StatsdMetric{Name: "temp", Value: 20, Raw: "20", Type: "g", SampleRate: 1}, now.Add(-2*time.Minute), time.Minute)
+	if aggregates := a.Snapshot(time.Minute); len(aggregates) != 1 || aggregates[0].Value != 20 || aggregates[0].Count != 0 {
+		t.Errorf("Expected the last value of the gauge, got %+v", aggregates)
+	}
+
+	a.Add(StatsdMetric{Name: "temp", Value: 5, Raw: "+5", Type: "g", SampleRate: 1}, now, time.Minute)
+	if aggregates := a.Snapshot(time.Minute); len(aggregates) != 1 || aggregates[0].Value != 25 || aggregates[0].Count != 1 {
+		t.Errorf("Expected the delta to apply to the last value, got %+v", aggregates)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	testTable := []struct {
+		values   []float64
+		p        float64
+		expected float64
+	}{
+		{[]float64{5}, 50, 5},
+		{[]float64{1, 2, 3, 4}, 50, 2},
+		{[]float64{1, 2, 3, 4}, 95, 4},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 95, 10},
+	}
+
+	for _, test := range testTable {
+		if result := percentile(test.values, test.p); result != test.expected {
+			t.Errorf("Expected %g, got %g", test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package protocol
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatsdLine(t *testing.T) {
+	testTable := []struct {
+		line     string
+		expected []StatsdMetric
+	}{
+		{
+			"page.views:1|c",
+			[]StatsdMetric{{Name: "page.views", Value: 1, Raw: "1", Type: "c", SampleRate: 1}},
+		},
+		{
+			"queue.size:-3|g",
+			[]StatsdMetric{{Name: "queue.size", Value: -3, Raw: "-3", Type: "g", SampleRate: 1}},
+		},
+		{
+			"api.latency:320|ms|@0.1|#env:prod,region:us",
+			[]StatsdMetric{{Name: "api.latency", Value: 320, Raw: "320", Type: "ms", SampleRate: 0.1, Tags: []string{"env:prod", "region:us"}}},
+		},
+		{
+			"users.unique:alice|s",
+			[]StatsdMetric{{Name: "users.unique", Value: 0, Raw: "alice", Type: "s", SampleRate: 1}},
+		},
+		{
+			"users.unique:NaN|s",
+			[]StatsdMetric{{Name: "users.unique", Value: 0, Raw: "NaN", Type: "s", SampleRate: 1}},
+		},
+		{
+			"file.size:10:12|d|#host:a|c:abc123|T1656581400",
+			[]StatsdMetric{
+				{Name: "file.size", Value: 10, Raw: "10", Type: "d", SampleRate: 1, Tags: []string{"host:a"}},
+				{Name: "file.size", Value: 12, Raw: "12", Type: "d", SampleRate: 1, Tags: []string{"host:a"}},
+			},
+		},
+	}
+
+	for _, test := range testTable {
+		metrics, err := ParseStatsdLine(test.line)
+		if err != nil {
+			t.Fatalf("%s: %s", test.line, err)
+		}
+
+		if !reflect.DeepEqual(metrics, test.expected) {
+			t.Errorf("Expected %+v, got %+v", test.expected, metrics)
+		}
+	}
+}
+
+func TestParseStatsdLineInvalid(t *testing.T) {
+	lines := []string{
+		"page.views",
+		"page.views|c",
+		":1|c",
+		"page.views:1|x",
+		"page.views:abc|c",
+		"page.views:1|c|@2",
+		"page.views:1|c|@NaN",
+		"queue.size:NaN|g",
+		"queue.size:+Inf|g",
+		"api.latency:-inf|ms",
+		"api.latency:1e400|h",
+	}
+
+	for _, line := range lines {
+		if _, err := ParseStatsdLine(line); err == nil {
+			t.Errorf("Expected error for %q", line)
+		}
+	}
+}
+
+func TestStatsdMetricString(t *testing.T) {
+	metric := StatsdMetric{Raw: "320", SampleRate: 0.5, Tags: []string{"env:prod", "region:us"}}
+	expected := "320 @0.5 #env:prod,region:us"
+
+	if metric.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, metric.String())
+	}
+}
+
+func TestStatsdDatagram(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 4)
+	statsdServer := &Statsd{rendererChannels: []chan RequestPayload{rpChannel}}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go statsdServer.serve(conn)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Write([]byte("page.views:1|c|#env:prod\nqueue.size:5|g\nbroken\n_sc|redis|0"))
+
+	expected := []struct {
+		method string
+		url    string
+		msg    string
+		metric bool
+	}{
+		{"COUNTER", "page.views", "1 #env:prod", true},
+		{"GAUGE", "queue.size", "5", true},
+		{"ERROR", "", "invalid metric, missing type: broken", false},
+		{"SERVICE_CHECK", "", "_sc|redis|0", false},
+	}
+
+	for _, e := range expected {
+		rp := <-rpChannel
+
+		if rp.Fields.Method != e.method {
+			t.Errorf("Expected %s, got %s", e.method, rp.Fields.Method)
+		}
+
+		if rp.Fields.Url != e.url {
+			t.Errorf("Expected %s, got %s", e.url, rp.Fields.Url)
+		}
+
+		if rp.Message != e.msg {
+			t.Errorf("Expected %s, got %s", e.msg, rp.Message)
+		}
+
+		if (rp.Metric != nil) != e.metric {
+			t.Errorf("Expected metric to be set: %t, got %+v", e.metric, rp.Metric)
+		}
+	}
+
+	metrics := statsdServer.Metrics()
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 aggregated metrics, got %d", len(metrics))
+	}
+}
+
+func TestStatsdQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	statsdServer := Statsd{rendererQuitChannels: chans}
+	statsdServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
@@ -0,0 +1,286 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+)
+
+// tcpMaxLineSize is the longest line a client can send before it is disconnected.
+const tcpMaxLineSize = 64 * 1024
+
+// Tcp is the protocol for accepting raw TCP connections.
+type Tcp struct {
+	// Addr is the address the TCP server will bind to.
+	Addr string
+
+	// Port is the port the TCP server will run on.
+	Port int
+
+	// Split determines how incoming data is divided into messages. "line" sends a
+	// message for each newline-delimited line, "chunk" sends each read as it arrives.
+	// Default is "line".
+	Split string
+
+	// Hex renders messages as a hex dump instead of text.
+	Hex bool
+
+	// Echo writes each message back to the client.
+	Echo bool
+
+	// Reply is written to the client after each message. Go escape sequences such as
+	// \r\n are interpreted.
+	Reply string
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming message to the Tcp protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+}
+
+// Start will start the TCP server.
+//
+// Sets the channel on our struct so that connections and messages can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Tcp) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			err = s.serve(s.listener)
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("TCP Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Tcp) Bind() (net.Addr, error) {
+	if s.listener == nil {
+		ln, err := listen(s.Addr, s.Port, "", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		s.listener = ln
+	}
+
+	return s.listener.Addr(), nil
+}
+
+func (s *Tcp) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// serve accepts connections on the listener until it is closed.
+func (s *Tcp) serve(ln net.Listener) error {
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn reads messages from the connection until the client disconnects.
+func (s *Tcp) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	remoteAddr := conn.RemoteAddr().String()
+	s.logMessage("CONNECTED", remoteAddr, "", 0)
+
+	var received, sent int
+	reply := s.reply()
+	next := s.reader(conn)
+
+	for {
+		message, err := next()
+		if len(message) > 0 {
+			received += len(message)
+			s.logMessage("RECEIVE", remoteAddr, s.format(message), len(message))
+
+			if s.Echo {
+				n, _ := conn.Write(message)
+				sent += n
+			}
+
+			if len(reply) > 0 {
+				n, _ := conn.Write(reply)
+				sent += n
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				s.logMessage("ERROR", remoteAddr, err.Error(), 0)
+			}
+			break
+		}
+	}
+
+	msg := fmt.Sprintf("received %d bytes, sent %d bytes", received, sent)
+	s.logMessage("DISCONNECTED", remoteAddr, msg, received)
+}
+
+// reader returns a function which reads the next message from the connection, a line or
+// a chunk. Lines are limited to tcpMaxLineSize, so a client which never sends a newline
+// is disconnected rather than buffered without limit.
+func (s *Tcp) reader(conn net.Conn) func() ([]byte, error) {
+	if s.Split == "chunk" {
+		buf := make([]byte, 32*1024)
+		return func() ([]byte, error) {
+			n, err := conn.Read(buf)
+			return buf[:n], err
+		}
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), tcpMaxLineSize)
+	scanner.Split(scanLine)
+
+	return func() ([]byte, error) {
+		if scanner.Scan() {
+			return scanner.Bytes(), nil
+		}
+
+		switch err := scanner.Err(); err {
+		case nil:
+			return nil, io.EOF
+		case bufio.ErrTooLong:
+			return nil, fmt.Errorf("line longer than %d bytes", tcpMaxLineSize)
+		default:
+			return nil, err
+		}
+	}
+}
+
+// scanLine splits the data into lines like bufio.ScanLines, but keeps the line endings
+// so the messages are echoed and counted as they were received.
+func scanLine(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// format renders the message as a hex dump or as text without the line ending.
+func (s *Tcp) format(message []byte) string {
+	if s.Split == "chunk" || s.Hex {
+		return formatData(message, s.Hex)
+	}
+
+	return strings.TrimRight(string(message), "\r\n")
+}
+
+// reply returns the reply with escape sequences interpreted.
+func (s *Tcp) reply() []byte {
+	return unescapeReply(s.Reply)
+}
+
+// logMessage sends connection events and incoming messages to the render channel.
+// The remote address is used as the url so the printer shows where it came from.
+func (s *Tcp) logMessage(method string, remoteAddr string, msg string, size int) {
+	req := RequestPayload{
+		ID: uuid.New().String(),
+		Fields: logrequest.RequestFields{
+			Method:        method,
+			Url:           remoteAddr,
+			RemoteAddress: remoteAddr,
+			Protocol:      "tcp",
+			Time:          time.Now(),
+		},
+		CreatedAt: time.Now(),
+		Message:   msg,
+		Size:      size,
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// formatData renders raw data as a hex dump or as text.
+func formatData(data []byte, asHex bool) string {
+	if asHex {
+		return strings.TrimSuffix(hex.Dump(data), "\n")
+	}
+
+	return string(data)
+}
+
+// unescapeReply interprets Go escape sequences such as \r\n and \" in a reply passed as
+// a flag. The reply is used as is if it cannot be unescaped.
+func unescapeReply(reply string) []byte {
+	if reply == "" {
+		return nil
+	}
+
+	var unescaped []byte
+	for rest := reply; len(rest) > 0; {
+		// Quotes do not need escaping, since the reply is not in a quoted string.
+		if rest[0] == '"' {
+			unescaped = append(unescaped, '"')
+			rest = rest[1:]
+			continue
+		}
+
+		value, multibyte, tail, err := strconv.UnquoteChar(rest, '"')
+		if err != nil {
+			return []byte(reply)
+		}
+
+		if value < utf8.RuneSelf || !multibyte {
+			unescaped = append(unescaped, byte(value))
+		} else {
+			var buf [utf8.UTFMax]byte
+			n := utf8.EncodeRune(buf[:], value)
+			unescaped = append(unescaped, buf[:n]...)
+		}
+
+		rest = tail
+	}
+
+	return unescaped
+}
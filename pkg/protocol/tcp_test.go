@@ -0,0 +1,209 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// startTcp serves the protocol on a random port and returns the address.
+func startTcp(t *testing.T, s *Tcp) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go s.serve(ln)
+
+	return ln.Addr().String()
+}
+
+func TestTcpLines(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	tcpServer := &Tcp{rendererChannels: []chan RequestPayload{rpChannel}}
+	addr := startTcp(t, tcpServer)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Write([]byte("PING\r\nHELLO world\n"))
+	conn.Close()
+
+	testTable := []struct {
+		method  string
+		message string
+		size    int
+	}{
+		{"CONNECTED", "", 0},
+		{"RECEIVE", "PING", 6},
+		{"RECEIVE", "HELLO world", 12},
+		{"DISCONNECTED", "received 18 bytes, sent 0 bytes", 18},
+	}
+
+	for _, test := range testTable {
+		rp := <-rpChannel
+
+		if rp.Fields.Method != test.method {
+			t.Errorf("Expected %s, got %s", test.method, rp.Fields.Method)
+		}
+
+		if rp.Message != test.message {
+			t.Errorf("Expected %s, got %s", test.message, rp.Message)
+		}
+
+		if rp.Size != test.size {
+			t.Errorf("Expected %d, got %d", test.size, rp.Size)
+		}
+
+		if rp.Fields.RemoteAddress != conn.LocalAddr().String() {
+			t.Errorf("Expected %s, got %s", conn.LocalAddr().String(), rp.Fields.RemoteAddress)
+		}
+	}
+}
+
+func TestTcpHex(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	tcpServer := &Tcp{Split: "chunk", Hex: true, rendererChannels: []chan RequestPayload{rpChannel}}
+	addr := startTcp(t, tcpServer)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	data := []byte{0x00, 0x01, 0xff}
+	conn.Write(data)
+
+	<-rpChannel // CONNECTED
+	rp := <-rpChannel
+	expected := strings.TrimSuffix(hex.Dump(data), "\n")
+	if rp.Message != expected {
+		t.Errorf("Expected %s, got %s", expected, rp.Message)
+	}
+}
+
+func TestTcpEchoAndReply(t *testing.T) {
+	testTable := []struct {
+		echo     bool
+		reply    string
+		expected string
+	}{
+		{true, "", "hello\n"},
+		{false, `+OK\r\n`, "+OK\r\n"},
+		{true, `+OK\r\n`, "hello\n+OK\r\n"},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 10)
+		tcpServer := &Tcp{Echo: test.echo, Reply: test.reply, rendererChannels: []chan RequestPayload{rpChannel}}
+		addr := startTcp(t, tcpServer)
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write([]byte("hello\n"))
+
+		reader := bufio.NewReader(conn)
+		buf := make([]byte, len(test.expected))
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf) != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, string(buf))
+		}
+
+		conn.Close()
+	}
+}
+
+func TestTcpLineTooLong(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	tcpServer := &Tcp{rendererChannels: []chan RequestPayload{rpChannel}}
+	addr := startTcp(t, tcpServer)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go conn.Write([]byte(strings.Repeat("a", tcpMaxLineSize+1)))
+
+	// The server closes the connection, which resets it since the rest of the line is
+	// unread.
+	io.Copy(io.Discard, conn)
+
+	expected := []string{"CONNECTED", "ERROR", "DISCONNECTED"}
+	for _, method := range expected {
+		if rp := <-rpChannel; rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s %s", method, rp.Fields.Method, rp.Message)
+		}
+	}
+}
+
+func TestUnescapeReply(t *testing.T) {
+	testTable := []struct {
+		reply    string
+		expected string
+	}{
+		{`+OK\r\n`, "+OK\r\n"},
+		{`{"ok": true}\n`, "{\"ok\": true}\n"},
+		{`say \"hi\"\n`, "say \"hi\"\n"},
+		{`caf\u00e9 \xff`, "caf\u00e9 \xff"},
+		{`bad \q`, `bad \q`},
+	}
+
+	for _, test := range testTable {
+		if result := string(unescapeReply(test.reply)); result != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.reply, test.expected, result)
+		}
+	}
+}
+
+func TestTcpQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	tcpServer := Tcp{rendererQuitChannels: chans}
+	tcpServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
+
+func TestTcpBind(t *testing.T) {
+	tcpServer := Tcp{Addr: "127.0.0.1"}
+
+	addr, err := tcpServer.Bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpServer.listener.Close()
+
+	if addr.(*net.TCPAddr).Port == 0 {
+		t.Errorf("Expected a port to be picked")
+	}
+
+	again, err := tcpServer.Bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again.String() != addr.String() {
+		t.Errorf("Expected Bind to return %s again, got %s", addr, again)
+	}
+}
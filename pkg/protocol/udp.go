@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+)
+
+// maxDatagramSize is the largest UDP payload we can receive.
+const maxDatagramSize = 64 * 1024
+
+// Udp is the protocol for receiving UDP datagrams.
+type Udp struct {
+	// Addr is the address the UDP server will bind to.
+	Addr string
+
+	// Port is the port the UDP server will run on.
+	Port int
+
+	// Hex renders datagrams as a hex dump instead of text.
+	Hex bool
+
+	// Echo sends each datagram back to its source.
+	Echo bool
+
+	// Reply is sent to the source of each datagram. Go escape sequences such as \r\n
+	// are interpreted.
+	Reply string
+
+	// conn is bound by Bind, before the server starts.
+	conn net.PacketConn
+
+	// rendererChannel is the channel which we send a RequestPayload to when
+	// receiving an incoming datagram to the Udp protocol.
+	rendererChannels     []chan RequestPayload
+	rendererQuitChannels []chan int
+}
+
+// Start will start the UDP server.
+//
+// Sets the channel on our struct so that incoming datagrams can be sent over it.
+//
+// In the case that we cannot start this server, we send a signal to our quit channel
+// to close renderers.
+func (s *Udp) Start(c []chan RequestPayload, quits []chan int, errors []chan int) {
+	s.rendererChannels = c
+	s.rendererQuitChannels = quits
+
+	go func() {
+		_, err := s.Bind()
+		if err == nil {
+			err = s.serve(s.conn)
+		}
+
+		str := pterm.Error.WithShowLineNumber(false).Sprintf("UDP Protocol: %s\n", err)
+		pterm.Printo(str) // Overwrite last line
+
+		// If the server fails to start, send a quit to all renderers, which will exit
+		// the main program.
+		s.quitRenderers()
+	}()
+
+	// If any of our renderers send an error signal, send a quit signal to all other
+	// renderers, which will exit the main program.
+	for range merge(errors) {
+		s.quitRenderers()
+		return
+	}
+}
+
+// Bind binds the connection which Start reads from, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (s *Udp) Bind() (net.Addr, error) {
+	if s.conn == nil {
+		conn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", s.Addr, s.Port))
+		if err != nil {
+			return nil, err
+		}
+
+		s.conn = conn
+	}
+
+	return s.conn.LocalAddr(), nil
+}
+
+func (s *Udp) quitRenderers() {
+	for _, quit := range s.rendererQuitChannels {
+		quit <- 1
+	}
+}
+
+// serve reads datagrams from the connection until it is closed.
+func (s *Udp) serve(conn net.PacketConn) error {
+	reply := unescapeReply(s.Reply)
+
+	return servePackets(conn, func(addr net.Addr, data []byte) {
+		s.logDatagram(addr.String(), data)
+
+		if s.Echo {
+			conn.WriteTo(data, addr)
+		}
+
+		if len(reply) > 0 {
+			conn.WriteTo(reply, addr)
+		}
+	})
+}
+
+// logDatagram sends an incoming datagram to the render channel. The source address is
+// used as the url so the printer shows where it came from.
+func (s *Udp) logDatagram(remoteAddr string, data []byte) {
+	req := RequestPayload{
+		ID: uuid.New().String(),
+		Fields: logrequest.RequestFields{
+			Method:        "RECEIVE",
+			Url:           remoteAddr,
+			RemoteAddress: remoteAddr,
+			Protocol:      "udp",
+			Time:          time.Now(),
+		},
+		CreatedAt: time.Now(),
+		Message:   formatData(data, s.Hex),
+		Size:      len(data),
+	}
+
+	for _, rendererChannel := range s.rendererChannels {
+		rendererChannel <- req
+	}
+}
+
+// servePackets calls handle for each datagram read from the connection until it is
+// closed. The data passed to handle is only valid until it returns.
+func servePackets(conn net.PacketConn, handle func(net.Addr, []byte)) error {
+	defer conn.Close()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		handle(addr, buf[:n])
+	}
+}
@@ -0,0 +1,135 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startUdp serves the protocol on a random port and returns the address.
+func startUdp(t *testing.T, s *Udp) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go s.serve(conn)
+
+	return conn.LocalAddr().String()
+}
+
+func TestUdpDatagram(t *testing.T) {
+	testTable := []struct {
+		hex      bool
+		data     []byte
+		expected string
+	}{
+		{false, []byte("page.views:1|c"), "page.views:1|c"},
+		{true, []byte{0xde, 0xad, 0xbe, 0xef}, strings.TrimSuffix(hex.Dump([]byte{0xde, 0xad, 0xbe, 0xef}), "\n")},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 1)
+		udpServer := &Udp{Hex: test.hex, rendererChannels: []chan RequestPayload{rpChannel}}
+		addr := startUdp(t, udpServer)
+
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write(test.data)
+		rp := <-rpChannel
+
+		if rp.Fields.Method != "RECEIVE" {
+			t.Errorf("Expected %s, got %s", "RECEIVE", rp.Fields.Method)
+		}
+
+		if rp.Message != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, rp.Message)
+		}
+
+		if rp.Size != len(test.data) {
+			t.Errorf("Expected %d, got %d", len(test.data), rp.Size)
+		}
+
+		if rp.Fields.RemoteAddress != conn.LocalAddr().String() {
+			t.Errorf("Expected %s, got %s", conn.LocalAddr().String(), rp.Fields.RemoteAddress)
+		}
+
+		conn.Close()
+	}
+}
+
+func TestUdpReply(t *testing.T) {
+	testTable := []struct {
+		echo     bool
+		reply    string
+		expected []string
+	}{
+		{true, "", []string{"hello"}},
+		{false, `ok\n`, []string{"ok\n"}},
+		{true, "ok", []string{"hello", "ok"}},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 1)
+		udpServer := &Udp{Echo: test.echo, Reply: test.reply, rendererChannels: []chan RequestPayload{rpChannel}}
+		addr := startUdp(t, udpServer)
+
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Write([]byte("hello"))
+		<-rpChannel
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		for _, expected := range test.expected {
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf[:n]) != expected {
+				t.Errorf("Expected %q, got %q", expected, string(buf[:n]))
+			}
+		}
+
+		conn.Close()
+	}
+}
+
+func TestUdpQuitRenderers(t *testing.T) {
+	q1 := make(chan int, 1)
+	q2 := make(chan int, 1)
+	chans := []chan int{q1, q2}
+
+	udpServer := Udp{rendererQuitChannels: chans}
+	udpServer.quitRenderers()
+	expectedQ1 := <-q1
+	expectedQ2 := <-q2
+
+	if expectedQ1 != 1 || expectedQ2 != 1 {
+		t.Error("Expected channel to receive quit signal")
+	}
+}
+
+func TestUdpBind(t *testing.T) {
+	udpServer := Udp{Addr: "127.0.0.1"}
+
+	addr, err := udpServer.Bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udpServer.conn.Close()
+
+	if addr.(*net.UDPAddr).Port == 0 {
+		t.Errorf("Expected a port to be picked")
+	}
+}
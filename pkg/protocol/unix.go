@@ -0,0 +1,133 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// PeerCredentials are the credentials of the process on the other end of a Unix domain
+// socket, where the OS provides them.
+type PeerCredentials struct {
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+
+	// Pid is 0 when the OS does not provide it.
+	Pid int `json:"pid"`
+}
+
+// String returns the credentials as uid=501 gid=20 pid=1234.
+func (p PeerCredentials) String() string {
+	str := fmt.Sprintf("uid=%d gid=%d", p.Uid, p.Gid)
+	if p.Pid > 0 {
+		str = fmt.Sprintf("%s pid=%d", str, p.Pid)
+	}
+
+	return str
+}
+
+// listen binds the address and port, or the Unix socket at socketPath if it is set.
+func listen(addr string, port int, socketPath string, mode os.FileMode) (net.Listener, error) {
+	if socketPath == "" {
+		return net.Listen("tcp", fmt.Sprintf("%s:%d", addr, port))
+	}
+
+	return listenUnix(socketPath, mode)
+}
+
+// listenUnix binds the Unix socket at path and sets its permissions to mode.
+//
+// A socket left behind by a previous run is removed, unless another process is still
+// accepting connections on it. The socket is removed when rh is interrupted, as the
+// listener would otherwise never be closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s already exists and is not a socket", path)
+		}
+
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is already in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	RemoveOnSignal(path)
+
+	return ln, nil
+}
+
+// RemoveOnSignal removes the file at path when rh receives an interrupt or terminate
+// signal. The signal is then raised again so that rh exits as it would have.
+func RemoveOnSignal(path string) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigs
+		os.Remove(path)
+		signal.Stop(sigs)
+
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	}()
+}
+
+// peerCredentialsKey is the context key for the peer credentials of a connection.
+type peerCredentialsKey struct{}
+
+// connKey is the context key for the connection a request was received on.
+type connKey struct{}
+
+// connContext adds the connection, and the peer credentials of Unix socket connections,
+// to the context of their requests. The credentials of connections wrapped in TLS are
+// not read. Tapped connections are added as the connection they tap.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	if tap, ok := c.(*frameTap); ok {
+		ctx = context.WithValue(ctx, frameTapKey{}, tap)
+		c = tap.Conn
+	}
+
+	ctx = context.WithValue(ctx, connKey{}, c)
+
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	creds, err := peerCredentials(uc)
+	if err != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, peerCredentialsKey{}, creds)
+}
+
+// requestPeerCredentials returns the peer credentials of the connection the request was
+// received on, or nil if there are none.
+func requestPeerCredentials(r *http.Request) *PeerCredentials {
+	creds, _ := r.Context().Value(peerCredentialsKey{}).(*PeerCredentials)
+	return creds
+}
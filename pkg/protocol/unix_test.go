@@ -0,0 +1,142 @@
+package protocol
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rh.sock")
+
+	ln, err := listenUnix(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		t.Errorf("Expected %s to be a socket", path)
+	}
+
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode %o, got %o", 0600, fi.Mode().Perm())
+	}
+
+	_, err = listenUnix(path, 0600)
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("Expected socket in use error, got %v", err)
+	}
+
+	ln.Close()
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rh.sock")
+
+	// A listener that does not unlink its socket, like a previous run that was killed.
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	ln, err = listenUnix(path, 0)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %s", err)
+	}
+	ln.Close()
+}
+
+func TestListenUnixNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rh.sock")
+	if err := ioutil.WriteFile(path, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := listenUnix(path, 0)
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Expected not a socket error, got %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected file to be kept, got %s", err)
+	}
+}
+
+func TestHttpUnixSocketPeerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("peer credentials are not supported on", runtime.GOOS)
+	}
+
+	path := filepath.Join(t.TempDir(), "rh.sock")
+	rpChan := make(chan RequestPayload, 1)
+	httpServer := Http{ResponseCode: 200, rendererChannels: []chan RequestPayload{rpChan}}
+
+	ln, err := listenUnix(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: httpServer.routes(), ConnContext: connContext}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+
+	resp, err := client.Get("http://unix/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	rp := <-rpChan
+	if rp.Peer == nil {
+		t.Fatal("Expected peer credentials")
+	}
+
+	if rp.Peer.Uid != os.Getuid() {
+		t.Errorf("Expected uid %d, got %d", os.Getuid(), rp.Peer.Uid)
+	}
+
+	if runtime.GOOS == "linux" {
+		if rp.Peer.Gid != os.Getgid() {
+			t.Errorf("Expected gid %d, got %d", os.Getgid(), rp.Peer.Gid)
+		}
+
+		if rp.Peer.Pid != os.Getpid() {
+			t.Errorf("Expected pid %d, got %d", os.Getpid(), rp.Peer.Pid)
+		}
+	}
+}
+
+func TestPeerCredentialsString(t *testing.T) {
+	tests := []struct {
+		peer     PeerCredentials
+		expected string
+	}{
+		{PeerCredentials{Uid: 501, Gid: 20, Pid: 1234}, "uid=501 gid=20 pid=1234"},
+		{PeerCredentials{Uid: 0, Gid: 0}, "uid=0 gid=0"},
+	}
+
+	for _, test := range tests {
+		if result := test.peer.String(); result != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, result)
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package protocol
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aaronvb/logparams"
@@ -24,6 +31,70 @@ type Ws struct {
 	// Port is the port the WS server will run on.
 	Port int
 
+	// UnixSocket is the path of a Unix domain socket to listen on instead of the
+	// address and port.
+	UnixSocket string
+
+	// UnixSocketMode sets the permissions of the Unix socket.
+	UnixSocketMode os.FileMode
+
+	// Subprotocols are the subprotocols the server offers during the handshake, in
+	// order of preference. The first one also requested by the client is selected.
+	Subprotocols []string
+
+	// EnableCompression negotiates permessage-deflate with clients that request it.
+	EnableCompression bool
+
+	// ReadLimit is the maximum size in bytes of a message read from a client. Messages
+	// over the limit close the connection. Default is 0, which means no limit.
+	ReadLimit int64
+
+	// AllowedOrigins restricts which Origin headers are accepted during the handshake.
+	// Entries can be a full origin(ie: https://example.com) or a host. Default is
+	// empty, which accepts any origin.
+	AllowedOrigins []string
+
+	// HandshakeDelay delays every handshake before the connection is upgraded.
+	HandshakeDelay time.Duration
+
+	// RejectStatus is the HTTP status returned when an upgrade is rejected. If it is set
+	// without RefuseEvery, every upgrade is rejected. Defaults to 503 when only
+	// RefuseEvery is set.
+	RejectStatus int
+
+	// RefuseEvery rejects every Nth connection with the RejectStatus.
+	RefuseEvery int
+
+	// DropAfterMessages drops the connection after N messages are received from the client.
+	DropAfterMessages int
+
+	// DropAfter drops the connection once it has been open for the given duration.
+	DropAfter time.Duration
+
+	// CloseCode is sent in a close frame when a connection is dropped. Default is 0,
+	// which drops the connection without a close frame.
+	CloseCode int
+
+	// GraphQL enables the GraphQL over WebSocket mock server, which understands the
+	// graphql-transport-ws and graphql-ws protocols.
+	GraphQL bool
+
+	// GraphQLFixtures are the results we reply with to GraphQL operations.
+	GraphQLFixtures GraphQLFixtures
+
+	// GraphQLInterval is the delay between each result sent for an operation.
+	GraphQLInterval time.Duration
+
+	// connections counts the incoming connections so we can refuse every Nth one.
+	connections uint64
+
+	// listener is bound by Bind, before the server starts.
+	listener net.Listener
+
+	// live has the settings new connections are accepted with once they were reloaded.
+	live   *Ws
+	liveMu sync.RWMutex
+
 	// rendererChannel is the channel which we send a RequestPayload to when
 	// receiving an incoming request to the Http protocol.
 	rendererChannels     []chan RequestPayload
@@ -45,13 +116,18 @@ func (ws *Ws) Start(c []chan RequestPayload, quits []chan int, errors []chan int
 		ErrorLog:    errorLog,
 		Handler:     ws.routes(),
 		IdleTimeout: 30 * time.Second,
+		ConnContext: connContext,
 	}
 
 	ws.rendererChannels = c
 	ws.rendererQuitChannels = quits
 
 	go func() {
-		err := srv.ListenAndServe()
+		_, err := ws.Bind()
+		if err == nil {
+			err = srv.Serve(ws.listener)
+		}
+
 		str := pterm.Error.WithShowLineNumber(false).Sprintf("Websocket Protocol: %s\n", err)
 		pterm.Printo(str) // Overwrite last line
 
@@ -68,6 +144,21 @@ func (ws *Ws) Start(c []chan RequestPayload, quits []chan int, errors []chan int
 	}
 }
 
+// Bind binds the listener which Start serves on, and returns its address. This lets
+// the address be shown before the server starts, such as the port picked for port 0.
+func (ws *Ws) Bind() (net.Addr, error) {
+	if ws.listener == nil {
+		ln, err := listen(ws.Addr, ws.Port, ws.UnixSocket, ws.UnixSocketMode)
+		if err != nil {
+			return nil, err
+		}
+
+		ws.listener = ln
+	}
+
+	return ws.listener.Addr(), nil
+}
+
 func (ws *Ws) quitRenderers() {
 	for _, quit := range ws.rendererQuitChannels {
 		quit <- 1
@@ -89,30 +180,82 @@ func (ws *Ws) routes() http.Handler {
 // connection. Once the connection is established, it will send all incoming messages to
 // the renderer channel.
 func (ws *Ws) defaultHandler(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddUint64(&ws.connections, 1)
+
+	// The connection keeps the settings it was accepted with if they are reloaded.
+	ws = ws.current()
+
+	if ws.HandshakeDelay > 0 {
+		ws.logMessage("FAULT", fmt.Sprintf("delayed handshake of connection %d by %s", n, ws.HandshakeDelay))
+		time.Sleep(ws.HandshakeDelay)
+	}
+
+	if status, ok := ws.rejectStatus(n); ok {
+		ws.logMessage("FAULT", fmt.Sprintf("rejected connection %d with status %d", n, status))
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		Subprotocols:      ws.subprotocols(),
+		EnableCompression: ws.EnableCompression,
+		CheckOrigin:       ws.checkOrigin,
 	}
 
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		ptermErr := pterm.Error.WithShowLineNumber(false).Sprintln(err)
-		pterm.Printo(ptermErr)
-
+		// The upgrader has already responded to the client with an error status.
+		ws.logMessage("ERROR", err.Error())
+		return
 	}
 
 	defer func(c *websocket.Conn) {
 		err := c.Close()
-		if err != nil {
+		// Dropped connections have already been closed.
+		if err != nil && !errors.Is(err, net.ErrClosed) {
 			ptermErr := pterm.Error.WithShowLineNumber(false).Sprintln(err)
 			pterm.Printo(ptermErr)
 		}
 	}(c)
 
+	if ws.ReadLimit > 0 {
+		c.SetReadLimit(ws.ReadLimit)
+	}
+
+	ws.logConnected(c, r)
+
+	var once sync.Once
+	var dropped int32
+	drop := func(reason string) {
+		once.Do(func() {
+			atomic.StoreInt32(&dropped, 1)
+			ws.drop(c, reason)
+		})
+	}
+
+	if ws.DropAfter > 0 {
+		timer := time.AfterFunc(ws.DropAfter, func() {
+			drop(fmt.Sprintf("after %s", ws.DropAfter))
+		})
+		defer timer.Stop()
+	}
+
+	var session *graphqlSession
+	if ws.GraphQL {
+		session = ws.newGraphQLSession(c)
+		defer session.close()
+	}
+
+	received := 0
 	for {
 		_, message, err := c.ReadMessage()
 		if err != nil {
+			// Dropping the connection logged a FAULT, and the session logged
+			// closing it.
+			if atomic.LoadInt32(&dropped) == 1 || (session != nil && session.closedConn()) {
+				break
+			}
+
 			closeErrors := []int{websocket.CloseNormalClosure}
 			if websocket.IsCloseError(err, closeErrors...) {
 				ws.logMessage("DISCONNECTED", err.Error())
@@ -123,7 +266,138 @@ func (ws *Ws) defaultHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Log incoming WS message
-		ws.logMessage("RECEIVE", string(message))
+		if session != nil {
+			session.handle(message)
+		} else {
+			ws.logMessage("RECEIVE", string(message))
+		}
+
+		received++
+		if ws.DropAfterMessages > 0 && received >= ws.DropAfterMessages {
+			drop(fmt.Sprintf("after %d messages", received))
+		}
+	}
+}
+
+// rejectStatus returns the status to reject the Nth connection with, and false if the
+// connection should be accepted.
+func (ws *Ws) rejectStatus(n uint64) (int, bool) {
+	if ws.RefuseEvery > 0 {
+		if n%uint64(ws.RefuseEvery) != 0 {
+			return 0, false
+		}
+
+		if ws.RejectStatus > 0 {
+			return ws.RejectStatus, true
+		}
+
+		return http.StatusServiceUnavailable, true
+	}
+
+	return ws.RejectStatus, ws.RejectStatus > 0
+}
+
+// drop closes the connection as an injected fault. If a close code is set we send a
+// close frame first, otherwise the underlying connection is closed abruptly.
+func (ws *Ws) drop(c *websocket.Conn, reason string) {
+	if ws.CloseCode > 0 {
+		msg := websocket.FormatCloseMessage(ws.CloseCode, "rh fault injection")
+		c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		ws.logMessage("FAULT", fmt.Sprintf("dropped connection %s with close code %d", reason, ws.CloseCode))
+	} else {
+		ws.logMessage("FAULT", fmt.Sprintf("dropped connection %s", reason))
+	}
+
+	c.Close()
+}
+
+// subprotocols returns the subprotocols offered during the handshake. GraphQL mode
+// offers the GraphQL protocols unless subprotocols are passed.
+func (ws *Ws) subprotocols() []string {
+	if ws.GraphQL && len(ws.Subprotocols) == 0 {
+		return graphqlSubprotocols
+	}
+
+	return ws.Subprotocols
+}
+
+// checkOrigin is used by the upgrader to accept or reject the Origin header of the
+// handshake. Requests without an Origin header are not from a browser and are accepted.
+func (ws *Ws) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(ws.AllowedOrigins) == 0 || origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range ws.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiatedExtensions returns the extensions the upgrader accepted for the request.
+// Gorilla does not expose these on the connection, so we mirror its negotiation, which
+// only supports permessage-deflate without context takeover.
+func (ws *Ws) negotiatedExtensions(r *http.Request) []string {
+	if !ws.EnableCompression {
+		return nil
+	}
+
+	for _, header := range r.Header["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(header, ",") {
+			name := strings.TrimSpace(strings.Split(ext, ";")[0])
+			if strings.EqualFold(name, "permessage-deflate") {
+				return []string{"permessage-deflate; server_no_context_takeover; client_no_context_takeover"}
+			}
+		}
+	}
+
+	return nil
+}
+
+// logConnected sends the CONNECTED event to the renderers once the handshake succeeds.
+// The negotiated subprotocol and extensions are recorded as the response headers.
+func (ws *Ws) logConnected(c *websocket.Conn, r *http.Request) {
+	headers := make(map[string][]string)
+	subprotocol := c.Subprotocol()
+	extensions := ws.negotiatedExtensions(r)
+
+	if subprotocol != "" {
+		headers["Sec-Websocket-Protocol"] = []string{subprotocol}
+	}
+
+	if len(extensions) > 0 {
+		headers["Sec-Websocket-Extensions"] = extensions
+	}
+
+	msg := make([]string, 0, 2)
+	if subprotocol != "" {
+		msg = append(msg, fmt.Sprintf("subprotocol: %s", subprotocol))
+	}
+
+	if len(extensions) > 0 {
+		msg = append(msg, fmt.Sprintf("extensions: %s", strings.Join(extensions, ", ")))
+	}
+
+	req := RequestPayload{
+		ID:        uuid.New().String(),
+		Fields:    logrequest.RequestFields{Method: "CONNECTED", Url: r.URL.RequestURI()},
+		Headers:   headers,
+		CreatedAt: time.Now(),
+		Message:   strings.Join(msg, ", "),
+		Peer:      requestPeerCredentials(r),
+	}
+
+	for _, rendererChannel := range ws.rendererChannels {
+		rendererChannel <- req
 	}
 }
 
@@ -144,6 +418,7 @@ func (ws *Ws) logRequest(next http.Handler) http.Handler {
 			Headers:     r.Header,
 			ParamFields: params.ToFields(),
 			CreatedAt:   time.Now(),
+			Peer:        requestPeerCredentials(r),
 		}
 
 		for _, rendererChannel := range ws.rendererChannels {
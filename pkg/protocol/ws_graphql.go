@@ -0,0 +1,382 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/aaronvb/logparams"
+	"github.com/aaronvb/logrequest"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// graphqlSubprotocols are offered when GraphQL mode is enabled and no subprotocols
+// are passed. graphql-transport-ws is the graphql-ws library protocol, graphql-ws is the
+// legacy subscriptions-transport-ws protocol used by older Apollo clients.
+var graphqlSubprotocols = []string{"graphql-transport-ws", "graphql-ws"}
+
+// GraphQLFixtures maps an operation name to the results we reply with. Each result is
+// the JSON execution result, ie: {"data": {"messageAdded": {"id": "1"}}}. String values
+// are templates which can use the operation, ie: "{{.Variables.room}}". The "*" key is
+// used for operations without their own results.
+type GraphQLFixtures map[string][]interface{}
+
+// graphqlTemplateData is passed to the fixture templates when a result is sent.
+type graphqlTemplateData struct {
+	ID            string
+	Index         int
+	OperationName string
+	Variables     map[string]interface{}
+}
+
+// LoadGraphQLFixtures reads a JSON fixture file which maps operation names to a list of
+// results, and parses the templates in each result.
+func LoadGraphQLFixtures(path string) (GraphQLFixtures, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string][]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("graphql fixture %s: %w", path, err)
+	}
+
+	fixtures := make(GraphQLFixtures, len(raw))
+	for name, results := range raw {
+		for _, result := range results {
+			parsed, err := parseGraphQLTemplates(result)
+			if err != nil {
+				return nil, fmt.Errorf("graphql fixture %s: %w", path, err)
+			}
+
+			fixtures[name] = append(fixtures[name], parsed)
+		}
+	}
+
+	return fixtures, nil
+}
+
+// results returns the results for the operation, falling back to the "*" key.
+func (f GraphQLFixtures) results(operationName string) []interface{} {
+	if results, ok := f[operationName]; ok {
+		return results
+	}
+
+	return f["*"]
+}
+
+// parseGraphQLTemplates walks a decoded JSON value and replaces strings containing
+// template actions with the parsed template.
+func parseGraphQLTemplates(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+
+		return template.New("").Option("missingkey=zero").Parse(value)
+	case map[string]interface{}:
+		parsed := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			c, err := parseGraphQLTemplates(child)
+			if err != nil {
+				return nil, err
+			}
+			parsed[k] = c
+		}
+
+		return parsed, nil
+	case []interface{}:
+		parsed := make([]interface{}, len(value))
+		for i, child := range value {
+			c, err := parseGraphQLTemplates(child)
+			if err != nil {
+				return nil, err
+			}
+			parsed[i] = c
+		}
+
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// renderGraphQLTemplates walks a parsed fixture result and executes its templates.
+func renderGraphQLTemplates(v interface{}, data graphqlTemplateData) (interface{}, error) {
+	switch value := v.(type) {
+	case *template.Template:
+		var buf bytes.Buffer
+		if err := value.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+
+		return buf.String(), nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			c, err := renderGraphQLTemplates(child, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = c
+		}
+
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, child := range value {
+			c, err := renderGraphQLTemplates(child, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = c
+		}
+
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+// graphqlMessage is a message of the graphql-transport-ws and graphql-ws protocols.
+type graphqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlOperation is the payload of a subscribe(or start) message.
+type graphqlOperation struct {
+	OperationName string                 `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlSession holds the state of a single GraphQL WebSocket connection.
+type graphqlSession struct {
+	ws   *Ws
+	conn *websocket.Conn
+
+	// legacy is true when the client speaks the subscriptions-transport-ws protocol.
+	legacy bool
+
+	// mu guards writes to the connection and the operations map.
+	mu sync.Mutex
+
+	// operations contain a stop channel for each running operation, keyed by id.
+	operations map[string]chan struct{}
+
+	// closed is set once the session closes the connection.
+	closed int32
+}
+
+// newGraphQLSession creates a session for the upgraded connection.
+func (ws *Ws) newGraphQLSession(c *websocket.Conn) *graphqlSession {
+	return &graphqlSession{
+		ws:         ws,
+		conn:       c,
+		legacy:     c.Subprotocol() == "graphql-ws",
+		operations: make(map[string]chan struct{}),
+	}
+}
+
+// handle logs an incoming message and replies the way a GraphQL server would.
+func (s *graphqlSession) handle(message []byte) {
+	var msg graphqlMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.Type == "" {
+		s.ws.logMessage("RECEIVE", string(message))
+		return
+	}
+
+	switch msg.Type {
+	case "connection_init":
+		s.ws.logMessage("CONNECTION_INIT", string(msg.Payload))
+		s.write(graphqlMessage{Type: "connection_ack"})
+	case "ping":
+		s.ws.logMessage("PING", string(msg.Payload))
+		s.write(graphqlMessage{Type: "pong"})
+	case "subscribe", "start":
+		if msg.Type == "start" {
+			s.legacy = true
+		}
+
+		var op graphqlOperation
+		if err := json.Unmarshal(msg.Payload, &op); err != nil {
+			s.ws.logMessage("ERROR", fmt.Sprintf("invalid %s payload: %s", msg.Type, err))
+			return
+		}
+
+		s.ws.logOperation(msg.ID, op)
+		s.start(msg.ID, op)
+	case "complete", "stop":
+		s.ws.logMessage("COMPLETE", msg.ID)
+		s.stop(msg.ID)
+	default:
+		s.ws.logMessage(strings.ToUpper(msg.Type), string(message))
+	}
+}
+
+// start sends the fixture results for the operation, followed by a complete message.
+// Operations without results stay open until the client completes them. An id which is
+// already running closes the connection with 4409, as graphql-transport-ws requires, and
+// replaces the running operation with the legacy protocol.
+func (s *graphqlSession) start(id string, op graphqlOperation) {
+	stop := make(chan struct{})
+
+	s.mu.Lock()
+	running, ok := s.operations[id]
+	if ok && !s.legacy {
+		s.mu.Unlock()
+		s.closeConn(4409, fmt.Sprintf("Subscriber for %s already exists", id))
+		return
+	}
+
+	if ok {
+		close(running)
+	}
+	s.operations[id] = stop
+	s.mu.Unlock()
+
+	results := s.ws.GraphQLFixtures.results(op.OperationName)
+	if len(results) == 0 {
+		return
+	}
+
+	nextType := "next"
+	if s.legacy {
+		nextType = "data"
+	}
+
+	go func() {
+		for i, result := range results {
+			if i > 0 && s.ws.GraphQLInterval > 0 {
+				select {
+				case <-stop:
+					return
+				case <-time.After(s.ws.GraphQLInterval):
+				}
+			}
+
+			data := graphqlTemplateData{ID: id, Index: i, OperationName: op.OperationName, Variables: op.Variables}
+			rendered, err := renderGraphQLTemplates(result, data)
+			if err != nil {
+				s.ws.logMessage("ERROR", err.Error())
+				continue
+			}
+
+			payload, _ := json.Marshal(rendered)
+			if !s.send(id, stop, graphqlMessage{ID: id, Type: nextType, Payload: payload}) {
+				return
+			}
+			s.ws.logMessage(strings.ToUpper(nextType), string(payload))
+		}
+
+		s.send(id, stop, graphqlMessage{ID: id, Type: "complete"})
+	}()
+}
+
+// send writes a message of the operation started with the stop channel, and returns
+// false if it is no longer running. Checking and writing under the lock keeps messages
+// from being sent after the client completes the operation, and the complete message
+// ends the operation as it is sent, so nothing follows it.
+func (s *graphqlSession) send(id string, stop chan struct{}, msg graphqlMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.operations[id] != stop {
+		return false
+	}
+
+	if msg.Type == "complete" {
+		delete(s.operations, id)
+	}
+
+	s.writeLocked(msg)
+
+	return true
+}
+
+// stop cancels a running operation.
+func (s *graphqlSession) stop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stop, ok := s.operations[id]; ok {
+		close(stop)
+		delete(s.operations, id)
+	}
+}
+
+// close cancels all running operations, called when the connection closes.
+func (s *graphqlSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stop := range s.operations {
+		close(stop)
+		delete(s.operations, id)
+	}
+}
+
+// closeConn closes the connection with a close frame, for the errors the protocol
+// closes the connection on.
+func (s *graphqlSession) closeConn(code int, text string) {
+	atomic.StoreInt32(&s.closed, 1)
+	s.ws.logMessage("DISCONNECTED", fmt.Sprintf("close %d: %s", code, text))
+
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	s.conn.Close()
+}
+
+// closedConn returns true if the session closed the connection, which was logged then.
+func (s *graphqlSession) closedConn() bool {
+	return atomic.LoadInt32(&s.closed) == 1
+}
+
+// write sends a message to the client. Gorilla connections support one concurrent
+// writer, so writes are serialized.
+func (s *graphqlSession) write(msg graphqlMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writeLocked(msg)
+}
+
+// writeLocked sends a message to the client, with mu held.
+func (s *graphqlSession) writeLocked(msg graphqlMessage) {
+	if err := s.conn.WriteJSON(msg); err != nil {
+		s.ws.logMessage("ERROR", err.Error())
+	}
+}
+
+// logOperation sends a subscribe operation to the renderers. The operation is kept as
+// structured JSON params, and as a compact JSON message for the printer and log file.
+func (ws *Ws) logOperation(id string, op graphqlOperation) {
+	msg, _ := json.Marshal(op)
+
+	req := RequestPayload{
+		ID:     uuid.New().String(),
+		Fields: logrequest.RequestFields{Method: "SUBSCRIBE"},
+		ParamFields: logparams.ParamFields{Json: map[string]interface{}{
+			"id":            id,
+			"operationName": op.OperationName,
+			"query":         op.Query,
+			"variables":     op.Variables,
+		}},
+		CreatedAt: time.Now(),
+		Message:   string(msg),
+	}
+
+	for _, rendererChannel := range ws.rendererChannels {
+		rendererChannel <- req
+	}
+}
@@ -0,0 +1,287 @@
+package protocol
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func writeGraphQLFixture(t *testing.T, fixture string) GraphQLFixtures {
+	dir, err := ioutil.TempDir("", "rh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "fixtures.json")
+	if err := ioutil.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadGraphQLFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fixtures
+}
+
+func TestLoadGraphQLFixturesInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testTable := []string{
+		`{"OnMessage": {"data": null}}`,
+		`{"OnMessage": [{"data": "{{.Variables"}]}`,
+	}
+
+	for _, fixture := range testTable {
+		path := filepath.Join(dir, "fixtures.json")
+		if err := ioutil.WriteFile(path, []byte(fixture), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadGraphQLFixtures(path); err == nil {
+			t.Errorf("Expected error for fixture %s", fixture)
+		}
+	}
+}
+
+func TestWsGraphQL(t *testing.T) {
+	testTable := []struct {
+		subprotocol string
+		subscribe   string
+		next        string
+	}{
+		{"graphql-transport-ws", "subscribe", "next"},
+		{"graphql-ws", "start", "data"},
+	}
+
+	fixtures := writeGraphQLFixture(t, `{
+		"OnMessage": [
+			{"data": {"message": {"room": "{{.Variables.room}}", "index": "{{.Index}}"}}},
+			{"data": {"message": {"room": "{{.Variables.room}}", "index": "{{.Index}}"}}}
+		]
+	}`)
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 20)
+		wsServer := Ws{GraphQL: true, GraphQLFixtures: fixtures, rendererChannels: []chan RequestPayload{rpChannel}}
+		srv := httptest.NewServer(wsServer.routes())
+
+		dialer := websocket.Dialer{Subprotocols: []string{test.subprotocol}}
+		wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+		wsReq, _, err := dialer.Dial(wsUrl, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if wsReq.Subprotocol() != test.subprotocol {
+			t.Errorf("Expected %s, got %s", test.subprotocol, wsReq.Subprotocol())
+		}
+
+		wsReq.WriteJSON(graphqlMessage{Type: "connection_init"})
+		var ack graphqlMessage
+		if err := wsReq.ReadJSON(&ack); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if ack.Type != "connection_ack" {
+			t.Errorf("Expected %s, got %s", "connection_ack", ack.Type)
+		}
+
+		payload := `{"operationName":"OnMessage","query":"subscription OnMessage($room: String!) { message(room: $room) { room } }","variables":{"room":"general"}}`
+		wsReq.WriteJSON(graphqlMessage{ID: "1", Type: test.subscribe, Payload: json.RawMessage(payload)})
+
+		for i := 0; i < 2; i++ {
+			var next graphqlMessage
+			if err := wsReq.ReadJSON(&next); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if next.Type != test.next || next.ID != "1" {
+				t.Errorf("Expected %s with id 1, got %s with id %s", test.next, next.Type, next.ID)
+			}
+
+			var result struct {
+				Data struct {
+					Message struct {
+						Room  string
+						Index string
+					}
+				}
+			}
+			json.Unmarshal(next.Payload, &result)
+			if result.Data.Message.Room != "general" || result.Data.Message.Index != strconv.Itoa(i) {
+				t.Errorf("Expected room general with index %d, got %s", i, string(next.Payload))
+			}
+		}
+
+		var complete graphqlMessage
+		if err := wsReq.ReadJSON(&complete); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if complete.Type != "complete" {
+			t.Errorf("Expected %s, got %s", "complete", complete.Type)
+		}
+
+		expected := []string{"GET", "CONNECTED", "CONNECTION_INIT", "SUBSCRIBE"}
+		for _, method := range expected {
+			rp := <-rpChannel
+			if rp.Fields.Method != method {
+				t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+			}
+
+			if method == "SUBSCRIBE" {
+				if rp.ParamFields.Json["operationName"] != "OnMessage" {
+					t.Errorf("Expected %s, got %v", "OnMessage", rp.ParamFields.Json["operationName"])
+				}
+
+				if !strings.HasPrefix(rp.ParamFields.Json["query"].(string), "subscription OnMessage") {
+					t.Errorf("Expected query, got %v", rp.ParamFields.Json["query"])
+				}
+
+				variables := rp.ParamFields.Json["variables"].(map[string]interface{})
+				if variables["room"] != "general" {
+					t.Errorf("Expected %s, got %v", "general", variables["room"])
+				}
+			}
+		}
+
+		wsReq.Close()
+		srv.Close()
+	}
+}
+
+func TestWsGraphQLNoFixture(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := Ws{GraphQL: true, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := dialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer wsReq.Close()
+
+	wsReq.WriteJSON(graphqlMessage{Type: "ping"})
+	var pong graphqlMessage
+	if err := wsReq.ReadJSON(&pong); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if pong.Type != "pong" {
+		t.Errorf("Expected %s, got %s", "pong", pong.Type)
+	}
+
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"subscription { a }"}`)})
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "complete"})
+
+	expected := []string{"GET", "CONNECTED", "PING", "SUBSCRIBE", "COMPLETE"}
+	for _, method := range expected {
+		rp := <-rpChannel
+		if rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+		}
+	}
+}
+
+func TestWsGraphQLDuplicateID(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := Ws{GraphQL: true, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := dialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer wsReq.Close()
+
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"subscription { a }"}`)})
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"subscription { a }"}`)})
+
+	var msg graphqlMessage
+	err = wsReq.ReadJSON(&msg)
+	if !websocket.IsCloseError(err, 4409) {
+		t.Errorf("Expected close error 4409, got %v", err)
+	}
+
+	expected := []string{"GET", "CONNECTED", "SUBSCRIBE", "SUBSCRIBE", "DISCONNECTED"}
+	for _, method := range expected {
+		rp := <-rpChannel
+		if rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+		}
+	}
+
+	select {
+	case rp := <-rpChannel:
+		t.Errorf("Expected no more messages, got %s", rp.Fields.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWsGraphQLCompleteStopsOperation(t *testing.T) {
+	fixtures := writeGraphQLFixture(t, `{
+		"OnMessage": [{"data": {"index": "{{.Index}}"}}, {"data": {"index": "{{.Index}}"}}, {"data": {"index": "{{.Index}}"}}]
+	}`)
+
+	wsServer := Ws{GraphQL: true, GraphQLFixtures: fixtures, GraphQLInterval: 50 * time.Millisecond}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := dialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer wsReq.Close()
+
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"operationName":"OnMessage"}`)})
+
+	var next graphqlMessage
+	if err := wsReq.ReadJSON(&next); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if next.Type != "next" {
+		t.Errorf("Expected %s, got %s", "next", next.Type)
+	}
+
+	wsReq.WriteJSON(graphqlMessage{ID: "1", Type: "complete"})
+	wsReq.WriteJSON(graphqlMessage{Type: "ping"})
+
+	var pong graphqlMessage
+	if err := wsReq.ReadJSON(&pong); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if pong.Type != "pong" {
+		t.Errorf("Expected %s, got %s", "pong", pong.Type)
+	}
+
+	wsReq.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var msg graphqlMessage
+	if err := wsReq.ReadJSON(&msg); err == nil {
+		t.Errorf("Expected no messages after complete, got %s", msg.Type)
+	}
+}
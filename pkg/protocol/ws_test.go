@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -33,7 +34,7 @@ func TestWsLogRequestOneRenderer(t *testing.T) {
 		},
 	}
 
-	rpChannel := make(chan RequestPayload, len(testTable))
+	rpChannel := make(chan RequestPayload, len(testTable)*2)
 	wsServer := Ws{rendererChannels: []chan RequestPayload{rpChannel}}
 	srv := httptest.NewServer(wsServer.routes())
 	defer srv.Close()
@@ -50,6 +51,7 @@ func TestWsLogRequestOneRenderer(t *testing.T) {
 		defer wsReq.Close()
 
 		rp := <-rpChannel
+		<-rpChannel // CONNECTED
 
 		if rp.Fields.Method != test.method {
 			t.Errorf("Expected %s, got %s", test.method, rp.Fields.Method)
@@ -81,8 +83,8 @@ func TestWsLogRequestManyRenderers(t *testing.T) {
 		{http.MethodGet, "/foo/bar?hello=world", "{\"hello\" => \"world\"}"},
 	}
 
-	rpChannelA := make(chan RequestPayload, len(testTable))
-	rpChannelB := make(chan RequestPayload, len(testTable))
+	rpChannelA := make(chan RequestPayload, len(testTable)*2)
+	rpChannelB := make(chan RequestPayload, len(testTable)*2)
 	wsServer := Ws{rendererChannels: []chan RequestPayload{rpChannelA, rpChannelB}}
 	srv := httptest.NewServer(wsServer.routes())
 	defer srv.Close()
@@ -99,6 +101,8 @@ func TestWsLogRequestManyRenderers(t *testing.T) {
 
 		rpA := <-rpChannelA
 		rpB := <-rpChannelB
+		<-rpChannelA // CONNECTED
+		<-rpChannelB // CONNECTED
 
 		if rpA.Fields.Method != test.method {
 			t.Errorf("Expected %s, got %s", test.method, rpA.Fields.Method)
@@ -174,7 +178,7 @@ func TestWsLogMessage(t *testing.T) {
 		{"RECEIVE", "buzz"},
 	}
 
-	rpChannel := make(chan RequestPayload, len(testTable)+1)
+	rpChannel := make(chan RequestPayload, len(testTable)+2)
 	wsServer := Ws{rendererChannels: []chan RequestPayload{rpChannel}}
 	srv := httptest.NewServer(wsServer.routes())
 	defer srv.Close()
@@ -193,6 +197,11 @@ func TestWsLogMessage(t *testing.T) {
 		t.Errorf("Expected %s, got %s", "GET", wsRequest.Fields.Method)
 	}
 
+	wsConnected := <-rpChannel
+	if wsConnected.Fields.Method != "CONNECTED" {
+		t.Errorf("Expected %s, got %s", "CONNECTED", wsConnected.Fields.Method)
+	}
+
 	// Test each message
 	for _, test := range testTable {
 		if err := wsReq.WriteMessage(websocket.TextMessage, []byte(test.message)); err != nil {
@@ -210,3 +219,263 @@ func TestWsLogMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestWsSubprotocolNegotiation(t *testing.T) {
+	testTable := []struct {
+		offered     []string
+		requested   []string
+		compression bool
+		expected    string
+		extensions  bool
+	}{
+		{[]string{"graphql-ws", "mqtt"}, []string{"mqtt"}, false, "mqtt", false},
+		{[]string{"graphql-ws", "mqtt"}, []string{"mqtt", "graphql-ws"}, false, "graphql-ws", false},
+		{[]string{"graphql-ws"}, []string{"mqtt"}, false, "", false},
+		{nil, nil, true, "", true},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 3)
+		wsServer := Ws{
+			Subprotocols:      test.offered,
+			EnableCompression: test.compression,
+			rendererChannels:  []chan RequestPayload{rpChannel},
+		}
+		srv := httptest.NewServer(wsServer.routes())
+
+		dialer := websocket.Dialer{Subprotocols: test.requested, EnableCompression: test.compression}
+		wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+		wsReq, _, err := dialer.Dial(wsUrl, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		<-rpChannel // GET
+		rp := <-rpChannel
+
+		if rp.Fields.Method != "CONNECTED" {
+			t.Errorf("Expected %s, got %s", "CONNECTED", rp.Fields.Method)
+		}
+
+		if wsReq.Subprotocol() != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, wsReq.Subprotocol())
+		}
+
+		if test.expected != "" && rp.Headers["Sec-Websocket-Protocol"][0] != test.expected {
+			t.Errorf("Expected %s, got %v", test.expected, rp.Headers["Sec-Websocket-Protocol"])
+		}
+
+		if test.expected == "" && rp.Headers["Sec-Websocket-Protocol"] != nil {
+			t.Errorf("Expected no subprotocol, got %v", rp.Headers["Sec-Websocket-Protocol"])
+		}
+
+		if test.extensions != (rp.Headers["Sec-Websocket-Extensions"] != nil) {
+			t.Errorf("Expected extensions %t, got %v", test.extensions, rp.Headers["Sec-Websocket-Extensions"])
+		}
+
+		wsReq.Close()
+		srv.Close()
+	}
+}
+
+func TestWsAllowedOrigins(t *testing.T) {
+	testTable := []struct {
+		origin   string
+		accepted bool
+	}{
+		{"https://example.com", true},
+		{"http://app.example.com:3000", true},
+		{"https://evil.com", false},
+		{"", true},
+	}
+
+	for _, test := range testTable {
+		rpChannel := make(chan RequestPayload, 2)
+		wsServer := Ws{
+			AllowedOrigins:   []string{"https://example.com", "app.example.com:3000"},
+			rendererChannels: []chan RequestPayload{rpChannel},
+		}
+		srv := httptest.NewServer(wsServer.routes())
+
+		header := http.Header{}
+		if test.origin != "" {
+			header.Set("Origin", test.origin)
+		}
+
+		wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+		wsReq, resp, err := websocket.DefaultDialer.Dial(wsUrl, header)
+
+		<-rpChannel // GET
+		rp := <-rpChannel
+
+		if test.accepted {
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if rp.Fields.Method != "CONNECTED" {
+				t.Errorf("Expected %s, got %s", "CONNECTED", rp.Fields.Method)
+			}
+
+			wsReq.Close()
+		} else {
+			if err == nil {
+				t.Errorf("Expected handshake with origin %s to fail", test.origin)
+			}
+
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+			}
+
+			if rp.Fields.Method != "ERROR" {
+				t.Errorf("Expected %s, got %s", "ERROR", rp.Fields.Method)
+			}
+		}
+
+		srv.Close()
+	}
+}
+
+func TestWsRefuseEvery(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := Ws{RefuseEvery: 2, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	for i := 1; i <= 4; i++ {
+		wsReq, resp, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+
+		<-rpChannel // GET
+		rp := <-rpChannel
+
+		if i%2 == 0 {
+			if err == nil {
+				t.Fatalf("Expected connection %d to be refused", i)
+			}
+
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("Expected %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+			}
+
+			if rp.Fields.Method != "FAULT" {
+				t.Errorf("Expected %s, got %s", "FAULT", rp.Fields.Method)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if rp.Fields.Method != "CONNECTED" {
+				t.Errorf("Expected %s, got %s", "CONNECTED", rp.Fields.Method)
+			}
+
+			wsReq.Close()
+			<-rpChannel // ERROR from the closed connection
+		}
+	}
+}
+
+func TestWsRejectStatus(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 2)
+	wsServer := Ws{RejectStatus: http.StatusTooManyRequests, rendererChannels: []chan RequestPayload{rpChannel}}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	_, resp, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err == nil {
+		t.Fatal("Expected connection to be rejected")
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	<-rpChannel // GET
+	rp := <-rpChannel
+	if rp.Fields.Method != "FAULT" {
+		t.Errorf("Expected %s, got %s", "FAULT", rp.Fields.Method)
+	}
+}
+
+func TestWsDropAfterMessages(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := Ws{
+		DropAfterMessages: 2,
+		CloseCode:         websocket.CloseTryAgainLater,
+		rendererChannels:  []chan RequestPayload{rpChannel},
+	}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer wsReq.Close()
+
+	for _, msg := range []string{"one", "two"} {
+		if err := wsReq.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	_, _, err = wsReq.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Errorf("Expected close code %d, got %v", websocket.CloseTryAgainLater, err)
+	}
+
+	expected := []string{"GET", "CONNECTED", "RECEIVE", "RECEIVE", "FAULT"}
+	for _, method := range expected {
+		rp := <-rpChannel
+		if rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+		}
+	}
+
+	// The dropped connection is not logged again as an error.
+	select {
+	case rp := <-rpChannel:
+		t.Errorf("Expected no more messages, got %s", rp.Fields.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWsDropAfter(t *testing.T) {
+	rpChannel := make(chan RequestPayload, 10)
+	wsServer := Ws{
+		DropAfter:        10 * time.Millisecond,
+		HandshakeDelay:   10 * time.Millisecond,
+		rendererChannels: []chan RequestPayload{rpChannel},
+	}
+	srv := httptest.NewServer(wsServer.routes())
+	defer srv.Close()
+
+	wsUrl := strings.Replace(srv.URL, "http", "ws", 1)
+	wsReq, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer wsReq.Close()
+
+	if _, _, err = wsReq.ReadMessage(); err == nil {
+		t.Error("Expected connection to be dropped")
+	}
+
+	expected := []string{"GET", "FAULT", "CONNECTED", "FAULT"}
+	for _, method := range expected {
+		rp := <-rpChannel
+		if rp.Fields.Method != method {
+			t.Errorf("Expected %s, got %s", method, rp.Fields.Method)
+		}
+	}
+
+	// The dropped connection is not logged again as an error.
+	select {
+	case rp := <-rpChannel:
+		t.Errorf("Expected no more messages, got %s", rp.Fields.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -26,6 +26,10 @@ type Logger struct {
 	Addr string
 	Port int
 
+	// UnixSocket replaces the address and port in the start text when the protocol
+	// listens on a Unix domain socket.
+	UnixSocket string
+
 	// Protocol is the protocol the web UI server will use.
 	Protocol string
 
@@ -73,6 +77,10 @@ func (l *Logger) Start(wg *sync.WaitGroup, rp chan protocol.RequestPayload, q ch
 
 // startText returns the starting log string
 func (l *Logger) startText() string {
+	if l.UnixSocket != "" {
+		return fmt.Sprintf("Listening on %s+unix://%s", l.Protocol, l.UnixSocket)
+	}
+
 	return fmt.Sprintf("Listening on %s://%s:%d", l.Protocol, l.Addr, l.Port)
 }
 
@@ -87,18 +95,80 @@ func (l *Logger) incomingRequest(r protocol.RequestPayload) {
 	str := fmt.Sprintf("%s: %s\n", time.Now().Format("2006/02/01 15:04:05"), l.incomingRequestText(r))
 	l.logFile.WriteString(str)
 
+	// Validation errors are always logged, since they are what validation is for.
+	if r.Validation != nil {
+		for _, err := range r.Validation.Errors {
+			str := fmt.Sprintf("%s: Validation error %s\n", time.Now().Format("2006/02/01 15:04:05"), err)
+			l.logFile.WriteString(str)
+		}
+	}
+
 	if l.Details {
 		headersWithJoinedValues, keys := l.incomingRequestHeaders(r.Headers)
 		for _, key := range keys {
 			str := fmt.Sprintf("%s: %s: %s\n", time.Now().Format("2006/02/01 15:04:05"), key, headersWithJoinedValues[key])
 			l.logFile.WriteString(str)
 		}
+
+		trailersWithJoinedValues, keys := l.incomingRequestHeaders(r.Trailers)
+		for _, key := range keys {
+			str := fmt.Sprintf("%s: Trailer %s: %s\n", time.Now().Format("2006/02/01 15:04:05"), key, trailersWithJoinedValues[key])
+			l.logFile.WriteString(str)
+		}
+
+		for _, file := range r.Attachments {
+			str := fmt.Sprintf("%s: %s\n", time.Now().Format("2006/02/01 15:04:05"), l.attachmentText(file))
+			l.logFile.WriteString(str)
+		}
 	}
 }
 
 // incomingRequestText converts the RequestPayload into a printable string.
 func (l *Logger) incomingRequestText(r protocol.RequestPayload) string {
-	return fmt.Sprintf("%s %s %s", r.Fields.Method, r.Fields.Url, r.Message)
+	text := fmt.Sprintf("%s %s %s", r.Fields.Method, r.Fields.Url, r.Message)
+	if r.StreamID > 0 {
+		text = fmt.Sprintf("%s (%s, stream %d)", text, r.Fields.Protocol, r.StreamID)
+	}
+
+	if r.Fault != nil {
+		text = fmt.Sprintf("%s (chaos: %s)", text, r.Fault)
+	}
+
+	if r.Sequence != nil {
+		text = fmt.Sprintf("%s (%s)", text, r.Sequence)
+	}
+
+	if r.Validation != nil {
+		text = fmt.Sprintf("%s (%s)", text, r.Validation)
+	}
+
+	if r.Signature != nil {
+		text = fmt.Sprintf("%s (%s)", text, r.Signature)
+	}
+
+	if r.Encoding != nil {
+		text = fmt.Sprintf("%s (%s)", text, r.Encoding)
+	}
+
+	if len(r.Attachments) > 0 {
+		text = fmt.Sprintf("%s (%s)", text, pluralize(len(r.Attachments), "file"))
+	}
+
+	if r.Peer != nil {
+		text = fmt.Sprintf("%s (%s)", text, r.Peer)
+	}
+
+	return text
+}
+
+// attachmentText converts an uploaded file into a log string.
+func (l *Logger) attachmentText(file protocol.UploadedFile) string {
+	text := fmt.Sprintf("File %s: %s (%s, %s, sha256 %s)", file.Field, file.Filename, file.ContentType, pluralize(file.Size, "byte"), file.Sha256)
+	if file.Path != "" {
+		text = fmt.Sprintf("%s saved to %s", text, file.Path)
+	}
+
+	return text
 }
 
 // incomingRequestHeaders takes the headers from the request, sorts them alphabetically,
@@ -2,7 +2,9 @@ package renderer
 
 import (
 	"fmt"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aaronvb/logrequest"
@@ -19,6 +21,16 @@ func TestLoggerStartText(t *testing.T) {
 	}
 }
 
+func TestLoggerStartTextUnixSocket(t *testing.T) {
+	logger := Logger{Protocol: "ws", Addr: "localhost", Port: 8080, UnixSocket: "/tmp/rh.sock"}
+	text := logger.startText()
+	expected := "Listening on ws+unix:///tmp/rh.sock"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
 func TestLoggerIncomingRequest(t *testing.T) {
 	logger := Logger{}
 	fields := logrequest.RequestFields{
@@ -35,6 +47,148 @@ func TestLoggerIncomingRequest(t *testing.T) {
 	}
 }
 
+func TestLoggerIncomingRequestHttp2(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{
+		Method:   "GET",
+		Url:      "/foobar",
+		Protocol: "HTTP/2.0",
+	}
+	rp := protocol.RequestPayload{Fields: fields, StreamID: 3}
+	text := logger.incomingRequestText(rp)
+	expected := "GET /foobar  (HTTP/2.0, stream 3)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestPeer(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "GET", Url: "/foobar"}
+	peer := &protocol.PeerCredentials{Uid: 501, Gid: 20}
+	rp := protocol.RequestPayload{Fields: fields, Peer: peer}
+	text := logger.incomingRequestText(rp)
+	expected := "GET /foobar  (uid=501 gid=20)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestEncoding(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/foobar"}
+	encoding := &protocol.BodyEncoding{Encoding: "zstd", CompressedSize: 20, DecompressedSize: 100}
+	rp := protocol.RequestPayload{Fields: fields, Encoding: encoding}
+	text := logger.incomingRequestText(rp)
+	expected := "POST /foobar  (zstd, 20 → 100 bytes)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestSignature(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/webhooks"}
+	signature := &protocol.SignatureVerification{Profile: "stripe", Result: "valid"}
+	rp := protocol.RequestPayload{Fields: fields, Signature: signature}
+	text := logger.incomingRequestText(rp)
+	expected := "POST /webhooks  (stripe signature valid)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestFault(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/webhooks"}
+	fault := &protocol.ChaosFault{Kind: protocol.ChaosReset}
+	rp := protocol.RequestPayload{Fields: fields, Fault: fault}
+	text := logger.incomingRequestText(rp)
+	expected := "POST /webhooks  (chaos: connection reset)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestSequence(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/charge"}
+	sequence := &protocol.SequencePosition{Route: "POST /charge", Call: 1, Response: 1, Length: 3}
+	rp := protocol.RequestPayload{Fields: fields, Sequence: sequence}
+	text := logger.incomingRequestText(rp)
+	expected := "POST /charge  (POST /charge response 1 of 3, call 1)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestValidation(t *testing.T) {
+	logger := Logger{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/pets"}
+	errs := []protocol.ValidationError{{Location: "/body/name", Message: "is required"}}
+	validation := &protocol.Validation{Operation: "POST /pets", Errors: errs}
+	rp := protocol.RequestPayload{Fields: fields, Validation: validation}
+	text := logger.incomingRequestText(rp)
+	expected := "POST /pets  (POST /pets invalid: /body/name: is required)"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestLoggerIncomingRequestValidationErrors(t *testing.T) {
+	file, err := ioutil.TempFile(t.TempDir(), "rh.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	logger := Logger{logFile: file}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/orders"}
+	errs := []protocol.ValidationError{{Location: "/body/items/0/price", Message: "expected number, got string"}, {Location: "/body/id", Message: "is required"}}
+	validation := &protocol.Validation{Operation: "POST /orders (order.json)", Errors: errs}
+	logger.incomingRequest(protocol.RequestPayload{Fields: fields, Validation: validation})
+
+	b, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"POST /orders  (POST /orders (order.json) invalid: 2 errors)\n",
+		"Validation error /body/items/0/price: expected number, got string\n",
+		"Validation error /body/id: is required\n",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("Expected the log to contain %q, got %q", expected, b)
+		}
+	}
+}
+
+func TestLoggerAttachmentText(t *testing.T) {
+	logger := Logger{}
+	file := protocol.UploadedFile{
+		Field:       "avatar",
+		Filename:    "me.png",
+		ContentType: "image/png",
+		Size:        1,
+		Sha256:      "abc",
+		Path:        "/tmp/rh-uploads/me.png",
+	}
+	text := logger.attachmentText(file)
+	expected := "File avatar: me.png (image/png, 1 byte, sha256 abc) saved to /tmp/rh-uploads/me.png"
+
+	if text != expected {
+		t.Errorf("Expected %s, got %s", expected, text)
+	}
+}
+
 func TestIncomingRequestHeadersText(t *testing.T) {
 	logger := Logger{}
 	headers := map[string][]string{
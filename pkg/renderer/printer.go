@@ -44,11 +44,7 @@ func (p *Printer) Start(wg *sync.WaitGroup, rp chan protocol.RequestPayload, q c
 func (p *Printer) incomingRequest(r protocol.RequestPayload) {
 	p.Spinner.Stop()
 
-	prefix := pterm.Prefix{
-		Text:  r.Fields.Method,
-		Style: pterm.NewStyle(pterm.BgGray, pterm.FgWhite),
-	}
-
+	prefix := p.incomingRequestPrefix(r)
 	text := p.incomingRequestText(r)
 	pterm.Info.WithPrefix(prefix).Println(text)
 
@@ -59,11 +55,37 @@ func (p *Printer) incomingRequest(r protocol.RequestPayload) {
 		if table != "" {
 			pterm.Printf("%s\n", table)
 		}
+
+		trailersTable := p.incomingRequestTrailersTable(r)
+		if trailersTable != "" {
+			pterm.Printf("%s\n", trailersTable)
+		}
+
+		attachmentsTable := p.incomingRequestAttachmentsTable(r)
+		if attachmentsTable != "" {
+			pterm.Printf("%s\n", attachmentsTable)
+		}
+
+		validationTable := p.incomingRequestValidationTable(r)
+		if validationTable != "" {
+			pterm.Printf("%s\n", validationTable)
+		}
 	}
 
 	p.startSpinner()
 }
 
+// incomingRequestPrefix returns the method of the request as the prefix, which is red if
+// the request failed validation.
+func (p *Printer) incomingRequestPrefix(r protocol.RequestPayload) pterm.Prefix {
+	style := pterm.NewStyle(pterm.BgGray, pterm.FgWhite)
+	if r.Validation != nil && !r.Validation.Valid {
+		style = pterm.NewStyle(pterm.BgRed, pterm.FgWhite)
+	}
+
+	return pterm.Prefix{Text: r.Fields.Method, Style: style}
+}
+
 // incomingRequestText converts the RequestPayload into a printable string.
 func (p *Printer) incomingRequestText(r protocol.RequestPayload) string {
 	urlWithStyle := ""
@@ -76,18 +98,125 @@ func (p *Printer) incomingRequestText(r protocol.RequestPayload) string {
 		WithStyle(pterm.NewStyle(pterm.Fuzzy)).Sprintf(r.Message)
 
 	text := fmt.Sprintf("%s%s", urlWithStyle, paramsWithStyle)
+
+	// HTTP/2 requests show the protocol version and stream so clients can be checked.
+	if r.StreamID > 0 {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(pterm.FgGray)).Sprintf(" (%s, stream %d)", r.Fields.Protocol, r.StreamID)
+	}
+
+	// Injected faults are shown in red, so they can be lined up with the client's retries.
+	if r.Fault != nil {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(pterm.FgRed)).Sprintf(" (chaos: %s)", r.Fault)
+	}
+
+	if r.Sequence != nil {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(pterm.FgGray)).Sprintf(" (%s)", r.Sequence)
+	}
+
+	// Requests which fail validation are shown in red.
+	if r.Validation != nil {
+		color := pterm.FgGreen
+		if !r.Validation.Valid {
+			color = pterm.FgRed
+		}
+
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(color)).Sprintf(" (%s)", r.Validation)
+	}
+
+	if r.Signature != nil {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(signatureColor(r.Signature.Result))).Sprintf(" (%s)", r.Signature)
+	}
+
+	// Decoding errors are shown in red, since the params could not be parsed.
+	if r.Encoding != nil {
+		color := pterm.FgGray
+		if r.Encoding.Error != "" {
+			color = pterm.FgRed
+		}
+
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(color)).Sprintf(" (%s)", r.Encoding)
+	}
+
+	if len(r.Attachments) > 0 {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(pterm.FgGray)).Sprintf(" (%s)", pluralize(len(r.Attachments), "file"))
+	}
+
+	// Requests over a Unix socket show the client process.
+	if r.Peer != nil {
+		text += pterm.DefaultBasicText.
+			WithStyle(pterm.NewStyle(pterm.FgGray)).Sprintf(" (%s)", r.Peer)
+	}
+
 	return text
 }
 
 // incomingRequestHeadersTable constructs the headers table string from the RequestPayload.
 // This takes the headers map from the request and sorts it alphabetically by key.
 func (p *Printer) incomingRequestHeadersTable(r protocol.RequestPayload) string {
-	if len(r.Headers) == 0 {
+	return p.headersTable("Header", r.Headers)
+}
+
+// incomingRequestTrailersTable constructs the trailers table string from the RequestPayload.
+func (p *Printer) incomingRequestTrailersTable(r protocol.RequestPayload) string {
+	return p.headersTable("Trailer", r.Trailers)
+}
+
+// incomingRequestAttachmentsTable constructs the table of uploaded files from the
+// RequestPayload, in the order they were uploaded.
+func (p *Printer) incomingRequestAttachmentsTable(r protocol.RequestPayload) string {
+	if len(r.Attachments) == 0 {
 		return ""
 	}
 
-	keys := make([]string, 0, len(r.Headers))
-	for key := range r.Headers {
+	rows := [][]string{{"Field", "Filename", "Content-Type", "Size", "SHA-256"}}
+	for _, file := range r.Attachments {
+		rows = append(rows, []string{file.Field, file.Filename, file.ContentType, pluralize(file.Size, "byte"), file.Sha256})
+	}
+
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(rows).Srender()
+	if err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+	}
+
+	return table
+}
+
+// incomingRequestValidationTable constructs the table of validation errors from the
+// RequestPayload.
+func (p *Printer) incomingRequestValidationTable(r protocol.RequestPayload) string {
+	if r.Validation == nil || len(r.Validation.Errors) == 0 {
+		return ""
+	}
+
+	rows := [][]string{{"Location", "Error"}}
+	for _, err := range r.Validation.Errors {
+		rows = append(rows, []string{err.Location, err.Message})
+	}
+
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(rows).Srender()
+	if err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+	}
+
+	return table
+}
+
+// headersTable renders the headers sorted alphabetically by key, with the title as the
+// first column header.
+func (p *Printer) headersTable(title string, headers map[string][]string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
 		keys = append(keys, key)
 	}
 
@@ -95,11 +224,11 @@ func (p *Printer) incomingRequestHeadersTable(r protocol.RequestPayload) string
 
 	var headersFormatted [][]string
 
-	headerRow := []string{"Header", "Value"}
+	headerRow := []string{title, "Value"}
 	headersFormatted = append(headersFormatted, headerRow)
 
 	for _, key := range keys {
-		value := strings.Join(r.Headers[key], ",")
+		value := strings.Join(headers[key], ",")
 		headersRow := []string{key, value}
 		headersFormatted = append(headersFormatted, headersRow)
 	}
@@ -133,3 +262,24 @@ func (p *Printer) startSpinner() {
 
 	p.Spinner = spinner
 }
+
+// signatureColor returns the color a signature verification result is shown in.
+func signatureColor(result string) pterm.Color {
+	switch result {
+	case protocol.SignatureValid:
+		return pterm.FgGreen
+	case protocol.SignatureMissing:
+		return pterm.FgYellow
+	}
+
+	return pterm.FgRed
+}
+
+// pluralize returns the count with the noun, which is made plural unless the count is 1.
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -2,6 +2,7 @@ package renderer
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/aaronvb/logrequest"
@@ -26,6 +27,222 @@ func TestIncomingRequestText(t *testing.T) {
 	}
 }
 
+func TestIncomingRequestTextHttp2(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{
+		Method:   "GET",
+		Url:      "/foobar",
+		Protocol: "HTTP/2.0",
+	}
+	rp := protocol.RequestPayload{Fields: fields, StreamID: 3}
+	result := printer.incomingRequestText(rp)
+	expected := "/foobar  (HTTP/2.0, stream 3)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextPeer(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "GET", Url: "/foobar"}
+	peer := &protocol.PeerCredentials{Uid: 501, Gid: 20, Pid: 1234}
+	rp := protocol.RequestPayload{Fields: fields, Peer: peer}
+	result := printer.incomingRequestText(rp)
+	expected := "/foobar  (uid=501 gid=20 pid=1234)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextAttachments(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/upload"}
+	files := []protocol.UploadedFile{{Filename: "a.png"}, {Filename: "b.png"}}
+	rp := protocol.RequestPayload{Fields: fields, Attachments: files}
+	result := printer.incomingRequestText(rp)
+	expected := "/upload  (2 files)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextEncoding(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/foobar"}
+	tests := []struct {
+		encoding protocol.BodyEncoding
+		expected string
+	}{
+		{
+			protocol.BodyEncoding{Encoding: "gzip", CompressedSize: 20, DecompressedSize: 100},
+			"/foobar  (gzip, 20 → 100 bytes)",
+		},
+		{
+			protocol.BodyEncoding{Encoding: "br", CompressedSize: 20, Error: "br: invalid data"},
+			"/foobar  (br, decoding failed: br: invalid data)",
+		},
+	}
+
+	for _, test := range tests {
+		encoding := test.encoding
+		rp := protocol.RequestPayload{Fields: fields, Encoding: &encoding}
+		result := printer.incomingRequestText(rp)
+
+		if result != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, result)
+		}
+	}
+}
+
+func TestIncomingRequestTextSignature(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/webhooks"}
+	signature := &protocol.SignatureVerification{Profile: "github", Result: "invalid", Reason: "signature does not match"}
+	rp := protocol.RequestPayload{Fields: fields, Signature: signature}
+	result := printer.incomingRequestText(rp)
+	expected := "/webhooks  (github signature invalid: signature does not match)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextFault(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/webhooks"}
+	fault := &protocol.ChaosFault{Kind: protocol.ChaosStatus, StatusCode: 503, RetryAfter: 5}
+	rp := protocol.RequestPayload{Fields: fields, Fault: fault}
+	result := printer.incomingRequestText(rp)
+	expected := "/webhooks  (chaos: 503 Service Unavailable, retry after 5s)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextSequence(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST", Url: "/charge"}
+	sequence := &protocol.SequencePosition{Route: "POST /charge", Call: 4, Response: 3, Length: 3}
+	rp := protocol.RequestPayload{Fields: fields, Sequence: sequence}
+	result := printer.incomingRequestText(rp)
+	expected := "/charge  (POST /charge response 3 of 3, call 4)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestIncomingRequestTextValidation(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "GET", Url: "/pets/1"}
+	tests := []struct {
+		validation protocol.Validation
+		expected   string
+	}{
+		{
+			protocol.Validation{Operation: "GET /pets/{petId}", Valid: true},
+			"/pets/1  (GET /pets/{petId} valid)",
+		},
+		{
+			protocol.Validation{Operation: "GET /pets/{petId}", Errors: []protocol.ValidationError{{Location: "/query/limit", Message: "must be at most 100"}}},
+			"/pets/1  (GET /pets/{petId} invalid: /query/limit: must be at most 100)",
+		},
+		{
+			protocol.Validation{Operation: "GET /pets/{petId}", Errors: make([]protocol.ValidationError, 2)},
+			"/pets/1  (GET /pets/{petId} invalid: 2 errors)",
+		},
+	}
+
+	for _, test := range tests {
+		validation := test.validation
+		rp := protocol.RequestPayload{Fields: fields, Validation: &validation}
+		result := printer.incomingRequestText(rp)
+
+		if result != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, result)
+		}
+	}
+}
+
+func TestIncomingRequestPrefix(t *testing.T) {
+	printer := Printer{}
+	fields := logrequest.RequestFields{Method: "POST"}
+	tests := []struct {
+		validation *protocol.Validation
+		background pterm.Color
+	}{
+		{nil, pterm.BgGray},
+		{&protocol.Validation{Valid: true}, pterm.BgGray},
+		{&protocol.Validation{Errors: []protocol.ValidationError{{Location: "/body", Message: "invalid JSON"}}}, pterm.BgRed},
+	}
+
+	for _, test := range tests {
+		rp := protocol.RequestPayload{Fields: fields, Validation: test.validation}
+		prefix := printer.incomingRequestPrefix(rp)
+		expected := pterm.NewStyle(test.background, pterm.FgWhite)
+
+		if prefix.Text != "POST" || !reflect.DeepEqual(*prefix.Style, *expected) {
+			t.Errorf("Expected POST with %v, got %s with %v", *expected, prefix.Text, *prefix.Style)
+		}
+	}
+}
+
+func TestIncomingRequestValidationTable(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	errs := []protocol.ValidationError{{Location: "/body/name", Message: "is required"}}
+	rp := protocol.RequestPayload{Validation: &protocol.Validation{Errors: errs}}
+	result := printer.incomingRequestValidationTable(rp)
+
+	expected, err := pterm.DefaultTable.WithHasHeader().WithData([][]string{
+		{"Location", "Error"},
+		{"/body/name", "is required"},
+	}).Srender()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+
+	rp = protocol.RequestPayload{Validation: &protocol.Validation{Valid: true}}
+	if result := printer.incomingRequestValidationTable(rp); result != "" {
+		t.Errorf("Expected no table for a valid request, got %s", result)
+	}
+}
+
+func TestIncomingRequestTrailersTable(t *testing.T) {
+	pterm.DisableColor()
+	printer := Printer{}
+	rp := protocol.RequestPayload{Trailers: map[string][]string{"X-Checksum": {"abc"}}}
+	result := printer.incomingRequestTrailersTable(rp)
+
+	expected, err := pterm.DefaultTable.WithHasHeader().WithData([][]string{
+		{"Trailer", "Value"},
+		{"X-Checksum", "abc"},
+	}).Srender()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
 func TestIncomingRequestHeadersTables(t *testing.T) {
 	pterm.DisableColor()
 	printer := Printer{}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"runtime"
 	"sync"
@@ -18,6 +21,7 @@ import (
 	"github.com/aaronvb/request_hole/graph/generated"
 	"github.com/aaronvb/request_hole/graph/model"
 	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/store"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/pterm/pterm"
@@ -46,21 +50,41 @@ type Web struct {
 
 	StaticFiles http.FileSystem
 
-	mu sync.Mutex
+	// EventSender is the protocol which the web UI can send events through. Nil if the
+	// protocol does not support sending events.
+	EventSender protocol.EventSender
+
+	// MetricsAggregator is the protocol which the web UI reads the metrics aggregate
+	// from. Nil if the protocol does not aggregate metrics.
+	MetricsAggregator protocol.MetricsAggregator
+
+	// SequenceResetter is the protocol which the web UI reads and resets the response
+	// sequences of. Nil if the protocol has no response sequences.
+	SequenceResetter protocol.SequenceResetter
+
+	// RateLimiter is the protocol which the web UI reads the rate limit counters from.
+	// Nil if the protocol does not emulate a rate limit.
+	RateLimiter protocol.RateLimiter
+
+	// ConfigReloader reloads the settings of the protocol from the web UI. Nil if the
+	// protocol cannot be reloaded.
+	ConfigReloader protocol.ConfigReloader
+
+	// ResponseController changes the default response of the protocol from the web UI.
+	// Nil if the protocol has no default response.
+	ResponseController protocol.ResponseController
+
+	// listener is bound by Bind, before the web UI server starts.
+	listener net.Listener
 
 	// requests contain the incoming requests.
-	requests []*protocol.RequestPayload
+	requests store.Requests
 
-	// subscriptions contain the websocket connections to our graphql subscribers.
-	subscriptions map[string]chan *protocol.RequestPayload
+	// hub delivers the incoming requests to our graphql subscribers.
+	hub store.Hub
 }
 
 func (web *Web) Start(wg *sync.WaitGroup, rp chan protocol.RequestPayload, q chan int, e chan int) {
-	// Initialize requests as an empty slice so that we can return a proper json empty array
-	// if no values have been appended.
-	web.requests = make([]*protocol.RequestPayload, 0)
-	web.subscriptions = make(map[string]chan *protocol.RequestPayload)
-
 	addr := fmt.Sprintf("%s:%d", web.Address, web.Port)
 	errorLog := log.New(&httpErrorLog{}, "", 0)
 
@@ -74,8 +98,12 @@ func (web *Web) Start(wg *sync.WaitGroup, rp chan protocol.RequestPayload, q cha
 	defer wg.Done()
 
 	go func() {
-		open(fmt.Sprintf("http://%s/", addr))
-		err := srv.ListenAndServe()
+		_, err := web.Bind()
+		if err == nil {
+			open(fmt.Sprintf("http://%s:%d/", web.Address, web.Port))
+			err = srv.Serve(web.listener)
+		}
+
 		str := pterm.Error.WithShowLineNumber(false).Sprintf("Web: %s\n", err)
 		pterm.Printo(str) // Overwrite last line
 		e <- 1
@@ -92,10 +120,29 @@ func (web *Web) Start(wg *sync.WaitGroup, rp chan protocol.RequestPayload, q cha
 	}
 }
 
+// Bind binds the listener which the web UI server serves on, and returns its address.
+// The Port is set to the port that was bound, which is picked by the OS for port 0.
+func (web *Web) Bind() (net.Addr, error) {
+	if web.listener == nil {
+		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", web.Address, web.Port))
+		if err != nil {
+			return nil, err
+		}
+
+		web.listener = ln
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+			web.Port = addr.Port
+		}
+	}
+
+	return web.listener.Addr(), nil
+}
+
 // routes handles routes for our web UI.
 func (web *Web) routes() http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/requests", web.requestsHandler).Methods("GET")
+	r.HandleFunc("/attachments/{id}", web.attachmentHandler).Methods("GET")
 	r.Handle("/graphql", playground.Handler("GraphQL playground", "/query"))
 	r.HandleFunc("/query", web.gqlHandler)
 	handler := cors.Default().Handler(r)
@@ -107,7 +154,49 @@ func (web *Web) routes() http.Handler {
 // requestsHandler returns an array of incoming requests to our protocol server.
 func (web *Web) requestsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(web.requests)
+	json.NewEncoder(w).Encode(web.requests.Snapshot())
+}
+
+// attachmentHandler downloads a file that was uploaded with a multipart form.
+func (web *Web) attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	file := web.uploadedFile(mux.Vars(r)["id"])
+	if file == nil || file.Path == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// The file is whatever the client uploaded, so the browser must not render it as the
+	// page, whatever its content type.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
+
+// uploadedFile returns the uploaded file with the id, or nil if there is none.
+func (web *Web) uploadedFile(id string) *protocol.UploadedFile {
+	for _, req := range web.requests.Snapshot() {
+		for i := range req.Attachments {
+			if req.Attachments[i].ID == id {
+				file := req.Attachments[i]
+				return &file
+			}
+		}
+	}
+
+	return nil
 }
 
 func (web *Web) gqlHandler(w http.ResponseWriter, r *http.Request) {
@@ -122,9 +211,15 @@ func (web *Web) gqlHandler(w http.ResponseWriter, r *http.Request) {
 	// Pass pointer to requests and subscriptions
 	gqlSrv := handler.New(
 		generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{
-			RequestPayloads:        &web.requests,
-			RequestPayloadObserver: &web.subscriptions,
-			Info:                   &serverInfo,
+			RequestStore:       &web.requests,
+			Hub:                &web.hub,
+			Info:               &serverInfo,
+			Events:             web.EventSender,
+			MetricsAggregator:  web.MetricsAggregator,
+			SequenceResetter:   web.SequenceResetter,
+			RateLimiter:        web.RateLimiter,
+			ConfigReloader:     web.ConfigReloader,
+			ResponseController: web.ResponseController,
 		}}))
 	gqlSrv.AddTransport(transport.POST{})
 	gqlSrv.AddTransport(&transport.Websocket{
@@ -141,16 +236,12 @@ func (web *Web) gqlHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // incomingRequest is called when we receive a RequestPayload over the channel
-// from the protocol server. This will add it to the request slice which our web ui
-// will serve as JSON and be consumed on the front end.
+// from the protocol server. This will add it to the request store which our web ui
+// will serve as JSON and be consumed on the front end, and publish it to the
+// subscribers without waiting for them.
 func (web *Web) incomingRequest(req protocol.RequestPayload) {
-	web.mu.Lock()
-	web.requests = append(web.requests, &req)
-	web.mu.Unlock()
-
-	for _, subscriptions := range web.subscriptions {
-		subscriptions <- &req
-	}
+	web.requests.Add(&req)
+	web.hub.Publish(&req)
 }
 
 // httpErrorLog implements the logger interface.
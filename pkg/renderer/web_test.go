@@ -1,29 +1,120 @@
 package renderer
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/aaronvb/logrequest"
+	"github.com/aaronvb/request_hole/graph"
 	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/store"
 )
 
 func TestIncomingRequest(t *testing.T) {
 	rp := protocol.RequestPayload{Fields: logrequest.RequestFields{Url: "/foo"}}
 	webServer := Web{}
 
-	if len(webServer.requests) != 0 {
-		t.Errorf("Expected %d, got %d", 0, len(webServer.requests))
+	if webServer.requests.Len() != 0 {
+		t.Errorf("Expected %d, got %d", 0, webServer.requests.Len())
 	}
 
 	webServer.incomingRequest(rp)
 
-	if len(webServer.requests) != 1 {
-		t.Errorf("Expected %d, got %d", 0, len(webServer.requests))
+	if webServer.requests.Len() != 1 {
+		t.Errorf("Expected %d, got %d", 0, webServer.requests.Len())
+	}
+}
+
+func TestIncomingRequestSubscribers(t *testing.T) {
+	webServer := Web{}
+	_, slow := webServer.hub.Subscribe()
+	id, fast := webServer.hub.Subscribe()
+
+	n := store.SubscriberBuffer + 10
+	for i := 0; i < n; i++ {
+		webServer.incomingRequest(protocol.RequestPayload{Fields: logrequest.RequestFields{Url: fmt.Sprintf("/%d", i)}})
+
+		if req := <-fast; req.Fields.Url != fmt.Sprintf("/%d", i) {
+			t.Errorf("Expected /%d, got %s", i, req.Fields.Url)
+		}
+	}
+
+	if len(slow) != store.SubscriberBuffer {
+		t.Errorf("Expected the slow subscriber to have %d requests, got %d", store.SubscriberBuffer, len(slow))
+	}
+
+	if stats := webServer.hub.Stats(); stats.Dropped != 10 || stats.Delivered != uint64(n+store.SubscriberBuffer) {
+		t.Errorf("Expected 10 dropped and %d delivered, got %+v", n+store.SubscriberBuffer, stats)
+	}
+
+	webServer.hub.Unsubscribe(id)
+	if webServer.requests.Len() != n {
+		t.Errorf("Expected %d requests, got %d", n, webServer.requests.Len())
+	}
+}
+
+// TestIncomingRequestConcurrentResolvers runs the graph resolvers while requests come
+// in, so go test -race finds unguarded access to the request store and the hub.
+func TestIncomingRequestConcurrentResolvers(t *testing.T) {
+	webServer := Web{}
+	resolver := &graph.Resolver{RequestStore: &webServer.requests, Hub: &webServer.hub}
+
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(i int) {
+		webServer.incomingRequest(protocol.RequestPayload{Fields: logrequest.RequestFields{Url: fmt.Sprintf("/%d", i)}})
+	})
+	run(func(int) {
+		if _, err := resolver.Query().Requests(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(i int) {
+		if i%10 != 0 {
+			return
+		}
+
+		if _, err := resolver.Mutation().ClearRequests(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		ctx, cancel := context.WithCancel(context.Background())
+		requests, err := resolver.Subscription().Request(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+
+		select {
+		case <-requests:
+		default:
+		}
+		cancel()
+	})
+
+	wg.Wait()
+
+	webServer.incomingRequest(protocol.RequestPayload{Fields: logrequest.RequestFields{Url: "/last"}})
+	requests, _ := resolver.Query().Requests(context.Background())
+	if len(requests) == 0 || requests[len(requests)-1].Fields.Url != "/last" {
+		t.Errorf("Expected the last request to be stored, got %d requests", len(requests))
 	}
 }
 
@@ -40,7 +131,7 @@ func TestRequestHandler(t *testing.T) {
 		{protocol.RequestPayload{Message: "{\"foo\" => \"bar\"}"}},
 	}
 
-	webServer := Web{requests: make([]*protocol.RequestPayload, 0)}
+	webServer := Web{}
 	srv := httptest.NewServer(webServer.routes())
 
 	defer srv.Close()
@@ -80,3 +171,47 @@ func TestRequestHandler(t *testing.T) {
 		resp.Body.Close()
 	}
 }
+
+// GET /attachments/{id}
+func TestAttachmentHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file := protocol.UploadedFile{ID: "foo", Filename: "hello.txt", ContentType: "text/plain", Path: path}
+	webServer := Web{}
+	webServer.incomingRequest(protocol.RequestPayload{Attachments: []protocol.UploadedFile{file}})
+
+	srv := httptest.NewServer(webServer.routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/attachments/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "hello" {
+		t.Errorf("Expected %s, got %s", "hello", body)
+	}
+
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "attachment; filename=hello.txt" {
+		t.Errorf("Expected attachment disposition, got %s", disposition)
+	}
+
+	if nosniff := resp.Header.Get("X-Content-Type-Options"); nosniff != "nosniff" {
+		t.Errorf("Expected nosniff, got %s", nosniff)
+	}
+
+	resp, err = http.Get(srv.URL + "/attachments/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
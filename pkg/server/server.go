@@ -1,7 +1,13 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/aaronvb/request_hole/pkg/protocol"
@@ -31,9 +37,30 @@ type FlagData struct {
 	// BuildInfo contains the build information for rh. Set by goreleaser.
 	BuildInfo map[string]string
 
+	// Chaos describes the faults injected into requests, with the seed to reproduce
+	// them.
+	Chaos string
+
+	// RateLimit describes the rate limit requests are answered with 429 over.
+	RateLimit string
+
+	// OpenAPI describes the spec requests are validated against.
+	OpenAPI string
+
+	// Schemas lists the routes which JSON bodies are validated on, with their schema
+	// files.
+	Schemas []string
+
+	// StrictValidation determines if requests which fail validation are answered with
+	// 422.
+	StrictValidation bool
+
 	// Details determines if header details should be shown with the request,
 	Details bool
 
+	// Config is the config file the settings were loaded from, with its profile.
+	Config string
+
 	// LogFile contains the path and filename to the log file which the server
 	// will write to if log flag is passed.
 	LogFile string
@@ -44,6 +71,14 @@ type FlagData struct {
 	// Protocol is the protocol that the server will use to handle incoming requests.
 	Protocol string
 
+	// ReadyFile is the path to a file the server writes the protocol and web UI URLs
+	// to once they are listening.
+	ReadyFile string
+
+	// UnixSocket is the path of the Unix domain socket the server listens on instead
+	// of the address and port.
+	UnixSocket string
+
 	// ResponseCode is the response which our endpoint will return.
 	// Default is 200 if no response code is passed.
 	ResponseCode int
@@ -70,6 +105,11 @@ type FlagData struct {
 // Blocks main program until all goroutines are returned. In most cases the user will
 // force exit the CLI from the terminal.
 func (s *Server) Start() {
+	if err := s.bind(); err != nil {
+		pterm.Error.WithShowLineNumber(false).Println(err)
+		return
+	}
+
 	s.printServerInfo()
 
 	if len(s.Renderers) == 0 {
@@ -77,6 +117,16 @@ func (s *Server) Start() {
 		return
 	}
 
+	if s.FlagData.ReadyFile != "" {
+		if err := s.writeReadyFile(); err != nil {
+			pterm.Error.WithShowLineNumber(false).Println(err)
+			return
+		}
+
+		protocol.RemoveOnSignal(s.FlagData.ReadyFile)
+		defer os.Remove(s.FlagData.ReadyFile)
+	}
+
 	var wg sync.WaitGroup
 	var rpChans []chan protocol.RequestPayload
 	var rendererQuitChans []chan int
@@ -102,6 +152,129 @@ func (s *Server) Start() {
 	wg.Wait()
 }
 
+// binder is implemented by protocols and renderers that can bind their listener before
+// they start, so that the address that was bound can be shown.
+type binder interface {
+	Bind() (net.Addr, error)
+}
+
+// bind binds the protocol and the web renderer, if they support it, before anything is
+// printed. The ports are replaced with the ports that were bound, which are picked by
+// the OS when the port is 0.
+func (s *Server) bind() error {
+	if b, ok := s.Protocol.(binder); ok {
+		addr, err := b.Bind()
+		if err != nil {
+			return err
+		}
+
+		if port := addrPort(addr); port > 0 {
+			s.FlagData.Port = port
+		}
+	}
+
+	for _, r := range s.Renderers {
+		switch r := r.(type) {
+		case *renderer.Web:
+			r.RequestPort = s.FlagData.Port
+
+			addr, err := r.Bind()
+			if err != nil {
+				return fmt.Errorf("Web: %s", err)
+			}
+
+			if port := addrPort(addr); port > 0 {
+				s.FlagData.WebPort = port
+			}
+		case *renderer.Logger:
+			r.Port = s.FlagData.Port
+		}
+	}
+
+	return nil
+}
+
+// addrPort returns the port of a TCP or UDP address, otherwise 0.
+func addrPort(addr net.Addr) int {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.Port
+	case *net.UDPAddr:
+		return addr.Port
+	}
+
+	return 0
+}
+
+// readyFile is written to the ready file once the server is listening.
+type readyFile struct {
+	Protocol   string `json:"protocol"`
+	URL        string `json:"url"`
+	Address    string `json:"address,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	UnixSocket string `json:"unix_socket,omitempty"`
+	WebURL     string `json:"web_url,omitempty"`
+	Pid        int    `json:"pid"`
+}
+
+// writeReadyFile writes the URLs to the ready file. The file is written to a temporary
+// file first and renamed, so a script waiting for it never reads a partial file.
+func (s *Server) writeReadyFile() error {
+	ready := readyFile{
+		Protocol: s.FlagData.Protocol,
+		URL:      s.listenURL(),
+		Pid:      os.Getpid(),
+	}
+
+	if s.FlagData.UnixSocket != "" {
+		ready.UnixSocket = s.FlagData.UnixSocket
+	} else {
+		ready.Address = s.FlagData.Addr
+		ready.Port = s.FlagData.Port
+	}
+
+	if s.FlagData.Web {
+		ready.WebURL = s.webURL()
+	}
+
+	data, err := json.MarshalIndent(ready, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(s.FlagData.ReadyFile), ".rh-ready-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.FlagData.ReadyFile)
+}
+
+// listenURL returns the URL the protocol is listening on.
+func (s *Server) listenURL() string {
+	if s.FlagData.UnixSocket != "" {
+		return fmt.Sprintf("%s+unix://%s", s.FlagData.Protocol, s.FlagData.UnixSocket)
+	}
+
+	return fmt.Sprintf("%s://%s:%d", s.FlagData.Protocol, s.FlagData.Addr, s.FlagData.Port)
+}
+
+// webURL returns the URL of the web UI.
+func (s *Server) webURL() string {
+	return fmt.Sprintf("http://%s:%d", s.FlagData.WebAddress, s.FlagData.WebPort)
+}
+
 // printServerInfo prints the top header section of the CLI when we start.
 // This contains info such as flag options passed and build info.
 func (s *Server) printServerInfo() {
@@ -111,7 +284,7 @@ func (s *Server) printServerInfo() {
 
 	pterm.DefaultBox.
 		WithBoxStyle(pterm.NewStyle(pterm.FgGray)).
-		Printfln(text)
+		Println(text)
 }
 
 func (s *Server) startText() string {
@@ -122,10 +295,10 @@ func (s *Server) startText() string {
 		WithStyle(pterm.NewStyle(pterm.Fuzzy)).
 		Sprintf(s.FlagData.BuildInfo["version"])
 
-	text := fmt.Sprintf("%s %s\nListening on %s://%s:%d", primary, version, s.FlagData.Protocol, s.FlagData.Addr, s.FlagData.Port)
+	text := fmt.Sprintf("%s %s\nListening on %s", primary, version, s.listenURL())
 
 	if s.FlagData.Web {
-		text = fmt.Sprintf("%s\nWeb running on: http://%s:%d", text, s.FlagData.WebAddress, s.FlagData.WebPort)
+		text = fmt.Sprintf("%s\nWeb running on: %s", text, s.webURL())
 	}
 
 	if s.FlagData.Details {
@@ -136,6 +309,30 @@ func (s *Server) startText() string {
 		text = fmt.Sprintf("%s\nLog: %s", text, s.FlagData.LogFile)
 	}
 
+	if s.FlagData.Config != "" {
+		text = fmt.Sprintf("%s\nConfig: %s", text, s.FlagData.Config)
+	}
+
+	if s.FlagData.Chaos != "" {
+		text = fmt.Sprintf("%s\nChaos: %s", text, s.FlagData.Chaos)
+	}
+
+	if s.FlagData.RateLimit != "" {
+		text = fmt.Sprintf("%s\nRate limit: %s", text, s.FlagData.RateLimit)
+	}
+
+	if s.FlagData.OpenAPI != "" {
+		text = fmt.Sprintf("%s\nOpenAPI: %s", text, s.FlagData.OpenAPI)
+	}
+
+	if len(s.FlagData.Schemas) > 0 {
+		text = fmt.Sprintf("%s\nSchemas: %s", text, strings.Join(s.FlagData.Schemas, ", "))
+	}
+
+	if s.FlagData.StrictValidation {
+		text = fmt.Sprintf("%s\nStrict validation: %t", text, s.FlagData.StrictValidation)
+	}
+
 	return text
 }
 
@@ -1,9 +1,17 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/aaronvb/request_hole/pkg/renderer"
 	"github.com/pterm/pterm"
 )
 
@@ -58,6 +66,97 @@ func TestStartTextWithLogFile(t *testing.T) {
 	}
 }
 
+func TestStartTextWithChaos(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:      "localhost",
+		Port:      8080,
+		BuildInfo: map[string]string{"version": "dev"},
+		Chaos:     "503=10% (seed 42)",
+		Protocol:  "http",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http://localhost:8080\nChaos: 503=10% (seed 42)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestStartTextWithRateLimit(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:      "localhost",
+		Port:      8080,
+		BuildInfo: map[string]string{"version": "dev"},
+		RateLimit: "100 requests per 1m0s by ip (fixed_window)",
+		Protocol:  "http",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http://localhost:8080\nRate limit: 100 requests per 1m0s by ip (fixed_window)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestStartTextWithConfig(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:      "localhost",
+		Port:      8080,
+		BuildInfo: map[string]string{"version": "dev"},
+		Config:    ".rh.yaml (profile stripe)",
+		Protocol:  "http",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http://localhost:8080\nConfig: .rh.yaml (profile stripe)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestStartTextWithOpenAPI(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:      "localhost",
+		Port:      8080,
+		BuildInfo: map[string]string{"version": "dev"},
+		OpenAPI:   "Petstore 1.0.0 (4 operations)",
+		Protocol:  "http",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http://localhost:8080\nOpenAPI: Petstore 1.0.0 (4 operations)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestStartTextWithSchemas(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:             "localhost",
+		Port:             8080,
+		BuildInfo:        map[string]string{"version": "dev"},
+		Schemas:          []string{"POST /orders (order.json)", "/users/* (user.json)"},
+		StrictValidation: true,
+		Protocol:         "http",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http://localhost:8080\nSchemas: POST /orders (order.json), /users/* (user.json)\nStrict validation: true"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
 func TestStartTextWithWebUIDefault(t *testing.T) {
 	pterm.DisableColor()
 	flags := FlagData{
@@ -99,3 +198,104 @@ func TestStartTextWithWebUICustomFlags(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, result)
 	}
 }
+
+func TestStartTextWithUnixSocket(t *testing.T) {
+	pterm.DisableColor()
+	flags := FlagData{
+		Addr:       "localhost",
+		Protocol:   "http",
+		Port:       8080,
+		BuildInfo:  map[string]string{"version": "dev"},
+		UnixSocket: "/tmp/rh.sock",
+	}
+	server := Server{FlagData: flags}
+	result := server.startText()
+	expected := "Request Hole dev\nListening on http+unix:///tmp/rh.sock"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestBindPicksFreePort(t *testing.T) {
+	logger := &renderer.Logger{Addr: "127.0.0.1"}
+	web := &renderer.Web{Address: "127.0.0.1"}
+	flags := FlagData{Addr: "127.0.0.1", Protocol: "tcp", Web: true, WebAddress: "127.0.0.1"}
+	server := Server{
+		FlagData:  flags,
+		Protocol:  &protocol.Tcp{Addr: "127.0.0.1"},
+		Renderers: []renderer.Renderer{web, logger},
+	}
+
+	if err := server.bind(); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.FlagData.Port == 0 {
+		t.Errorf("Expected a port to be picked")
+	}
+
+	if logger.Port != server.FlagData.Port {
+		t.Errorf("Expected logger port %d, got %d", server.FlagData.Port, logger.Port)
+	}
+
+	if web.RequestPort != server.FlagData.Port {
+		t.Errorf("Expected web request port %d, got %d", server.FlagData.Port, web.RequestPort)
+	}
+
+	if server.FlagData.WebPort == 0 || web.Port != server.FlagData.WebPort {
+		t.Errorf("Expected web port %d, got %d", server.FlagData.WebPort, web.Port)
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", server.FlagData.Port))
+	if err != nil {
+		t.Fatalf("Expected port to be bound, got %s", err)
+	}
+	conn.Close()
+}
+
+func TestWriteReadyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rh.json")
+	flags := FlagData{
+		Addr:       "localhost",
+		Port:       54321,
+		Protocol:   "http",
+		ReadyFile:  path,
+		Web:        true,
+		WebAddress: "localhost",
+		WebPort:    54322,
+	}
+	server := Server{FlagData: flags}
+
+	if err := server.writeReadyFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ready map[string]interface{}
+	if err := json.Unmarshal(data, &ready); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"protocol": "http",
+		"url":      "http://localhost:54321",
+		"address":  "localhost",
+		"port":     float64(54321),
+		"web_url":  "http://localhost:54322",
+		"pid":      float64(os.Getpid()),
+	}
+
+	if !reflect.DeepEqual(ready, expected) {
+		t.Errorf("Expected %v, got %v", expected, ready)
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("Expected only the ready file, got %d files", len(files))
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+	"github.com/google/uuid"
+)
+
+// SubscriberBuffer is the number of requests a subscriber can fall behind by before the
+// requests published to it are dropped.
+const SubscriberBuffer = 64
+
+// Hub delivers the requests published to it to each subscriber. Publishing never waits
+// for a subscriber: a subscriber which is not keeping up misses the requests its buffer
+// has no room for, which are counted as dropped. The zero value has no subscribers.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]chan *protocol.RequestPayload
+
+	// statsMu guards the counters, which are updated while publishing under the read
+	// lock.
+	statsMu   sync.Mutex
+	delivered uint64
+	dropped   uint64
+}
+
+// HubStats are the counters of a hub.
+type HubStats struct {
+	// Subscribers is the number of subscribers.
+	Subscribers int
+
+	// Delivered and Dropped count the requests delivered to and dropped for each
+	// subscriber, since the hub was created.
+	Delivered uint64
+	Dropped   uint64
+}
+
+// Subscribe adds a subscriber, and returns its id and the channel the requests are
+// delivered on. The channel is closed by Unsubscribe.
+func (h *Hub) Subscribe() (string, <-chan *protocol.RequestPayload) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers == nil {
+		h.subscribers = make(map[string]chan *protocol.RequestPayload)
+	}
+
+	id := uuid.New().String()
+	requests := make(chan *protocol.RequestPayload, SubscriberBuffer)
+	h.subscribers[id] = requests
+
+	return id, requests
+}
+
+// Unsubscribe removes the subscriber and closes its channel. Unknown ids are ignored,
+// so a subscriber can be removed more than once.
+func (h *Hub) Unsubscribe(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if requests, ok := h.subscribers[id]; ok {
+		delete(h.subscribers, id)
+		close(requests)
+	}
+}
+
+// Publish delivers the request to each subscriber with room for it, and returns the
+// number of subscribers it was dropped for.
+func (h *Hub) Publish(req *protocol.RequestPayload) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered, dropped := 0, 0
+	for _, requests := range h.subscribers {
+		select {
+		case requests <- req:
+			delivered++
+		default:
+			dropped++
+		}
+	}
+
+	h.statsMu.Lock()
+	h.delivered += uint64(delivered)
+	h.dropped += uint64(dropped)
+	h.statsMu.Unlock()
+
+	return dropped
+}
+
+// Stats returns the counters of the hub.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	subscribers := len(h.subscribers)
+	h.mu.RUnlock()
+
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	return HubStats{Subscribers: subscribers, Delivered: h.delivered, Dropped: h.dropped}
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+)
+
+func TestHubPublish(t *testing.T) {
+	var hub Hub
+
+	if dropped := hub.Publish(&protocol.RequestPayload{}); dropped != 0 {
+		t.Errorf("Expected nothing dropped without subscribers, got %d", dropped)
+	}
+
+	id, requests := hub.Subscribe()
+	for i := 0; i < SubscriberBuffer; i++ {
+		if dropped := hub.Publish(&protocol.RequestPayload{}); dropped != 0 {
+			t.Fatalf("Expected request %d to be delivered, got %d dropped", i, dropped)
+		}
+	}
+
+	if dropped := hub.Publish(&protocol.RequestPayload{}); dropped != 1 {
+		t.Errorf("Expected the request to be dropped for the full subscriber, got %d", dropped)
+	}
+
+	expected := HubStats{Subscribers: 1, Delivered: SubscriberBuffer, Dropped: 1}
+	if stats := hub.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	hub.Unsubscribe(id)
+	hub.Unsubscribe(id)
+
+	received := 0
+	for range requests {
+		received++
+	}
+
+	if received != SubscriberBuffer {
+		t.Errorf("Expected the buffered requests before the channel is closed, got %d", received)
+	}
+
+	if dropped := hub.Publish(&protocol.RequestPayload{}); dropped != 0 {
+		t.Errorf("Expected nothing dropped after unsubscribing, got %d", dropped)
+	}
+
+	if stats := hub.Stats(); stats.Subscribers != 0 {
+		t.Errorf("Expected no subscribers, got %d", stats.Subscribers)
+	}
+}
+
+func TestHubConcurrent(t *testing.T) {
+	var hub Hub
+	var wg sync.WaitGroup
+
+	workers, n := 8, 200
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				hub.Publish(&protocol.RequestPayload{})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n/20; i++ {
+				id, requests := hub.Subscribe()
+
+				// Reads until the channel is closed, which also catches a send on it after
+				// Unsubscribe closed it.
+				read := make(chan struct{})
+				go func() {
+					defer close(read)
+					for range requests {
+					}
+				}()
+
+				hub.Unsubscribe(id)
+				<-read
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := hub.Stats(); stats.Subscribers != 0 {
+		t.Errorf("Expected no subscribers, got %d", stats.Subscribers)
+	}
+}
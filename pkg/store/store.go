@@ -0,0 +1,56 @@
+// Package store keeps the requests the web UI shows, and delivers them to the web UI
+// clients subscribed to them. Both are safe to use from many goroutines.
+package store
+
+import (
+	"sync"
+
+	"github.com/aaronvb/request_hole/pkg/protocol"
+)
+
+// Requests is the list of incoming requests. The zero value is an empty list.
+//
+// Requests are not modified once they were added, so they are shared by the snapshots.
+type Requests struct {
+	mu       sync.RWMutex
+	requests []*protocol.RequestPayload
+}
+
+// Add appends the request to the list.
+func (s *Requests) Add(req *protocol.RequestPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+}
+
+// Snapshot returns a copy of the list, which later changes do not affect. It is never
+// nil, so it is encoded as an empty JSON array.
+func (s *Requests) Snapshot() []*protocol.RequestPayload {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make([]*protocol.RequestPayload, len(s.requests))
+	copy(snapshot, s.requests)
+
+	return snapshot
+}
+
+// Clear removes every request, and returns how many were removed.
+func (s *Requests) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.requests)
+	s.requests = nil
+
+	return n
+}
+
+// Len returns the number of requests.
+func (s *Requests) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.requests)
+}
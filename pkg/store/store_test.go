@@ -0,0 +1,79 @@
+package store
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aaronvb/logrequest"
+	"github.com/aaronvb/request_hole/pkg/protocol"
+)
+
+func TestRequests(t *testing.T) {
+	var requests Requests
+
+	if snapshot := requests.Snapshot(); snapshot == nil || len(snapshot) != 0 {
+		t.Errorf("Expected an empty snapshot, got %v", snapshot)
+	}
+
+	requests.Add(&protocol.RequestPayload{Fields: logrequest.RequestFields{Url: "/foo"}})
+	snapshot := requests.Snapshot()
+	requests.Add(&protocol.RequestPayload{Fields: logrequest.RequestFields{Url: "/bar"}})
+
+	if len(snapshot) != 1 || snapshot[0].Fields.Url != "/foo" {
+		t.Errorf("Expected the snapshot not to change, got %v", snapshot)
+	}
+
+	if requests.Len() != 2 {
+		t.Errorf("Expected %d, got %d", 2, requests.Len())
+	}
+
+	if cleared := requests.Clear(); cleared != 2 {
+		t.Errorf("Expected %d cleared, got %d", 2, cleared)
+	}
+
+	if requests.Len() != 0 || len(snapshot) != 1 {
+		t.Errorf("Expected the requests to be cleared and the snapshot kept, got %d and %d", requests.Len(), len(snapshot))
+	}
+}
+
+func TestRequestsConcurrent(t *testing.T) {
+	var requests Requests
+	var wg sync.WaitGroup
+
+	workers, n := 8, 100
+	cleared := make(chan int, workers*n)
+	for w := 0; w < workers; w++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				requests.Add(&protocol.RequestPayload{})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				for _, req := range requests.Snapshot() {
+					_ = req.Fields.Url
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n/10; i++ {
+				cleared <- requests.Clear()
+			}
+		}()
+	}
+	wg.Wait()
+	close(cleared)
+
+	total := requests.Len()
+	for c := range cleared {
+		total += c
+	}
+
+	if total != workers*n {
+		t.Errorf("Expected every request to be kept or cleared once, got %d of %d", total, workers*n)
+	}
+}
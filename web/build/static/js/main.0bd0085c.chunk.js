@@ -1,2 +0,0 @@
-(this.webpackJsonpweb=this.webpackJsonpweb||[]).push([[0],{63:function(e,t,r){},76:function(e,t,r){"use strict";r.r(t);var s=r(3),n=r.n(s),a=r(49),c=r.n(a),l=(r(63),r(6)),o=r(11),i=r(24),d=r(87),u=r(91),b=r(93),j=r(1);var x=function(e,t){var r=arguments.length>2&&void 0!==arguments[2]?arguments[2]:"s";return"".concat(e," ").concat(t).concat(1!==e?r:"")},m=function(e){var t={};return null!=e.headers&&(t=e.headers),Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:x(Object.keys(t).length,"HEADER","S")}),Object.keys(t).map((function(e,r){return Object(j.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[Object(j.jsx)("span",{className:"text-gray-500",children:e}),Object(j.jsx)("span",{className:"ml-auto text-gray-900",children:t[e]})]},r)}))]})})},h=r(42),f=r.n(h);function O(e){return Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[Object(j.jsxs)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:[w(Object.keys(e.query).length,"QUERY PARAM","S")," "]}),Object.keys(e.query).map((function(t,r){return Object(j.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[Object(j.jsx)("span",{className:"text-gray-500",children:t}),Object(j.jsx)("span",{className:"ml-auto text-gray-900",children:e.query[t]})]},r)}))]})})}function g(e){return Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:w(Object.keys(e.form).length,"FORM PARAM","S")}),Object.keys(e.form).map((function(t,r){return Object(j.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[Object(j.jsx)("span",{className:"text-gray-500",children:t}),Object(j.jsx)("span",{className:"ml-auto text-gray-900",children:e.form[t]})]},r)}))]})})}function v(e){return Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"h-full bg-gray-100 p-4 rounded",children:[Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"JSON BODY"}),Object(j.jsx)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:Object(j.jsx)(f.a,{src:e.json,name:!1})})]})})}function p(e){return Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"h-full bg-gray-100 p-4 rounded",children:[Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"MESSAGE"}),Object(j.jsx)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:y(e.body)})]})})}var w=function(e,t){var r=arguments.length>2&&void 0!==arguments[2]?arguments[2]:"s";return"".concat(e," ").concat(t).concat(1!==e?r:"")};function y(e){try{var t=JSON.parse(e);return Object(j.jsx)(f.a,{src:t,name:!1})}catch(r){return e}}var N=function(e){return e.params&&e.params.json?Object(j.jsx)(v,{json:e.params.json}):e.params&&e.params.json_array?Object(j.jsx)(v,{json:e.params.json_array}):e.params&&e.params.query?Object(j.jsx)(O,{query:e.params.query}):e.params&&e.params.form?Object(j.jsx)(g,{form:e.params.form}):e.message?Object(j.jsx)(p,{body:e.message}):Object(j.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:Object(j.jsx)("div",{className:"bg-gray-100 p-4 rounded",children:Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400",children:"NO PARAMS"})})})};function k(e){var t=Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"cursor-pointer h-8 w-8 hover:text-black",viewBox:"0 0 20 20",fill:"currentColor",children:Object(j.jsx)("path",{fillRule:"evenodd",d:"M5.293 7.293a1 1 0 011.414 0L10 10.586l3.293-3.293a1 1 0 111.414 1.414l-4 4a1 1 0 01-1.414 0l-4-4a1 1 0 010-1.414z",clipRule:"evenodd"})}),r=Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"cursor-pointer h-8 w-8 hover:text-black",viewBox:"0 0 20 20",fill:"currentColor",children:Object(j.jsx)("path",{fillRule:"evenodd",d:"M14.707 12.707a1 1 0 01-1.414 0L10 9.414l-3.293 3.293a1 1 0 01-1.414-1.414l4-4a1 1 0 011.414 0l4 4a1 1 0 010 1.414z",clipRule:"evenodd"})});return Object(j.jsx)("button",{"data-testid":"toggleDetails",onClick:e.toggleDetails,className:"focus:outline-none flex ml-auto text-gray-500",children:e.showDetails?t:r})}var q=new Intl.RelativeTimeFormat(void 0,{numeric:"auto"}),S=[{amount:60,name:"seconds"},{amount:60,name:"minutes"},{amount:24,name:"hours"},{amount:7,name:"days"},{amount:4.34524,name:"weeks"},{amount:12,name:"months"},{amount:Number.POSITIVE_INFINITY,name:"years"}];var C,L,D,M=function(e){var t=function(e){if(void 0===e)return"";for(var t=(new Date(e)-new Date)/1e3,r=0;r<=S.length;r++){var s=S[r];if(Math.abs(t)<s.amount)return q.format(Math.round(t),s.name);t/=s.amount}}(e.created_at),r=Object(s.useState)(e.showAllDetails),n=Object(l.a)(r,2),a=n[0],c=n[1];return Object(s.useEffect)((function(){c(e.showAllDetails)}),[e.showAllDetails]),Object(j.jsxs)("div",{className:"shadow bg-white rounded-md py-4 px-4 flex flex-wrap md:flex-nowrap mb-3 animate-slide-right",children:[Object(j.jsxs)("div",{className:"md:w-56 md:mb-0 mb-6 flex-shrink-0 flex flex-col",children:[Object(j.jsx)("span",{className:"self-start inline-block py-1 px-2 rounded bg-indigo-50 text-indigo-500 text-s font-semibold tracking-widest",children:e.fields.method}),Object(j.jsx)("div",{className:"mt-1 text-gray-400 text-sm",children:t})]}),Object(j.jsxs)("div",{className:"md:flex-grow",children:[Object(j.jsxs)("div",{className:"flex w-full mx-auto",children:[""!==e.fields.url&&Object(j.jsxs)("div",{children:[Object(j.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400",children:"URL"}),Object(j.jsx)("h2",{className:"font-medium text-gray-800 title-font mb-5 text-xl",children:e.fields.url})]}),Object(j.jsx)(k,{id:e.id,showDetails:a,toggleDetails:function(){return c(!a)}})]}),a?Object(j.jsx)("section",{className:"text-gray-600 body-font border-t-2 pt-3 border-gray-100",children:Object(j.jsx)("div",{className:"container py-2 mx-auto",children:Object(j.jsxs)("div",{className:"flex flex-wrap -m-4",children:[e.headers&&Object(j.jsx)(m,{headers:e.headers}),Object(j.jsx)(N,{params:e.param_fields,message:e.message})]})})}):Object(j.jsx)("div",{})]})]})},R=Object(d.a)(C||(C=Object(o.a)(["\n  query GetAllRequests {\n    requests {\n      id\n      fields {\n        method\n        url\n      }\n      headers\n      param_fields {\n        form\n        query\n        json\n        json_array\n      }\n      created_at\n      message\n    }\n  }\n"]))),_=Object(d.a)(L||(L=Object(o.a)(["\n  subscription OnRequestCreated {\n    request {\n      id\n      fields {\n        method\n        url\n      }\n      headers\n      param_fields {\n        form\n        query\n        json\n        json_array\n      }\n      created_at\n      message\n    }\n  }\n"]))),A=Object(d.a)(D||(D=Object(o.a)(["\n  mutation ClearRequests {\n    clearRequests\n  }\n"])));function I(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:"All";return e.filter((function(e){return!("ALL"!==t&&t!==e.fields.method)}))}function E(e){return e.loading?Object(j.jsx)("div",{children:"Loading requests..."}):e.error?Object(j.jsx)("div",{children:"Failed to load."}):I(e.requests.slice().sort((function(e,t){return new Date(t.created_at)-new Date(e.created_at)})),e.selectedFilter).map((function(t){var r=t.id,s=t.fields,n=t.headers,a=t.param_fields,c=t.created_at,l=t.message;return Object(j.jsx)(M,{created_at:c,fields:s,headers:n,param_fields:a,id:r,showAllDetails:e.showAllDetails,message:l},r)}))}function F(e){var t=Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M13.875 18.825A10.05 10.05 0 0112 19c-4.478 0-8.268-2.943-9.543-7a9.97 9.97 0 011.563-3.029m5.858.908a3 3 0 114.243 4.243M9.878 9.878l4.242 4.242M9.88 9.88l-3.29-3.29m7.532 7.532l3.29 3.29M3 3l3.59 3.59m0 0A9.953 9.953 0 0112 5c4.478 0 8.268 2.943 9.543 7a10.025 10.025 0 01-4.132 5.411m0 0L21 21"})}),r=Object(j.jsxs)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:[Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M15 12a3 3 0 11-6 0 3 3 0 016 0z"}),Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M2.458 12C3.732 7.943 7.523 5 12 5c4.478 0 8.268 2.943 9.542 7-1.274 4.057-5.064 7-9.542 7-4.477 0-8.268-2.943-9.542-7z"})]});return Object(j.jsxs)("button",{onClick:e.toggle,className:"ml-1 items-center cursor-pointer inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[e.showAllDetails?t:r,e.showAllDetails?"Hide Details":"Show Details"]})}function T(e){return e.filters.map((function(t,r){return Object(j.jsx)("li",{onClick:function(){return e.setSelectedFilter(t)},children:Object(j.jsx)("button",{className:"".concat(r===e.filters.length-1?"rounded-b":""," focus:outline-none bg-indigo-500 hover:bg-indigo-900 py-2 px-4 block w-full text-left whitespace-no-wrap"),children:t})},r)}))}var B,V=function(e,t){var r=arguments.length>2&&void 0!==arguments[2]?arguments[2]:"s";return"".concat(e," ").concat(t).concat(1!==e?r:"")},z=function(e){var t=Object(u.a)(R),r=t.loading,n=t.error,a=t.data,c=t.subscribeToMore,o=Object(b.a)(A,{update:function(e){e.modify({fields:{requests:function(){return[]}}})}}),d=Object(l.a)(o,1)[0],x=Object(s.useState)([]),m=Object(l.a)(x,2),h=m[0],f=m[1],O=Object(s.useState)(!1),g=Object(l.a)(O,2),v=g[0],p=g[1],w=Object(s.useState)(!0),y=Object(l.a)(w,2),N=y[0],k=y[1],q=Object(s.useState)("ALL"),S=Object(l.a)(q,2),C=S[0],L=S[1];return Object(s.useEffect)((function(){a&&f(a.requests),v||(c({document:_,updateQuery:function(e,t){var r=t.subscriptionData;if(!r.data)return e;var s=r.data.request;return Object.assign({},e,{requests:[s].concat(Object(i.a)(e.requests))})}}),p(!0))}),[a,v,c]),Object(j.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:Object(j.jsxs)("div",{className:"container px-5 py-12 mx-auto",children:[Object(j.jsxs)("div",{className:"flex flex-wrap w-full",children:[Object(j.jsxs)("div",{className:"lg:w-1/2 w-full mb-6 lg:mb-0",children:[Object(j.jsx)("div",{className:"flex flex-col sm:flex-row sm:items-center items-start mx-auto",children:Object(j.jsx)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:V(I(h,C).length,"Request")})}),Object(j.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"})]}),Object(j.jsxs)("div",{className:"flex items-center lg:w-1/2 w-full mb-5 flex-row-reverse",children:[Object(j.jsxs)("div",{className:"group inline-block relative",children:[Object(j.jsxs)("button",{className:"ml-1 items-center cursor-pointer inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M3 4a1 1 0 011-1h16a1 1 0 011 1v2.586a1 1 0 01-.293.707l-6.414 6.414a1 1 0 00-.293.707V17l-4 4v-6.586a1 1 0 00-.293-.707L3.293 7.293A1 1 0 013 6.586V4z"})}),"Filter: ",C]}),Object(j.jsxs)("ul",{className:"absolute hidden right-0 w-max text-white pt-1 group-hover:block z-10",children:[Object(j.jsx)("li",{onClick:function(){return L("ALL")},children:Object(j.jsx)("button",{className:"focus:outline-none rounded-t bg-indigo-500 hover:bg-indigo-900 py-2 px-4 block w-full text-left whitespace-no-wrap",children:"ALL"})}),Object(j.jsx)(T,{filters:e.filters,setSelectedFilter:L})]})]}),Object(j.jsx)(F,{showAllDetails:N,toggle:function(){return k(!N)}}),Object(j.jsxs)("button",{onClick:function(){window.confirm("Are you sure you want to clear all requests?")&&d()},className:"cursor-pointer items-center inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"})}),"Clear Requests"]})]})]}),Object(j.jsx)(E,{selectedFilter:C,error:n,loading:r,requests:h,showAllDetails:N})]})})},P=Object(d.a)(B||(B=Object(o.a)(["\n  query GetServerInfo {\n    serverInfo {\n      request_address\n      request_port\n    }\n  }\n"])));function H(e){return e.filters.map((function(e,t){return Object(j.jsx)("option",{children:e},t)}))}var G,W=function(e){var t=Object(u.a)(P).data,r=Object(s.useState)("GET"),n=Object(l.a)(r,2),a=n[0],c=n[1],o=Object(s.useState)(""),i=Object(l.a)(o,2),d=i[0],b=i[1],x=Object(s.useState)(JSON.stringify({hello:"world"})),m=Object(l.a)(x,2),h=m[0],f=m[1];return Object(s.useEffect)((function(){t&&b("http://".concat(t.serverInfo.request_address,":").concat(t.serverInfo.request_port))}),[t]),e.visible?Object(j.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:Object(j.jsx)("div",{className:"container p-5 mx-auto max-w-2xl",children:Object(j.jsxs)("div",{className:"bg-white rounded shadow py-4 px-4",children:[Object(j.jsx)("h2",{className:"text-gray-900 text-lg mb-1 font-medium title-font",children:"Send a Request"}),Object(j.jsxs)("div",{className:"flex flex-wrap mb-4",children:[Object(j.jsxs)("div",{className:"md:pr-1 md:w-2/6 sm:w-1/2 w-full",children:[Object(j.jsx)("label",{htmlFor:"method",className:"tracking-midwest text-xs text-gray-400",children:"METHOD"}),Object(j.jsx)("div",{className:"flex",children:Object(j.jsxs)("div",{className:"relative w-full",children:[Object(j.jsx)("select",{name:"method",id:"method",className:"w-full rounded border appearance-none border-gray-300 py-2 focus:outline-none focus:ring-2 focus:ring-red-200 focus:border-red-500 text-base pl-3 pr-10",onChange:function(e){return c(e.target.value)},value:a,children:Object(j.jsx)(H,{filters:e.filters})}),Object(j.jsx)("span",{className:"absolute right-0 top-0 h-full w-10 text-center text-gray-600 pointer-events-none flex items-center justify-center",children:Object(j.jsx)("svg",{fill:"none",stroke:"currentColor",strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:"2",className:"w-4 h-4",viewBox:"0 0 24 24",children:Object(j.jsx)("path",{d:"M6 9l6 6 6-6"})})})]})})]}),Object(j.jsx)("div",{className:"md:pl-1 md:w-4/6 sm:w-1/2 w-full",children:Object(j.jsxs)("div",{className:"relative",children:[Object(j.jsx)("label",{htmlFor:"url",className:"tracking-midwest text-xs text-gray-400",children:"URL"}),Object(j.jsx)("input",{type:"text",id:"url",name:"url",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:d,onChange:function(e){return b(e.target.value)}})]})})]}),Object(j.jsxs)("div",{className:"relative mb-4",children:[Object(j.jsx)("label",{htmlFor:"body",className:"tracking-midwest text-xs text-gray-400",children:"BODY"}),Object(j.jsx)("textarea",{id:"body",name:"body",className:"w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out",onChange:function(e){return f(e.target.value)},value:h})]}),Object(j.jsx)("button",{onClick:function(){fetch(d,{method:a,body:"GET"===a||"HEAD"===a?null:h,headers:{"Content-Type":"application/json"}})},className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Send Request"}),Object(j.jsx)("button",{onClick:e.close,className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Close"})]})})}):Object(j.jsx)("div",{})},J=Object(d.a)(G||(G=Object(o.a)(["\n  query GetServerInfo {\n    serverInfo {\n      request_address\n      request_port\n      protocol\n    }\n  }\n"])));var U,Y=function(e){var t=Object(u.a)(J).data,r=Object(s.useState)(""),n=Object(l.a)(r,2),a=n[0],c=n[1],o=Object(s.useState)(JSON.stringify({hello:"world"})),i=Object(l.a)(o,2),d=i[0],b=i[1],x=Object(s.useState)(!1),m=Object(l.a)(x,2),h=m[0],f=m[1],O=Object(s.useState)(null),g=Object(l.a)(O,2),v=g[0],p=g[1];return Object(s.useEffect)((function(){t&&c("".concat(t.serverInfo.protocol,"://").concat(t.serverInfo.request_address,":").concat(t.serverInfo.request_port))}),[t]),e.visible?Object(j.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:Object(j.jsx)("div",{className:"container p-5 mx-auto max-w-2xl",children:Object(j.jsxs)("div",{className:"bg-white rounded shadow py-4 px-4",children:[Object(j.jsx)("h2",{className:"text-gray-900 text-lg mb-1 font-medium title-font",children:"Send a WebSocket Message"}),Object(j.jsx)("div",{className:"flex flex-wrap mb-4",children:Object(j.jsx)("div",{className:"w-full",children:Object(j.jsxs)("div",{className:"relative",children:[Object(j.jsx)("label",{htmlFor:"url",className:"tracking-midwest text-xs text-gray-400",children:"URL"}),!1===h?Object(j.jsx)("input",{type:"text",id:"url",name:"url",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:a,onChange:function(e){return c(e.target.value)}}):Object(j.jsxs)("div",{className:"text-green-500",children:["Connected to ",a]})]})})}),h&&Object(j.jsxs)("div",{className:"relative mb-4",children:[Object(j.jsx)("label",{htmlFor:"body",className:"tracking-midwest text-xs text-gray-400",children:"BODY"}),Object(j.jsx)("textarea",{id:"body",name:"body",className:"w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out",onChange:function(e){return b(e.target.value)},value:d})]}),!0===h?Object(j.jsx)("button",{onClick:function(){v.send(d)},className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Send Request"}):Object(j.jsx)("button",{onClick:function(){return function(){var e=new WebSocket(a);e.addEventListener("open",(function(t){f(!0),p(e)})),e.addEventListener("close",(function(e){f(!1),p(null)}))}()},className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Connect"}),!0===h&&Object(j.jsx)("button",{onClick:function(){v&&(v.close(),f(!1))},className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Disconnect"}),Object(j.jsx)("button",{onClick:e.close,className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Close"})]})})}):Object(j.jsx)("div",{})},Q=Object(d.a)(U||(U=Object(o.a)(["\n  query GetServerInfo {\n    serverInfo {\n      request_address\n      request_port\n      build_info\n      protocol\n    }\n  }\n"])));function K(e){return e.loading?Object(j.jsx)("div",{children:"Loading server info..."}):e.error?Object(j.jsx)("div",{children:"Failed to load server info."}):Object(j.jsxs)("div",{className:"bg-gray-100 rounded py-1 px-3 text-sm flex flex-wrap items-center justify-center",children:[Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:Object(j.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M5 12h14M5 12a2 2 0 01-2-2V6a2 2 0 012-2h14a2 2 0 012 2v4a2 2 0 01-2 2M5 12a2 2 0 00-2 2v4a2 2 0 002 2h14a2 2 0 002-2v-4a2 2 0 00-2-2m-2-4h.01M17 16h.01"})}),"Listening on: ",e.url]})}var X,Z=function(e){var t=Object(u.a)(Q),r=t.loading,n=t.error,a=t.data,c=Object(s.useState)(""),o=Object(l.a)(c,2),i=o[0],d=o[1],b=Object(s.useState)(""),x=Object(l.a)(b,2),m=x[0],h=x[1],f=Object(s.useState)(""),O=Object(l.a)(f,2),g=O[0],v=O[1];return Object(s.useEffect)((function(){a&&(d("".concat(a.serverInfo.protocol,"://").concat(a.serverInfo.request_address,":").concat(a.serverInfo.request_port)),h(a.serverInfo.build_info.version),v(a.serverInfo.protocol))}),[a]),Object(j.jsx)("header",{className:"text-gray-600 body-font border-b-2 bg-white",children:Object(j.jsxs)("div",{className:"container mx-auto flex flex-wrap p-5 flex-col md:flex-row items-center",children:[Object(j.jsxs)("a",{href:"/",className:"flex title-font font-medium items-center text-gray-900 mb-4 md:mb-0",children:[Object(j.jsx)("span",{className:"text-xl",children:"Request Hole"}),Object(j.jsx)("h2",{className:"tracking-widest text-sm ml-2 title-font font-light text-gray-400",children:m})]}),Object(j.jsx)("div",{className:"md:mr-auto md:ml-4 md:py-1 md:pl-4 md:border-l md:border-gray-400\tflex flex-wrap items-center text-base justify-center",children:Object(j.jsx)(K,{loading:r,error:n,url:i})}),Object(j.jsxs)("nav",{className:"md:ml-auto flex flex-wrap items-center text-base justify-center",children:[Object(j.jsxs)("button",{onClick:function(){return e.setSendRequestVisible(!e.sendRequestVisible)},className:"focus:outline-none mr-5 hover:text-gray-900 flex flex-wrap items-center text-base",children:[Object(j.jsxs)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-5 w-5 mr-1",viewBox:"0 0 20 20",fill:"currentColor",children:[Object(j.jsx)("path",{d:"M8.707 7.293a1 1 0 00-1.414 1.414l2 2a1 1 0 001.414 0l2-2a1 1 0 00-1.414-1.414L11 7.586V3a1 1 0 10-2 0v4.586l-.293-.293z"}),Object(j.jsx)("path",{d:"M3 5a2 2 0 012-2h1a1 1 0 010 2H5v7h2l1 2h4l1-2h2V5h-1a1 1 0 110-2h1a2 2 0 012 2v10a2 2 0 01-2 2H5a2 2 0 01-2-2V5z"})]}),"Send a ","ws"===g?"WebSocket Message":"Request"]}),Object(j.jsxs)("a",{href:"https://github.com/aaronvb/request_hole",className:"hover:text-gray-900 flex flex-wrap items-center text-base",children:[Object(j.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-5 w-5 mr-1",viewBox:"0 0 20 20",fill:"currentColor",children:Object(j.jsx)("path",{fillRule:"evenodd",d:"M12.316 3.051a1 1 0 01.633 1.265l-4 12a1 1 0 11-1.898-.632l4-12a1 1 0 011.265-.633zM5.707 6.293a1 1 0 010 1.414L3.414 10l2.293 2.293a1 1 0 11-1.414 1.414l-3-3a1 1 0 010-1.414l3-3a1 1 0 011.414 0zm8.586 0a1 1 0 011.414 0l3 3a1 1 0 010 1.414l-3 3a1 1 0 11-1.414-1.414L16.586 10l-2.293-2.293a1 1 0 010-1.414z",clipRule:"evenodd"})}),"View Project on GitHub"]})]})]})})},$=["GET","POST","PUT","PATCH","DELETE","HEAD","OPTIONS","RECEIVE"],ee=Object(d.a)(X||(X=Object(o.a)(["\n  query GetServerInfo {\n    serverInfo {\n      protocol\n    }\n  }\n"])));var te=function(){var e=Object(u.a)(ee).data,t=Object(s.useState)(!1),r=Object(l.a)(t,2),n=r[0],a=r[1],c=Object(s.useState)(""),o=Object(l.a)(c,2),i=o[0],d=o[1];return Object(s.useEffect)((function(){e&&d(e.serverInfo.protocol)}),[e]),Object(j.jsxs)("div",{children:[Object(j.jsx)(Z,{sendRequestVisible:n,setSendRequestVisible:a}),"ws"===i?Object(j.jsx)(Y,{visible:n,close:function(){return a(!1)}}):Object(j.jsx)(W,{filters:$,visible:n,close:function(){return a(!1)}}),Object(j.jsx)(z,{filters:$})]})},re=function(e){e&&e instanceof Function&&r.e(3).then(r.bind(null,94)).then((function(t){var r=t.getCLS,s=t.getFID,n=t.getFCP,a=t.getLCP,c=t.getTTFB;r(e),s(e),n(e),a(e),c(e)}))},se=r(52),ne=r(23),ae=r(89),ce=r(85),le=r(88),oe=r(90),ie=r(86),de=document.location.host;var ue=new ae.a({uri:"http://".concat(de,"/query")}),be=new se.a({uri:"ws://".concat(de,"/query"),options:{reconnect:!0}}),je=Object(ce.a)((function(e){var t=e.query,r=Object(ne.e)(t);return"OperationDefinition"===r.kind&&"subscription"===r.operation}),be,ue),xe=new le.a({link:je,cache:new oe.a({typePolicies:{ServerInfo:{merge:!0}}})});c.a.render(Object(j.jsx)(ie.a,{client:xe,children:Object(j.jsx)(n.a.StrictMode,{children:Object(j.jsx)(te,{})})}),document.getElementById("root")),re()}},[[76,1,2]]]);
-//# sourceMappingURL=main.0bd0085c.chunk.js.map
\ No newline at end of file
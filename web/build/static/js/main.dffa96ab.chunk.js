@@ -0,0 +1,296 @@
+(this.webpackJsonpweb=this.webpackJsonpweb||[]).push([[0],{76:function(module,exports,__webpack_require__){"use strict";
+(()=>{var He=Object.create;var te=Object.defineProperty;var Ue=Object.getOwnPropertyDescriptor;var Be=Object.getOwnPropertyNames;var Qe=Object.getPrototypeOf,We=Object.prototype.hasOwnProperty;var W=(e,t)=>()=>(t||e((t={exports:{}}).exports,t),t.exports);var Ge=(e,t,a,s)=>{if(t&&typeof t=="object"||typeof t=="function")for(let r of Be(t))!We.call(e,r)&&r!==a&&te(e,r,{get:()=>t[r],enumerable:!(s=Ue(t,r))||s.enumerable});return e};var o=(e,t,a)=>(a=e!=null?He(Qe(e)):{},Ge(t||!e||!e.__esModule?te(a,"default",{value:e,enumerable:!0}):a,e));var I=W((Zt,ae)=>{ae.exports=__webpack_require__(3)});var re=W((ea,se)=>{se.exports=__webpack_require__(49)});var d=W((aa,ce)=>{ce.exports=__webpack_require__(1)});var xe=W((oa,ge)=>{ge.exports=__webpack_require__(42)});var Pe=o(I()),je=o(re());var S=__webpack_require__(91).a,D=__webpack_require__(93).a,g=__webpack_require__(87).a,oe=__webpack_require__(88).a,ne=__webpack_require__(90).a,ie=__webpack_require__(89).a,le=__webpack_require__(85).a,de=__webpack_require__(86).a;var G=o(I());var M=o(d());function Ye(e){let t=e.attachments||[];return(0,M.jsx)("div",{className:"p-4 w-full",children:(0,M.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,M.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:me(t.length,"FILE","S")}),t.map((a,s)=>(0,M.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,M.jsx)("span",{className:"text-gray-500",children:a.field}),(0,M.jsx)("span",{className:"ml-4 text-gray-900",children:a.path!==""?(0,M.jsx)("a",{href:`/attachments/${a.id}`,className:"text-indigo-500 hover:underline",children:a.filename}):a.filename}),(0,M.jsxs)("span",{className:"ml-auto text-gray-900",children:[a.content_type,", ",me(a.size,"byte")]}),(0,M.jsx)("span",{className:"ml-4 font-mono text-gray-500 truncate w-24",children:a.sha256})]},s))]})})}var me=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`,ue=Ye;var O=o(d());function Je(e){let t=e.noun||"HEADER",a={};return e.headers!=null&&(a=e.headers),(0,O.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,O.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,O.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:Xe(Object.keys(a).length,t,"S")}),Object.keys(a).map((s,r)=>(0,O.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,O.jsx)("span",{className:"text-gray-500",children:s}),(0,O.jsx)("span",{className:"ml-auto text-gray-900",children:a[s]})]},r))]})})}var Xe=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`,K=Je;var Z=o(xe());var be=o(I()),p=o(d());function Ke(e){let t=e.email,[a,s]=(0,be.useState)(t.html?"html":"text"),r=[["from",t.from||"<>"],["to",(t.to||[]).join(", ")],["subject",t.subject],["helo",t.helo],["auth user",t.auth_user],["tls",t.tls?"yes":"no"]];return(0,p.jsx)("div",{className:"p-4 w-full",children:(0,p.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,p.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"EMAIL"}),r.map(([f,v],k)=>(0,p.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,p.jsx)("span",{className:"text-gray-500",children:f}),(0,p.jsx)("span",{className:"ml-auto text-gray-900",children:v})]},k)),(0,p.jsxs)("div",{className:"flex border-t border-gray-200 pt-2 text-xs",children:[t.html&&(0,p.jsx)(fe,{name:"HTML",active:a==="html",onClick:()=>s("html")}),t.text&&(0,p.jsx)(fe,{name:"TEXT",active:a==="text",onClick:()=>s("text")})]}),(0,p.jsx)("div",{className:"py-2 text-xs",children:a==="html"&&t.html?(0,p.jsx)("iframe",{title:`email-${e.id}`,sandbox:"",srcDoc:t.html,className:"w-full h-96 bg-white rounded"}):(0,p.jsx)("pre",{className:"whitespace-pre-wrap text-gray-900",children:t.text})}),t.attachments&&t.attachments.length>0&&(0,p.jsxs)("div",{children:[(0,p.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:ve(t.attachments.length,"ATTACHMENT","S")}),t.attachments.map((f,v)=>(0,p.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,p.jsx)("span",{className:"text-gray-500",children:f.filename||f.content_id}),(0,p.jsxs)("span",{className:"ml-auto text-gray-900",children:[f.content_type,","," ",ve(f.size,"byte")]})]},v))]})]})})}function fe(e){return(0,p.jsx)("button",{onClick:e.onClick,className:`${e.active?"bg-indigo-500 text-white":"text-gray-500"} focus:outline-none mr-1 py-1 px-3 rounded`,children:e.name})}var ve=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`,he=Ke;var n=o(d());function Ze(e){return e.email?(0,n.jsx)(he,{id:e.id,email:e.email}):e.metric?(0,n.jsx)(at,{metric:e.metric}):e.params&&e.params.json?(0,n.jsx)(pe,{json:e.params.json}):e.params&&e.params.json_array?(0,n.jsx)(pe,{json:e.params.json_array}):e.params&&e.params.query?(0,n.jsx)(et,{query:e.params.query}):e.params&&e.params.form?(0,n.jsx)(tt,{form:e.params.form}):e.message?(0,n.jsx)(st,{body:e.message}):(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsx)("div",{className:"bg-gray-100 p-4 rounded",children:(0,n.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400",children:"NO PARAMS"})})})}function et(e){return(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,n.jsxs)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:[ye(Object.keys(e.query).length,"QUERY PARAM","S")," "]}),Object.keys(e.query).map((t,a)=>(0,n.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,n.jsx)("span",{className:"text-gray-500",children:t}),(0,n.jsx)("span",{className:"ml-auto text-gray-900",children:e.query[t]})]},a))]})})}function tt(e){return(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,n.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:ye(Object.keys(e.form).length,"FORM PARAM","S")}),Object.keys(e.form).map((t,a)=>(0,n.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,n.jsx)("span",{className:"text-gray-500",children:t}),(0,n.jsx)("span",{className:"ml-auto text-gray-900",children:e.form[t]})]},a))]})})}function at(e){let t=[["name",e.metric.name],["value",e.metric.raw],["type",e.metric.type],["sample rate",e.metric.sample_rate]];return(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsxs)("div",{className:"bg-gray-100 p-4 rounded",children:[(0,n.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"METRIC"}),t.map(([a,s],r)=>(0,n.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,n.jsx)("span",{className:"text-gray-500",children:a}),(0,n.jsx)("span",{className:"ml-auto text-gray-900",children:s})]},r)),e.metric.tags&&e.metric.tags.length>0&&(0,n.jsxs)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:[(0,n.jsx)("span",{className:"text-gray-500",children:"tags"}),(0,n.jsx)("span",{className:"ml-auto text-gray-900",children:e.metric.tags.join(", ")})]})]})})}function pe(e){return(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsxs)("div",{className:"h-full bg-gray-100 p-4 rounded",children:[(0,n.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"JSON BODY"}),(0,n.jsx)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:(0,n.jsx)(Z.default,{src:e.json,name:!1})})]})})}function st(e){return(0,n.jsx)("div",{className:"p-4 md:w-1/2 w-full",children:(0,n.jsxs)("div",{className:"h-full bg-gray-100 p-4 rounded",children:[(0,n.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400 mb-2",children:"MESSAGE"}),(0,n.jsx)("div",{className:"flex border-t border-gray-200 py-2 text-xs",children:rt(e.body)})]})})}var ye=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`;function rt(e){try{let t=JSON.parse(e);return(0,n.jsx)(Z.default,{src:t,name:!1})}catch(t){return e}}var we=Ze;var i=o(d());function ot(e){let t=(0,i.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"cursor-pointer h-8 w-8 hover:text-black",viewBox:"0 0 20 20",fill:"currentColor",children:(0,i.jsx)("path",{fillRule:"evenodd",d:"M5.293 7.293a1 1 0 011.414 0L10 10.586l3.293-3.293a1 1 0 111.414 1.414l-4 4a1 1 0 01-1.414 0l-4-4a1 1 0 010-1.414z",clipRule:"evenodd"})}),a=(0,i.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"cursor-pointer h-8 w-8 hover:text-black",viewBox:"0 0 20 20",fill:"currentColor",children:(0,i.jsx)("path",{fillRule:"evenodd",d:"M14.707 12.707a1 1 0 01-1.414 0L10 9.414l-3.293 3.293a1 1 0 01-1.414-1.414l4-4a1 1 0 011.414 0l4 4a1 1 0 010 1.414z",clipRule:"evenodd"})});return(0,i.jsx)("button",{"data-testid":"toggleDetails",onClick:e.toggleDetails,className:"focus:outline-none flex ml-auto text-gray-500",children:e.showDetails?t:a})}function nt(e){let t=ct(e.created_at),[a,s]=(0,G.useState)(e.showAllDetails);return(0,G.useEffect)(()=>{s(e.showAllDetails)},[e.showAllDetails]),(0,i.jsxs)("div",{className:"shadow bg-white rounded-md py-4 px-4 flex flex-wrap md:flex-nowrap mb-3 animate-slide-right",children:[(0,i.jsxs)("div",{className:"md:w-56 md:mb-0 mb-6 flex-shrink-0 flex flex-col",children:[(0,i.jsx)("span",{className:"self-start inline-block py-1 px-2 rounded text-s font-semibold tracking-widest "+(e.validation&&!e.validation.valid?"bg-red-50 text-red-500":"bg-indigo-50 text-indigo-500"),children:e.fields.method}),(0,i.jsx)("div",{className:"mt-1 text-gray-400 text-sm",children:t}),e.stream_id>0&&(0,i.jsxs)("div",{className:"text-gray-400 text-sm",children:[e.fields.protocol,", stream ",e.stream_id]}),e.peer&&(0,i.jsxs)("div",{className:"text-gray-400 text-sm",children:["uid ",e.peer.uid,", gid ",e.peer.gid,e.peer.pid>0&&`, pid ${e.peer.pid}`]}),e.encoding&&e.encoding.error!==""&&(0,i.jsxs)("div",{className:"text-red-500 text-sm",children:[e.encoding.encoding,", decoding failed: ",e.encoding.error]}),e.encoding&&e.encoding.error===""&&(0,i.jsxs)("div",{className:"text-gray-400 text-sm",children:[e.encoding.encoding,", ",e.encoding.compressed_size," \u2192"," ",Ne(e.encoding.decompressed_size,"byte")]}),e.fault&&(0,i.jsxs)("div",{className:"text-red-500 text-sm",children:["chaos: ",it(e.fault)]}),e.sequence&&(0,i.jsxs)("div",{className:"text-gray-400 text-sm",children:["response ",e.sequence.response," of ",e.sequence.length,", call ",e.sequence.call]}),e.validation&&(0,i.jsxs)("div",{className:(e.validation.valid?"text-green-500":"text-red-500")+" text-sm",children:[e.validation.operation," ",e.validation.valid?"valid":"invalid",e.validation.errors.map(r=>(0,i.jsxs)("div",{children:[r.location,": ",r.message]},r.location+r.message))]}),e.signature&&(0,i.jsxs)("div",{className:lt(e.signature.result)+" text-sm",children:[e.signature.profile," signature ",e.signature.result,e.signature.reason!==""&&`: ${e.signature.reason}`]}),e.size>0&&(0,i.jsx)("div",{className:"text-gray-400 text-sm",children:Ne(e.size,"byte")})]}),(0,i.jsxs)("div",{className:"md:flex-grow",children:[(0,i.jsxs)("div",{className:"flex w-full mx-auto",children:[e.fields.url!==""&&(0,i.jsxs)("div",{children:[(0,i.jsx)("h2",{className:"tracking-midwest text-xs text-gray-400",children:"URL"}),(0,i.jsx)("h2",{className:"font-medium text-gray-800 title-font mb-5 text-xl",children:e.fields.url})]}),(0,i.jsx)(ot,{id:e.id,showDetails:a,toggleDetails:()=>s(!a)})]}),a?(0,i.jsx)("section",{className:"text-gray-600 body-font border-t-2 pt-3 border-gray-100",children:(0,i.jsx)("div",{className:"container py-2 mx-auto",children:(0,i.jsxs)("div",{className:"flex flex-wrap -m-4",children:[e.headers&&(0,i.jsx)(K,{headers:e.headers}),e.trailers&&(0,i.jsx)(K,{headers:e.trailers,noun:"TRAILER"}),(0,i.jsx)(we,{params:e.param_fields,message:e.message,metric:e.metric,email:e.email,id:e.id}),e.attachments&&e.attachments.length>0&&(0,i.jsx)(ue,{attachments:e.attachments})]})})}):(0,i.jsx)("div",{})]})]})}var Ne=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`,it=e=>e.kind==="reset"?"connection reset":e.kind==="hang"?"hang":e.retry_after>0?`${e.status_code}, retry after ${e.retry_after}s`:`${e.status_code}`,lt=e=>({valid:"text-green-500",missing:"text-yellow-500"})[e]||"text-red-500",dt=new Intl.RelativeTimeFormat(void 0,{numeric:"auto"}),_e=[{amount:60,name:"seconds"},{amount:60,name:"minutes"},{amount:24,name:"hours"},{amount:7,name:"days"},{amount:4.34524,name:"weeks"},{amount:12,name:"months"},{amount:Number.POSITIVE_INFINITY,name:"years"}];function ct(e){if(e===void 0)return"";let a=(new Date(e)-new Date)/1e3;for(let s=0;s<=_e.length;s++){let r=_e[s];if(Math.abs(a)<r.amount)return dt.format(Math.round(a),r.name);a/=r.amount}}var ke=nt;var F=o(I()),l=o(d()),mt=g`
+  query GetAllRequests {
+    requests {
+      id
+      fields {
+        method
+        url
+        protocol
+      }
+      headers
+      param_fields {
+        form
+        query
+        json
+        json_array
+      }
+      created_at
+      message
+      size
+      stream_id
+      trailers
+      peer {
+        uid
+        gid
+        pid
+      }
+      encoding {
+        encoding
+        compressed_size
+        decompressed_size
+        error
+      }
+      signature {
+        profile
+        result
+        reason
+      }
+      fault {
+        kind
+        status_code
+        retry_after
+      }
+      sequence {
+        route
+        call
+        response
+        length
+      }
+      validation {
+        operation
+        valid
+        errors {
+          location
+          message
+        }
+      }
+      attachments {
+        id
+        field
+        filename
+        content_type
+        size
+        sha256
+        path
+      }
+      metric {
+        name
+        value
+        raw
+        type
+        sample_rate
+        tags
+      }
+      email {
+        helo
+        auth_user
+        tls
+        from
+        to
+        subject
+        text
+        html
+        attachments {
+          filename
+          content_type
+          content_id
+          disposition
+          size
+        }
+      }
+    }
+  }
+`,ut=g`
+  subscription OnRequestCreated {
+    request {
+      id
+      fields {
+        method
+        url
+        protocol
+      }
+      headers
+      param_fields {
+        form
+        query
+        json
+        json_array
+      }
+      created_at
+      message
+      size
+      stream_id
+      trailers
+      peer {
+        uid
+        gid
+        pid
+      }
+      encoding {
+        encoding
+        compressed_size
+        decompressed_size
+        error
+      }
+      signature {
+        profile
+        result
+        reason
+      }
+      fault {
+        kind
+        status_code
+        retry_after
+      }
+      sequence {
+        route
+        call
+        response
+        length
+      }
+      validation {
+        operation
+        valid
+        errors {
+          location
+          message
+        }
+      }
+      attachments {
+        id
+        field
+        filename
+        content_type
+        size
+        sha256
+        path
+      }
+      metric {
+        name
+        value
+        raw
+        type
+        sample_rate
+        tags
+      }
+      email {
+        helo
+        auth_user
+        tls
+        from
+        to
+        subject
+        text
+        html
+        attachments {
+          filename
+          content_type
+          content_id
+          disposition
+          size
+        }
+      }
+    }
+  }
+`,gt=g`
+  mutation ClearRequests {
+    clearRequests
+  }
+`;function Se(e,t="All"){return e.filter(a=>!(t!=="ALL"&&t!==a.fields.method))}function xt(e){if(e.loading)return(0,l.jsx)("div",{children:"Loading requests..."});if(e.error)return(0,l.jsx)("div",{children:"Failed to load."});let t=e.requests.slice().sort((a,s)=>new Date(s.created_at)-new Date(a.created_at));return Se(t,e.selectedFilter).map(({id:a,fields:s,headers:r,param_fields:f,created_at:v,message:k,size:N,stream_id:_,trailers:q,peer:$,encoding:A,signature:R,fault:L,sequence:j,validation:E,attachments:X,metric:B,email:Q})=>(0,l.jsx)(ke,{created_at:v,fields:s,headers:r,param_fields:f,id:a,showAllDetails:e.showAllDetails,message:k,size:N,stream_id:_,trailers:q,peer:$,encoding:A,signature:R,fault:L,sequence:j,validation:E,attachments:X,metric:B,email:Q},a))}function ft(e){let t=(0,l.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:(0,l.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M13.875 18.825A10.05 10.05 0 0112 19c-4.478 0-8.268-2.943-9.543-7a9.97 9.97 0 011.563-3.029m5.858.908a3 3 0 114.243 4.243M9.878 9.878l4.242 4.242M9.88 9.88l-3.29-3.29m7.532 7.532l3.29 3.29M3 3l3.59 3.59m0 0A9.953 9.953 0 0112 5c4.478 0 8.268 2.943 9.543 7a10.025 10.025 0 01-4.132 5.411m0 0L21 21"})}),a=(0,l.jsxs)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:[(0,l.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M15 12a3 3 0 11-6 0 3 3 0 016 0z"}),(0,l.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M2.458 12C3.732 7.943 7.523 5 12 5c4.478 0 8.268 2.943 9.542 7-1.274 4.057-5.064 7-9.542 7-4.477 0-8.268-2.943-9.542-7z"})]});return(0,l.jsxs)("button",{onClick:e.toggle,className:"ml-1 items-center cursor-pointer inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[e.showAllDetails?t:a,e.showAllDetails?"Hide Details":"Show Details"]})}function vt(e){return e.filters.map((t,a)=>(0,l.jsx)("li",{onClick:()=>e.setSelectedFilter(t),children:(0,l.jsx)("button",{className:`${a===e.filters.length-1?"rounded-b":""} focus:outline-none bg-indigo-500 hover:bg-indigo-900 py-2 px-4 block w-full text-left whitespace-no-wrap`,children:t})},a))}function bt(e){let{loading:t,error:a,data:s,subscribeToMore:r}=S(mt),[f]=D(gt,{update(L){L.modify({fields:{requests(){return[]}}})}}),[v,k]=(0,F.useState)([]),[N,_]=(0,F.useState)(!1),[q,$]=(0,F.useState)(!0),[A,R]=(0,F.useState)("ALL");return(0,F.useEffect)(()=>{s&&k(s.requests),N||(r({document:ut,updateQuery:(L,{subscriptionData:j})=>{if(!j.data)return L;let E=j.data.request;return Object.assign({},L,{requests:[E,...L.requests]})}}),_(!0))},[s,N,r]),(0,l.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:(0,l.jsxs)("div",{className:"container px-5 py-12 mx-auto",children:[(0,l.jsxs)("div",{className:"flex flex-wrap w-full",children:[(0,l.jsxs)("div",{className:"lg:w-1/2 w-full mb-6 lg:mb-0",children:[(0,l.jsx)("div",{className:"flex flex-col sm:flex-row sm:items-center items-start mx-auto",children:(0,l.jsx)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:ht(Se(v,A).length,"Request")})}),(0,l.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"})]}),(0,l.jsxs)("div",{className:"flex items-center lg:w-1/2 w-full mb-5 flex-row-reverse",children:[(0,l.jsxs)("div",{className:"group inline-block relative",children:[(0,l.jsxs)("button",{className:"ml-1 items-center cursor-pointer inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[(0,l.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:(0,l.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M3 4a1 1 0 011-1h16a1 1 0 011 1v2.586a1 1 0 01-.293.707l-6.414 6.414a1 1 0 00-.293.707V17l-4 4v-6.586a1 1 0 00-.293-.707L3.293 7.293A1 1 0 013 6.586V4z"})}),"Filter: ",A]}),(0,l.jsxs)("ul",{className:"absolute hidden right-0 w-max text-white pt-1 group-hover:block z-10",children:[(0,l.jsx)("li",{onClick:()=>R("ALL"),children:(0,l.jsx)("button",{className:"focus:outline-none rounded-t bg-indigo-500 hover:bg-indigo-900 py-2 px-4 block w-full text-left whitespace-no-wrap",children:"ALL"})}),(0,l.jsx)(vt,{filters:e.filters,setSelectedFilter:R})]})]}),(0,l.jsx)(ft,{showAllDetails:q,toggle:()=>$(!q)}),(0,l.jsxs)("button",{onClick:()=>{window.confirm("Are you sure you want to clear all requests?")&&f()},className:"cursor-pointer items-center inline-flex bg-indigo-500 border-0 py-1 px-3 focus:outline-none hover:bg-indigo-900 rounded text-white",children:[(0,l.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:(0,l.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"})}),"Clear Requests"]})]})]}),(0,l.jsx)(xt,{selectedFilter:A,error:a,loading:t,requests:v,showAllDetails:q})]})})}var ht=(e,t,a="s")=>`${e} ${t}${e!==1?a:""}`,qe=bt;var V=o(I());var m=o(d()),pt=g`
+  query GetServerInfo {
+    serverInfo {
+      request_address
+      request_port
+    }
+  }
+`;function yt(e){return e.filters.map((t,a)=>(0,m.jsx)("option",{children:t},a))}function wt(e){let{data:t}=S(pt),[a,s]=(0,V.useState)("GET"),[r,f]=(0,V.useState)(""),[v,k]=(0,V.useState)(JSON.stringify({hello:"world"})),N=()=>{fetch(r,{method:a,body:a==="GET"||a==="HEAD"?null:v,headers:{"Content-Type":"application/json"}})};return(0,V.useEffect)(()=>{t&&f(`http://${t.serverInfo.request_address}:${t.serverInfo.request_port}`)},[t]),e.visible?(0,m.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:(0,m.jsx)("div",{className:"container p-5 mx-auto max-w-2xl",children:(0,m.jsxs)("div",{className:"bg-white rounded shadow py-4 px-4",children:[(0,m.jsx)("h2",{className:"text-gray-900 text-lg mb-1 font-medium title-font",children:"Send a Request"}),(0,m.jsxs)("div",{className:"flex flex-wrap mb-4",children:[(0,m.jsxs)("div",{className:"md:pr-1 md:w-2/6 sm:w-1/2 w-full",children:[(0,m.jsx)("label",{htmlFor:"method",className:"tracking-midwest text-xs text-gray-400",children:"METHOD"}),(0,m.jsx)("div",{className:"flex",children:(0,m.jsxs)("div",{className:"relative w-full",children:[(0,m.jsx)("select",{name:"method",id:"method",className:"w-full rounded border appearance-none border-gray-300 py-2 focus:outline-none focus:ring-2 focus:ring-red-200 focus:border-red-500 text-base pl-3 pr-10",onChange:_=>s(_.target.value),value:a,children:(0,m.jsx)(yt,{filters:e.filters})}),(0,m.jsx)("span",{className:"absolute right-0 top-0 h-full w-10 text-center text-gray-600 pointer-events-none flex items-center justify-center",children:(0,m.jsx)("svg",{fill:"none",stroke:"currentColor",strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:"2",className:"w-4 h-4",viewBox:"0 0 24 24",children:(0,m.jsx)("path",{d:"M6 9l6 6 6-6"})})})]})})]}),(0,m.jsx)("div",{className:"md:pl-1 md:w-4/6 sm:w-1/2 w-full",children:(0,m.jsxs)("div",{className:"relative",children:[(0,m.jsx)("label",{htmlFor:"url",className:"tracking-midwest text-xs text-gray-400",children:"URL"}),(0,m.jsx)("input",{type:"text",id:"url",name:"url",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:r,onChange:_=>f(_.target.value)})]})})]}),(0,m.jsxs)("div",{className:"relative mb-4",children:[(0,m.jsx)("label",{htmlFor:"body",className:"tracking-midwest text-xs text-gray-400",children:"BODY"}),(0,m.jsx)("textarea",{id:"body",name:"body",className:"w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out",onChange:_=>k(_.target.value),value:v})]}),(0,m.jsx)("button",{onClick:()=>N(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Send Request"}),(0,m.jsx)("button",{onClick:e.close,className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Close"})]})})}):(0,m.jsx)("div",{})}var Re=wt;var P=o(I());var y=o(d()),Nt=g`
+  query GetServerInfo {
+    serverInfo {
+      request_address
+      request_port
+      protocol
+    }
+  }
+`;function _t(e){let{data:t}=S(Nt),[a,s]=(0,P.useState)(""),[r,f]=(0,P.useState)(JSON.stringify({hello:"world"})),[v,k]=(0,P.useState)(!1),[N,_]=(0,P.useState)(null),q=()=>{N.send(r)},$=()=>{let R=new WebSocket(a);R.addEventListener("open",function(L){k(!0),_(R)}),R.addEventListener("close",function(L){k(!1),_(null)})},A=()=>{N&&(N.close(),k(!1))};return(0,P.useEffect)(()=>{t&&s(`${t.serverInfo.protocol}://${t.serverInfo.request_address}:${t.serverInfo.request_port}`)},[t]),e.visible?(0,y.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:(0,y.jsx)("div",{className:"container p-5 mx-auto max-w-2xl",children:(0,y.jsxs)("div",{className:"bg-white rounded shadow py-4 px-4",children:[(0,y.jsx)("h2",{className:"text-gray-900 text-lg mb-1 font-medium title-font",children:"Send a WebSocket Message"}),(0,y.jsx)("div",{className:"flex flex-wrap mb-4",children:(0,y.jsx)("div",{className:"w-full",children:(0,y.jsxs)("div",{className:"relative",children:[(0,y.jsx)("label",{htmlFor:"url",className:"tracking-midwest text-xs text-gray-400",children:"URL"}),v===!1?(0,y.jsx)("input",{type:"text",id:"url",name:"url",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:a,onChange:R=>s(R.target.value)}):(0,y.jsxs)("div",{className:"text-green-500",children:["Connected to ",a]})]})})}),v&&(0,y.jsxs)("div",{className:"relative mb-4",children:[(0,y.jsx)("label",{htmlFor:"body",className:"tracking-midwest text-xs text-gray-400",children:"BODY"}),(0,y.jsx)("textarea",{id:"body",name:"body",className:"w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out",onChange:R=>f(R.target.value),value:r})]}),v===!0?(0,y.jsx)("button",{onClick:()=>q(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Send Request"}):(0,y.jsx)("button",{onClick:()=>$(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Connect"}),v===!0&&(0,y.jsx)("button",{onClick:()=>A(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Disconnect"}),(0,y.jsx)("button",{onClick:e.close,className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Close"})]})})}):(0,y.jsx)("div",{})}var Ee=_t;var Y=o(I());var w=o(d()),kt=g`
+  mutation SendEvent($input: SseEvent!) {
+    sendEvent(input: $input)
+  }
+`;function St(e){let[t,a]=(0,Y.useState)(""),[s,r]=(0,Y.useState)(""),[f,v]=(0,Y.useState)(JSON.stringify({hello:"world"})),[k,{data:N}]=D(kt),_=()=>{k({variables:{input:{event:t,id:s,data:f}}})};return e.visible?(0,w.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font h-full",children:(0,w.jsx)("div",{className:"container p-5 mx-auto max-w-2xl",children:(0,w.jsxs)("div",{className:"bg-white rounded shadow py-4 px-4",children:[(0,w.jsx)("h2",{className:"text-gray-900 text-lg mb-1 font-medium title-font",children:"Send an Event"}),(0,w.jsxs)("div",{className:"flex flex-wrap mb-4",children:[(0,w.jsxs)("div",{className:"md:pr-1 md:w-4/6 sm:w-1/2 w-full",children:[(0,w.jsx)("label",{htmlFor:"event",className:"tracking-midwest text-xs text-gray-400",children:"EVENT"}),(0,w.jsx)("input",{type:"text",id:"event",name:"event",placeholder:"message",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:t,onChange:q=>a(q.target.value)})]}),(0,w.jsxs)("div",{className:"md:pl-1 md:w-2/6 sm:w-1/2 w-full",children:[(0,w.jsx)("label",{htmlFor:"id",className:"tracking-midwest text-xs text-gray-400",children:"ID"}),(0,w.jsx)("input",{type:"text",id:"id",name:"id",placeholder:"auto",className:"w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",value:s,onChange:q=>r(q.target.value)})]})]}),(0,w.jsxs)("div",{className:"relative mb-4",children:[(0,w.jsx)("label",{htmlFor:"data",className:"tracking-midwest text-xs text-gray-400",children:"DATA"}),(0,w.jsx)("textarea",{id:"data",name:"data",className:"w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out",onChange:q=>v(q.target.value),value:f})]}),(0,w.jsx)("button",{onClick:()=>_(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Send Event"}),(0,w.jsx)("button",{onClick:e.close,className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Close"}),N&&(0,w.jsxs)("span",{className:"ml-2 text-sm text-gray-400",children:["Sent to ",N.sendEvent," client",N.sendEvent!==1?"s":""]})]})})}):(0,w.jsx)("div",{})}var Ce=St;var u=o(d()),qt=g`
+  query GetMetrics {
+    metrics {
+      name
+      type
+      tags
+      count
+      value
+      p50
+      p95
+    }
+  }
+`,Rt={c:"counter",g:"gauge",ms:"timer",h:"histogram",s:"set",d:"distribution"};function Et(){let{data:e}=S(qt,{pollInterval:2e3});return!e||e.metrics.length===0?(0,u.jsx)("div",{}):(0,u.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font",children:(0,u.jsxs)("div",{className:"container px-5 pt-12 mx-auto",children:[(0,u.jsx)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:"Metrics"}),(0,u.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"}),(0,u.jsx)("div",{className:"shadow bg-white rounded-md py-4 px-4 overflow-x-auto",children:(0,u.jsxs)("table",{className:"table-auto w-full text-left text-sm",children:[(0,u.jsx)("thead",{children:(0,u.jsxs)("tr",{className:"tracking-midwest text-xs text-gray-400",children:[(0,u.jsx)("th",{className:"py-2",children:"NAME"}),(0,u.jsx)("th",{className:"py-2",children:"TYPE"}),(0,u.jsx)("th",{className:"py-2",children:"TAGS"}),(0,u.jsx)("th",{className:"py-2 text-right",children:"COUNT"}),(0,u.jsx)("th",{className:"py-2 text-right",children:"VALUE"}),(0,u.jsx)("th",{className:"py-2 text-right",children:"P50"}),(0,u.jsx)("th",{className:"py-2 text-right",children:"P95"})]})}),(0,u.jsx)("tbody",{children:e.metrics.map((t,a)=>(0,u.jsxs)("tr",{className:"border-t border-gray-200",children:[(0,u.jsx)("td",{className:"py-2 font-medium text-gray-800",children:t.name}),(0,u.jsx)("td",{className:"py-2",children:Rt[t.type]||t.type}),(0,u.jsx)("td",{className:"py-2",children:t.tags?t.tags.join(", "):""}),(0,u.jsx)("td",{className:"py-2 text-right",children:t.count}),(0,u.jsx)("td",{className:"py-2 text-right",children:ee(t.value)}),(0,u.jsx)("td",{className:"py-2 text-right",children:ee(t.p50)}),(0,u.jsx)("td",{className:"py-2 text-right",children:ee(t.p95)})]},a))})]})})]})})}var ee=e=>e==null?"":Number(e.toFixed(2)).toString(),Ae=Et;var x=o(d()),Ct=g`
+  query GetSequences {
+    sequences {
+      route
+      calls
+      next
+      length
+      repeat
+    }
+  }
+`,At=g`
+  mutation ResetSequence($route: String) {
+    resetSequence(route: $route)
+  }
+`;function Lt(){let{data:e,refetch:t}=S(Ct,{pollInterval:2e3}),[a]=D(At,{onCompleted:()=>t()});if(!e||e.sequences.length===0)return(0,x.jsx)("div",{});let s=r=>{a({variables:{route:r}})};return(0,x.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font",children:(0,x.jsxs)("div",{className:"container px-5 pt-12 mx-auto",children:[(0,x.jsxs)("div",{className:"flex items-center justify-between",children:[(0,x.jsx)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:"Response Sequences"}),(0,x.jsx)("button",{onClick:()=>s(null),className:"text-white bg-red-500 border-0 py-1 px-4 focus:outline-none hover:bg-red-600 rounded text-sm",children:"Reset All"})]}),(0,x.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"}),(0,x.jsx)("div",{className:"shadow bg-white rounded-md py-4 px-4 overflow-x-auto",children:(0,x.jsxs)("table",{className:"table-auto w-full text-left text-sm",children:[(0,x.jsx)("thead",{children:(0,x.jsxs)("tr",{className:"tracking-midwest text-xs text-gray-400",children:[(0,x.jsx)("th",{className:"py-2",children:"ROUTE"}),(0,x.jsx)("th",{className:"py-2 text-right",children:"CALLS"}),(0,x.jsx)("th",{className:"py-2 text-right",children:"NEXT"}),(0,x.jsx)("th",{className:"py-2",children:"REPEAT"}),(0,x.jsx)("th",{className:"py-2"})]})}),(0,x.jsx)("tbody",{children:e.sequences.map(r=>(0,x.jsxs)("tr",{className:"border-t border-gray-200",children:[(0,x.jsx)("td",{className:"py-2 font-medium text-gray-800",children:r.route}),(0,x.jsx)("td",{className:"py-2 text-right",children:r.calls}),(0,x.jsxs)("td",{className:"py-2 text-right",children:[r.next," of ",r.length]}),(0,x.jsx)("td",{className:"py-2",children:r.repeat}),(0,x.jsx)("td",{className:"py-2 text-right",children:(0,x.jsx)("button",{onClick:()=>s(r.route),"aria-label":`Reset ${r.route}`,className:"text-red-500 hover:text-red-600",children:"Reset"})})]},r.route))})]})})]})})}var Le=Lt;var b=o(d()),It=g`
+  query GetRateLimits {
+    serverInfo {
+      rate_limits {
+        key
+        limit
+        remaining
+        reset
+        allowed
+        rejected
+      }
+    }
+  }
+`;function Mt(){let{data:e}=S(It,{pollInterval:2e3});return!e||e.serverInfo.rate_limits.length===0?(0,b.jsx)("div",{}):(0,b.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font",children:(0,b.jsxs)("div",{className:"container px-5 pt-12 mx-auto",children:[(0,b.jsx)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:"Rate Limits"}),(0,b.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"}),(0,b.jsx)("div",{className:"shadow bg-white rounded-md py-4 px-4 overflow-x-auto",children:(0,b.jsxs)("table",{className:"table-auto w-full text-left text-sm",children:[(0,b.jsx)("thead",{children:(0,b.jsxs)("tr",{className:"tracking-midwest text-xs text-gray-400",children:[(0,b.jsx)("th",{className:"py-2",children:"KEY"}),(0,b.jsx)("th",{className:"py-2 text-right",children:"REMAINING"}),(0,b.jsx)("th",{className:"py-2 text-right",children:"RESET"}),(0,b.jsx)("th",{className:"py-2 text-right",children:"ALLOWED"}),(0,b.jsx)("th",{className:"py-2 text-right",children:"REJECTED"})]})}),(0,b.jsx)("tbody",{children:e.serverInfo.rate_limits.map(t=>(0,b.jsxs)("tr",{className:"border-t border-gray-200",children:[(0,b.jsx)("td",{className:"py-2 font-medium text-gray-800",children:t.key||"(none)"}),(0,b.jsxs)("td",{className:"py-2 text-right",children:[t.remaining," of ",t.limit]}),(0,b.jsxs)("td",{className:"py-2 text-right",children:[t.reset,"s"]}),(0,b.jsx)("td",{className:"py-2 text-right",children:t.allowed}),(0,b.jsx)("td",{className:`py-2 text-right ${t.rejected>0?"text-red-500":""}`,children:t.rejected})]},t.key))})]})})]})})}var Ie=Mt;var z=o(I());var c=o(d()),Dt=g`
+  query GetResponse {
+    serverInfo {
+      response {
+        status_code
+        headers
+        body
+        delay
+        paused
+      }
+    }
+  }
+`,Tt=g`
+  mutation UpdateResponse($input: ResponseUpdate!) {
+    updateResponse(input: $input) {
+      status_code
+    }
+  }
+`,$t=g`
+  mutation ResetResponse {
+    resetResponse {
+      status_code
+    }
+  }
+`,Ot=g`
+  mutation SetPaused($paused: Boolean!) {
+    setPaused(paused: $paused) {
+      paused
+    }
+  }
+`;function zt(e){return Object.keys(e||{}).sort().map(t=>`${t}: ${e[t]}`).join(`
+`)}function Ft(e){let t={};return e.split(`
+`).forEach(a=>{let s=a.indexOf(":");s>0&&a.slice(0,s).trim()!==""&&(t[a.slice(0,s).trim()]=a.slice(s+1).trim())}),t}function Pt(){let{data:e,refetch:t}=S(Dt),[a,s]=(0,z.useState)(""),[r,f]=(0,z.useState)(""),[v,k]=(0,z.useState)(""),[N,_]=(0,z.useState)(""),[q,$]=(0,z.useState)(""),A={onCompleted:()=>{$(""),t()},onError:T=>$(T.message)},[R]=D(Tt,A),[L]=D($t,A),[j]=D(Ot,A),E=e&&e.serverInfo&&e.serverInfo.response;if((0,z.useEffect)(()=>{E&&(s(String(E.status_code)),f(zt(E.headers)),k(E.body),_(E.delay))},[E]),!E)return(0,c.jsx)("div",{});let X=()=>{R({variables:{input:{status_code:parseInt(a,10),headers:Ft(r),body:v,delay:N}}})},B="w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out",Q="w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-24 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out";return(0,c.jsx)("section",{className:"text-gray-600 bg-gray-100 body-font",children:(0,c.jsxs)("div",{className:"container px-5 pt-12 mx-auto",children:[(0,c.jsxs)("div",{className:"flex items-center justify-between",children:[(0,c.jsxs)("h1",{className:"sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900",children:["Response",E.paused&&(0,c.jsx)("span",{className:"ml-2 align-middle text-xs font-medium py-1 px-2 rounded bg-red-500 text-white",children:"PAUSED"})]}),(0,c.jsx)("button",{onClick:()=>j({variables:{paused:!E.paused}}),className:"text-white bg-red-500 border-0 py-1 px-4 focus:outline-none hover:bg-red-600 rounded text-sm",children:E.paused?"Resume":"Pause"})]}),(0,c.jsx)("div",{className:"h-1 w-1/6 bg-indigo-500 rounded mb-4"}),(0,c.jsxs)("div",{className:"shadow bg-white rounded-md py-4 px-4",children:[(0,c.jsxs)("div",{className:"flex flex-wrap mb-4",children:[(0,c.jsxs)("div",{className:"md:pr-1 md:w-1/2 w-full",children:[(0,c.jsx)("label",{htmlFor:"status_code",className:"tracking-midwest text-xs text-gray-400",children:"STATUS CODE"}),(0,c.jsx)("input",{type:"number",id:"status_code",name:"status_code",className:B,value:a,onChange:T=>s(T.target.value)})]}),(0,c.jsxs)("div",{className:"md:pl-1 md:w-1/2 w-full",children:[(0,c.jsx)("label",{htmlFor:"delay",className:"tracking-midwest text-xs text-gray-400",children:"DELAY"}),(0,c.jsx)("input",{type:"text",id:"delay",name:"delay",placeholder:"100ms-2s",className:B,value:N,onChange:T=>_(T.target.value)})]})]}),(0,c.jsxs)("div",{className:"flex flex-wrap mb-4",children:[(0,c.jsxs)("div",{className:"md:pr-1 md:w-1/2 w-full",children:[(0,c.jsx)("label",{htmlFor:"headers",className:"tracking-midwest text-xs text-gray-400",children:"HEADERS"}),(0,c.jsx)("textarea",{id:"headers",name:"headers",placeholder:"Retry-After: 30",className:Q,value:r,onChange:T=>f(T.target.value)})]}),(0,c.jsxs)("div",{className:"md:pl-1 md:w-1/2 w-full",children:[(0,c.jsx)("label",{htmlFor:"body",className:"tracking-midwest text-xs text-gray-400",children:"BODY"}),(0,c.jsx)("textarea",{id:"body",name:"body",className:Q,value:v,onChange:T=>k(T.target.value)})]})]}),(0,c.jsx)("button",{onClick:()=>X(),className:"mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Apply"}),(0,c.jsx)("button",{onClick:()=>L(),className:"text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base",children:"Reset"}),q&&(0,c.jsx)("span",{className:"ml-2 text-sm text-red-500",children:q})]})]})})}var Me=Pt;var H=o(I()),h=o(d()),jt=g`
+  query GetServerInfo {
+    serverInfo {
+      request_address
+      request_port
+      build_info
+      protocol
+    }
+  }
+`;function Vt(e){return e.loading?(0,h.jsx)("div",{children:"Loading server info..."}):e.error?(0,h.jsx)("div",{children:"Failed to load server info."}):(0,h.jsxs)("div",{className:"bg-gray-100 rounded py-1 px-3 text-sm flex flex-wrap items-center justify-center",children:[(0,h.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-4 w-4 mr-1",fill:"none",viewBox:"0 0 24 24",stroke:"currentColor",children:(0,h.jsx)("path",{strokeLinecap:"round",strokeLinejoin:"round",strokeWidth:2,d:"M5 12h14M5 12a2 2 0 01-2-2V6a2 2 0 012-2h14a2 2 0 012 2v4a2 2 0 01-2 2M5 12a2 2 0 00-2 2v4a2 2 0 002 2h14a2 2 0 002-2v-4a2 2 0 00-2-2m-2-4h.01M17 16h.01"})}),"Listening on: ",e.url]})}function Ht(e){let{loading:t,error:a,data:s}=S(jt),[r,f]=(0,H.useState)(""),[v,k]=(0,H.useState)(""),[N,_]=(0,H.useState)("");return(0,H.useEffect)(()=>{s&&(f(`${s.serverInfo.protocol}://${s.serverInfo.request_address}:${s.serverInfo.request_port}`),k(s.serverInfo.build_info.version),_(s.serverInfo.protocol))},[s]),(0,h.jsx)("header",{className:"text-gray-600 body-font border-b-2 bg-white",children:(0,h.jsxs)("div",{className:"container mx-auto flex flex-wrap p-5 flex-col md:flex-row items-center",children:[(0,h.jsxs)("a",{href:"/",className:"flex title-font font-medium items-center text-gray-900 mb-4 md:mb-0",children:[(0,h.jsx)("span",{className:"text-xl",children:"Request Hole"}),(0,h.jsx)("h2",{className:"tracking-widest text-sm ml-2 title-font font-light text-gray-400",children:v})]}),(0,h.jsx)("div",{className:"md:mr-auto md:ml-4 md:py-1 md:pl-4 md:border-l md:border-gray-400	flex flex-wrap items-center text-base justify-center",children:(0,h.jsx)(Vt,{loading:t,error:a,url:r})}),(0,h.jsxs)("nav",{className:"md:ml-auto flex flex-wrap items-center text-base justify-center",children:[(0,h.jsxs)("button",{onClick:()=>e.setSendRequestVisible(!e.sendRequestVisible),className:"focus:outline-none mr-5 hover:text-gray-900 flex flex-wrap items-center text-base",children:[(0,h.jsxs)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-5 w-5 mr-1",viewBox:"0 0 20 20",fill:"currentColor",children:[(0,h.jsx)("path",{d:"M8.707 7.293a1 1 0 00-1.414 1.414l2 2a1 1 0 001.414 0l2-2a1 1 0 00-1.414-1.414L11 7.586V3a1 1 0 10-2 0v4.586l-.293-.293z"}),(0,h.jsx)("path",{d:"M3 5a2 2 0 012-2h1a1 1 0 010 2H5v7h2l1 2h4l1-2h2V5h-1a1 1 0 110-2h1a2 2 0 012 2v10a2 2 0 01-2 2H5a2 2 0 01-2-2V5z"})]}),Ut(N)]}),(0,h.jsxs)("a",{href:"https://github.com/aaronvb/request_hole",className:"hover:text-gray-900 flex flex-wrap items-center text-base",children:[(0,h.jsx)("svg",{xmlns:"http://www.w3.org/2000/svg",className:"h-5 w-5 mr-1",viewBox:"0 0 20 20",fill:"currentColor",children:(0,h.jsx)("path",{fillRule:"evenodd",d:"M12.316 3.051a1 1 0 01.633 1.265l-4 12a1 1 0 11-1.898-.632l4-12a1 1 0 011.265-.633zM5.707 6.293a1 1 0 010 1.414L3.414 10l2.293 2.293a1 1 0 11-1.414 1.414l-3-3a1 1 0 010-1.414l3-3a1 1 0 011.414 0zm8.586 0a1 1 0 011.414 0l3 3a1 1 0 010 1.414l-3 3a1 1 0 11-1.414-1.414L16.586 10l-2.293-2.293a1 1 0 010-1.414z",clipRule:"evenodd"})}),"View Project on GitHub"]})]})]})})}function Ut(e){switch(e){case"ws":return"Send a WebSocket Message";case"sse":return"Send an Event";default:return"Send a Request"}}var De=Ht;var U=o(I()),C=o(d()),Te=["GET","POST","PUT","PATCH","DELETE","HEAD","OPTIONS","RECEIVE"],Bt=g`
+  query GetServerInfo {
+    serverInfo {
+      protocol
+    }
+  }
+`;function Qt(){let{data:e}=S(Bt),[t,a]=(0,U.useState)(!1),[s,r]=(0,U.useState)("");return(0,U.useEffect)(()=>{e&&r(e.serverInfo.protocol)},[e]),(0,C.jsxs)("div",{children:[(0,C.jsx)(De,{sendRequestVisible:t,setSendRequestVisible:a}),s==="ws"?(0,C.jsx)(Ee,{visible:t,close:()=>a(!1)}):s==="sse"?(0,C.jsx)(Ce,{visible:t,close:()=>a(!1)}):(0,C.jsx)(Re,{filters:Te,visible:t,close:()=>a(!1)}),s==="statsd"&&(0,C.jsx)(Ae,{}),s==="http"&&(0,C.jsx)(Me,{}),s==="http"&&(0,C.jsx)(Le,{}),s==="http"&&(0,C.jsx)(Ie,{}),(0,C.jsx)(qe,{filters:Te})]})}var $e=Qt;var Wt=e=>{e&&e instanceof Function&&__webpack_require__.e(3).then(__webpack_require__.bind(null,94)).then(({getCLS:t,getFID:a,getFCP:s,getLCP:r,getTTFB:f})=>{t(e),a(e),s(e),r(e),f(e)})},Oe=Wt;var ze=__webpack_require__(52).a;var Fe=__webpack_require__(23).e;var J=o(d()),Ve=document.location.host,Gt=new ie({uri:`http://${Ve}/query`}),Yt=new ze({uri:`ws://${Ve}/query`,options:{reconnect:!0}}),Jt=le(({query:e})=>{let t=Fe(e);return t.kind==="OperationDefinition"&&t.operation==="subscription"},Yt,Gt),Xt=new oe({link:Jt,cache:new ne({typePolicies:{ServerInfo:{merge:!0}}})});je.default.render((0,J.jsx)(de,{client:Xt,children:(0,J.jsx)(Pe.default.StrictMode,{children:(0,J.jsx)($e,{})})}),document.getElementById("root"));Oe();})();}},[[76,1,2]]]);
+//# sourceMappingURL=main.dffa96ab.chunk.js.map
\ No newline at end of file
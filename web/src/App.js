@@ -1,6 +1,11 @@
 import Requests from "./Requests";
 import SendRequest from "./SendRequest";
 import SendWebSocket from "./SendWebSocket";
+import SendEvent from "./SendEvent";
+import Metrics from "./Metrics";
+import Sequences from "./Sequences";
+import RateLimits from "./RateLimits";
+import ResponseControls from "./ResponseControls";
 import Header from "./Header";
 import { useQuery, gql } from "@apollo/client";
 import { useState, useEffect } from "react";
@@ -46,6 +51,11 @@ function App() {
           visible={sendRequestVisible}
           close={() => setSendRequestVisible(false)}
         />
+      ) : protocol === "sse" ? (
+        <SendEvent
+          visible={sendRequestVisible}
+          close={() => setSendRequestVisible(false)}
+        />
       ) : (
         <SendRequest
           filters={filters}
@@ -54,6 +64,10 @@ function App() {
         />
       )}
 
+      {protocol === "statsd" && <Metrics />}
+      {protocol === "http" && <ResponseControls />}
+      {protocol === "http" && <Sequences />}
+      {protocol === "http" && <RateLimits />}
       <Requests filters={filters} />
     </div>
   );
@@ -0,0 +1,43 @@
+function Attachments(props) {
+  const attachments = props.attachments || [];
+
+  return (
+    <div className="p-4 w-full">
+      <div className="bg-gray-100 p-4 rounded">
+        <h2 className="tracking-midwest text-xs text-gray-400 mb-2">
+          {pluralize(attachments.length, "FILE", "S")}
+        </h2>
+        {attachments.map((attachment, i) => {
+          return (
+            <div key={i} className="flex border-t border-gray-200 py-2 text-xs">
+              <span className="text-gray-500">{attachment.field}</span>
+              <span className="ml-4 text-gray-900">
+                {attachment.path !== "" ? (
+                  <a
+                    href={`/attachments/${attachment.id}`}
+                    className="text-indigo-500 hover:underline"
+                  >
+                    {attachment.filename}
+                  </a>
+                ) : (
+                  attachment.filename
+                )}
+              </span>
+              <span className="ml-auto text-gray-900">
+                {attachment.content_type}, {pluralize(attachment.size, "byte")}
+              </span>
+              <span className="ml-4 font-mono text-gray-500 truncate w-24">
+                {attachment.sha256}
+              </span>
+            </div>
+          );
+        })}
+      </div>
+    </div>
+  );
+}
+
+const pluralize = (count, noun, suffix = "s") =>
+  `${count} ${noun}${count !== 1 ? suffix : ""}`;
+
+export default Attachments;
@@ -0,0 +1,52 @@
+import { render, screen } from "@testing-library/react";
+import Attachments from "./Attachments";
+
+const attachments = [
+  {
+    id: "foo",
+    field: "avatar",
+    filename: "me.png",
+    content_type: "image/png",
+    size: 1234,
+    sha256: "abc",
+    path: "/tmp/rh-uploads/foo-me.png",
+  },
+  {
+    id: "bar",
+    field: "docs",
+    filename: "readme.txt",
+    content_type: "text/plain",
+    size: 1,
+    sha256: "def",
+    path: "",
+  },
+];
+
+describe("Attachments", () => {
+  test("renders file count", () => {
+    render(<Attachments attachments={attachments} />);
+
+    expect(screen.getByText("2 FILES")).toBeInTheDocument();
+  });
+
+  test("renders download link for stored files", () => {
+    render(<Attachments attachments={attachments} />);
+
+    expect(screen.getByText("me.png").closest("a")).toHaveAttribute(
+      "href",
+      "/attachments/foo"
+    );
+  });
+
+  test("does not render download link for files that were not stored", () => {
+    render(<Attachments attachments={attachments} />);
+
+    expect(screen.getByText("readme.txt").closest("a")).toBeNull();
+  });
+
+  test("renders content type and size", () => {
+    render(<Attachments attachments={attachments} />);
+
+    expect(screen.getByText("image/png, 1234 bytes")).toBeInTheDocument();
+  });
+});
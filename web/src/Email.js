@@ -0,0 +1,102 @@
+import { useState } from "react";
+
+function Email(props) {
+  const email = props.email;
+  const [view, setView] = useState(email.html ? "html" : "text");
+
+  const envelope = [
+    ["from", email.from || "<>"],
+    ["to", (email.to || []).join(", ")],
+    ["subject", email.subject],
+    ["helo", email.helo],
+    ["auth user", email.auth_user],
+    ["tls", email.tls ? "yes" : "no"],
+  ];
+
+  return (
+    <div className="p-4 w-full">
+      <div className="bg-gray-100 p-4 rounded">
+        <h2 className="tracking-midwest text-xs text-gray-400 mb-2">EMAIL</h2>
+        {envelope.map(([key, value], i) => {
+          return (
+            <div key={i} className="flex border-t border-gray-200 py-2 text-xs">
+              <span className="text-gray-500">{key}</span>
+              <span className="ml-auto text-gray-900">{value}</span>
+            </div>
+          );
+        })}
+        <div className="flex border-t border-gray-200 pt-2 text-xs">
+          {email.html && (
+            <Tab
+              name="HTML"
+              active={view === "html"}
+              onClick={() => setView("html")}
+            />
+          )}
+          {email.text && (
+            <Tab
+              name="TEXT"
+              active={view === "text"}
+              onClick={() => setView("text")}
+            />
+          )}
+        </div>
+        <div className="py-2 text-xs">
+          {view === "html" && email.html ? (
+            <iframe
+              title={`email-${props.id}`}
+              sandbox=""
+              srcDoc={email.html}
+              className="w-full h-96 bg-white rounded"
+            />
+          ) : (
+            <pre className="whitespace-pre-wrap text-gray-900">
+              {email.text}
+            </pre>
+          )}
+        </div>
+        {email.attachments && email.attachments.length > 0 && (
+          <div>
+            <h2 className="tracking-midwest text-xs text-gray-400 mb-2">
+              {pluralize(email.attachments.length, "ATTACHMENT", "S")}
+            </h2>
+            {email.attachments.map((attachment, i) => {
+              return (
+                <div
+                  key={i}
+                  className="flex border-t border-gray-200 py-2 text-xs"
+                >
+                  <span className="text-gray-500">
+                    {attachment.filename || attachment.content_id}
+                  </span>
+                  <span className="ml-auto text-gray-900">
+                    {attachment.content_type},{" "}
+                    {pluralize(attachment.size, "byte")}
+                  </span>
+                </div>
+              );
+            })}
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
+
+function Tab(props) {
+  return (
+    <button
+      onClick={props.onClick}
+      className={`${
+        props.active ? "bg-indigo-500 text-white" : "text-gray-500"
+      } focus:outline-none mr-1 py-1 px-3 rounded`}
+    >
+      {props.name}
+    </button>
+  );
+}
+
+const pluralize = (count, noun, suffix = "s") =>
+  `${count} ${noun}${count !== 1 ? suffix : ""}`;
+
+export default Email;
@@ -0,0 +1,66 @@
+import { render, screen } from "@testing-library/react";
+import Email from "./Email";
+
+const email = {
+  helo: "app.local",
+  auth_user: "app",
+  tls: true,
+  from: "app@example.com",
+  to: ["bob@example.com", "carol@example.com"],
+  subject: "Welcome",
+  text: "Hello Bob",
+  html: "<p>Hello Bob</p>",
+  attachments: [
+    {
+      filename: "receipt.pdf",
+      content_type: "application/pdf",
+      content_id: "",
+      disposition: "attachment",
+      size: 8,
+    },
+  ],
+};
+
+describe("Email", () => {
+  test("renders envelope", () => {
+    render(<Email id="1" email={email} />);
+
+    expect(screen.getByText("app@example.com")).toBeInTheDocument();
+    expect(
+      screen.getByText("bob@example.com, carol@example.com")
+    ).toBeInTheDocument();
+    expect(screen.getByText("Welcome")).toBeInTheDocument();
+  });
+
+  test("renders html body in a sandboxed frame", () => {
+    render(<Email id="1" email={email} />);
+
+    const frame = screen.getByTitle("email-1");
+    expect(frame).toHaveAttribute("sandbox", "");
+    expect(frame).toHaveAttribute("srcdoc", "<p>Hello Bob</p>");
+  });
+
+  test("switches to the text body", () => {
+    render(<Email id="1" email={email} />);
+
+    screen.getByRole("button", { name: "TEXT" }).click();
+
+    expect(screen.getByText("Hello Bob")).toBeInTheDocument();
+    expect(screen.queryByTitle("email-1")).not.toBeInTheDocument();
+  });
+
+  test("renders text body without html", () => {
+    render(<Email id="1" email={{ ...email, html: "" }} />);
+
+    expect(screen.getByText("Hello Bob")).toBeInTheDocument();
+    expect(screen.queryByRole("button", { name: "HTML" })).toBeNull();
+  });
+
+  test("renders attachments", () => {
+    render(<Email id="1" email={email} />);
+
+    expect(screen.getByText(/1 attachment/i)).toBeInTheDocument();
+    expect(screen.getByText("receipt.pdf")).toBeInTheDocument();
+    expect(screen.getByText("application/pdf, 8 bytes")).toBeInTheDocument();
+  });
+});
@@ -85,7 +85,7 @@ function Header(props) {
               <path d="M8.707 7.293a1 1 0 00-1.414 1.414l2 2a1 1 0 001.414 0l2-2a1 1 0 00-1.414-1.414L11 7.586V3a1 1 0 10-2 0v4.586l-.293-.293z" />
               <path d="M3 5a2 2 0 012-2h1a1 1 0 010 2H5v7h2l1 2h4l1-2h2V5h-1a1 1 0 110-2h1a2 2 0 012 2v10a2 2 0 01-2 2H5a2 2 0 01-2-2V5z" />
             </svg>
-            Send a {protocol === "ws" ? "WebSocket Message" : "Request"}
+            {sendLabel(protocol)}
           </button>
           <a
             href="https://github.com/aaronvb/request_hole"
@@ -111,4 +111,15 @@ function Header(props) {
   );
 }
 
+function sendLabel(protocol) {
+  switch (protocol) {
+    case "ws":
+      return "Send a WebSocket Message";
+    case "sse":
+      return "Send an Event";
+    default:
+      return "Send a Request";
+  }
+}
+
 export default Header;
@@ -44,6 +44,27 @@ const wsMocks = [
   },
 ];
 
+const sseMocks = [
+  {
+    request: {
+      query: SERVER_INFO,
+    },
+    result: {
+      data: {
+        serverInfo: {
+          build_info: {
+            version: "foo-build",
+          },
+          request_address: "foo-request-address",
+          request_port: "foo-request-port",
+          web_port: "foo-web-port",
+          protocol: "sse",
+        },
+      },
+    },
+  },
+];
+
 describe("Header", () => {
   test("has title", () => {
     render(
@@ -156,4 +177,17 @@ describe("Header fetches and renders server info", () => {
     );
     expect(buildInfo).toBeInTheDocument();
   });
+
+  test("has send event for sse", async () => {
+    render(
+      <MockedProvider mocks={sseMocks} addTypename={false}>
+        <Header />
+      </MockedProvider>
+    );
+
+    await waitFor(() => new Promise((resolve) => setTimeout(resolve, 0)));
+
+    const sendEvent = screen.getByText(/Send an Event/i);
+    expect(sendEvent).toBeInTheDocument();
+  });
 });
@@ -0,0 +1,82 @@
+import { useQuery, gql } from "@apollo/client";
+
+export const METRICS = gql`
+  query GetMetrics {
+    metrics {
+      name
+      type
+      tags
+      count
+      value
+      p50
+      p95
+    }
+  }
+`;
+
+const TYPES = {
+  c: "counter",
+  g: "gauge",
+  ms: "timer",
+  h: "histogram",
+  s: "set",
+  d: "distribution",
+};
+
+function Metrics() {
+  const { data } = useQuery(METRICS, { pollInterval: 2000 });
+
+  if (!data || data.metrics.length === 0) {
+    return <div></div>;
+  }
+
+  return (
+    <section className="text-gray-600 bg-gray-100 body-font">
+      <div className="container px-5 pt-12 mx-auto">
+        <h1 className="sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900">
+          Metrics
+        </h1>
+        <div className="h-1 w-1/6 bg-indigo-500 rounded mb-4"></div>
+        <div className="shadow bg-white rounded-md py-4 px-4 overflow-x-auto">
+          <table className="table-auto w-full text-left text-sm">
+            <thead>
+              <tr className="tracking-midwest text-xs text-gray-400">
+                <th className="py-2">NAME</th>
+                <th className="py-2">TYPE</th>
+                <th className="py-2">TAGS</th>
+                <th className="py-2 text-right">COUNT</th>
+                <th className="py-2 text-right">VALUE</th>
+                <th className="py-2 text-right">P50</th>
+                <th className="py-2 text-right">P95</th>
+              </tr>
+            </thead>
+            <tbody>
+              {data.metrics.map((metric, i) => (
+                <tr key={i} className="border-t border-gray-200">
+                  <td className="py-2 font-medium text-gray-800">
+                    {metric.name}
+                  </td>
+                  <td className="py-2">{TYPES[metric.type] || metric.type}</td>
+                  <td className="py-2">
+                    {metric.tags ? metric.tags.join(", ") : ""}
+                  </td>
+                  <td className="py-2 text-right">{metric.count}</td>
+                  <td className="py-2 text-right">{format(metric.value)}</td>
+                  <td className="py-2 text-right">{format(metric.p50)}</td>
+                  <td className="py-2 text-right">{format(metric.p95)}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      </div>
+    </section>
+  );
+}
+
+const format = (value) =>
+  value === null || value === undefined
+    ? ""
+    : Number(value.toFixed(2)).toString();
+
+export default Metrics;
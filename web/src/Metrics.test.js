@@ -0,0 +1,64 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import { MockedProvider } from "@apollo/client/testing";
+import Metrics, { METRICS } from "./Metrics";
+
+const mocks = [
+  {
+    request: {
+      query: METRICS,
+    },
+    result: {
+      data: {
+        metrics: [
+          {
+            name: "api.latency",
+            type: "ms",
+            tags: ["env:prod"],
+            count: 4,
+            value: 25.125,
+            p50: 20,
+            p95: 40,
+          },
+          {
+            name: "page.views",
+            type: "c",
+            tags: null,
+            count: 2,
+            value: 5,
+            p50: null,
+            p95: null,
+          },
+        ],
+      },
+    },
+  },
+];
+
+describe("Metrics", () => {
+  test("renders aggregated metrics", async () => {
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <Metrics />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByText("api.latency")).toBeInTheDocument()
+    );
+    expect(screen.getByText("timer")).toBeInTheDocument();
+    expect(screen.getByText("env:prod")).toBeInTheDocument();
+    expect(screen.getByText("25.13")).toBeInTheDocument();
+    expect(screen.getByText("page.views")).toBeInTheDocument();
+    expect(screen.getByText("counter")).toBeInTheDocument();
+  });
+
+  test("renders nothing without metrics", () => {
+    render(
+      <MockedProvider mocks={[]} addTypename={false}>
+        <Metrics />
+      </MockedProvider>
+    );
+
+    expect(screen.queryByText("Metrics")).not.toBeInTheDocument();
+  });
+});
@@ -0,0 +1,71 @@
+import { useQuery, gql } from "@apollo/client";
+
+export const RATE_LIMITS = gql`
+  query GetRateLimits {
+    serverInfo {
+      rate_limits {
+        key
+        limit
+        remaining
+        reset
+        allowed
+        rejected
+      }
+    }
+  }
+`;
+
+function RateLimits() {
+  const { data } = useQuery(RATE_LIMITS, { pollInterval: 2000 });
+
+  if (!data || data.serverInfo.rate_limits.length === 0) {
+    return <div></div>;
+  }
+
+  return (
+    <section className="text-gray-600 bg-gray-100 body-font">
+      <div className="container px-5 pt-12 mx-auto">
+        <h1 className="sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900">
+          Rate Limits
+        </h1>
+        <div className="h-1 w-1/6 bg-indigo-500 rounded mb-4"></div>
+        <div className="shadow bg-white rounded-md py-4 px-4 overflow-x-auto">
+          <table className="table-auto w-full text-left text-sm">
+            <thead>
+              <tr className="tracking-midwest text-xs text-gray-400">
+                <th className="py-2">KEY</th>
+                <th className="py-2 text-right">REMAINING</th>
+                <th className="py-2 text-right">RESET</th>
+                <th className="py-2 text-right">ALLOWED</th>
+                <th className="py-2 text-right">REJECTED</th>
+              </tr>
+            </thead>
+            <tbody>
+              {data.serverInfo.rate_limits.map((counter) => (
+                <tr key={counter.key} className="border-t border-gray-200">
+                  <td className="py-2 font-medium text-gray-800">
+                    {counter.key || "(none)"}
+                  </td>
+                  <td className="py-2 text-right">
+                    {counter.remaining} of {counter.limit}
+                  </td>
+                  <td className="py-2 text-right">{counter.reset}s</td>
+                  <td className="py-2 text-right">{counter.allowed}</td>
+                  <td
+                    className={`py-2 text-right ${
+                      counter.rejected > 0 ? "text-red-500" : ""
+                    }`}
+                  >
+                    {counter.rejected}
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      </div>
+    </section>
+  );
+}
+
+export default RateLimits;
@@ -0,0 +1,58 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import { MockedProvider } from "@apollo/client/testing";
+import RateLimits, { RATE_LIMITS } from "./RateLimits";
+
+const rateLimits = (counters) => ({
+  request: {
+    query: RATE_LIMITS,
+  },
+  result: {
+    data: {
+      serverInfo: {
+        rate_limits: counters,
+      },
+    },
+  },
+});
+
+describe("RateLimits", () => {
+  test("renders rate limit counters", async () => {
+    const mocks = [
+      rateLimits([
+        {
+          key: "127.0.0.1",
+          limit: 100,
+          remaining: 0,
+          reset: 42,
+          allowed: 100,
+          rejected: 7,
+        },
+      ]),
+    ];
+
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <RateLimits />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByText("127.0.0.1")).toBeInTheDocument()
+    );
+    expect(screen.getByText("0 of 100")).toBeInTheDocument();
+    expect(screen.getByText("42s")).toBeInTheDocument();
+    expect(screen.getByText("7")).toHaveClass("text-red-500");
+  });
+
+  test("renders nothing without counters", async () => {
+    render(
+      <MockedProvider mocks={[rateLimits([])]} addTypename={false}>
+        <RateLimits />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.queryByText("Rate Limits")).not.toBeInTheDocument()
+    );
+  });
+});
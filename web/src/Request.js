@@ -1,4 +1,5 @@
 import React, { useEffect, useState } from "react";
+import Attachments from "./Attachments";
 import RequestHeaders from "./RequestHeaders";
 import RequestParams from "./RequestParams";
 
@@ -54,10 +55,77 @@ function Request(props) {
   return (
     <div className="shadow bg-white rounded-md py-4 px-4 flex flex-wrap md:flex-nowrap mb-3 animate-slide-right">
       <div className="md:w-56 md:mb-0 mb-6 flex-shrink-0 flex flex-col">
-        <span className="self-start inline-block py-1 px-2 rounded bg-indigo-50 text-indigo-500 text-s font-semibold tracking-widest">
+        <span
+          className={
+            "self-start inline-block py-1 px-2 rounded text-s font-semibold tracking-widest " +
+            (props.validation && !props.validation.valid
+              ? "bg-red-50 text-red-500"
+              : "bg-indigo-50 text-indigo-500")
+          }
+        >
           {props.fields.method}
         </span>
         <div className="mt-1 text-gray-400 text-sm">{time}</div>
+        {props.stream_id > 0 && (
+          <div className="text-gray-400 text-sm">
+            {props.fields.protocol}, stream {props.stream_id}
+          </div>
+        )}
+        {props.peer && (
+          <div className="text-gray-400 text-sm">
+            uid {props.peer.uid}, gid {props.peer.gid}
+            {props.peer.pid > 0 && `, pid ${props.peer.pid}`}
+          </div>
+        )}
+        {props.encoding && props.encoding.error !== "" && (
+          <div className="text-red-500 text-sm">
+            {props.encoding.encoding}, decoding failed: {props.encoding.error}
+          </div>
+        )}
+        {props.encoding && props.encoding.error === "" && (
+          <div className="text-gray-400 text-sm">
+            {props.encoding.encoding}, {props.encoding.compressed_size} →{" "}
+            {pluralize(props.encoding.decompressed_size, "byte")}
+          </div>
+        )}
+        {props.fault && (
+          <div className="text-red-500 text-sm">
+            chaos: {faultText(props.fault)}
+          </div>
+        )}
+        {props.sequence && (
+          <div className="text-gray-400 text-sm">
+            response {props.sequence.response} of {props.sequence.length},
+            call {props.sequence.call}
+          </div>
+        )}
+        {props.validation && (
+          <div
+            className={
+              (props.validation.valid ? "text-green-500" : "text-red-500") +
+              " text-sm"
+            }
+          >
+            {props.validation.operation}{" "}
+            {props.validation.valid ? "valid" : "invalid"}
+            {props.validation.errors.map((error) => (
+              <div key={error.location + error.message}>
+                {error.location}: {error.message}
+              </div>
+            ))}
+          </div>
+        )}
+        {props.signature && (
+          <div className={signatureColor(props.signature.result) + " text-sm"}>
+            {props.signature.profile} signature {props.signature.result}
+            {props.signature.reason !== "" && `: ${props.signature.reason}`}
+          </div>
+        )}
+        {props.size > 0 && (
+          <div className="text-gray-400 text-sm">
+            {pluralize(props.size, "byte")}
+          </div>
+        )}
       </div>
       <div className="md:flex-grow">
         <div className="flex w-full mx-auto">
@@ -80,10 +148,19 @@ function Request(props) {
             <div className="container py-2 mx-auto">
               <div className="flex flex-wrap -m-4">
                 {props.headers && <RequestHeaders headers={props.headers} />}
+                {props.trailers && (
+                  <RequestHeaders headers={props.trailers} noun="TRAILER" />
+                )}
                 <RequestParams
                   params={props.param_fields}
                   message={props.message}
+                  metric={props.metric}
+                  email={props.email}
+                  id={props.id}
                 />
+                {props.attachments && props.attachments.length > 0 && (
+                  <Attachments attachments={props.attachments} />
+                )}
               </div>
             </div>
           </section>
@@ -95,6 +172,22 @@ function Request(props) {
   );
 }
 
+const pluralize = (count, noun, suffix = "s") =>
+  `${count} ${noun}${count !== 1 ? suffix : ""}`;
+
+const faultText = (fault) => {
+  if (fault.kind === "reset") return "connection reset";
+  if (fault.kind === "hang") return "hang";
+
+  return fault.retry_after > 0
+    ? `${fault.status_code}, retry after ${fault.retry_after}s`
+    : `${fault.status_code}`;
+};
+
+const signatureColor = (result) =>
+  ({ valid: "text-green-500", missing: "text-yellow-500" }[result] ||
+  "text-red-500");
+
 // Calculate relative time
 // https://blog.webdevsimplified.com/2020-07/relative-time-format/
 //
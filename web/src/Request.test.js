@@ -28,6 +28,170 @@ describe("Request", () => {
     ).toBeInTheDocument();
   });
 
+  test("renders size", () => {
+    render(<Request fields={{}} size={12} />);
+
+    expect(screen.getByText("12 bytes")).toBeInTheDocument();
+  });
+
+  test("renders protocol and stream id", () => {
+    render(<Request fields={{ protocol: "HTTP/2.0" }} stream_id={3} />);
+
+    expect(screen.getByText("HTTP/2.0, stream 3")).toBeInTheDocument();
+  });
+
+  test("renders peer credentials", () => {
+    render(<Request fields={{}} peer={{ uid: 501, gid: 20, pid: 1234 }} />);
+
+    expect(screen.getByText("uid 501, gid 20, pid 1234")).toBeInTheDocument();
+  });
+
+  test("renders trailers", () => {
+    render(
+      <Request
+        fields={{}}
+        showAllDetails={true}
+        trailers={{ "X-Checksum": ["abc"] }}
+      />
+    );
+
+    expect(screen.getByText(/1 trailer$/i)).toBeInTheDocument();
+  });
+
+  test("renders body encoding", () => {
+    render(
+      <Request
+        fields={{}}
+        encoding={{
+          encoding: "gzip",
+          compressed_size: 20,
+          decompressed_size: 100,
+          error: "",
+        }}
+      />
+    );
+
+    expect(screen.getByText("gzip, 20 → 100 bytes")).toBeInTheDocument();
+  });
+
+  test("renders body decoding error", () => {
+    render(
+      <Request
+        fields={{}}
+        encoding={{
+          encoding: "br",
+          compressed_size: 20,
+          decompressed_size: 0,
+          error: "br: invalid data",
+        }}
+      />
+    );
+
+    expect(
+      screen.getByText("br, decoding failed: br: invalid data")
+    ).toBeInTheDocument();
+  });
+
+  test("renders signature verification", () => {
+    render(
+      <Request
+        fields={{}}
+        signature={{
+          profile: "github",
+          result: "invalid",
+          reason: "signature does not match",
+        }}
+      />
+    );
+
+    const signature = screen.getByText(
+      "github signature invalid: signature does not match"
+    );
+    expect(signature).toBeInTheDocument();
+    expect(signature).toHaveClass("text-red-500");
+  });
+
+  test("renders injected fault", () => {
+    render(
+      <Request
+        fields={{}}
+        fault={{ kind: "status", status_code: 503, retry_after: 5 }}
+      />
+    );
+
+    expect(screen.getByText("chaos: 503, retry after 5s")).toBeInTheDocument();
+  });
+
+  test("renders sequence position", () => {
+    render(
+      <Request
+        fields={{}}
+        sequence={{ route: "POST /charge", call: 4, response: 3, length: 3 }}
+      />
+    );
+
+    expect(screen.getByText("response 3 of 3, call 4")).toBeInTheDocument();
+  });
+
+  test("renders validation errors", () => {
+    render(
+      <Request
+        fields={{}}
+        validation={{
+          operation: "POST /pets",
+          valid: false,
+          errors: [{ location: "/body/name", message: "is required" }],
+        }}
+      />
+    );
+
+    expect(screen.getByText("/body/name: is required")).toBeInTheDocument();
+    expect(screen.getByText(/POST \/pets invalid/)).toHaveClass("text-red-500");
+  });
+
+  test("renders the method in red when validation fails", () => {
+    render(
+      <Request
+        fields={{ method: "POST" }}
+        validation={{
+          operation: "POST /orders (order.json)",
+          valid: false,
+          errors: [{ location: "/body", message: "invalid JSON" }],
+        }}
+      />
+    );
+
+    expect(screen.getByText("POST")).toHaveClass("bg-red-50");
+  });
+
+  test("renders attachments", () => {
+    render(
+      <Request
+        fields={{}}
+        showAllDetails={true}
+        attachments={[
+          {
+            id: "foo",
+            field: "avatar",
+            filename: "me.png",
+            content_type: "image/png",
+            size: 12,
+            sha256: "abc",
+            path: "",
+          },
+        ]}
+      />
+    );
+
+    expect(screen.getByText("1 FILE")).toBeInTheDocument();
+  });
+
+  test("does not render size if zero", () => {
+    render(<Request fields={{}} size={0} />);
+
+    expect(screen.queryByText(/bytes?$/i)).not.toBeInTheDocument();
+  });
+
   test("does not render headers component if no headers", () => {
     render(<Request fields={{ headers: null }} />);
 
@@ -1,4 +1,5 @@
 function RequestHeaders(props) {
+  const noun = props.noun || "HEADER";
   let headers = {};
 
   if (props.headers != null) {
@@ -9,7 +10,7 @@ function RequestHeaders(props) {
     <div className="p-4 md:w-1/2 w-full">
       <div className="bg-gray-100 p-4 rounded">
         <h2 className="tracking-midwest text-xs text-gray-400 mb-2">
-          {pluralize(Object.keys(headers).length, "HEADER", "S")}
+          {pluralize(Object.keys(headers).length, noun, "S")}
         </h2>
         {Object.keys(headers).map((key, i) => {
           return (
@@ -31,4 +31,12 @@ describe("RequestHeaders", () => {
     expect(screen.getByText(header)).toBeInTheDocument();
     expect(screen.getByText(headers[header])).toBeInTheDocument();
   });
+
+  test("renders count with noun", () => {
+    render(
+      <RequestHeaders headers={{ "X-Checksum": ["abc"] }} noun="TRAILER" />
+    );
+
+    expect(screen.getByText(/1 trailer$/i)).toBeInTheDocument();
+  });
 });
@@ -1,7 +1,12 @@
 import ReactJson from "react-json-view";
+import Email from "./Email";
 
 function RequestParams(props) {
-  if (props.params && props.params.json) {
+  if (props.email) {
+    return <Email id={props.id} email={props.email} />;
+  } else if (props.metric) {
+    return <MetricParams metric={props.metric} />;
+  } else if (props.params && props.params.json) {
     return <JsonParams json={props.params.json} />;
   } else if (props.params && props.params.json_array) {
     return <JsonParams json={props.params.json_array} />;
@@ -62,6 +67,39 @@ function FormParams(props) {
   );
 }
 
+function MetricParams(props) {
+  const rows = [
+    ["name", props.metric.name],
+    ["value", props.metric.raw],
+    ["type", props.metric.type],
+    ["sample rate", props.metric.sample_rate],
+  ];
+
+  return (
+    <div className="p-4 md:w-1/2 w-full">
+      <div className="bg-gray-100 p-4 rounded">
+        <h2 className="tracking-midwest text-xs text-gray-400 mb-2">METRIC</h2>
+        {rows.map(([key, value], i) => {
+          return (
+            <div key={i} className="flex border-t border-gray-200 py-2 text-xs">
+              <span className="text-gray-500">{key}</span>
+              <span className="ml-auto text-gray-900">{value}</span>
+            </div>
+          );
+        })}
+        {props.metric.tags && props.metric.tags.length > 0 && (
+          <div className="flex border-t border-gray-200 py-2 text-xs">
+            <span className="text-gray-500">tags</span>
+            <span className="ml-auto text-gray-900">
+              {props.metric.tags.join(", ")}
+            </span>
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
+
 function JsonParams(props) {
   return (
     <div className="p-4 md:w-1/2 w-full">
@@ -67,4 +67,30 @@ describe("RequestParams", () => {
       expect(screen.getByText(/friday/)).toBeInTheDocument();
     });
   });
+
+  describe("metric", () => {
+    const metric = {
+      name: "api.latency",
+      value: 320,
+      raw: "320",
+      type: "ms",
+      sample_rate: 0.5,
+      tags: ["env:prod", "region:us"],
+    };
+
+    test("renders metric", () => {
+      render(<RequestParams params={{}} metric={metric} />);
+
+      expect(screen.getByText(/metric/i)).toBeInTheDocument();
+      expect(screen.getByText("api.latency")).toBeInTheDocument();
+      expect(screen.getByText("320")).toBeInTheDocument();
+      expect(screen.getByText("0.5")).toBeInTheDocument();
+    });
+
+    test("renders metric tags", () => {
+      render(<RequestParams params={{}} metric={metric} />);
+
+      expect(screen.getByText("env:prod, region:us")).toBeInTheDocument();
+    });
+  });
 });
@@ -9,6 +9,7 @@ export const ALL_REQUESTS = gql`
       fields {
         method
         url
+        protocol
       }
       headers
       param_fields {
@@ -19,6 +20,78 @@ export const ALL_REQUESTS = gql`
       }
       created_at
       message
+      size
+      stream_id
+      trailers
+      peer {
+        uid
+        gid
+        pid
+      }
+      encoding {
+        encoding
+        compressed_size
+        decompressed_size
+        error
+      }
+      signature {
+        profile
+        result
+        reason
+      }
+      fault {
+        kind
+        status_code
+        retry_after
+      }
+      sequence {
+        route
+        call
+        response
+        length
+      }
+      validation {
+        operation
+        valid
+        errors {
+          location
+          message
+        }
+      }
+      attachments {
+        id
+        field
+        filename
+        content_type
+        size
+        sha256
+        path
+      }
+      metric {
+        name
+        value
+        raw
+        type
+        sample_rate
+        tags
+      }
+      email {
+        helo
+        auth_user
+        tls
+        from
+        to
+        subject
+        text
+        html
+        attachments {
+          filename
+          content_type
+          content_id
+          disposition
+          size
+        }
+      }
     }
   }
 `;
@@ -30,6 +103,7 @@ export const REQUESTS_SUBSCRIPTION = gql`
       fields {
         method
         url
+        protocol
       }
       headers
       param_fields {
@@ -40,6 +114,78 @@ export const REQUESTS_SUBSCRIPTION = gql`
       }
       created_at
       message
+      size
+      stream_id
+      trailers
+      peer {
+        uid
+        gid
+        pid
+      }
+      encoding {
+        encoding
+        compressed_size
+        decompressed_size
+        error
+      }
+      signature {
+        profile
+        result
+        reason
+      }
+      fault {
+        kind
+        status_code
+        retry_after
+      }
+      sequence {
+        route
+        call
+        response
+        length
+      }
+      validation {
+        operation
+        valid
+        errors {
+          location
+          message
+        }
+      }
+      attachments {
+        id
+        field
+        filename
+        content_type
+        size
+        sha256
+        path
+      }
+      metric {
+        name
+        value
+        raw
+        type
+        sample_rate
+        tags
+      }
+      email {
+        helo
+        auth_user
+        tls
+        from
+        to
+        subject
+        text
+        html
+        attachments {
+          filename
+          content_type
+          content_id
+          disposition
+          size
+        }
+      }
     }
   }
 `;
@@ -66,7 +212,26 @@ function AllRequests(props) {
     .sort((a, b) => new Date(b.created_at) - new Date(a.created_at));
 
   return filterRequests(sortedRequests, props.selectedFilter).map(
-    ({ id, fields, headers, param_fields, created_at, message }) => (
+    ({
+      id,
+      fields,
+      headers,
+      param_fields,
+      created_at,
+      message,
+      size,
+      stream_id,
+      trailers,
+      peer,
+      encoding,
+      signature,
+      fault,
+      sequence,
+      validation,
+      attachments,
+      metric,
+      email,
+    }) => (
       <Request
         key={id}
         created_at={created_at}
@@ -76,6 +241,18 @@ function AllRequests(props) {
         id={id}
         showAllDetails={props.showAllDetails}
         message={message}
+        size={size}
+        stream_id={stream_id}
+        trailers={trailers}
+        peer={peer}
+        encoding={encoding}
+        signature={signature}
+        fault={fault}
+        sequence={sequence}
+        validation={validation}
+        attachments={attachments}
+        metric={metric}
+        email={email}
       />
     )
   );
@@ -19,6 +19,7 @@ const mocks = [
             fields: {
               method: "GET",
               url: "/",
+              protocol: "HTTP/1.1",
             },
             headers: {
               Accept: ["*/*"],
@@ -37,6 +38,18 @@ const mocks = [
             },
             created_at: "2021-07-09T13:41:27-10:00",
             message: "",
+            size: 0,
+            stream_id: 0,
+            trailers: null,
+            peer: null,
+            encoding: null,
+            signature: null,
+            fault: null,
+            sequence: null,
+            validation: null,
+            attachments: null,
+            metric: null,
+            email: null,
           },
         ],
       },
@@ -0,0 +1,227 @@
+import { useState, useEffect } from "react";
+import { useQuery, useMutation, gql } from "@apollo/client";
+
+export const RESPONSE = gql`
+  query GetResponse {
+    serverInfo {
+      response {
+        status_code
+        headers
+        body
+        delay
+        paused
+      }
+    }
+  }
+`;
+
+export const UPDATE_RESPONSE = gql`
+  mutation UpdateResponse($input: ResponseUpdate!) {
+    updateResponse(input: $input) {
+      status_code
+    }
+  }
+`;
+
+export const RESET_RESPONSE = gql`
+  mutation ResetResponse {
+    resetResponse {
+      status_code
+    }
+  }
+`;
+
+export const SET_PAUSED = gql`
+  mutation SetPaused($paused: Boolean!) {
+    setPaused(paused: $paused) {
+      paused
+    }
+  }
+`;
+
+// formatHeaders returns the headers as one "Name: value" line each.
+export function formatHeaders(headers) {
+  return Object.keys(headers || {})
+    .sort()
+    .map((name) => `${name}: ${headers[name]}`)
+    .join("\n");
+}
+
+// parseHeaders parses "Name: value" lines, skipping lines without a name.
+export function parseHeaders(text) {
+  const headers = {};
+  text.split("\n").forEach((line) => {
+    const i = line.indexOf(":");
+    if (i > 0 && line.slice(0, i).trim() !== "") {
+      headers[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+    }
+  });
+
+  return headers;
+}
+
+function ResponseControls() {
+  const { data, refetch } = useQuery(RESPONSE);
+  const [statusCode, setStatusCode] = useState("");
+  const [headers, setHeaders] = useState("");
+  const [body, setBody] = useState("");
+  const [delay, setDelay] = useState("");
+  const [error, setError] = useState("");
+
+  const options = {
+    onCompleted: () => {
+      setError("");
+      refetch();
+    },
+    onError: (e) => setError(e.message),
+  };
+  const [updateResponse] = useMutation(UPDATE_RESPONSE, options);
+  const [resetResponse] = useMutation(RESET_RESPONSE, options);
+  const [setPaused] = useMutation(SET_PAUSED, options);
+
+  const response = data && data.serverInfo && data.serverInfo.response;
+
+  useEffect(() => {
+    if (response) {
+      setStatusCode(String(response.status_code));
+      setHeaders(formatHeaders(response.headers));
+      setBody(response.body);
+      setDelay(response.delay);
+    }
+  }, [response]);
+
+  if (!response) {
+    return <div></div>;
+  }
+
+  const apply = () => {
+    updateResponse({
+      variables: {
+        input: {
+          status_code: parseInt(statusCode, 10),
+          headers: parseHeaders(headers),
+          body,
+          delay,
+        },
+      },
+    });
+  };
+
+  const inputClassName =
+    "w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out";
+  const textareaClassName =
+    "w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-24 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out";
+
+  return (
+    <section className="text-gray-600 bg-gray-100 body-font">
+      <div className="container px-5 pt-12 mx-auto">
+        <div className="flex items-center justify-between">
+          <h1 className="sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900">
+            Response
+            {response.paused && (
+              <span className="ml-2 align-middle text-xs font-medium py-1 px-2 rounded bg-red-500 text-white">
+                PAUSED
+              </span>
+            )}
+          </h1>
+          <button
+            onClick={() =>
+              setPaused({ variables: { paused: !response.paused } })
+            }
+            className="text-white bg-red-500 border-0 py-1 px-4 focus:outline-none hover:bg-red-600 rounded text-sm"
+          >
+            {response.paused ? "Resume" : "Pause"}
+          </button>
+        </div>
+        <div className="h-1 w-1/6 bg-indigo-500 rounded mb-4"></div>
+        <div className="shadow bg-white rounded-md py-4 px-4">
+          <div className="flex flex-wrap mb-4">
+            <div className="md:pr-1 md:w-1/2 w-full">
+              <label
+                htmlFor="status_code"
+                className="tracking-midwest text-xs text-gray-400"
+              >
+                STATUS CODE
+              </label>
+              <input
+                type="number"
+                id="status_code"
+                name="status_code"
+                className={inputClassName}
+                value={statusCode}
+                onChange={(e) => setStatusCode(e.target.value)}
+              />
+            </div>
+            <div className="md:pl-1 md:w-1/2 w-full">
+              <label
+                htmlFor="delay"
+                className="tracking-midwest text-xs text-gray-400"
+              >
+                DELAY
+              </label>
+              <input
+                type="text"
+                id="delay"
+                name="delay"
+                placeholder="100ms-2s"
+                className={inputClassName}
+                value={delay}
+                onChange={(e) => setDelay(e.target.value)}
+              />
+            </div>
+          </div>
+          <div className="flex flex-wrap mb-4">
+            <div className="md:pr-1 md:w-1/2 w-full">
+              <label
+                htmlFor="headers"
+                className="tracking-midwest text-xs text-gray-400"
+              >
+                HEADERS
+              </label>
+              <textarea
+                id="headers"
+                name="headers"
+                placeholder="Retry-After: 30"
+                className={textareaClassName}
+                value={headers}
+                onChange={(e) => setHeaders(e.target.value)}
+              />
+            </div>
+            <div className="md:pl-1 md:w-1/2 w-full">
+              <label
+                htmlFor="body"
+                className="tracking-midwest text-xs text-gray-400"
+              >
+                BODY
+              </label>
+              <textarea
+                id="body"
+                name="body"
+                className={textareaClassName}
+                value={body}
+                onChange={(e) => setBody(e.target.value)}
+              />
+            </div>
+          </div>
+          <button
+            onClick={() => apply()}
+            className="mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base"
+          >
+            Apply
+          </button>
+          <button
+            onClick={() => resetResponse()}
+            className="text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base"
+          >
+            Reset
+          </button>
+          {error && (
+            <span className="ml-2 text-sm text-red-500">{error}</span>
+          )}
+        </div>
+      </div>
+    </section>
+  );
+}
+
+export default ResponseControls;
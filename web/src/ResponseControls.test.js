@@ -0,0 +1,98 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import { MockedProvider } from "@apollo/client/testing";
+import ResponseControls, {
+  RESPONSE,
+  SET_PAUSED,
+  formatHeaders,
+  parseHeaders,
+} from "./ResponseControls";
+
+const response = (settings) => ({
+  request: {
+    query: RESPONSE,
+  },
+  result: {
+    data: {
+      serverInfo: {
+        response: settings,
+      },
+    },
+  },
+});
+
+const settings = {
+  status_code: 503,
+  headers: { "Retry-After": "30" },
+  body: "failing",
+  delay: "2s",
+  paused: false,
+};
+
+describe("ResponseControls", () => {
+  test("renders the default response", async () => {
+    render(
+      <MockedProvider mocks={[response(settings)]} addTypename={false}>
+        <ResponseControls />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByLabelText(/status code/i)).toHaveValue(503)
+    );
+    expect(screen.getByLabelText(/headers/i)).toHaveValue("Retry-After: 30");
+    expect(screen.getByLabelText(/body/i)).toHaveValue("failing");
+    expect(screen.getByLabelText(/delay/i)).toHaveValue("2s");
+    expect(screen.getByRole("button", { name: "Apply" })).toBeInTheDocument();
+    expect(screen.getByRole("button", { name: "Reset" })).toBeInTheDocument();
+    expect(screen.queryByText("PAUSED")).not.toBeInTheDocument();
+  });
+
+  test("pauses the endpoint", async () => {
+    const mocks = [
+      response(settings),
+      {
+        request: { query: SET_PAUSED, variables: { paused: true } },
+        result: { data: { setPaused: { paused: true } } },
+      },
+      response({ ...settings, paused: true }),
+    ];
+
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <ResponseControls />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByRole("button", { name: "Pause" })).toBeInTheDocument()
+    );
+    screen.getByRole("button", { name: "Pause" }).click();
+
+    await waitFor(() =>
+      expect(screen.getByText("PAUSED")).toBeInTheDocument()
+    );
+    expect(screen.getByRole("button", { name: "Resume" })).toBeInTheDocument();
+  });
+
+  test("renders nothing without a default response", async () => {
+    render(
+      <MockedProvider mocks={[response(null)]} addTypename={false}>
+        <ResponseControls />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.queryByText("Response")).not.toBeInTheDocument()
+    );
+  });
+
+  test("formats and parses headers", () => {
+    const headers = { "X-B": "2", "X-A": "1" };
+
+    expect(formatHeaders(headers)).toBe("X-A: 1\nX-B: 2");
+    expect(parseHeaders("X-A: 1\n: skipped\nX-B: a:b\n")).toEqual({
+      "X-A": "1",
+      "X-B": "a:b",
+    });
+  });
+});
@@ -0,0 +1,106 @@
+import { useState } from "react";
+import { useMutation, gql } from "@apollo/client";
+
+export const SEND_EVENT = gql`
+  mutation SendEvent($input: SseEvent!) {
+    sendEvent(input: $input)
+  }
+`;
+
+function SendEvent(props) {
+  const [event, setEvent] = useState("");
+  const [id, setId] = useState("");
+  const [data, setData] = useState(JSON.stringify({ hello: "world" }));
+  const [sendEvent, { data: result }] = useMutation(SEND_EVENT);
+
+  const send = () => {
+    sendEvent({ variables: { input: { event, id, data } } });
+  };
+
+  if (!props.visible) {
+    return <div></div>;
+  } else {
+    return (
+      <section className="text-gray-600 bg-gray-100 body-font h-full">
+        <div className="container p-5 mx-auto max-w-2xl">
+          <div className="bg-white rounded shadow py-4 px-4">
+            <h2 className="text-gray-900 text-lg mb-1 font-medium title-font">
+              Send an Event
+            </h2>
+            <div className="flex flex-wrap mb-4">
+              <div className="md:pr-1 md:w-4/6 sm:w-1/2 w-full">
+                <label
+                  htmlFor="event"
+                  className="tracking-midwest text-xs text-gray-400"
+                >
+                  EVENT
+                </label>
+                <input
+                  type="text"
+                  id="event"
+                  name="event"
+                  placeholder="message"
+                  className="w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out"
+                  value={event}
+                  onChange={(e) => setEvent(e.target.value)}
+                />
+              </div>
+              <div className="md:pl-1 md:w-2/6 sm:w-1/2 w-full">
+                <label
+                  htmlFor="id"
+                  className="tracking-midwest text-xs text-gray-400"
+                >
+                  ID
+                </label>
+                <input
+                  type="text"
+                  id="id"
+                  name="id"
+                  placeholder="auto"
+                  className="w-full rounded border border-gray-300 focus:border-red-500 focus:bg-white focus:ring-2 focus:ring-red-200 text-base outline-none text-gray-700 py-1 px-3 leading-8 transition-colors duration-200 ease-in-out"
+                  value={id}
+                  onChange={(e) => setId(e.target.value)}
+                />
+              </div>
+            </div>
+            <div className="relative mb-4">
+              <label
+                htmlFor="data"
+                className="tracking-midwest text-xs text-gray-400"
+              >
+                DATA
+              </label>
+              <textarea
+                id="data"
+                name="data"
+                className="w-full bg-white rounded border border-gray-300 focus:border-red-500 focus:ring-2 focus:ring-red-200 h-32 text-base outline-none text-gray-700 py-1 px-3 resize-none leading-6 transition-colors duration-200 ease-in-out"
+                onChange={(e) => setData(e.target.value)}
+                value={data}
+              />
+            </div>
+            <button
+              onClick={() => send()}
+              className="mr-2 text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base"
+            >
+              Send Event
+            </button>
+            <button
+              onClick={props.close}
+              className="text-white bg-red-500 border-0 py-2 px-6 focus:outline-none hover:bg-red-600 rounded text-base"
+            >
+              Close
+            </button>
+            {result && (
+              <span className="ml-2 text-sm text-gray-400">
+                Sent to {result.sendEvent} client
+                {result.sendEvent !== 1 ? "s" : ""}
+              </span>
+            )}
+          </div>
+        </div>
+      </section>
+    );
+  }
+}
+
+export default SendEvent;
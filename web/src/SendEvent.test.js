@@ -0,0 +1,63 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import userEvent from "@testing-library/user-event";
+import { MockedProvider } from "@apollo/client/testing";
+import SendEvent, { SEND_EVENT } from "./SendEvent";
+
+const mocks = [
+  {
+    request: {
+      query: SEND_EVENT,
+      variables: {
+        input: { event: "update", id: "", data: '{"hello":"world"}' },
+      },
+    },
+    result: {
+      data: {
+        sendEvent: 2,
+      },
+    },
+  },
+];
+
+describe("SendEvent", () => {
+  test("has form fields", () => {
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <SendEvent visible={true} />
+      </MockedProvider>
+    );
+
+    expect(screen.getByLabelText(/event/i)).toBeInTheDocument();
+    expect(screen.getByLabelText(/id/i)).toBeInTheDocument();
+    expect(screen.getByLabelText(/data/i)).toBeInTheDocument();
+    expect(
+      screen.getByRole("button", { name: "Send Event" })
+    ).toBeInTheDocument();
+    expect(screen.getByRole("button", { name: "Close" })).toBeInTheDocument();
+  });
+
+  test("visibility prop hides component", () => {
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <SendEvent visible={false} />
+      </MockedProvider>
+    );
+
+    expect(screen.queryAllByRole("button")).toHaveLength(0);
+  });
+
+  test("shows how many clients received the event", async () => {
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <SendEvent visible={true} />
+      </MockedProvider>
+    );
+
+    userEvent.type(screen.getByLabelText(/event/i), "update");
+    screen.getByRole("button", { name: "Send Event" }).click();
+
+    await waitFor(() =>
+      expect(screen.getByText(/Sent to 2 clients/i)).toBeInTheDocument()
+    );
+  });
+});
@@ -0,0 +1,91 @@
+import { useQuery, useMutation, gql } from "@apollo/client";
+
+export const SEQUENCES = gql`
+  query GetSequences {
+    sequences {
+      route
+      calls
+      next
+      length
+      repeat
+    }
+  }
+`;
+
+export const RESET_SEQUENCE = gql`
+  mutation ResetSequence($route: String) {
+    resetSequence(route: $route)
+  }
+`;
+
+function Sequences() {
+  const { data, refetch } = useQuery(SEQUENCES, { pollInterval: 2000 });
+  const [resetSequence] = useMutation(RESET_SEQUENCE, {
+    onCompleted: () => refetch(),
+  });
+
+  if (!data || data.sequences.length === 0) {
+    return <div></div>;
+  }
+
+  const reset = (route) => {
+    resetSequence({ variables: { route } });
+  };
+
+  return (
+    <section className="text-gray-600 bg-gray-100 body-font">
+      <div className="container px-5 pt-12 mx-auto">
+        <div className="flex items-center justify-between">
+          <h1 className="sm:text-2xl text-xl font-medium title-font mb-2 text-gray-900">
+            Response Sequences
+          </h1>
+          <button
+            onClick={() => reset(null)}
+            className="text-white bg-red-500 border-0 py-1 px-4 focus:outline-none hover:bg-red-600 rounded text-sm"
+          >
+            Reset All
+          </button>
+        </div>
+        <div className="h-1 w-1/6 bg-indigo-500 rounded mb-4"></div>
+        <div className="shadow bg-white rounded-md py-4 px-4 overflow-x-auto">
+          <table className="table-auto w-full text-left text-sm">
+            <thead>
+              <tr className="tracking-midwest text-xs text-gray-400">
+                <th className="py-2">ROUTE</th>
+                <th className="py-2 text-right">CALLS</th>
+                <th className="py-2 text-right">NEXT</th>
+                <th className="py-2">REPEAT</th>
+                <th className="py-2"></th>
+              </tr>
+            </thead>
+            <tbody>
+              {data.sequences.map((sequence) => (
+                <tr key={sequence.route} className="border-t border-gray-200">
+                  <td className="py-2 font-medium text-gray-800">
+                    {sequence.route}
+                  </td>
+                  <td className="py-2 text-right">{sequence.calls}</td>
+                  <td className="py-2 text-right">
+                    {sequence.next} of {sequence.length}
+                  </td>
+                  <td className="py-2">{sequence.repeat}</td>
+                  <td className="py-2 text-right">
+                    <button
+                      onClick={() => reset(sequence.route)}
+                      aria-label={`Reset ${sequence.route}`}
+                      className="text-red-500 hover:text-red-600"
+                    >
+                      Reset
+                    </button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      </div>
+    </section>
+  );
+}
+
+export default Sequences;
@@ -0,0 +1,83 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import { MockedProvider } from "@apollo/client/testing";
+import Sequences, { SEQUENCES, RESET_SEQUENCE } from "./Sequences";
+
+const sequences = (calls, next) => ({
+  request: {
+    query: SEQUENCES,
+  },
+  result: {
+    data: {
+      sequences: [
+        {
+          route: "POST /charge",
+          calls: calls,
+          next: next,
+          length: 3,
+          repeat: "last",
+        },
+      ],
+    },
+  },
+});
+
+describe("Sequences", () => {
+  test("renders response sequences", async () => {
+    render(
+      <MockedProvider mocks={[sequences(2, 3)]} addTypename={false}>
+        <Sequences />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByText("POST /charge")).toBeInTheDocument()
+    );
+    expect(screen.getByText("3 of 3")).toBeInTheDocument();
+    expect(screen.getByText("last")).toBeInTheDocument();
+    expect(
+      screen.getByRole("button", { name: "Reset All" })
+    ).toBeInTheDocument();
+  });
+
+  test("resets a sequence", async () => {
+    let reset = false;
+    const mocks = [
+      sequences(2, 3),
+      {
+        request: {
+          query: RESET_SEQUENCE,
+          variables: { route: "POST /charge" },
+        },
+        result: () => {
+          reset = true;
+          return { data: { resetSequence: 1 } };
+        },
+      },
+      sequences(0, 1),
+    ];
+
+    render(
+      <MockedProvider mocks={mocks} addTypename={false}>
+        <Sequences />
+      </MockedProvider>
+    );
+
+    await waitFor(() =>
+      expect(screen.getByText("POST /charge")).toBeInTheDocument()
+    );
+    screen.getByRole("button", { name: "Reset POST /charge" }).click();
+
+    await waitFor(() => expect(reset).toBe(true));
+    await waitFor(() => expect(screen.getByText("1 of 3")).toBeInTheDocument());
+  });
+
+  test("renders nothing without sequences", () => {
+    render(
+      <MockedProvider mocks={[]} addTypename={false}>
+        <Sequences />
+      </MockedProvider>
+    );
+
+    expect(screen.queryByText("Response Sequences")).not.toBeInTheDocument();
+  });
+});